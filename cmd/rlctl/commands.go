@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// usage descreve os subcomandos do rlctl.
+const usage = `uso: rlctl [opções] <comando> [identificador]
+
+comandos:
+  status <id>     mostra limite, contagem, infrações e bloqueio do identificador
+  block <id>      bloqueia o identificador (duração em -duration)
+  unblock <id>    remove o bloqueio do identificador
+  list-blocked    lista os identificadores bloqueados
+
+opções:
+`
+
+// errUsage indica argumentos inválidos; a ajuda já foi impressa.
+var errUsage = errors.New("argumentos inválidos")
+
+// options são as opções e o comando lidos da linha de comando.
+type options struct {
+	redisAddr  string
+	isToken    bool
+	duration   time.Duration
+	command    string
+	identifier string
+}
+
+// parseOptions lê as opções e o comando. Os identificadores são IPs, a não ser com -token.
+func parseOptions(args []string, stderr io.Writer) (*options, error) {
+	defaultAddr := os.Getenv("REDIS_ADDR")
+	if defaultAddr == "" {
+		defaultAddr = "localhost:6379"
+	}
+
+	opts := &options{}
+	fs := flag.NewFlagSet("rlctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.redisAddr, "redis", defaultAddr, "Endereço do Redis (padrão: REDIS_ADDR)")
+	fs.BoolVar(&opts.isToken, "token", false, "O identificador é um token (padrão: IP)")
+	fs.DurationVar(&opts.duration, "duration", 0, "Duração do bloqueio (0 usa BLOCK_DURATION_IP ou BLOCK_DURATION_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return nil, errUsage
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fs.Usage()
+		return nil, errUsage
+	}
+	opts.command = rest[0]
+	switch opts.command {
+	case "status", "block", "unblock":
+		if len(rest) != 2 || rest[1] == "" {
+			fmt.Fprintf(stderr, "o comando %s precisa de um identificador\n", opts.command)
+			return nil, errUsage
+		}
+		opts.identifier = rest[1]
+	case "list-blocked":
+		if len(rest) != 1 {
+			fmt.Fprintln(stderr, "o comando list-blocked não recebe argumentos")
+			return nil, errUsage
+		}
+	default:
+		fmt.Fprintf(stderr, "comando desconhecido: %s\n", opts.command)
+		fs.Usage()
+		return nil, errUsage
+	}
+	return opts, nil
+}
+
+// execute roda o comando contra o rate limiter e escreve o resultado em out.
+func execute(ctx context.Context, rl *rateLimiter.RateLimiter, opts *options, out io.Writer) error {
+	switch opts.command {
+	case "status":
+		status, err := rl.Status(ctx, opts.identifier, opts.isToken)
+		if err != nil {
+			return err
+		}
+		printStatus(out, status, time.Now())
+	case "block":
+		if err := rl.Block(ctx, opts.identifier, opts.isToken, opts.duration); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s %s bloqueado\n", scope(opts.isToken), opts.identifier)
+	case "unblock":
+		removed, err := rl.Unblock(ctx, opts.identifier, opts.isToken)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			fmt.Fprintf(out, "%s %s não estava bloqueado\n", scope(opts.isToken), opts.identifier)
+			return nil
+		}
+		fmt.Fprintf(out, "%s %s desbloqueado\n", scope(opts.isToken), opts.identifier)
+	case "list-blocked":
+		blocked, err := rl.ListBlocked(ctx)
+		if err != nil {
+			return err
+		}
+		for _, b := range blocked {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", scope(b.IsToken), b.Identifier, formatExpiry(b.Info.ExpiresAt), b.Info.Reason)
+		}
+	default:
+		return fmt.Errorf("comando desconhecido: %s", opts.command)
+	}
+	return nil
+}
+
+// printStatus escreve o estado do identificador, um campo por linha.
+func printStatus(out io.Writer, status *rateLimiter.IdentifierStatus, now time.Time) {
+	fmt.Fprintf(out, "identificador: %s (%s)\n", status.Identifier, scope(status.IsToken))
+	fmt.Fprintf(out, "limite: %d a cada %s\n", status.Limit, status.Window)
+	fmt.Fprintf(out, "contagem: %d\n", status.Count)
+	fmt.Fprintf(out, "infrações: %d\n", status.Offenses)
+	if status.Block == nil {
+		fmt.Fprintln(out, "bloqueado: não")
+		return
+	}
+	fmt.Fprintf(out, "bloqueado: sim, até %s", formatExpiry(status.Block.ExpiresAt))
+	if !status.Block.ExpiresAt.IsZero() {
+		fmt.Fprintf(out, " (restam %s)", status.Block.ExpiresAt.Sub(now).Round(time.Second))
+	}
+	if status.Block.Reason != "" {
+		fmt.Fprintf(out, ", motivo: %s", status.Block.Reason)
+	}
+	fmt.Fprintln(out)
+}
+
+// scope retorna o escopo do identificador como texto.
+func scope(isToken bool) string {
+	if isToken {
+		return "token"
+	}
+	return "ip"
+}
+
+// formatExpiry formata o fim de um bloqueio ("-" quando desconhecido).
+func formatExpiry(expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return "-"
+	}
+	return expiresAt.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/rateLimiter"
+)
+
+// newTestLimiter cria um rate limiter sobre um miniredis
+func newTestLimiter(t *testing.T) (*miniredis.Miniredis, *rateLimiter.RateLimiter) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 300,
+		KeyPrefix:                 "app:",
+	}
+	return mr, rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+}
+
+// runCommand lê os argumentos e executa o comando, retornando a saída
+func runCommand(t *testing.T, rl *rateLimiter.RateLimiter, args ...string) string {
+	opts, err := parseOptions(args, &bytes.Buffer{})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, execute(context.Background(), rl, opts, &out))
+	return out.String()
+}
+
+// Test_ParseOptions verifica a leitura das opções e a validação dos comandos
+func Test_ParseOptions(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "redis:6379")
+	opts, err := parseOptions([]string{"-token", "-duration", "10m", "block", "abc"}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, &options{redisAddr: "redis:6379", isToken: true, duration: 10 * time.Minute, command: "block", identifier: "abc"}, opts)
+
+	opts, err = parseOptions([]string{"-redis", "localhost:6380", "list-blocked"}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:6380", opts.redisAddr)
+
+	for _, args := range [][]string{
+		{},
+		{"status"},
+		{"unblock", "a", "b"},
+		{"list-blocked", "a"},
+		{"reset", "a"},
+		{"-duration", "dez", "block", "a"},
+	} {
+		var stderr bytes.Buffer
+		_, err := parseOptions(args, &stderr)
+		assert.ErrorIs(t, err, errUsage, "%v", args)
+		assert.NotEmpty(t, stderr.String(), "%v", args)
+	}
+}
+
+// Test_Commands verifica status, block, unblock e list-blocked contra o Redis
+func Test_Commands(t *testing.T) {
+	mr, rl := newTestLimiter(t)
+	ctx := context.Background()
+
+	// Duas requisições do IP e o bloqueio de outro pelo limite
+	for i := 0; i < 2; i++ {
+		_, err := rl.Allow(ctx, "10.0.0.1", false)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := rl.Allow(ctx, "10.0.0.2", false)
+		require.NoError(t, err)
+	}
+
+	out := runCommand(t, rl, "status", "10.0.0.1")
+	assert.Contains(t, out, "identificador: 10.0.0.1 (ip)\n")
+	assert.Contains(t, out, "limite: 2 a cada 1s\n")
+	assert.Contains(t, out, "contagem: 2\n")
+	assert.Contains(t, out, "bloqueado: não\n")
+
+	out = runCommand(t, rl, "status", "10.0.0.2")
+	assert.Contains(t, out, "infrações: 1\n")
+	assert.Contains(t, out, "bloqueado: sim")
+	assert.Contains(t, out, "motivo: rate_limit_exceeded")
+
+	// Bloqueio manual de um token, com a duração configurada
+	out = runCommand(t, rl, "-token", "block", "abc")
+	assert.Equal(t, "token abc bloqueado\n", out)
+	assert.Equal(t, 300*time.Second, mr.TTL("app:blocked_token_abc"))
+	allowed, err := rl.Allow(ctx, "abc", true)
+	require.NoError(t, err)
+	assert.False(t, allowed, "O token bloqueado manualmente deveria ser rejeitado")
+	out = runCommand(t, rl, "-token", "status", "abc")
+	assert.Contains(t, out, "motivo: manual")
+
+	runCommand(t, rl, "-duration", "2h", "block", "10.0.0.3")
+	assert.Equal(t, 2*time.Hour, mr.TTL("app:blocked_ip_10.0.0.3"))
+
+	out = runCommand(t, rl, "list-blocked")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "ip\t10.0.0.2\t"), "Ordenados pelo fim do bloqueio: %q", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "token\tabc\t"), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "ip\t10.0.0.3\t"), lines[2])
+	assert.True(t, strings.HasSuffix(lines[2], "\tmanual"), lines[2])
+
+	out = runCommand(t, rl, "-token", "unblock", "abc")
+	assert.Equal(t, "token abc desbloqueado\n", out)
+	assert.False(t, mr.Exists("app:blocked_token_abc"))
+	out = runCommand(t, rl, "-token", "unblock", "abc")
+	assert.Equal(t, "token abc não estava bloqueado\n", out)
+
+	out = runCommand(t, rl, "unblock", "10.0.0.2")
+	assert.Equal(t, "ip 10.0.0.2 desbloqueado\n", out)
+	assert.True(t, mr.Exists("app:offenses_ip_10.0.0.2"), "O desbloqueio preserva as infrações")
+}
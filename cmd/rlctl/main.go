@@ -0,0 +1,52 @@
+// rlctl inspeciona e administra o estado do rate limiter em um Redis, com as mesmas chaves
+// e a mesma configuração (variáveis de ambiente ou .env) do servidor.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/rateLimiter"
+)
+
+func main() {
+	opts, err := parseOptions(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := run(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run conecta ao Redis, monta o rate limiter com a configuração do ambiente e executa o comando.
+func run(opts *options) error {
+	cfg, err := config.LoadConfigRateLimiter()
+	if err != nil {
+		return fmt.Errorf("erro ao carregar configuração: %w", err)
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: opts.redisAddr})
+	defer rdb.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("não foi possível conectar ao Redis em %s: %w", opts.redisAddr, err)
+	}
+
+	// Os limites por token do Redis valem também na inspeção
+	var resolver db.LimitResolver = rateLimiter.NewStaticLimitResolver(cfg)
+	if cfg.TokenLimitsHash != "" {
+		resolver = redisStore.NewRedisLimitResolver(rdb, cfg.TokenLimitsHash, resolver)
+	}
+	rl := rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(rdb), rateLimiter.WithLimitResolver(resolver))
+	return execute(ctx, rl, opts, os.Stdout)
+}
@@ -2,12 +2,190 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Modos de falha aplicados quando o store retorna erro.
+const (
+	// FailureModeClosed propaga o erro do store e a requisição não é atendida.
+	FailureModeClosed = "closed"
+	// FailureModeOpen permite a requisição quando o store está indisponível.
+	FailureModeOpen = "open"
+)
+
+// Algoritmos de contagem disponíveis.
+const (
+	// AlgorithmFixedWindow conta as requisições em janelas fixas iniciadas na primeira requisição.
+	AlgorithmFixedWindow = "fixed_window"
+	// AlgorithmSlidingWindow usa a aproximação de janela deslizante com dois buckets.
+	AlgorithmSlidingWindow = "sliding_window"
+	// AlgorithmCalendarWindow conta cotas que renovam na virada do dia ou do mês.
+	AlgorithmCalendarWindow = "calendar_window"
+	// AlgorithmLeakyBucket suaviza a vazão: as requisições entram em uma fila virtual que vaza
+	// a um ritmo fixo e são descartadas, sem bloqueio, quando ela está cheia.
+	AlgorithmLeakyBucket = "leaky_bucket"
+)
+
+// Fontes do horário usado pela janela deslizante.
+const (
+	// TimeSourceServer usa o horário do Redis, o mesmo para todas as instâncias.
+	TimeSourceServer = "server"
+	// TimeSourceClient usa o relógio de cada instância da aplicação.
+	TimeSourceClient = "client"
+)
+
+// Períodos das cotas de calendário.
+const (
+	CalendarPeriodDaily   = "daily"
+	CalendarPeriodMonthly = "monthly"
+)
+
+// Classes de requisição usadas quando leituras e escritas têm contadores separados.
+const (
+	ClassRead  = "read"
+	ClassWrite = "write"
+	// ClassUnknown é a classe das requisições sem token e sem IP resolvível (ex.: unix sockets).
+	ClassUnknown = "unknown"
+	// ClassPreflight é a classe das requisições OPTIONS com PreflightSeparate.
+	ClassPreflight = "preflight"
+)
+
+// Tratamento das requisições OPTIONS, como o preflight do CORS.
+const (
+	// PreflightCount conta as requisições OPTIONS como as demais (padrão).
+	PreflightCount = "count"
+	// PreflightSkip libera as requisições OPTIONS sem contabilizá-las.
+	PreflightSkip = "skip"
+	// PreflightSeparate conta as requisições OPTIONS em um contador próprio, com os limites da
+	// classe "preflight" (PREFLIGHT_MAX_REQUESTS_PER_IP e PREFLIGHT_MAX_REQUESTS_PER_TOKEN).
+	PreflightSeparate = "separate"
+	// PreflightCoalesce conta o preflight e não conta a requisição real que o segue, da mesma
+	// origem e com o método pedido, dentro de PREFLIGHT_COALESCE_WINDOW.
+	PreflightCoalesce = "coalesce"
+)
+
+// Tratamento das requisições com o header do token presente, mas vazio.
+const (
+	// EmptyTokenFallbackIP trata o header vazio como ausente: a requisição é contada pelo IP (padrão).
+	EmptyTokenFallbackIP = "fallback_ip"
+	// EmptyTokenReject rejeita a requisição com 400.
+	EmptyTokenReject = "reject"
+	// EmptyTokenAnonymous conta todas essas requisições em um único token anônimo, com os
+	// limites por token.
+	EmptyTokenAnonymous = "treat_as_anonymous_token"
+)
+
+// Reconciliação das contagens do fallback em memória quando o Redis volta a responder.
+const (
+	// ReconcilePrimary mantém as contagens do Redis e descarta as do fallback (padrão).
+	ReconcilePrimary = "primary"
+	// ReconcileMax fica com a maior das duas contagens de cada chave.
+	ReconcileMax = "max"
+	// ReconcileSumCapped soma as duas contagens, limitada ao limite geral do escopo.
+	ReconcileSumCapped = "sum-capped"
+)
+
+// Formatos do corpo das respostas rejeitadas.
+const (
+	// RejectionFormatText envia o corpo em texto puro, a partir de REJECTION_BODY_TEMPLATE (padrão).
+	RejectionFormatText = "text"
+	// RejectionFormatProblemJSON envia um documento application/problem+json (RFC 7807).
+	RejectionFormatProblemJSON = "problem_json"
+)
+
+// Componentes que identificam um cliente sem token.
+const (
+	KeyComponentsIP             = "ip"
+	KeyComponentsUserAgent      = "user_agent"
+	KeyComponentsIPAndUserAgent = "ip_user_agent"
+)
+
+// Esquemas de headers de rate limit enviados nas respostas.
+const (
+	HeaderSchemeXRateLimit = "x-ratelimit"
+	HeaderSchemeDraft      = "draft"
+	HeaderSchemeBoth       = "both"
+)
+
+// Estratégias de escolha do IP do cliente no X-Forwarded-For.
+const (
+	// XForwardedForLeftmost usa o endereço mais à esquerda, o cliente original informado pelo
+	// primeiro proxy. Pode ser forjado pelo cliente; só é seguro se o primeiro proxy confiável
+	// descartar o header recebido.
+	XForwardedForLeftmost = "leftmost"
+	// XForwardedForRightmostUntrusted usa o primeiro endereço não confiável lido da direita para
+	// a esquerda, o cliente que se conectou ao proxy confiável mais externo.
+	XForwardedForRightmostUntrusted = "rightmost_untrusted"
+)
+
+// Reações da proteção de cardinalidade quando surgem identificadores novos demais na janela.
+const (
+	// CardinalitySubnet conta os IPs novos pela sub-rede (/24 no IPv4, /64 no IPv6) e rejeita
+	// os tokens novos, que não têm agrupamento equivalente.
+	CardinalitySubnet = "subnet"
+	// CardinalityReject rejeita todos os identificadores novos.
+	CardinalityReject = "reject"
+)
+
+// ClassLimit são os limites de uma classe de requisição. Valores zero usam os limites gerais.
+type ClassLimit struct {
+	MaxRequestsPerIP    int
+	MaxRequestsPerToken int
+}
+
+// BasicAuthSource é o nome, em TOKEN_SOURCES, da fonte que usa o usuário do header
+// Authorization com o esquema Basic como token.
+const BasicAuthSource = "basic"
+
+// TokenSource é um header de onde o token pode ser lido, associado opcionalmente a uma faixa
+// de limites (Tier vazio usa os limites gerais dos tokens). Com BasicAuth, o token é o usuário
+// decodificado do header Authorization: Basic, e a senha é ignorada.
+type TokenSource struct {
+	Header    string
+	Tier      string
+	BasicAuth bool
+}
+
+// TokenTier são os limites de uma faixa de tokens. Valores zero usam os limites gerais.
+type TokenTier struct {
+	MaxRequests          int
+	WindowSeconds        int
+	BlockDurationSeconds int
+}
+
+// RouteLimit é o sub-limite de uma rota cara: além do próprio limite, cada token (ou IP) tem uma
+// cota separada para os caminhos sob PathPrefix. Valores zero usam os limites gerais do escopo.
+type RouteLimit struct {
+	Name                 string
+	PathPrefix           string
+	MaxRequests          int
+	WindowSeconds        int
+	BlockDurationSeconds int
+}
+
+// BlockSeverityTier é uma faixa de severidade do bloqueio: com MinRatio vezes o limite de
+// requisições na janela, o bloqueio dura pelo menos Duration.
+type BlockSeverityTier struct {
+	MinRatio float64
+	Duration time.Duration
+}
+
+// ScheduleRange é uma faixa do dia em que os limites são multiplicados por Multiplier. Start e
+// End são o tempo desde a meia-noite; com End menor ou igual a Start, a faixa vira a meia-noite.
+type ScheduleRange struct {
+	Start      time.Duration
+	End        time.Duration
+	Multiplier float64
+}
+
 // LimiterConfig armazena as configurações do rate limiter.
 type LimiterConfig struct {
 	MaxRequestsPerIP          int
@@ -15,6 +193,253 @@ type LimiterConfig struct {
 	BlockDurationIPSeconds    int
 	BlockDurationTokenSeconds int
 	TokenHeaderName           string
+	// WindowIPSeconds e WindowTokenSeconds são as janelas de contagem dos limites da
+	// configuração (0 usa 1 segundo).
+	WindowIPSeconds    int
+	WindowTokenSeconds int
+	// KeyPrefix é prefixado a todas as chaves no store, separando instâncias que dividem o mesmo Redis.
+	KeyPrefix string
+	// IPKeyPrefix e TokenKeyPrefix vêm depois de KeyPrefix nas chaves de cada escopo (contador,
+	// bloqueio, infrações etc.), para aplicar ACLs ou políticas diferentes por escopo no Redis.
+	IPKeyPrefix    string
+	TokenKeyPrefix string
+	// RejectionBodyTemplate é o modelo do corpo das respostas 429 (vazio usa a mensagem padrão).
+	RejectionBodyTemplate string
+	// RejectionFormat é o formato do corpo das respostas rejeitadas: "text" (padrão) ou
+	// "problem_json" (RFC 7807, com o modelo como detail).
+	RejectionFormat string
+	// Disabled desliga o rate limiting (chave de emergência). Vem de RATE_LIMITER_ENABLED=false;
+	// o valor zero mantém o limitador ligado.
+	Disabled bool
+	// FairShareTokensPerIP divide o limite do IP em cotas por token quando vários tokens
+	// compartilham o mesmo IP, evitando que um token guloso esgote o limite dos demais.
+	FairShareTokensPerIP bool
+	// Algorithm escolhe o algoritmo de contagem (padrão: fixed_window).
+	Algorithm string
+	// SlidingWindowTimeSource é a fonte do horário da janela deslizante e do leaky bucket:
+	// "server" (padrão, horário do Redis) ou "client" (relógio da instância).
+	SlidingWindowTimeSource string
+	// StrictLimits faz a janela deslizante verificar o bloqueio, contar e bloquear em uma única
+	// operação atômica, sem admissões além do limite sob concorrência. A janela fixa e as cotas
+	// de calendário já funcionam assim.
+	StrictLimits bool
+	// KeepCounterOnBlock mantém o contador de requisições ao bloquear. Vem de
+	// RESET_COUNTER_ON_BLOCK=false; o valor zero zera o contador, como sempre foi. Mantido,
+	// um bloqueio mais curto que a janela termina com a cota ainda esgotada.
+	KeepCounterOnBlock bool
+	// GraceOverage é quantas requisições além do limite recebem só o 429, sem bloqueio nem
+	// infração, até o fim da janela; a seguinte gera o bloqueio completo. Vale para a janela
+	// fixa e as cotas de calendário.
+	GraceOverage int
+	// BlockSeverity gradua a duração do bloqueio pelo excesso, ordenadas por MinRatio: quem
+	// passa pouco do limite recebe o bloqueio configurado, e quem chega a MinRatio vezes o
+	// limite na janela (contando as requisições rejeitadas no bloqueio), o da faixa. Vale
+	// para a janela fixa e as cotas de calendário.
+	BlockSeverity []BlockSeverityTier
+	// AlignWindows alinha as janelas fixas ao relógio (ex.: o início de cada minuto), em vez de
+	// iniciá-las na primeira requisição: as janelas de todos os clientes e instâncias viram juntas.
+	AlignWindows bool
+	// LeakyBucketCapacity é a profundidade da fila virtual do leaky bucket (0 usa o próprio
+	// limite). O bucket vaza o limite a cada janela; capacidades menores suavizam as rajadas.
+	LeakyBucketCapacity int
+	// DebugLogging registra, em nível debug, as chaves, a contagem, o limite e a decisão de
+	// cada requisição. Gera um registro por requisição: não use em produção.
+	DebugLogging bool
+	// CalendarPeriod é o período das cotas de calendário: "daily" (padrão) ou "monthly".
+	CalendarPeriod string
+	// CalendarLocation é o fuso horário em que o período vira (nil usa UTC).
+	CalendarLocation *time.Location
+	// MaxBytesPerWindow é a cota de bytes de resposta por identificador (0 desliga). Ao ser
+	// excedida, o identificador é bloqueado a partir da próxima requisição.
+	MaxBytesPerWindow      int64
+	BandwidthWindowSeconds int
+	// BoostMultiplier multiplica todos os limites até BoostUntil (0 desliga).
+	BoostMultiplier float64
+	BoostUntil      time.Time
+	// LimitSchedule multiplica os limites conforme o horário, no fuso ScheduleLocation (nil
+	// usa UTC). Vale a primeira faixa que contém o horário; fora delas, os limites não mudam.
+	LimitSchedule    []ScheduleRange
+	ScheduleLocation *time.Location
+	// FailureMode define o que acontece quando o store falha: "closed" (padrão) ou "open".
+	FailureMode string
+	// GlobalLimit é o teto de requisições por janela somando todo o tráfego (0 desliga).
+	GlobalLimit         int
+	GlobalWindowSeconds int
+	// GlobalLimitStatus é o status HTTP devolvido quando o limite global é atingido (429 ou 503).
+	GlobalLimitStatus int
+	// BlockStatusCode é o status HTTP das respostas rejeitadas pelo limite (4xx ou 5xx, padrão 429).
+	BlockStatusCode int
+	// StoreErrorStatus é o status HTTP devolvido quando o store falha no modo fechado (503 ou 500).
+	StoreErrorStatus            int
+	StoreErrorRetryAfterSeconds int
+	// RedisRetryMaxAttempts é o total de tentativas das operações do store diante de erros
+	// transitórios do Redis (1 desliga as repetições), com espera inicial de RedisRetryBackoffMs,
+	// dobrada a cada tentativa, mais a fração aleatória RedisRetryJitter (0 a 1).
+	RedisRetryMaxAttempts int
+	RedisRetryBackoffMs   int
+	RedisRetryJitter      float64
+	// CircuitBreakerThreshold é o número de erros consecutivos do store que abre o circuito (0 desliga).
+	CircuitBreakerThreshold       int
+	CircuitBreakerCooldownSeconds int
+	// TokenLimitsHash é o hash do Redis com limites por token (vazio desliga os limites dinâmicos).
+	TokenLimitsHash      string
+	LimitCacheTTLSeconds int
+	LimitCacheMaxSize    int
+	// MaxIdentifierLength é o tamanho máximo de um token antes de ser trocado pelo hash (0 desliga).
+	MaxIdentifierLength int
+	// RejectLongIdentifiers rejeita com 400 os tokens acima de MaxIdentifierLength.
+	RejectLongIdentifiers bool
+	// EmptyTokenPolicy define o tratamento do header do token presente, mas vazio:
+	// "fallback_ip" (padrão), "reject" ou "treat_as_anonymous_token".
+	EmptyTokenPolicy string
+	// KeyComponents define o que identifica um cliente sem token: "ip" (padrão), "user_agent"
+	// ou "ip_user_agent" (a requisição precisa caber nos contadores do IP e do User-Agent).
+	KeyComponents string
+	// PathKeyPattern é uma expressão regular aplicada ao caminho da requisição; o primeiro grupo
+	// de captura (ex.: o ID em ^/users/([^/]+)/posts) entra na chave do contador, que passa a
+	// ser por cliente e recurso (vazio desliga).
+	PathKeyPattern string
+	// RouteCaseInsensitive e RouteIgnoreTrailingSlash normalizam o caminho antes de casar com as
+	// regras por rota (como PathKeyPattern): /Login, /login e /login/ casam da mesma forma.
+	RouteCaseInsensitive     bool
+	RouteIgnoreTrailingSlash bool
+	// UnknownBucket conta as requisições sem token e sem IP resolvível em um único contador
+	// global, com os limites da classe "unknown", em vez de responder com erro.
+	UnknownBucket bool
+	// SplitReadWrite separa os contadores de leitura (ReadMethods) e escrita (demais métodos).
+	SplitReadWrite bool
+	ReadMethods    []string
+	// PreflightPolicy define como as requisições OPTIONS são contadas: "count" (padrão),
+	// "skip", "separate" ou "coalesce".
+	PreflightPolicy string
+	// ClassLimits são os limites específicos de cada classe de requisição.
+	ClassLimits map[string]ClassLimit
+	// TrustedProxies são os proxies (IPs ou CIDRs) cujo X-Forwarded-For é considerado.
+	TrustedProxies []string
+	// XForwardedForSelect escolhe o endereço do X-Forwarded-For usado como IP do cliente:
+	// "rightmost_untrusted" (padrão) ou "leftmost".
+	XForwardedForSelect string
+	// SkipPrivateNetworks libera clientes em redes privadas ou de loopback.
+	SkipPrivateNetworks bool
+	// CardinalityMaxNew é quantos identificadores novos podem surgir a cada
+	// CardinalityWindowSeconds (0 desliga a proteção). Acima disso, os identificadores novos
+	// recebem CardinalityFallback: "subnet" (padrão) ou "reject". Os já conhecidos não mudam.
+	CardinalityMaxNew        int
+	CardinalityWindowSeconds int
+	CardinalityFallback      string
+	// HeaderScheme define os headers de rate limit enviados: "x-ratelimit" (padrão), "draft"
+	// (RateLimit-* do draft da IETF) ou "both".
+	HeaderScheme string
+	// RemainingDecimals são as casas decimais de X-RateLimit-Remaining (0, o padrão, mantém o
+	// valor inteiro). No leaky bucket e na janela deslizante, a cota restante tem fração.
+	RemainingDecimals int
+	// TarpitDelayMs atrasa as respostas 429 para desacelerar clientes abusivos (0 desliga).
+	TarpitDelayMs int
+	// IdempotencyKeyHeader é o header com a chave de idempotência (vazio desliga a proteção).
+	IdempotencyKeyHeader  string
+	IdempotencyTTLSeconds int
+	// UtilizationTopN é quantos identificadores mais ocupados têm a utilização exposta em /metrics (0 desliga).
+	UtilizationTopN                int
+	UtilizationScanIntervalSeconds int
+	// RedisPoolStatsIntervalSeconds é o intervalo da publicação das estatísticas do pool de
+	// conexões do Redis em /metrics (0 desliga).
+	RedisPoolStatsIntervalSeconds int
+	// PreloadTokens são tokens cujos limites são carregados no cache durante a inicialização.
+	PreloadTokens []string
+	// MemoryFallback atende as requisições com um store em memória enquanto o circuit breaker
+	// está aberto, semeado com as últimas contagens do Redis (precisa de CircuitBreakerThreshold).
+	MemoryFallback bool
+	// FallbackSeedIntervalSeconds é o intervalo entre as cópias das contagens do Redis
+	// usadas para semear o fallback.
+	FallbackSeedIntervalSeconds int
+	// MaxBlockDurationSeconds é o teto das durações de bloqueio configuradas (incluindo as faixas
+	// de severidade e os limites por token), contra erros de configuração (0 desliga).
+	MaxBlockDurationSeconds int
+	// IPHashSecret é o segredo do HMAC aplicado aos IPs antes de formar as chaves, para que o
+	// Redis não guarde IPs (vazio usa os IPs). Trocá-lo zera os contadores e bloqueios dos IPs.
+	IPHashSecret string
+	// WarmupMultiplier multiplica os limites logo após a inicialização, caindo linearmente até os
+	// valores configurados ao fim de WarmupSeconds (1 ou 0 desliga).
+	WarmupMultiplier float64
+	WarmupSeconds    int
+	// AuditStream é o stream do Redis que recebe cada bloqueio (XADD) para auditoria, com o
+	// identificador em hash, o escopo e o horário (vazio desliga).
+	AuditStream string
+	// TokenTrimSpace e TokenCaseInsensitive normalizam o token (sem os espaços nas pontas e em
+	// minúsculas) antes de formar as chaves; IPUnmapIPv4 converte ::ffff:1.2.3.4 para 1.2.3.4.
+	TokenTrimSpace       bool
+	TokenCaseInsensitive bool
+	IPUnmapIPv4          bool
+	// ConsecutiveViolationThreshold é quantas requisições consecutivas além do limite o cliente
+	// precisa fazer para receber o 429 e o bloqueio; as anteriores seguem sem cota, para tolerar
+	// rajadas acidentais (0 ou 1 rejeita já a primeira). Vale para a janela fixa e as cotas de
+	// calendário.
+	ConsecutiveViolationThreshold int
+	// OverheadHeader envia em X-RateLimit-Overhead os microssegundos gastos no limitador em
+	// cada requisição, para medir o custo em produção. Só para diagnóstico.
+	OverheadHeader bool
+	// MaxUploadBytesPerWindow é a cota de bytes lidos do corpo das requisições por identificador,
+	// na janela de BandwidthWindowSeconds (0 desliga). O upload que a excede é interrompido.
+	MaxUploadBytesPerWindow int64
+	// TokenSources são os headers de onde o token é lido, na ordem de preferência: vale o
+	// primeiro presente na requisição, com os limites da sua faixa em TokenTiers. Vazio usa
+	// apenas TokenHeaderName.
+	TokenSources []TokenSource
+	TokenTiers   map[string]TokenTier
+	// RedisConnectMaxAttempts e RedisConnectMaxWaitSeconds limitam a espera pelo Redis na
+	// inicialização (0 não limita; sem nenhum dos dois, uma única tentativa), com espera inicial
+	// de RedisConnectBackoffMs entre as tentativas, dobrada a cada nova falha.
+	RedisConnectMaxAttempts    int
+	RedisConnectMaxWaitSeconds int
+	RedisConnectBackoffMs      int
+	// Region é o rótulo da região (ou data center) incluído nas chaves depois de KeyPrefix, para
+	// que cada região aplique os limites de forma independente mesmo dividindo o Redis (vazio
+	// compartilha as chaves entre as regiões).
+	Region string
+	// GenerationCheckSeconds é o intervalo da verificação do marcador de geração, que detecta
+	// reinícios do Redis sem persistência (0 desliga). Com BlockJournal, os bloqueios gravados
+	// por esta instância ficam também em memória e são reaplicados após o reinício.
+	GenerationCheckSeconds int
+	BlockJournal           bool
+	// ConfigEndpointSecret protege o endpoint /debug/config, que expõe a configuração efetiva
+	// com os segredos ocultos (vazio desliga o endpoint).
+	ConfigEndpointSecret string
+	// SoftLimitPerIP e SoftLimitPerToken são os limites de alerta de cada escopo: as requisições
+	// da janela além deles são permitidas, mas contadas na métrica
+	// ratelimiter_soft_limit_exceeded_total e registradas em log. Devem ficar abaixo dos limites
+	// que bloqueiam (0 desliga).
+	SoftLimitPerIP    int
+	SoftLimitPerToken int
+	// RouteLimits são os sub-limites por rota: a requisição precisa caber no limite do
+	// identificador e no da rota mais específica que casar com o caminho.
+	RouteLimits []RouteLimit
+	// FallbackReconcilePolicy define como as contagens do fallback em memória são levadas ao
+	// Redis quando o circuito fecha: ReconcilePrimary, ReconcileMax ou ReconcileSumCapped.
+	FallbackReconcilePolicy string
+	// RequestHistorySize é quantas das últimas requisições de cada identificador ficam em
+	// memória para diagnóstico (0 desliga), e RequestHistoryMaxIdentifiers, de quantos
+	// identificadores no máximo.
+	RequestHistorySize           int
+	RequestHistoryMaxIdentifiers int
+	// ExpvarName é o nome do mapa do expvar com os contadores das decisões, exposto em
+	// /debug/vars (vazio desliga).
+	ExpvarName string
+	// PreflightCoalesceSeconds é por quanto tempo, com PreflightCoalesce, um preflight espera
+	// pela requisição real que ele cobre.
+	PreflightCoalesceSeconds int
+	// NonCountingMethodsIP e NonCountingMethodsToken são os métodos HTTP (em maiúsculas) que,
+	// em cada escopo, não consomem a cota: a requisição só é rejeitada durante um bloqueio ativo.
+	NonCountingMethodsIP    []string
+	NonCountingMethodsToken []string
+}
+
+// StoreKeyPrefix retorna o prefixo comum a todas as chaves do rate limiter no store: KeyPrefix
+// seguido, quando configurada, da região e de ":".
+func (c *LimiterConfig) StoreKeyPrefix() string {
+	if c.Region == "" {
+		return c.KeyPrefix
+	}
+	return c.KeyPrefix + c.Region + ":"
 }
 
 func LoadConfigRateLimiter() (*LimiterConfig, error) {
@@ -41,24 +466,29 @@ func LoadConfigRateLimiter() (*LimiterConfig, error) {
 		return nil, fmt.Errorf("erro ao converter MAX_REQUESTS_PER_TOKEN: %w", err)
 	}
 
-	blockDurationIPStr := os.Getenv("BLOCK_DURATION_IP_SECONDS")
-	if blockDurationIPStr == "" {
-		fmt.Println("Aviso: BLOCK_DURATION_IP_SECONDS não definido, usando valor padrão (300)")
-		blockDurationIPStr = "300"
+	if os.Getenv("BLOCK_DURATION_IP") == "" && os.Getenv("BLOCK_DURATION_IP_SECONDS") == "" {
+		fmt.Println("Aviso: BLOCK_DURATION_IP não definido, usando valor padrão (5m)")
+	}
+	blockDurationIP, err := durationSecondsEnv("BLOCK_DURATION_IP", 300)
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv("BLOCK_DURATION_TOKEN") == "" && os.Getenv("BLOCK_DURATION_TOKEN_SECONDS") == "" {
+		fmt.Println("Aviso: BLOCK_DURATION_TOKEN não definido, usando valor padrão (5m)")
 	}
-	blockDurationIP, err := strconv.Atoi(blockDurationIPStr)
+	blockDurationToken, err := durationSecondsEnv("BLOCK_DURATION_TOKEN", 300)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao converter BLOCK_DURATION_IP_SECONDS: %w", err)
+		return nil, err
 	}
 
-	blockDurationTokenStr := os.Getenv("BLOCK_DURATION_TOKEN_SECONDS")
-	if blockDurationTokenStr == "" {
-		fmt.Println("Aviso: BLOCK_DURATION_TOKEN_SECONDS não definido, usando valor padrão (300)")
-		blockDurationTokenStr = "300"
+	windowIP, err := durationSecondsEnv("WINDOW_IP", 1)
+	if err != nil {
+		return nil, err
 	}
-	blockDurationToken, err := strconv.Atoi(blockDurationTokenStr)
+	windowToken, err := durationSecondsEnv("WINDOW_TOKEN", 1)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao converter BLOCK_DURATION_TOKEN_SECONDS: %w", err)
+		return nil, err
 	}
 
 	tokenHeaderName := os.Getenv("TOKEN_HEADER_NAME")
@@ -67,11 +497,937 @@ func LoadConfigRateLimiter() (*LimiterConfig, error) {
 		tokenHeaderName = "API_KEY"
 	}
 
+	enabled := true
+	if enabledStr := os.Getenv("RATE_LIMITER_ENABLED"); enabledStr != "" {
+		enabled, err = strconv.ParseBool(enabledStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter RATE_LIMITER_ENABLED: %w", err)
+		}
+	}
+
+	fairShare := false
+	if fairShareStr := os.Getenv("FAIR_SHARE_TOKENS_PER_IP"); fairShareStr != "" {
+		fairShare, err = strconv.ParseBool(fairShareStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter FAIR_SHARE_TOKENS_PER_IP: %w", err)
+		}
+	}
+
+	algorithm := os.Getenv("ALGORITHM")
+	if algorithm == "" {
+		algorithm = AlgorithmFixedWindow
+	}
+	if algorithm != AlgorithmFixedWindow && algorithm != AlgorithmSlidingWindow && algorithm != AlgorithmCalendarWindow && algorithm != AlgorithmLeakyBucket {
+		return nil, fmt.Errorf("valor inválido para ALGORITHM: %q (use %q, %q, %q ou %q)", algorithm, AlgorithmFixedWindow, AlgorithmSlidingWindow, AlgorithmCalendarWindow, AlgorithmLeakyBucket)
+	}
+
+	alignWindows := false
+	if alignStr := os.Getenv("ALIGN_WINDOWS"); alignStr != "" {
+		alignWindows, err = strconv.ParseBool(alignStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter ALIGN_WINDOWS: %w", err)
+		}
+	}
+
+	leakyBucketCapacity := 0
+	if capacityStr := os.Getenv("LEAKY_BUCKET_CAPACITY"); capacityStr != "" {
+		leakyBucketCapacity, err = strconv.Atoi(capacityStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter LEAKY_BUCKET_CAPACITY: %w", err)
+		}
+	}
+
+	timeSource := os.Getenv("SLIDING_WINDOW_TIME_SOURCE")
+	if timeSource == "" {
+		timeSource = TimeSourceServer
+	}
+	if timeSource != TimeSourceServer && timeSource != TimeSourceClient {
+		return nil, fmt.Errorf("valor inválido para SLIDING_WINDOW_TIME_SOURCE: %q (use %q ou %q)", timeSource, TimeSourceServer, TimeSourceClient)
+	}
+
+	strictLimits := false
+	if strictLimitsStr := os.Getenv("STRICT_LIMITS"); strictLimitsStr != "" {
+		strictLimits, err = strconv.ParseBool(strictLimitsStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter STRICT_LIMITS: %w", err)
+		}
+	}
+
+	debugLogging := false
+	if debugStr := os.Getenv("DEBUG_LOGGING"); debugStr != "" {
+		debugLogging, err = strconv.ParseBool(debugStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter DEBUG_LOGGING: %w", err)
+		}
+	}
+
+	resetCounterOnBlock := true
+	if resetStr := os.Getenv("RESET_COUNTER_ON_BLOCK"); resetStr != "" {
+		resetCounterOnBlock, err = strconv.ParseBool(resetStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter RESET_COUNTER_ON_BLOCK: %w", err)
+		}
+	}
+
+	graceOverage := 0
+	if graceStr := os.Getenv("GRACE_OVERAGE"); graceStr != "" {
+		graceOverage, err = strconv.Atoi(graceStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter GRACE_OVERAGE: %w", err)
+		}
+		if graceOverage < 0 {
+			return nil, fmt.Errorf("valor inválido para GRACE_OVERAGE: %d (use um valor não negativo)", graceOverage)
+		}
+	}
+
+	blockSeverity, err := parseBlockSeverity(os.Getenv("BLOCK_SEVERITY"))
+	if err != nil {
+		return nil, err
+	}
+
+	calendarPeriod := os.Getenv("CALENDAR_PERIOD")
+	if calendarPeriod == "" {
+		calendarPeriod = CalendarPeriodDaily
+	}
+	if calendarPeriod != CalendarPeriodDaily && calendarPeriod != CalendarPeriodMonthly {
+		return nil, fmt.Errorf("valor inválido para CALENDAR_PERIOD: %q (use %q ou %q)", calendarPeriod, CalendarPeriodDaily, CalendarPeriodMonthly)
+	}
+
+	calendarLocation := time.UTC
+	if timezone := os.Getenv("CALENDAR_TIMEZONE"); timezone != "" {
+		calendarLocation, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar CALENDAR_TIMEZONE: %w", err)
+		}
+	}
+
+	var maxBytes int64
+	if maxBytesStr := os.Getenv("MAX_BYTES_PER_WINDOW"); maxBytesStr != "" {
+		maxBytes, err = strconv.ParseInt(maxBytesStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter MAX_BYTES_PER_WINDOW: %w", err)
+		}
+	}
+
+	var maxUploadBytes int64
+	if maxUploadStr := os.Getenv("MAX_UPLOAD_BYTES_PER_WINDOW"); maxUploadStr != "" {
+		maxUploadBytes, err = strconv.ParseInt(maxUploadStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter MAX_UPLOAD_BYTES_PER_WINDOW: %w", err)
+		}
+	}
+
+	bandwidthWindow, err := durationSecondsEnv("BANDWIDTH_WINDOW", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	var boostMultiplier float64
+	var boostUntil time.Time
+	if boostMultiplierStr := os.Getenv("BOOST_MULTIPLIER"); boostMultiplierStr != "" {
+		boostMultiplier, err = strconv.ParseFloat(boostMultiplierStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter BOOST_MULTIPLIER: %w", err)
+		}
+		boostUntil, err = time.Parse(time.RFC3339, os.Getenv("BOOST_UNTIL"))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter BOOST_UNTIL (use RFC 3339): %w", err)
+		}
+	}
+
+	limitSchedule, err := parseLimitSchedule(os.Getenv("LIMIT_SCHEDULE"))
+	if err != nil {
+		return nil, err
+	}
+	scheduleLocation := time.UTC
+	if timezone := os.Getenv("SCHEDULE_TIMEZONE"); timezone != "" {
+		scheduleLocation, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar SCHEDULE_TIMEZONE: %w", err)
+		}
+	}
+
+	warmupMultiplier := 0.0
+	if warmupMultiplierStr := os.Getenv("WARMUP_MULTIPLIER"); warmupMultiplierStr != "" {
+		warmupMultiplier, err = strconv.ParseFloat(warmupMultiplierStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter WARMUP_MULTIPLIER: %w", err)
+		}
+		if warmupMultiplier < 1 || math.IsNaN(warmupMultiplier) || math.IsInf(warmupMultiplier, 0) {
+			return nil, fmt.Errorf("valor inválido para WARMUP_MULTIPLIER: %q (use um valor maior ou igual a 1)", warmupMultiplierStr)
+		}
+	}
+	warmup, err := durationSecondsEnv("WARMUP_DURATION", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	failureMode := os.Getenv("FAILURE_MODE")
+	if failureMode == "" {
+		failureMode = FailureModeClosed
+	}
+	if failureMode != FailureModeClosed && failureMode != FailureModeOpen {
+		return nil, fmt.Errorf("valor inválido para FAILURE_MODE: %q (use %q ou %q)", failureMode, FailureModeClosed, FailureModeOpen)
+	}
+
+	globalLimit, err := atoiEnv("GLOBAL_LIMIT")
+	if err != nil {
+		return nil, err
+	}
+
+	globalWindow, err := durationSecondsEnv("GLOBAL_WINDOW", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	globalLimitStatus := 429
+	if globalLimitStatusStr := os.Getenv("GLOBAL_LIMIT_STATUS"); globalLimitStatusStr != "" {
+		globalLimitStatus, err = strconv.Atoi(globalLimitStatusStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter GLOBAL_LIMIT_STATUS: %w", err)
+		}
+		if globalLimitStatus != 429 && globalLimitStatus != 503 {
+			return nil, fmt.Errorf("valor inválido para GLOBAL_LIMIT_STATUS: %d (use 429 ou 503)", globalLimitStatus)
+		}
+	}
+
+	blockStatusCode := 429
+	if blockStatusCodeStr := os.Getenv("BLOCK_STATUS_CODE"); blockStatusCodeStr != "" {
+		blockStatusCode, err = strconv.Atoi(blockStatusCodeStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter BLOCK_STATUS_CODE: %w", err)
+		}
+		if blockStatusCode < 400 || blockStatusCode > 599 {
+			return nil, fmt.Errorf("valor inválido para BLOCK_STATUS_CODE: %d (use um status 4xx ou 5xx)", blockStatusCode)
+		}
+	}
+
+	storeErrorStatus := 503
+	if storeErrorStatusStr := os.Getenv("STORE_ERROR_STATUS"); storeErrorStatusStr != "" {
+		storeErrorStatus, err = strconv.Atoi(storeErrorStatusStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter STORE_ERROR_STATUS: %w", err)
+		}
+		if storeErrorStatus != 500 && storeErrorStatus != 503 {
+			return nil, fmt.Errorf("valor inválido para STORE_ERROR_STATUS: %d (use 500 ou 503)", storeErrorStatus)
+		}
+	}
+
+	storeErrorRetryAfter, err := durationSecondsEnv("STORE_ERROR_RETRY_AFTER", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	retryMaxAttempts := 1
+	if retryMaxAttemptsStr := os.Getenv("REDIS_RETRY_MAX_ATTEMPTS"); retryMaxAttemptsStr != "" {
+		retryMaxAttempts, err = strconv.Atoi(retryMaxAttemptsStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REDIS_RETRY_MAX_ATTEMPTS: %w", err)
+		}
+	}
+
+	retryBackoff := 50
+	if retryBackoffStr := os.Getenv("REDIS_RETRY_BACKOFF_MS"); retryBackoffStr != "" {
+		retryBackoff, err = strconv.Atoi(retryBackoffStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REDIS_RETRY_BACKOFF_MS: %w", err)
+		}
+	}
+
+	retryJitter := 0.5
+	if retryJitterStr := os.Getenv("REDIS_RETRY_JITTER"); retryJitterStr != "" {
+		retryJitter, err = strconv.ParseFloat(retryJitterStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REDIS_RETRY_JITTER: %w", err)
+		}
+		if retryJitter < 0 || retryJitter > 1 {
+			return nil, fmt.Errorf("valor inválido para REDIS_RETRY_JITTER: %v (use um valor entre 0 e 1)", retryJitter)
+		}
+	}
+
+	connectMaxAttempts := 10
+	if connectMaxAttemptsStr := os.Getenv("REDIS_CONNECT_MAX_ATTEMPTS"); connectMaxAttemptsStr != "" {
+		connectMaxAttempts, err = strconv.Atoi(connectMaxAttemptsStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REDIS_CONNECT_MAX_ATTEMPTS: %w", err)
+		}
+	}
+
+	connectMaxWait, err := durationSecondsEnv("REDIS_CONNECT_MAX_WAIT", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	connectBackoff := 200
+	if connectBackoffStr := os.Getenv("REDIS_CONNECT_BACKOFF_MS"); connectBackoffStr != "" {
+		connectBackoff, err = strconv.Atoi(connectBackoffStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REDIS_CONNECT_BACKOFF_MS: %w", err)
+		}
+	}
+
+	breakerThreshold := 0
+	if breakerThresholdStr := os.Getenv("CIRCUIT_BREAKER_THRESHOLD"); breakerThresholdStr != "" {
+		breakerThreshold, err = strconv.Atoi(breakerThresholdStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter CIRCUIT_BREAKER_THRESHOLD: %w", err)
+		}
+	}
+
+	breakerCooldown, err := durationSecondsEnv("CIRCUIT_BREAKER_COOLDOWN", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBlockDuration, err := durationSecondsEnv("MAX_BLOCK_DURATION", 0)
+	if err != nil {
+		return nil, err
+	}
+	if maxBlockDuration > 0 && max(blockDurationIP, blockDurationToken) > maxBlockDuration {
+		fmt.Printf("Aviso: BLOCK_DURATION_IP ou BLOCK_DURATION_TOKEN acima de MAX_BLOCK_DURATION, os bloqueios serão limitados a %ds\n", maxBlockDuration)
+	}
+
+	memoryFallback := false
+	if memoryFallbackStr := os.Getenv("MEMORY_FALLBACK"); memoryFallbackStr != "" {
+		memoryFallback, err = strconv.ParseBool(memoryFallbackStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter MEMORY_FALLBACK: %w", err)
+		}
+	}
+
+	fallbackSeedInterval, err := durationSecondsEnv("FALLBACK_SEED_INTERVAL", 5)
+	if err != nil {
+		return nil, err
+	}
+	if memoryFallback && fallbackSeedInterval <= 0 {
+		return nil, fmt.Errorf("valor inválido para FALLBACK_SEED_INTERVAL: %ds (use um intervalo positivo com MEMORY_FALLBACK)", fallbackSeedInterval)
+	}
+
+	limitCacheTTL, err := durationSecondsEnv("LIMIT_CACHE_TTL", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	limitCacheMaxSize := 10000
+	if limitCacheMaxSizeStr := os.Getenv("LIMIT_CACHE_MAX_SIZE"); limitCacheMaxSizeStr != "" {
+		limitCacheMaxSize, err = strconv.Atoi(limitCacheMaxSizeStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter LIMIT_CACHE_MAX_SIZE: %w", err)
+		}
+	}
+
+	idempotencyTTL, err := durationSecondsEnv("IDEMPOTENCY_TTL", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	tarpitDelay, err := atoiEnv("TARPIT_DELAY_MS")
+	if err != nil {
+		return nil, err
+	}
+
+	utilizationTopN, err := atoiEnv("UTILIZATION_TOP_N")
+	if err != nil {
+		return nil, err
+	}
+
+	utilizationInterval, err := durationSecondsEnv("UTILIZATION_SCAN_INTERVAL", 15)
+	if err != nil {
+		return nil, err
+	}
+	if utilizationTopN > 0 && utilizationInterval <= 0 {
+		return nil, fmt.Errorf("valor inválido para UTILIZATION_SCAN_INTERVAL: %ds (use um intervalo positivo com UTILIZATION_TOP_N)", utilizationInterval)
+	}
+
+	redisPoolStatsInterval, err := durationSecondsEnv("REDIS_POOL_STATS_INTERVAL", 15)
+	if err != nil {
+		return nil, err
+	}
+
+	var preloadTokens []string
+	for _, token := range strings.Split(os.Getenv("PRELOAD_TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			preloadTokens = append(preloadTokens, token)
+		}
+	}
+
+	maxIdentifierLength := 1024
+	if maxIdentifierLengthStr := os.Getenv("MAX_IDENTIFIER_LENGTH"); maxIdentifierLengthStr != "" {
+		maxIdentifierLength, err = strconv.Atoi(maxIdentifierLengthStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter MAX_IDENTIFIER_LENGTH: %w", err)
+		}
+	}
+
+	rejectLongIdentifiers := false
+	if rejectLongIdentifiersStr := os.Getenv("REJECT_LONG_IDENTIFIERS"); rejectLongIdentifiersStr != "" {
+		rejectLongIdentifiers, err = strconv.ParseBool(rejectLongIdentifiersStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REJECT_LONG_IDENTIFIERS: %w", err)
+		}
+	}
+
+	rejectionFormat := os.Getenv("REJECTION_FORMAT")
+	if rejectionFormat == "" {
+		rejectionFormat = RejectionFormatText
+	}
+	if rejectionFormat != RejectionFormatText && rejectionFormat != RejectionFormatProblemJSON {
+		return nil, fmt.Errorf("valor inválido para REJECTION_FORMAT: %q (use %q ou %q)", rejectionFormat, RejectionFormatText, RejectionFormatProblemJSON)
+	}
+
+	emptyTokenPolicy := os.Getenv("EMPTY_TOKEN_POLICY")
+	if emptyTokenPolicy == "" {
+		emptyTokenPolicy = EmptyTokenFallbackIP
+	}
+	if emptyTokenPolicy != EmptyTokenFallbackIP && emptyTokenPolicy != EmptyTokenReject && emptyTokenPolicy != EmptyTokenAnonymous {
+		return nil, fmt.Errorf("valor inválido para EMPTY_TOKEN_POLICY: %q (use %q, %q ou %q)", emptyTokenPolicy, EmptyTokenFallbackIP, EmptyTokenReject, EmptyTokenAnonymous)
+	}
+
+	keyComponents := os.Getenv("KEY_COMPONENTS")
+	if keyComponents == "" {
+		keyComponents = KeyComponentsIP
+	}
+	if keyComponents != KeyComponentsIP && keyComponents != KeyComponentsUserAgent && keyComponents != KeyComponentsIPAndUserAgent {
+		return nil, fmt.Errorf("valor inválido para KEY_COMPONENTS: %q (use %q, %q ou %q)", keyComponents, KeyComponentsIP, KeyComponentsUserAgent, KeyComponentsIPAndUserAgent)
+	}
+
+	pathKeyPattern := os.Getenv("PATH_KEY_PATTERN")
+	if pathKeyPattern != "" {
+		re, err := regexp.Compile(pathKeyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter PATH_KEY_PATTERN: %w", err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("valor inválido para PATH_KEY_PATTERN: %q (use um grupo de captura com o parâmetro, ex.: ^/users/([^/]+)/posts)", pathKeyPattern)
+		}
+	}
+
+	routeCaseInsensitive := false
+	if caseStr := os.Getenv("ROUTE_CASE_INSENSITIVE"); caseStr != "" {
+		routeCaseInsensitive, err = strconv.ParseBool(caseStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter ROUTE_CASE_INSENSITIVE: %w", err)
+		}
+	}
+
+	routeIgnoreTrailingSlash := false
+	if slashStr := os.Getenv("ROUTE_IGNORE_TRAILING_SLASH"); slashStr != "" {
+		routeIgnoreTrailingSlash, err = strconv.ParseBool(slashStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter ROUTE_IGNORE_TRAILING_SLASH: %w", err)
+		}
+	}
+
+	tokenTrimSpace := false
+	if trimStr := os.Getenv("TOKEN_TRIM_SPACE"); trimStr != "" {
+		tokenTrimSpace, err = strconv.ParseBool(trimStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter TOKEN_TRIM_SPACE: %w", err)
+		}
+	}
+
+	tokenCaseInsensitive := false
+	if caseStr := os.Getenv("TOKEN_CASE_INSENSITIVE"); caseStr != "" {
+		tokenCaseInsensitive, err = strconv.ParseBool(caseStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter TOKEN_CASE_INSENSITIVE: %w", err)
+		}
+	}
+
+	ipUnmapIPv4 := false
+	if unmapStr := os.Getenv("IP_UNMAP_IPV4"); unmapStr != "" {
+		ipUnmapIPv4, err = strconv.ParseBool(unmapStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter IP_UNMAP_IPV4: %w", err)
+		}
+	}
+
+	consecutiveViolations := 0
+	if violationsStr := os.Getenv("CONSECUTIVE_VIOLATION_THRESHOLD"); violationsStr != "" {
+		consecutiveViolations, err = strconv.Atoi(violationsStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter CONSECUTIVE_VIOLATION_THRESHOLD: %w", err)
+		}
+		if consecutiveViolations < 0 {
+			return nil, fmt.Errorf("valor inválido para CONSECUTIVE_VIOLATION_THRESHOLD: %d (use um valor não negativo)", consecutiveViolations)
+		}
+	}
+
+	overheadHeader := false
+	if overheadStr := os.Getenv("DEBUG_OVERHEAD_HEADER"); overheadStr != "" {
+		overheadHeader, err = strconv.ParseBool(overheadStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter DEBUG_OVERHEAD_HEADER: %w", err)
+		}
+	}
+
+	tokenTiers, err := parseTokenTiers(os.Getenv("TOKEN_TIERS"))
+	if err != nil {
+		return nil, err
+	}
+	tokenSources, err := parseTokenSources(os.Getenv("TOKEN_SOURCES"), tokenTiers)
+	if err != nil {
+		return nil, err
+	}
+
+	generationCheck, err := durationSecondsEnv("GENERATION_CHECK_INTERVAL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	blockJournal := false
+	if blockJournalStr := os.Getenv("BLOCK_JOURNAL"); blockJournalStr != "" {
+		blockJournal, err = strconv.ParseBool(blockJournalStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter BLOCK_JOURNAL: %w", err)
+		}
+	}
+
+	softLimitIP, err := atoiEnv("SOFT_LIMIT_PER_IP")
+	if err != nil {
+		return nil, err
+	}
+	softLimitToken, err := atoiEnv("SOFT_LIMIT_PER_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	if softLimitIP < 0 || softLimitToken < 0 {
+		return nil, fmt.Errorf("valor inválido para SOFT_LIMIT_PER_IP ou SOFT_LIMIT_PER_TOKEN: %d, %d (use valores não negativos)", softLimitIP, softLimitToken)
+	}
+
+	routeLimits, err := parseRouteLimits(os.Getenv("ROUTE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+
+	reconcilePolicy := os.Getenv("FALLBACK_RECONCILE_POLICY")
+	if reconcilePolicy == "" {
+		reconcilePolicy = ReconcilePrimary
+	}
+	if reconcilePolicy != ReconcilePrimary && reconcilePolicy != ReconcileMax && reconcilePolicy != ReconcileSumCapped {
+		return nil, fmt.Errorf("valor inválido para FALLBACK_RECONCILE_POLICY: %q (use %q, %q ou %q)", reconcilePolicy, ReconcilePrimary, ReconcileMax, ReconcileSumCapped)
+	}
+
+	requestHistorySize, err := atoiEnv("REQUEST_HISTORY_SIZE")
+	if err != nil {
+		return nil, err
+	}
+	requestHistoryMaxIdentifiers := 1000
+	if value := os.Getenv("REQUEST_HISTORY_MAX_IDENTIFIERS"); value != "" {
+		if requestHistoryMaxIdentifiers, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("erro ao converter REQUEST_HISTORY_MAX_IDENTIFIERS: %w", err)
+		}
+	}
+	if requestHistorySize < 0 || requestHistoryMaxIdentifiers < 1 {
+		return nil, fmt.Errorf("valor inválido para REQUEST_HISTORY_SIZE ou REQUEST_HISTORY_MAX_IDENTIFIERS: %d, %d (use um tamanho não negativo e ao menos 1 identificador)", requestHistorySize, requestHistoryMaxIdentifiers)
+	}
+
+	preflightCoalesceWindow, err := durationSecondsEnv("PREFLIGHT_COALESCE_WINDOW", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	nonCountingIP := parseMethods(os.Getenv("NON_COUNTING_METHODS_IP"))
+	nonCountingToken := parseMethods(os.Getenv("NON_COUNTING_METHODS_TOKEN"))
+
+	headerScheme := os.Getenv("HEADER_SCHEME")
+	if headerScheme == "" {
+		headerScheme = HeaderSchemeXRateLimit
+	}
+	if headerScheme != HeaderSchemeXRateLimit && headerScheme != HeaderSchemeDraft && headerScheme != HeaderSchemeBoth {
+		return nil, fmt.Errorf("valor inválido para HEADER_SCHEME: %q (use %q, %q ou %q)", headerScheme, HeaderSchemeXRateLimit, HeaderSchemeDraft, HeaderSchemeBoth)
+	}
+
+	remainingDecimals := 0
+	if decimalsStr := os.Getenv("REMAINING_DECIMALS"); decimalsStr != "" {
+		remainingDecimals, err = strconv.Atoi(decimalsStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter REMAINING_DECIMALS: %w", err)
+		}
+		if remainingDecimals < 0 || remainingDecimals > 6 {
+			return nil, fmt.Errorf("valor inválido para REMAINING_DECIMALS: %d (use de 0 a 6)", remainingDecimals)
+		}
+	}
+
+	unknownBucket := false
+	if unknownBucketStr := os.Getenv("UNKNOWN_BUCKET"); unknownBucketStr != "" {
+		unknownBucket, err = strconv.ParseBool(unknownBucketStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter UNKNOWN_BUCKET: %w", err)
+		}
+	}
+
+	splitReadWrite := false
+	if splitReadWriteStr := os.Getenv("SPLIT_READ_WRITE"); splitReadWriteStr != "" {
+		splitReadWrite, err = strconv.ParseBool(splitReadWriteStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter SPLIT_READ_WRITE: %w", err)
+		}
+	}
+
+	readMethods := []string{"GET", "HEAD", "OPTIONS"}
+	if readMethodsStr := os.Getenv("READ_METHODS"); readMethodsStr != "" {
+		readMethods = parseMethods(readMethodsStr)
+	}
+
+	classLimits := map[string]ClassLimit{}
+	for _, class := range []string{ClassRead, ClassWrite, ClassUnknown, ClassPreflight} {
+		prefix := strings.ToUpper(class)
+		var limit ClassLimit
+		if limit.MaxRequestsPerIP, err = atoiEnv(prefix + "_MAX_REQUESTS_PER_IP"); err != nil {
+			return nil, err
+		}
+		if limit.MaxRequestsPerToken, err = atoiEnv(prefix + "_MAX_REQUESTS_PER_TOKEN"); err != nil {
+			return nil, err
+		}
+		classLimits[class] = limit
+	}
+
+	preflightPolicy := os.Getenv("PREFLIGHT_POLICY")
+	if preflightPolicy == "" {
+		preflightPolicy = PreflightCount
+	}
+	if preflightPolicy != PreflightCount && preflightPolicy != PreflightSkip && preflightPolicy != PreflightSeparate && preflightPolicy != PreflightCoalesce {
+		return nil, fmt.Errorf("valor inválido para PREFLIGHT_POLICY: %q (use %q, %q, %q ou %q)", preflightPolicy, PreflightCount, PreflightSkip, PreflightSeparate, PreflightCoalesce)
+	}
+
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	xffSelect := os.Getenv("X_FORWARDED_FOR_SELECT")
+	if xffSelect == "" {
+		xffSelect = XForwardedForRightmostUntrusted
+	}
+	if xffSelect != XForwardedForLeftmost && xffSelect != XForwardedForRightmostUntrusted {
+		return nil, fmt.Errorf("valor inválido para X_FORWARDED_FOR_SELECT: %q (use %q ou %q)", xffSelect, XForwardedForLeftmost, XForwardedForRightmostUntrusted)
+	}
+
+	cardinalityMaxNew, err := atoiEnv("CARDINALITY_MAX_NEW_IDENTIFIERS")
+	if err != nil {
+		return nil, err
+	}
+	cardinalityWindow, err := durationSecondsEnv("CARDINALITY_WINDOW", 60)
+	if err != nil {
+		return nil, err
+	}
+	cardinalityFallback := os.Getenv("CARDINALITY_FALLBACK")
+	if cardinalityFallback == "" {
+		cardinalityFallback = CardinalitySubnet
+	}
+	if cardinalityFallback != CardinalitySubnet && cardinalityFallback != CardinalityReject {
+		return nil, fmt.Errorf("valor inválido para CARDINALITY_FALLBACK: %q (use %q ou %q)", cardinalityFallback, CardinalitySubnet, CardinalityReject)
+	}
+
+	skipPrivate := false
+	if skipPrivateStr := os.Getenv("SKIP_PRIVATE_NETWORKS"); skipPrivateStr != "" {
+		skipPrivate, err = strconv.ParseBool(skipPrivateStr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter SKIP_PRIVATE_NETWORKS: %w", err)
+		}
+	}
+
 	return &LimiterConfig{
-		MaxRequestsPerIP:          maxRequestsIP,
-		MaxRequestsPerToken:       maxRequestsToken,
-		BlockDurationIPSeconds:    blockDurationIP,
-		BlockDurationTokenSeconds: blockDurationToken,
-		TokenHeaderName:           tokenHeaderName,
+		MaxRequestsPerIP:               maxRequestsIP,
+		MaxRequestsPerToken:            maxRequestsToken,
+		BlockDurationIPSeconds:         blockDurationIP,
+		BlockDurationTokenSeconds:      blockDurationToken,
+		WindowIPSeconds:                windowIP,
+		WindowTokenSeconds:             windowToken,
+		TokenHeaderName:                tokenHeaderName,
+		KeyPrefix:                      os.Getenv("KEY_PREFIX"),
+		IPKeyPrefix:                    os.Getenv("IP_KEY_PREFIX"),
+		TokenKeyPrefix:                 os.Getenv("TOKEN_KEY_PREFIX"),
+		RejectionBodyTemplate:          os.Getenv("REJECTION_BODY_TEMPLATE"),
+		RejectionFormat:                rejectionFormat,
+		Disabled:                       !enabled,
+		FairShareTokensPerIP:           fairShare,
+		Algorithm:                      algorithm,
+		SlidingWindowTimeSource:        timeSource,
+		StrictLimits:                   strictLimits,
+		KeepCounterOnBlock:             !resetCounterOnBlock,
+		GraceOverage:                   graceOverage,
+		BlockSeverity:                  blockSeverity,
+		AlignWindows:                   alignWindows,
+		LeakyBucketCapacity:            leakyBucketCapacity,
+		DebugLogging:                   debugLogging,
+		CalendarPeriod:                 calendarPeriod,
+		CalendarLocation:               calendarLocation,
+		MaxBytesPerWindow:              maxBytes,
+		BandwidthWindowSeconds:         bandwidthWindow,
+		BoostMultiplier:                boostMultiplier,
+		BoostUntil:                     boostUntil,
+		LimitSchedule:                  limitSchedule,
+		ScheduleLocation:               scheduleLocation,
+		FailureMode:                    failureMode,
+		GlobalLimit:                    globalLimit,
+		GlobalWindowSeconds:            globalWindow,
+		GlobalLimitStatus:              globalLimitStatus,
+		BlockStatusCode:                blockStatusCode,
+		StoreErrorStatus:               storeErrorStatus,
+		StoreErrorRetryAfterSeconds:    storeErrorRetryAfter,
+		RedisRetryMaxAttempts:          retryMaxAttempts,
+		RedisRetryBackoffMs:            retryBackoff,
+		RedisRetryJitter:               retryJitter,
+		CircuitBreakerThreshold:        breakerThreshold,
+		CircuitBreakerCooldownSeconds:  breakerCooldown,
+		TokenLimitsHash:                os.Getenv("TOKEN_LIMITS_HASH"),
+		LimitCacheTTLSeconds:           limitCacheTTL,
+		LimitCacheMaxSize:              limitCacheMaxSize,
+		MaxIdentifierLength:            maxIdentifierLength,
+		RejectLongIdentifiers:          rejectLongIdentifiers,
+		EmptyTokenPolicy:               emptyTokenPolicy,
+		KeyComponents:                  keyComponents,
+		PathKeyPattern:                 pathKeyPattern,
+		RouteCaseInsensitive:           routeCaseInsensitive,
+		RouteIgnoreTrailingSlash:       routeIgnoreTrailingSlash,
+		UnknownBucket:                  unknownBucket,
+		SplitReadWrite:                 splitReadWrite,
+		PreflightPolicy:                preflightPolicy,
+		ReadMethods:                    readMethods,
+		ClassLimits:                    classLimits,
+		TrustedProxies:                 trustedProxies,
+		XForwardedForSelect:            xffSelect,
+		SkipPrivateNetworks:            skipPrivate,
+		CardinalityMaxNew:              cardinalityMaxNew,
+		CardinalityWindowSeconds:       cardinalityWindow,
+		CardinalityFallback:            cardinalityFallback,
+		HeaderScheme:                   headerScheme,
+		RemainingDecimals:              remainingDecimals,
+		TarpitDelayMs:                  tarpitDelay,
+		IdempotencyKeyHeader:           os.Getenv("IDEMPOTENCY_KEY_HEADER"),
+		IdempotencyTTLSeconds:          idempotencyTTL,
+		UtilizationTopN:                utilizationTopN,
+		UtilizationScanIntervalSeconds: utilizationInterval,
+		RedisPoolStatsIntervalSeconds:  redisPoolStatsInterval,
+		PreloadTokens:                  preloadTokens,
+		MemoryFallback:                 memoryFallback,
+		FallbackSeedIntervalSeconds:    fallbackSeedInterval,
+		MaxBlockDurationSeconds:        maxBlockDuration,
+		IPHashSecret:                   os.Getenv("IP_HASH_SECRET"),
+		WarmupMultiplier:               warmupMultiplier,
+		WarmupSeconds:                  warmup,
+		AuditStream:                    os.Getenv("AUDIT_STREAM"),
+		TokenTrimSpace:                 tokenTrimSpace,
+		TokenCaseInsensitive:           tokenCaseInsensitive,
+		IPUnmapIPv4:                    ipUnmapIPv4,
+		ConsecutiveViolationThreshold:  consecutiveViolations,
+		OverheadHeader:                 overheadHeader,
+		MaxUploadBytesPerWindow:        maxUploadBytes,
+		TokenSources:                   tokenSources,
+		TokenTiers:                     tokenTiers,
+		RedisConnectMaxAttempts:        connectMaxAttempts,
+		RedisConnectMaxWaitSeconds:     connectMaxWait,
+		RedisConnectBackoffMs:          connectBackoff,
+		Region:                         strings.TrimSpace(os.Getenv("REGION")),
+		GenerationCheckSeconds:         generationCheck,
+		BlockJournal:                   blockJournal,
+		ConfigEndpointSecret:           os.Getenv("CONFIG_ENDPOINT_SECRET"),
+		SoftLimitPerIP:                 softLimitIP,
+		SoftLimitPerToken:              softLimitToken,
+		RouteLimits:                    routeLimits,
+		FallbackReconcilePolicy:        reconcilePolicy,
+		RequestHistorySize:             requestHistorySize,
+		RequestHistoryMaxIdentifiers:   requestHistoryMaxIdentifiers,
+		ExpvarName:                     os.Getenv("EXPVAR_NAME"),
+		PreflightCoalesceSeconds:       preflightCoalesceWindow,
+		NonCountingMethodsIP:           nonCountingIP,
+		NonCountingMethodsToken:        nonCountingToken,
 	}, nil
 }
+
+// parseMethods lê uma lista de métodos HTTP separados por vírgula, convertidos para maiúsculas.
+func parseMethods(value string) []string {
+	var methods []string
+	for _, method := range strings.Split(value, ",") {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// parseRouteLimits lê os sub-limites por rota no formato nome:prefixo=max/janela/bloqueio,
+// separados por vírgula (ex.: reports:/api/reports=5/1m/10m,export:/api/export=2/1h/1h).
+func parseRouteLimits(value string) ([]RouteLimit, error) {
+	var limits []RouteLimit
+	names := map[string]bool{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(item, "=")
+		name, prefix, okRoute := strings.Cut(strings.TrimSpace(route), ":")
+		name, prefix = strings.TrimSpace(name), strings.TrimSpace(prefix)
+		parts := strings.Split(strings.TrimSpace(limit), "/")
+		if !ok || !okRoute || name == "" || names[name] || !strings.HasPrefix(prefix, "/") || len(parts) != 3 {
+			return nil, fmt.Errorf("valor inválido para ROUTE_LIMITS: %q (use nome:prefixo=max/janela/bloqueio separados por vírgula, com nomes únicos, ex.: reports:/api/reports=5/1m/10m)", item)
+		}
+		maxRequests, maxErr := strconv.Atoi(parts[0])
+		window, windowErr := time.ParseDuration(parts[1])
+		block, blockErr := time.ParseDuration(parts[2])
+		if maxErr != nil || windowErr != nil || blockErr != nil || maxRequests < 0 ||
+			window < 0 || block < 0 || window%time.Second != 0 || block%time.Second != 0 {
+			return nil, fmt.Errorf("valor inválido para ROUTE_LIMITS: %q (use máximo e durações não negativos, em segundos inteiros)", item)
+		}
+		names[name] = true
+		limits = append(limits, RouteLimit{
+			Name:                 name,
+			PathPrefix:           prefix,
+			MaxRequests:          maxRequests,
+			WindowSeconds:        int(window / time.Second),
+			BlockDurationSeconds: int(block / time.Second),
+		})
+	}
+	return limits, nil
+}
+
+// parseTokenSources lê os headers do token no formato header[:faixa], separados por vírgula
+// (ex.: API_KEY,X-Api-Key:partner,Authorization:premium). Toda faixa citada precisa estar
+// definida em TOKEN_TIERS. O nome BasicAuthSource no lugar do header usa o usuário do Basic Auth.
+func parseTokenSources(value string, tiers map[string]TokenTier) ([]TokenSource, error) {
+	var sources []TokenSource
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		header, tier, _ := strings.Cut(item, ":")
+		source := TokenSource{Header: strings.TrimSpace(header), Tier: strings.TrimSpace(tier)}
+		if strings.EqualFold(source.Header, BasicAuthSource) {
+			source.Header, source.BasicAuth = "Authorization", true
+		}
+		if source.Header == "" {
+			return nil, fmt.Errorf("valor inválido para TOKEN_SOURCES: %q (use header[:faixa] separados por vírgula, ex.: API_KEY,X-Api-Key:partner)", item)
+		}
+		if _, ok := tiers[source.Tier]; source.Tier != "" && !ok {
+			return nil, fmt.Errorf("faixa %q de TOKEN_SOURCES não definida em TOKEN_TIERS", source.Tier)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// parseTokenTiers lê as faixas de limites dos tokens no formato nome=max/janela/bloqueio,
+// separadas por vírgula (ex.: partner=100/1s/5m,premium=1000/1s/1m). Janela e bloqueio zero
+// usam os valores gerais dos tokens.
+func parseTokenTiers(value string) (map[string]TokenTier, error) {
+	tiers := map[string]TokenTier{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, limit, ok := strings.Cut(item, "=")
+		parts := strings.Split(strings.TrimSpace(limit), "/")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("valor inválido para TOKEN_TIERS: %q (use nome=max/janela/bloqueio separados por vírgula, ex.: partner=100/1s/5m)", item)
+		}
+		maxRequests, maxErr := strconv.Atoi(parts[0])
+		window, windowErr := time.ParseDuration(parts[1])
+		block, blockErr := time.ParseDuration(parts[2])
+		if maxErr != nil || windowErr != nil || blockErr != nil || maxRequests < 0 ||
+			window < 0 || block < 0 || window%time.Second != 0 || block%time.Second != 0 {
+			return nil, fmt.Errorf("valor inválido para TOKEN_TIERS: %q (use máximo e durações não negativos, em segundos inteiros)", item)
+		}
+		tiers[name] = TokenTier{
+			MaxRequests:          maxRequests,
+			WindowSeconds:        int(window / time.Second),
+			BlockDurationSeconds: int(block / time.Second),
+		}
+	}
+	return tiers, nil
+}
+
+// parseBlockSeverity lê as faixas de severidade no formato razão:duração separados por
+// vírgula (ex.: 2:5m,10:1h) e as ordena pela razão.
+func parseBlockSeverity(value string) ([]BlockSeverityTier, error) {
+	var tiers []BlockSeverityTier
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		ratioStr, durationStr, ok := strings.Cut(item, ":")
+		ratio, ratioErr := strconv.ParseFloat(strings.TrimSpace(ratioStr), 64)
+		duration, durationErr := time.ParseDuration(strings.TrimSpace(durationStr))
+		if !ok || ratioErr != nil || durationErr != nil || ratio < 1 || duration <= 0 {
+			return nil, fmt.Errorf("valor inválido para BLOCK_SEVERITY: %q (use razão:duração separados por vírgula, com razão de pelo menos 1, ex.: 2:5m,10:1h)", item)
+		}
+		tiers = append(tiers, BlockSeverityTier{MinRatio: ratio, Duration: duration})
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinRatio < tiers[j].MinRatio })
+	return tiers, nil
+}
+
+// parseLimitSchedule lê as faixas de horário no formato início-fim=multiplicador, separadas
+// por vírgula (ex.: 09:00-18:00=2,22:00-06:00=0.5).
+func parseLimitSchedule(value string) ([]ScheduleRange, error) {
+	var ranges []ScheduleRange
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		span, multiplierStr, ok := strings.Cut(item, "=")
+		startStr, endStr, okSpan := strings.Cut(strings.TrimSpace(span), "-")
+		start, startErr := time.Parse("15:04", strings.TrimSpace(startStr))
+		end, endErr := time.Parse("15:04", strings.TrimSpace(endStr))
+		multiplier, multiplierErr := strconv.ParseFloat(strings.TrimSpace(multiplierStr), 64)
+		if !ok || !okSpan || startErr != nil || endErr != nil || multiplierErr != nil || multiplier <= 0 ||
+			math.IsNaN(multiplier) || math.IsInf(multiplier, 0) {
+			return nil, fmt.Errorf("valor inválido para LIMIT_SCHEDULE: %q (use início-fim=multiplicador separados por vírgula, ex.: 09:00-18:00=2,22:00-06:00=0.5)", item)
+		}
+		ranges = append(ranges, ScheduleRange{
+			Start:      time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+			End:        time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+			Multiplier: multiplier,
+		})
+	}
+	return ranges, nil
+}
+
+// durationSecondsEnv lê uma duração em segundos inteiros. name aceita o formato de
+// time.ParseDuration (ex.: 90s, 2m, 1h30m); sem ele, vale o número de segundos em
+// name_SECONDS, mantido por compatibilidade. Sem nenhum dos dois, retorna def. Valores negativos
+// são rejeitados nos dois formatos.
+func durationSecondsEnv(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		secondsName := name + "_SECONDS"
+		secondsStr := os.Getenv(secondsName)
+		if secondsStr == "" {
+			return def, nil
+		}
+		seconds, err := strconv.Atoi(secondsStr)
+		if err != nil {
+			return 0, fmt.Errorf("erro ao converter %s: %w", secondsName, err)
+		}
+		if seconds < 0 {
+			return 0, fmt.Errorf("valor inválido para %s: %d (use um número não negativo de segundos)", secondsName, seconds)
+		}
+		return seconds, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao converter %s (use uma duração como 90s, 2m ou 1h30m): %w", name, err)
+	}
+	if d < 0 || d%time.Second != 0 {
+		return 0, fmt.Errorf("valor inválido para %s: %q (use uma duração não negativa em segundos inteiros)", name, value)
+	}
+	return int(d / time.Second), nil
+}
+
+// atoiEnv converte uma variável de ambiente opcional em inteiro (0 se não definida).
+func atoiEnv(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao converter %s: %w", name, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_DurationSecondsEnv verifica a leitura das durações legíveis e do formato em segundos
+func Test_DurationSecondsEnv(t *testing.T) {
+	for value, expected := range map[string]int{"90s": 90, "2m": 120, "1h": 3600, "1h30m": 5400, "0s": 0} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("WINDOW_IP", value)
+			t.Setenv("WINDOW_IP_SECONDS", "7")
+			seconds, err := durationSecondsEnv("WINDOW_IP", 1)
+			require.NoError(t, err)
+			assert.Equal(t, expected, seconds, "A duração legível tem precedência sobre *_SECONDS")
+		})
+	}
+
+	t.Run("seconds", func(t *testing.T) {
+		t.Setenv("WINDOW_IP_SECONDS", "45")
+		seconds, err := durationSecondsEnv("WINDOW_IP", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 45, seconds)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		seconds, err := durationSecondsEnv("WINDOW_IP", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, seconds)
+	})
+
+	for name, env := range map[string]map[string]string{
+		"invalid":          {"WINDOW_IP": "5 minutos"},
+		"fractional":       {"WINDOW_IP": "1500ms"},
+		"negative":         {"WINDOW_IP": "-1m"},
+		"seconds":          {"WINDOW_IP_SECONDS": "1m"},
+		"negative_seconds": {"WINDOW_IP_SECONDS": "-5"},
+	} {
+		t.Run("error/"+name, func(t *testing.T) {
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+			_, err := durationSecondsEnv("WINDOW_IP", 1)
+			assert.ErrorContains(t, err, "WINDOW_IP")
+		})
+	}
+}
+
+// Test_LoadConfigRateLimiter_Durations verifica que as durações legíveis chegam à configuração
+func Test_LoadConfigRateLimiter_Durations(t *testing.T) {
+	t.Setenv("BLOCK_DURATION_IP", "2m")
+	t.Setenv("BLOCK_DURATION_TOKEN_SECONDS", "90")
+	t.Setenv("WINDOW_TOKEN", "1h")
+	t.Setenv("GLOBAL_WINDOW", "90s")
+
+	cfg, err := LoadConfigRateLimiter()
+	require.NoError(t, err)
+	assert.Equal(t, 120, cfg.BlockDurationIPSeconds)
+	assert.Equal(t, 90, cfg.BlockDurationTokenSeconds)
+	assert.Equal(t, 1, cfg.WindowIPSeconds)
+	assert.Equal(t, 3600, cfg.WindowTokenSeconds)
+	assert.Equal(t, 90, cfg.GlobalWindowSeconds)
+
+	t.Setenv("BLOCK_DURATION_IP", "2 minutos")
+	_, err = LoadConfigRateLimiter()
+	assert.ErrorContains(t, err, "BLOCK_DURATION_IP")
+}
+
+// Test_LoadConfigRateLimiter_FallbackSeedInterval verifica que o fallback em memória exige um
+// intervalo positivo de cópia das contagens
+func Test_LoadConfigRateLimiter_FallbackSeedInterval(t *testing.T) {
+	t.Setenv("FALLBACK_SEED_INTERVAL", "0s")
+	_, err := LoadConfigRateLimiter()
+	require.NoError(t, err, "Sem o fallback em memória o intervalo não é usado")
+
+	t.Setenv("MEMORY_FALLBACK", "true")
+	_, err = LoadConfigRateLimiter()
+	assert.ErrorContains(t, err, "FALLBACK_SEED_INTERVAL")
+
+	t.Setenv("FALLBACK_SEED_INTERVAL", "")
+	t.Setenv("FALLBACK_SEED_INTERVAL_SECONDS", "0")
+	_, err = LoadConfigRateLimiter()
+	assert.ErrorContains(t, err, "FALLBACK_SEED_INTERVAL")
+
+	t.Setenv("FALLBACK_SEED_INTERVAL_SECONDS", "10")
+	cfg, err := LoadConfigRateLimiter()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.FallbackSeedIntervalSeconds)
+}
+
+// Test_LoadConfigRateLimiter_UtilizationScanInterval verifica que o monitor de utilização exige
+// um intervalo positivo de varredura
+func Test_LoadConfigRateLimiter_UtilizationScanInterval(t *testing.T) {
+	t.Setenv("UTILIZATION_SCAN_INTERVAL", "0s")
+	_, err := LoadConfigRateLimiter()
+	require.NoError(t, err, "Sem UTILIZATION_TOP_N o intervalo não é usado")
+
+	t.Setenv("UTILIZATION_TOP_N", "10")
+	_, err = LoadConfigRateLimiter()
+	assert.ErrorContains(t, err, "UTILIZATION_SCAN_INTERVAL")
+}
+
+// Test_LoadConfigRateLimiter_WarmupMultiplier verifica que o multiplicador do aquecimento
+// precisa ser um número finito maior ou igual a 1
+func Test_LoadConfigRateLimiter_WarmupMultiplier(t *testing.T) {
+	t.Setenv("WARMUP_MULTIPLIER", "1.5")
+	cfg, err := LoadConfigRateLimiter()
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, cfg.WarmupMultiplier)
+
+	for _, value := range []string{"0.5", "NaN", "Inf", "+Inf", "-Inf"} {
+		t.Setenv("WARMUP_MULTIPLIER", value)
+		_, err := LoadConfigRateLimiter()
+		assert.ErrorContains(t, err, "WARMUP_MULTIPLIER", value)
+	}
+}
+
+// Test_ParseBlockSeverity verifica a leitura e a ordenação das faixas de severidade
+func Test_ParseBlockSeverity(t *testing.T) {
+	tiers, err := parseBlockSeverity(" 10:1h, 2:5m ,")
+	require.NoError(t, err)
+	assert.Equal(t, []BlockSeverityTier{{MinRatio: 2, Duration: 5 * time.Minute}, {MinRatio: 10, Duration: time.Hour}}, tiers)
+
+	tiers, err = parseBlockSeverity("")
+	require.NoError(t, err)
+	assert.Empty(t, tiers)
+
+	for _, value := range []string{"2", "2:cinco", "x:5m", "0.5:5m", "2:0s"} {
+		_, err := parseBlockSeverity(value)
+		assert.ErrorContains(t, err, "BLOCK_SEVERITY", value)
+	}
+}
+
+// Test_ParseLimitSchedule verifica a leitura das faixas de horário, inclusive as que viram a meia-noite
+func Test_ParseLimitSchedule(t *testing.T) {
+	ranges, err := parseLimitSchedule("09:00-18:30=2, 22:00-06:00=0.5")
+	require.NoError(t, err)
+	assert.Equal(t, []ScheduleRange{
+		{Start: 9 * time.Hour, End: 18*time.Hour + 30*time.Minute, Multiplier: 2},
+		{Start: 22 * time.Hour, End: 6 * time.Hour, Multiplier: 0.5},
+	}, ranges)
+
+	for _, value := range []string{"09:00-18:00", "09:00=2", "9h-18h=2", "09:00-18:00=0", "09:00-25:00=2",
+		"09:00-18:00=NaN", "09:00-18:00=Inf", "09:00-18:00=-Inf"} {
+		_, err := parseLimitSchedule(value)
+		assert.ErrorContains(t, err, "LIMIT_SCHEDULE", value)
+	}
+}
+
+func Test_ParseTokenSources(t *testing.T) {
+	tiers, err := parseTokenTiers("partner=100/1s/5m, premium=1000/0s/0s")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TokenTier{
+		"partner": {MaxRequests: 100, WindowSeconds: 1, BlockDurationSeconds: 300},
+		"premium": {MaxRequests: 1000},
+	}, tiers)
+
+	sources, err := parseTokenSources("API_KEY, X-Api-Key:partner,Authorization:premium", tiers)
+	require.NoError(t, err)
+	assert.Equal(t, []TokenSource{
+		{Header: "API_KEY"},
+		{Header: "X-Api-Key", Tier: "partner"},
+		{Header: "Authorization", Tier: "premium"},
+	}, sources)
+
+	for _, value := range []string{"partner", "partner=100/1s", "=100/1s/5m", "partner=-1/1s/5m", "partner=100/500ms/5m"} {
+		_, err := parseTokenTiers(value)
+		assert.ErrorContains(t, err, "TOKEN_TIERS", value)
+	}
+	sources, err = parseTokenSources("Basic:partner,API_KEY", tiers)
+	require.NoError(t, err)
+	assert.Equal(t, []TokenSource{
+		{Header: "Authorization", Tier: "partner", BasicAuth: true},
+		{Header: "API_KEY"},
+	}, sources)
+
+	for _, value := range []string{":partner", "X-Api-Key:gold"} {
+		_, err := parseTokenSources(value, tiers)
+		assert.ErrorContains(t, err, "TOKEN_", value)
+	}
+}
+
+func Test_ParseRouteLimits(t *testing.T) {
+	limits, err := parseRouteLimits("reports:/api/reports=5/1m/10m, export:/api/export=2/0s/0s")
+	require.NoError(t, err)
+	assert.Equal(t, []RouteLimit{
+		{Name: "reports", PathPrefix: "/api/reports", MaxRequests: 5, WindowSeconds: 60, BlockDurationSeconds: 600},
+		{Name: "export", PathPrefix: "/api/export", MaxRequests: 2},
+	}, limits)
+
+	limits, err = parseRouteLimits("")
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	for _, value := range []string{
+		"reports", "reports=5/1m/10m", ":/api/reports=5/1m/10m", "reports:api/reports=5/1m/10m",
+		"reports:/api/reports=5/1m", "reports:/api/reports=-1/1m/10m", "reports:/api/reports=5/500ms/10m",
+		"reports:/a=5/1m/10m,reports:/b=5/1m/10m",
+	} {
+		_, err := parseRouteLimits(value)
+		assert.ErrorContains(t, err, "ROUTE_LIMITS", value)
+	}
+}
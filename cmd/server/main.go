@@ -4,20 +4,75 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 
 	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+	"rateLimiter/infra/db/breaker"
+	"rateLimiter/infra/db/memory"
 	redisStore "rateLimiter/infra/db/redis"
 	"rateLimiter/internal/rateLimiter"
+	"rateLimiter/pkg/metrics"
 	"rateLimiter/pkg/middleware"
 )
 
+// preloadLimits carrega os limites dos tokens informados, em cada faixa e classe da
+// configuração, sem impedir a inicialização em caso de erro.
+func preloadLimits(preloader db.LimitPreloader, cfg *config.LimiterConfig, tokens []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, preloadCtx := range rateLimiter.PreloadContexts(ctx, cfg) {
+		if err := preloader.Preload(preloadCtx, tokens); err != nil {
+			log.Printf("Aviso: não foi possível pré-carregar os limites dos tokens: %v", err)
+			return
+		}
+	}
+	log.Printf("Limites de %d tokens pré-carregados.", len(tokens))
+}
+
+// reconcileCap retorna o teto da soma na reconciliação: o limite geral do escopo, para os
+// contadores de IP e de token, e nenhum para as demais chaves.
+func reconcileCap(cfg *config.LimiterConfig) func(key string) int64 {
+	ipPrefix := cfg.StoreKeyPrefix() + cfg.IPKeyPrefix + "ip_"
+	tokenPrefix := cfg.StoreKeyPrefix() + cfg.TokenKeyPrefix + "token_"
+	return func(key string) int64 {
+		switch {
+		case strings.HasPrefix(key, tokenPrefix):
+			return int64(cfg.MaxRequestsPerToken)
+		case strings.HasPrefix(key, ipPrefix):
+			return int64(cfg.MaxRequestsPerIP)
+		}
+		return 0
+	}
+}
+
+// fallbackKeyPatterns retorna os padrões das chaves do rate limiter copiadas para o fallback em
+// memória e reconciliadas na recuperação: contadores, bloqueios e infrações de cada escopo e o
+// contador global. As demais chaves do Redis, como o hash de TOKEN_LIMITS_HASH, ficam de fora.
+func fallbackKeyPatterns(cfg *config.LimiterConfig) []string {
+	prefix := cfg.StoreKeyPrefix()
+	patterns := []string{prefix + rateLimiter.GlobalIdentifier}
+	for _, scope := range []struct{ prefix, key string }{
+		{cfg.IPKeyPrefix, "ip_"},
+		{cfg.TokenKeyPrefix, "token_"},
+	} {
+		for _, family := range []string{"", "blocked_", "offenses_"} {
+			patterns = append(patterns, prefix+scope.prefix+family+scope.key+"*")
+		}
+	}
+	return patterns
+}
+
 func main() {
 	// Carregar configuração
 	configRateLimiter, err := config.LoadConfigRateLimiter()
@@ -35,17 +90,125 @@ func main() {
 		Addr: redisAddr,
 	})
 
-	// Verificar conexão com o Redis
-	ctxRedis, cancelRedis := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancelRedis()
-	if err := rdb.Ping(ctxRedis).Err(); err != nil {
+	// Verificar conexão com o Redis, esperando por ele se ainda estiver subindo
+	err = redisStore.WaitForRedis(context.Background(), rdb, redisStore.ConnectPolicy{
+		MaxAttempts: configRateLimiter.RedisConnectMaxAttempts,
+		MaxWait:     time.Duration(configRateLimiter.RedisConnectMaxWaitSeconds) * time.Second,
+		BaseBackoff: time.Duration(configRateLimiter.RedisConnectBackoffMs) * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	})
+	if err != nil {
 		log.Fatalf("Não foi possível conectar ao Redis em %s: %v", redisAddr, err)
 	}
 	log.Println("Conectado ao Redis com sucesso!")
+	ctxRedis, cancelRedis := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRedis()
+
+	registry := metrics.NewRegistry()
 
 	// Criar store e rate limiter
-	store := redisStore.NewRedisStore(rdb)
-	rl := rateLimiter.NewRateLimiter(configRateLimiter, store)
+	baseStore := redisStore.NewRedisStore(rdb,
+		redisStore.WithServerTime(configRateLimiter.SlidingWindowTimeSource == config.TimeSourceServer),
+		redisStore.WithRetryPolicy(redisStore.RetryPolicy{
+			MaxAttempts: configRateLimiter.RedisRetryMaxAttempts,
+			BaseBackoff: time.Duration(configRateLimiter.RedisRetryBackoffMs) * time.Millisecond,
+			Jitter:      configRateLimiter.RedisRetryJitter,
+		}),
+		redisStore.WithAuditStream(configRateLimiter.AuditStream))
+	if configRateLimiter.BlockJournal {
+		redisStore.WithBlockJournal(db.NewMemoryBlockJournal())(baseStore)
+	}
+	if err := baseStore.Verify(ctxRedis); err != nil {
+		log.Fatalf("O Redis em %s não é compatível com o rate limiter (é preciso o Redis 3.2 ou superior, com scripts Lua liberados): %v", redisAddr, err)
+	}
+	if baseStore.ScriptsDisabled() {
+		switch configRateLimiter.Algorithm {
+		case config.AlgorithmSlidingWindow, config.AlgorithmLeakyBucket:
+			log.Fatalf("O algoritmo %s exige scripts Lua, que o Redis em %s não aceita", configRateLimiter.Algorithm, redisAddr)
+		}
+	}
+	// Publicar periodicamente a utilização dos identificadores mais ocupados e o estado do pool do
+	// Redis, e copiar as contagens para o fallback em memória
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	if configRateLimiter.GenerationCheckSeconds > 0 {
+		watcher := redisStore.NewGenerationWatcher(baseStore, configRateLimiter.StoreKeyPrefix()+"generation")
+		go watcher.Run(monitorCtx, time.Duration(configRateLimiter.GenerationCheckSeconds)*time.Second)
+	}
+
+	var store db.Store = db.NewObservedStore(baseStore, registry)
+	if configRateLimiter.CircuitBreakerThreshold > 0 {
+		breakerCfg := breaker.Config{
+			FailureThreshold: configRateLimiter.CircuitBreakerThreshold,
+			Cooldown:         time.Duration(configRateLimiter.CircuitBreakerCooldownSeconds) * time.Second,
+			Metrics:          registry,
+		}
+		if configRateLimiter.MemoryFallback {
+			fallback := memory.NewMemoryStore(memory.Config{})
+			baseline := db.NewBaseline(baseStore, db.BaselineConfig{
+				Matches: fallbackKeyPatterns(configRateLimiter),
+				Exclude: func(key string) bool { return key == configRateLimiter.TokenLimitsHash },
+			})
+			go baseline.Run(monitorCtx, time.Duration(configRateLimiter.FallbackSeedIntervalSeconds)*time.Second)
+			breakerCfg.Fallback = fallback
+			breakerCfg.OnFallback = func(db.Store) {
+				n, err := baseline.Seed(context.Background(), fallback)
+				if err != nil {
+					log.Printf("Aviso: o fallback em memória começa sem as contagens do Redis: %v", err)
+					return
+				}
+				log.Printf("Fallback em memória ativado com %d chaves copiadas do Redis.", n)
+			}
+			breakerCfg.OnRecover = func(db.Store) {
+				n, err := db.Reconcile(context.Background(), baseStore, fallback, db.ReconcileConfig{
+					Policy:   db.ReconcilePolicy(configRateLimiter.FallbackReconcilePolicy),
+					Matches:  fallbackKeyPatterns(configRateLimiter),
+					Cap:      reconcileCap(configRateLimiter),
+					Baseline: baseline.SeededCount,
+				})
+				if err != nil {
+					log.Printf("Aviso: as contagens do fallback em memória não foram reconciliadas: %v", err)
+					return
+				}
+				log.Printf("Redis de volta: %d chaves reconciliadas com o fallback em memória.", n)
+			}
+		}
+		store = breaker.NewStore(store, breakerCfg)
+	}
+
+	var resolver db.LimitResolver = rateLimiter.NewStaticLimitResolver(configRateLimiter)
+	if configRateLimiter.TokenLimitsHash != "" {
+		redisResolver := redisStore.NewRedisLimitResolver(rdb, configRateLimiter.TokenLimitsHash, resolver)
+		cachingResolver := rateLimiter.NewCachingLimitResolver(
+			redisResolver,
+			time.Duration(configRateLimiter.LimitCacheTTLSeconds)*time.Second,
+			configRateLimiter.LimitCacheMaxSize,
+		)
+		resolver = cachingResolver
+
+		// Pré-carregar os limites dos tokens conhecidos para evitar consultas na primeira requisição
+		if len(configRateLimiter.PreloadTokens) > 0 {
+			preloadLimits(cachingResolver, configRateLimiter, configRateLimiter.PreloadTokens)
+		}
+	}
+	limiterOpts := []rateLimiter.Option{rateLimiter.WithLimitResolver(resolver), rateLimiter.WithMetrics(registry)}
+	if configRateLimiter.DebugLogging {
+		// Os registros de depuração só aparecem com um handler em nível debug
+		debugLogger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		limiterOpts = append(limiterOpts, rateLimiter.WithLogger(debugLogger))
+	}
+	rl := rateLimiter.NewRateLimiter(configRateLimiter, store, limiterOpts...)
+
+	if configRateLimiter.UtilizationTopN > 0 {
+		monitor := rateLimiter.NewUtilizationMonitor(baseStore, resolver, registry,
+			configRateLimiter.StoreKeyPrefix(), configRateLimiter.UtilizationTopN).
+			WithScopePrefixes(configRateLimiter.IPKeyPrefix, configRateLimiter.TokenKeyPrefix)
+		go monitor.Run(monitorCtx, time.Duration(configRateLimiter.UtilizationScanIntervalSeconds)*time.Second)
+	}
+	if configRateLimiter.RedisPoolStatsIntervalSeconds > 0 {
+		collector := redisStore.NewPoolStatsCollector(baseStore, registry)
+		go collector.Run(monitorCtx, time.Duration(configRateLimiter.RedisPoolStatsIntervalSeconds)*time.Second)
+	}
 
 	// Configurar servidor HTTP
 	router := http.NewServeMux()
@@ -54,7 +217,77 @@ func main() {
 	})
 
 	// Aplicar o middleware de rate limiting
-	protectedHandler := middleware.RateLimit(rl)(router)
+	trustedProxies, err := middleware.ParsePrefixes(configRateLimiter.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Erro ao carregar TRUSTED_PROXIES: %v", err)
+	}
+	middlewareOpts := []middleware.Option{
+		middleware.WithTrustedProxies(trustedProxies...),
+		middleware.WithXForwardedForSelect(configRateLimiter.XForwardedForSelect),
+		middleware.WithPreflightPolicy(configRateLimiter.PreflightPolicy),
+		middleware.WithEmptyTokenPolicy(configRateLimiter.EmptyTokenPolicy),
+		middleware.WithRejectionBody(configRateLimiter.RejectionBodyTemplate),
+		middleware.WithRejectionFormat(configRateLimiter.RejectionFormat),
+		middleware.WithSkipPrivateNetworks(configRateLimiter.SkipPrivateNetworks),
+		middleware.WithMaxIdentifierLength(configRateLimiter.MaxIdentifierLength, configRateLimiter.RejectLongIdentifiers),
+		middleware.WithKeyComponents(configRateLimiter.KeyComponents),
+		middleware.WithIPHashing([]byte(configRateLimiter.IPHashSecret)),
+		middleware.WithIdentifierNormalization(middleware.IdentifierNormalization{
+			TrimTokens:            configRateLimiter.TokenTrimSpace,
+			CaseInsensitiveTokens: configRateLimiter.TokenCaseInsensitive,
+			UnmapIPv4:             configRateLimiter.IPUnmapIPv4,
+		}),
+		middleware.WithUnknownBucket(configRateLimiter.UnknownBucket),
+		middleware.WithIdempotencyKey(configRateLimiter.IdempotencyKeyHeader),
+		middleware.WithHeaderScheme(configRateLimiter.HeaderScheme),
+		middleware.WithRemainingDecimals(configRateLimiter.RemainingDecimals),
+		middleware.WithTarpit(time.Duration(configRateLimiter.TarpitDelayMs) * time.Millisecond),
+		middleware.WithOverheadHeader(configRateLimiter.OverheadHeader),
+		middleware.WithStoreErrorResponse(configRateLimiter.StoreErrorStatus,
+			time.Duration(configRateLimiter.StoreErrorRetryAfterSeconds)*time.Second),
+	}
+	if configRateLimiter.PathKeyPattern != "" {
+		middlewareOpts = append(middlewareOpts,
+			middleware.WithPathParam(middleware.PathRegexParam(regexp.MustCompile(configRateLimiter.PathKeyPattern))))
+	}
+	if configRateLimiter.PathKeyPattern != "" || len(configRateLimiter.RouteLimits) > 0 {
+		middlewareOpts = append(middlewareOpts,
+			middleware.WithRouteNormalization(middleware.RouteNormalization{
+				CaseInsensitive:     configRateLimiter.RouteCaseInsensitive,
+				IgnoreTrailingSlash: configRateLimiter.RouteIgnoreTrailingSlash,
+			}))
+	}
+	if configRateLimiter.SplitReadWrite {
+		middlewareOpts = append(middlewareOpts,
+			middleware.WithMethodClassifier(middleware.ReadWriteClassifier(configRateLimiter.ReadMethods...)))
+	}
+	if configRateLimiter.ExpvarName != "" {
+		vars, err := middleware.ExpvarMap(configRateLimiter.ExpvarName)
+		if err != nil {
+			log.Fatalf("Erro ao carregar EXPVAR_NAME: %v", err)
+		}
+		middlewareOpts = append(middlewareOpts, middleware.WithExpvarMap(vars))
+	}
+	var history *middleware.RequestHistory
+	if configRateLimiter.RequestHistorySize > 0 {
+		history = middleware.NewRequestHistory(configRateLimiter.RequestHistorySize, configRateLimiter.RequestHistoryMaxIdentifiers)
+		middlewareOpts = append(middlewareOpts, middleware.WithRequestHistory(history))
+	}
+	protectedHandler := middleware.RateLimit(rl, middlewareOpts...)(router)
+
+	// Os endpoints de métricas e de diagnóstico ficam fora do rate limiting
+	rootMux := http.NewServeMux()
+	rootMux.Handle("/metrics", registry)
+	if configRateLimiter.ConfigEndpointSecret != "" {
+		rootMux.Handle("/debug/config", middleware.ConfigHandler(rl, configRateLimiter.ConfigEndpointSecret))
+		if configRateLimiter.ExpvarName != "" {
+			rootMux.Handle("/debug/vars", middleware.ExpvarHandler(configRateLimiter.ConfigEndpointSecret))
+		}
+		if history != nil {
+			rootMux.Handle("/debug/history", middleware.RequestHistoryHandler(history, configRateLimiter.ConfigEndpointSecret))
+		}
+	}
+	rootMux.Handle("/", protectedHandler)
 
 	serverPort := os.Getenv("SERVER_PORT")
 	if serverPort == "" {
@@ -63,7 +296,7 @@ func main() {
 
 	srv := &http.Server{
 		Addr:         ":" + serverPort,
-		Handler:      protectedHandler,
+		Handler:      rootMux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
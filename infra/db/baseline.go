@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BaselineConfig define os parâmetros do Baseline.
+type BaselineConfig struct {
+	// Matches são os padrões (glob do Redis) das chaves copiadas (padrão: "*"). Cada padrão é
+	// uma varredura do store de origem a cada Refresh, e as chaves copiadas ficam em memória:
+	// restrinja-os às chaves do rate limiter.
+	Matches []string
+	// Exclude descarta chaves que casam com Matches mas não pertencem ao rate limiter (ex.: o
+	// hash dos limites por token com um nome como "token_limits") (opcional).
+	Exclude func(key string) bool
+	// Timeout limita a leitura feita por Seed antes de recorrer à última cópia (padrão: 1s).
+	Timeout time.Duration
+	// Now permite injetar o relógio nos testes (opcional).
+	Now func() time.Time
+}
+
+// Baseline guarda a última cópia das chaves de um store (ex.: o Redis) para semear outro com
+// contagens realistas em vez de zero, como o fallback em memória quando o circuit breaker abre
+// ou uma instância recém-iniciada. As contagens herdadas são aproximadas: o que mudou no
+// store de origem depois da cópia se perde.
+type Baseline struct {
+	source Snapshotter
+	cfg    BaselineConfig
+
+	mu      sync.Mutex
+	entries []SnapshotEntry
+	takenAt time.Time
+	seeded  map[string]int64
+}
+
+// NewBaseline cria um Baseline que copia as chaves de source.
+func NewBaseline(source Snapshotter, cfg BaselineConfig) *Baseline {
+	if len(cfg.Matches) == 0 {
+		cfg.Matches = []string{"*"}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Baseline{source: source, cfg: cfg}
+}
+
+// Refresh substitui a cópia guardada pelas chaves atuais do store de origem.
+func (b *Baseline) Refresh(ctx context.Context) error {
+	takenAt := b.cfg.Now()
+	entries, err := dumpMatching(ctx, b.source, b.cfg.Matches)
+	if err != nil {
+		return fmt.Errorf("erro ao copiar as contagens do store: %w", err)
+	}
+	if b.cfg.Exclude != nil {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if !b.cfg.Exclude(entry.Key) {
+				kept = append(kept, entry)
+			}
+		}
+		entries = kept
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries, b.takenAt = entries, takenAt
+	return nil
+}
+
+// Run atualiza a cópia a cada intervalo até o contexto ser cancelado, para que Seed tenha
+// contagens recentes mesmo que o store de origem já esteja fora do ar. Com um intervalo não
+// positivo, atualiza a cópia uma única vez.
+func (b *Baseline) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		if err := b.Refresh(ctx); err != nil {
+			log.Printf("Aviso: %v", err)
+		}
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := b.Refresh(ctx); err != nil {
+			log.Printf("Aviso: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Seed grava as contagens em target e retorna quantas chaves foram gravadas. Tenta primeiro
+// uma cópia nova, limitada por Timeout, e, se o store de origem falhar, usa a última guardada,
+// com os TTLs descontados do tempo passado desde a cópia; as chaves já expiradas ficam de fora.
+func (b *Baseline) Seed(ctx context.Context, target Snapshotter) (int, error) {
+	refreshCtx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	refreshErr := b.Refresh(refreshCtx)
+	cancel()
+
+	b.mu.Lock()
+	entries, takenAt := b.entries, b.takenAt
+	b.mu.Unlock()
+	if takenAt.IsZero() {
+		return 0, refreshErr
+	}
+
+	age := b.cfg.Now().Sub(takenAt)
+	seeded := make([]SnapshotEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.TTL > 0 {
+			if entry.TTL <= age {
+				continue
+			}
+			entry.TTL -= age
+		}
+		seeded = append(seeded, entry)
+	}
+	if err := target.Restore(ctx, seeded); err != nil {
+		return 0, fmt.Errorf("erro ao gravar as contagens copiadas: %w", err)
+	}
+
+	counts := make(map[string]int64, len(seeded))
+	for _, entry := range seeded {
+		if count, err := strconv.ParseInt(entry.Value, 10, 64); err == nil && entry.Fields == nil {
+			counts[entry.Key] = count
+		}
+	}
+	b.mu.Lock()
+	b.seeded = counts
+	b.mu.Unlock()
+	return len(seeded), nil
+}
+
+// SeededCount retorna a contagem com que a chave foi semeada pelo último Seed (0 se ela não
+// foi semeada ou não é um contador). Serve para separar, na reconciliação, as contagens
+// herdadas das feitas no store semeado (ver ReconcileConfig.Baseline).
+func (b *Baseline) SeededCount(key string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seeded[key]
+}
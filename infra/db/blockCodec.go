@@ -0,0 +1,23 @@
+package db
+
+import "encoding/json"
+
+// BlockCodec serializa os metadados dos bloqueios (BlockInfo) gravados no store. Um formato
+// binário compacto (ex.: msgpack) economiza memória quando há muitos bloqueios ativos.
+type BlockCodec interface {
+	Marshal(info BlockInfo) ([]byte, error)
+	Unmarshal(data []byte, info *BlockInfo) error
+}
+
+// JSONBlockCodec grava os metadados em JSON, o formato padrão.
+type JSONBlockCodec struct{}
+
+// Marshal serializa os metadados em JSON.
+func (JSONBlockCodec) Marshal(info BlockInfo) ([]byte, error) {
+	return json.Marshal(info)
+}
+
+// Unmarshal lê os metadados em JSON.
+func (JSONBlockCodec) Unmarshal(data []byte, info *BlockInfo) error {
+	return json.Unmarshal(data, info)
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BlockJournal guarda os bloqueios fora do store, para que sejam reaplicados quando o Redis
+// reinicia sem persistência e perde as chaves.
+type BlockJournal interface {
+	// Record registra o bloqueio gravado em key, válido até info.ExpiresAt.
+	Record(ctx context.Context, key string, info BlockInfo) error
+	// Forget descarta os bloqueios removidos do store (ex.: desbloqueio manual).
+	Forget(ctx context.Context, keys ...string) error
+	// Active retorna os bloqueios ainda válidos em now, por chave.
+	Active(ctx context.Context, now time.Time) (map[string]BlockInfo, error)
+}
+
+// MemoryBlockJournal guarda os bloqueios na memória do processo. Sobrevive a um reinício do
+// Redis, mas não ao do servidor, e cada instância conhece só os bloqueios que gravou.
+type MemoryBlockJournal struct {
+	mu     sync.Mutex
+	blocks map[string]BlockInfo
+}
+
+// NewMemoryBlockJournal cria um journal em memória vazio.
+func NewMemoryBlockJournal() *MemoryBlockJournal {
+	return &MemoryBlockJournal{blocks: make(map[string]BlockInfo)}
+}
+
+// Record registra o bloqueio. Bloqueios sem ExpiresAt são ignorados, já que não há como saber
+// o tempo restante ao reaplicá-los.
+func (j *MemoryBlockJournal) Record(_ context.Context, key string, info BlockInfo) error {
+	if info.ExpiresAt.IsZero() {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.blocks[key] = info
+	return nil
+}
+
+// Forget descarta os bloqueios das chaves informadas.
+func (j *MemoryBlockJournal) Forget(_ context.Context, keys ...string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, key := range keys {
+		delete(j.blocks, key)
+	}
+	return nil
+}
+
+// Active retorna os bloqueios que expiram depois de now, descartando os expirados.
+func (j *MemoryBlockJournal) Active(_ context.Context, now time.Time) (map[string]BlockInfo, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	active := make(map[string]BlockInfo, len(j.blocks))
+	for key, info := range j.blocks {
+		if !info.ExpiresAt.After(now) {
+			delete(j.blocks, key)
+			continue
+		}
+		active[key] = info
+	}
+	return active, nil
+}
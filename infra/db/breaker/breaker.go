@@ -0,0 +1,436 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/pkg/metrics"
+)
+
+// ErrCircuitOpen é retornado sem chamar o store quando o circuito está aberto.
+var ErrCircuitOpen = errors.New("circuit breaker aberto: store indisponível")
+
+// State representa o estado do circuit breaker.
+type State int
+
+const (
+	// Closed é o estado normal: as chamadas chegam ao store.
+	Closed State = iota
+	// Open faz as chamadas falharem imediatamente, ou seguirem para o Fallback, até o fim do cooldown.
+	Open
+	// HalfOpen deixa passar uma única chamada de teste.
+	HalfOpen
+)
+
+// String retorna o nome do estado.
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Config define os parâmetros do circuit breaker.
+type Config struct {
+	// FailureThreshold é o número de erros consecutivos que abre o circuito.
+	FailureThreshold int
+	// Cooldown é o tempo que o circuito fica aberto antes de testar o store novamente.
+	Cooldown time.Duration
+	// Metrics recebe o estado do circuito e as chamadas interrompidas (opcional).
+	Metrics metrics.Recorder
+	// Now permite injetar o relógio nos testes (opcional).
+	Now func() time.Time
+	// Fallback atende as chamadas enquanto o circuito está aberto, em vez de ErrCircuitOpen
+	// (opcional; ex.: um memory.MemoryStore).
+	Fallback db.Store
+	// OnFallback é chamado cada vez que o circuito abre, para preparar o Fallback (opcional;
+	// ex.: Baseline.Seed, com as últimas contagens do Redis). As chamadas feitas enquanto ele
+	// executa já são atendidas pelo Fallback.
+	OnFallback func(fallback db.Store)
+	// OnRecover é chamado quando o circuito fecha, depois de um período em que o Fallback
+	// atendeu as chamadas, para reconciliar as contagens que divergiram (opcional; ex.:
+	// db.Reconcile).
+	OnRecover func(fallback db.Store)
+}
+
+// Store é um decorator de db.Store que interrompe as chamadas após erros consecutivos. Com um
+// Fallback, as chamadas interrompidas são atendidas por ele.
+type Store struct {
+	next db.Store
+	cfg  Config
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// OnFallback e OnRecover rodam fora da requisição, um de cada vez e na ordem das transições
+	hookMu      sync.Mutex
+	hookQueue   []func(db.Store)
+	hookRunning bool
+	hooks       sync.WaitGroup
+}
+
+// NewStore cria um circuit breaker em volta do store informado.
+func NewStore(next db.Store, cfg Config) *Store {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.Noop{}
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	s := &Store{next: next, cfg: cfg}
+	s.cfg.Metrics.SetGauge("ratelimiter_store_circuit_state", float64(Closed), nil)
+	return s
+}
+
+// State retorna o estado atual do circuito.
+func (s *Store) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// before decide para qual store a chamada segue: o decorado, o Fallback com o circuito aberto
+// ou nenhum (ErrCircuitOpen).
+func (s *Store) before() (db.Store, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state == Open && s.cfg.Now().Sub(s.openedAt) >= s.cfg.Cooldown {
+		s.setState(HalfOpen)
+	}
+
+	switch s.state {
+	case Open:
+		return s.shortCircuit()
+	case HalfOpen:
+		// Apenas uma chamada de teste por vez enquanto o circuito está meio aberto
+		if s.probing {
+			return s.shortCircuit()
+		}
+		s.probing = true
+	}
+	return s.next, nil
+}
+
+// shortCircuit desvia a chamada para o Fallback, se houver. Deve ser chamado com o lock.
+func (s *Store) shortCircuit() (db.Store, error) {
+	s.cfg.Metrics.IncCounter("ratelimiter_store_circuit_short_circuits_total", nil)
+	if s.cfg.Fallback != nil {
+		return s.cfg.Fallback, nil
+	}
+	return nil, ErrCircuitOpen
+}
+
+// after registra o resultado da chamada e atualiza o estado. As chamadas atendidas pelo
+// Fallback não contam, nem as interrompidas pelo cancelamento ou pelo prazo do próprio contexto
+// (ex.: o cliente desconectou), que não dizem nada sobre o store. Quando o circuito abre,
+// OnFallback é disparado em segundo plano, e quando fecha, OnRecover.
+func (s *Store) after(ctx context.Context, target db.Store, err error) {
+	if target != s.next {
+		return
+	}
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		s.mu.Lock()
+		s.probing = false
+		s.mu.Unlock()
+		return
+	}
+	opened, recovered := s.recordResult(err)
+	if s.cfg.Fallback == nil {
+		return
+	}
+	if opened && s.cfg.OnFallback != nil {
+		s.runHook(s.cfg.OnFallback)
+	}
+	if recovered && s.cfg.OnRecover != nil {
+		s.runHook(s.cfg.OnRecover)
+	}
+}
+
+// runHook enfileira o hook para execução em segundo plano, para que a requisição que causou a
+// transição não espere a cópia ou a reconciliação das chaves. Os hooks rodam um de cada vez, na
+// ordem em que foram disparados.
+func (s *Store) runHook(hook func(db.Store)) {
+	s.hooks.Add(1)
+	s.hookMu.Lock()
+	s.hookQueue = append(s.hookQueue, hook)
+	if s.hookRunning {
+		s.hookMu.Unlock()
+		return
+	}
+	s.hookRunning = true
+	s.hookMu.Unlock()
+
+	go func() {
+		for {
+			s.hookMu.Lock()
+			if len(s.hookQueue) == 0 {
+				s.hookRunning = false
+				s.hookMu.Unlock()
+				return
+			}
+			next := s.hookQueue[0]
+			s.hookQueue = s.hookQueue[1:]
+			s.hookMu.Unlock()
+			s.callHook(next)
+		}
+	}()
+}
+
+// callHook executa o hook, registrando em log um panic em vez de derrubar o processo.
+func (s *Store) callHook(hook func(db.Store)) {
+	defer s.hooks.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Erro no hook do circuit breaker: %v", r)
+		}
+	}()
+	hook(s.cfg.Fallback)
+}
+
+// recordResult atualiza o estado com o resultado da chamada e informa se o circuito abriu ou
+// fechou.
+func (s *Store) recordResult(err error) (opened, recovered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wasProbe := s.state == HalfOpen
+	s.probing = false
+
+	if err == nil {
+		s.failures = 0
+		if s.state != Closed {
+			s.setState(Closed)
+			return false, true
+		}
+		return false, false
+	}
+
+	s.failures++
+	if wasProbe || s.failures >= s.cfg.FailureThreshold {
+		s.openedAt = s.cfg.Now()
+		opened = s.state != Open
+		s.setState(Open)
+		return opened, false
+	}
+	return false, false
+}
+
+// setState altera o estado e publica a métrica. Deve ser chamado com o lock.
+func (s *Store) setState(state State) {
+	s.state = state
+	s.cfg.Metrics.SetGauge("ratelimiter_store_circuit_state", float64(state), nil)
+}
+
+// Increment delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	target, err := s.before()
+	if err != nil {
+		return 0, err
+	}
+	count, err := target.Increment(ctx, key, window)
+	s.after(ctx, target, err)
+	return count, err
+}
+
+// IncrementBy delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	target, err := s.before()
+	if err != nil {
+		return 0, err
+	}
+	total, err := target.IncrementBy(ctx, key, n, window)
+	s.after(ctx, target, err)
+	return total, err
+}
+
+// IncrementWithTTL delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) IncrementWithTTL(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	target, err := s.before()
+	if err != nil {
+		return 0, 0, err
+	}
+	count, ttl, err := target.IncrementWithTTL(ctx, key, window)
+	s.after(ctx, target, err)
+	return count, ttl, err
+}
+
+// CheckAndCount delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) CheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	target, err := s.before()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	allowed, remaining, retryAfter, err := target.CheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	s.after(ctx, target, err)
+	return allowed, remaining, retryAfter, err
+}
+
+// CheckAndCountWithGlobal delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) CheckAndCountWithGlobal(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global db.GlobalCount) (bool, int64, time.Duration, int64, error) {
+	target, err := s.before()
+	if err != nil {
+		return false, 0, 0, 0, err
+	}
+	allowed, remaining, retryAfter, globalCount, err := target.CheckAndCountWithGlobal(ctx, keys, limit, window, blockDuration, now, global)
+	s.after(ctx, target, err)
+	return allowed, remaining, retryAfter, globalCount, err
+}
+
+// SlidingWindow delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	target, err := s.before()
+	if err != nil {
+		return false, 0, err
+	}
+	allowed, count, err := target.SlidingWindow(ctx, key, limit, window, now)
+	s.after(ctx, target, err)
+	return allowed, count, err
+}
+
+// SlidingWindowCheckAndCount delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) SlidingWindowCheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	target, err := s.before()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	allowed, count, retryAfter, err := target.SlidingWindowCheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	s.after(ctx, target, err)
+	return allowed, count, retryAfter, err
+}
+
+// LeakyBucket delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) LeakyBucket(ctx context.Context, keys db.CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	target, err := s.before()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	allowed, level, retryAfter, err := target.LeakyBucket(ctx, keys, capacity, leakInterval, now)
+	s.after(ctx, target, err)
+	return allowed, level, retryAfter, err
+}
+
+// Count delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) Count(ctx context.Context, key string) (int64, error) {
+	target, err := s.before()
+	if err != nil {
+		return 0, err
+	}
+	count, err := target.Count(ctx, key)
+	s.after(ctx, target, err)
+	return count, err
+}
+
+// IsBlocked delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) IsBlocked(ctx context.Context, key string) (bool, error) {
+	target, err := s.before()
+	if err != nil {
+		return false, err
+	}
+	blocked, err := target.IsBlocked(ctx, key)
+	s.after(ctx, target, err)
+	return blocked, err
+}
+
+// Block delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) Block(ctx context.Context, key string, duration time.Duration, info db.BlockInfo) error {
+	target, err := s.before()
+	if err != nil {
+		return err
+	}
+	err = target.Block(ctx, key, duration, info)
+	s.after(ctx, target, err)
+	return err
+}
+
+// BlockInfo delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) BlockInfo(ctx context.Context, key string) (*db.BlockInfo, error) {
+	target, err := s.before()
+	if err != nil {
+		return nil, err
+	}
+	info, err := target.BlockInfo(ctx, key)
+	s.after(ctx, target, err)
+	return info, err
+}
+
+// Get delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	target, err := s.before()
+	if err != nil {
+		return nil, err
+	}
+	val, err := target.Get(ctx, key)
+	s.after(ctx, target, err)
+	return val, err
+}
+
+// Set delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	target, err := s.before()
+	if err != nil {
+		return err
+	}
+	err = target.Set(ctx, key, value, ttl)
+	s.after(ctx, target, err)
+	return err
+}
+
+// Reset delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) Reset(ctx context.Context, key string) error {
+	target, err := s.before()
+	if err != nil {
+		return err
+	}
+	err = target.Reset(ctx, key)
+	s.after(ctx, target, err)
+	return err
+}
+
+// ResetAll delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) ResetAll(ctx context.Context, keys ...string) error {
+	target, err := s.before()
+	if err != nil {
+		return err
+	}
+	err = target.ResetAll(ctx, keys...)
+	s.after(ctx, target, err)
+	return err
+}
+
+// DeleteMatching delega ao store se o circuito permitir, ou ao Fallback.
+func (s *Store) DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error) {
+	target, err := s.before()
+	if err != nil {
+		return 0, err
+	}
+	n, err := target.DeleteMatching(ctx, match, allow)
+	s.after(ctx, target, err)
+	return n, err
+}
+
+// Close espera os hooks em execução e fecha o store decorado e o Fallback, independentemente
+// do estado do circuito.
+func (s *Store) Close() error {
+	s.hooks.Wait()
+	err := s.next.Close()
+	if s.cfg.Fallback != nil {
+		err = errors.Join(err, s.cfg.Fallback.Close())
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/pkg/metrics"
+)
+
+// fakeStore é um store que conta as chamadas e retorna o erro configurado
+type fakeStore struct {
+	calls  int
+	err    error
+	closed bool
+}
+
+func (f *fakeStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	f.calls++
+	return 1, f.err
+}
+
+func (f *fakeStore) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	f.calls++
+	return n, f.err
+}
+
+func (f *fakeStore) IncrementWithTTL(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	f.calls++
+	return 1, window, f.err
+}
+
+func (f *fakeStore) CheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	f.calls++
+	return true, limit - 1, 0, f.err
+}
+
+func (f *fakeStore) CheckAndCountWithGlobal(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global db.GlobalCount) (bool, int64, time.Duration, int64, error) {
+	f.calls++
+	return true, 0, 0, 0, f.err
+}
+
+func (f *fakeStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	f.calls++
+	return true, 1, f.err
+}
+
+func (f *fakeStore) SlidingWindowCheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	f.calls++
+	return true, 1, 0, f.err
+}
+
+func (f *fakeStore) LeakyBucket(ctx context.Context, keys db.CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	f.calls++
+	return true, 1, 0, f.err
+}
+
+func (f *fakeStore) Count(ctx context.Context, key string) (int64, error) {
+	f.calls++
+	return 1, f.err
+}
+
+func (f *fakeStore) IsBlocked(ctx context.Context, key string) (bool, error) {
+	f.calls++
+	return false, f.err
+}
+
+func (f *fakeStore) Block(ctx context.Context, key string, duration time.Duration, info db.BlockInfo) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeStore) BlockInfo(ctx context.Context, key string) (*db.BlockInfo, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *fakeStore) Get(ctx context.Context, key string) ([]byte, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *fakeStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeStore) Reset(ctx context.Context, key string) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeStore) ResetAll(ctx context.Context, keys ...string) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeStore) DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error) {
+	f.calls++
+	return 0, f.err
+}
+
+func (f *fakeStore) Close() error {
+	f.closed = true
+	return nil
+}
+
+// fakeClock é um relógio controlado manualmente
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// Test_Breaker_OpensAfterConsecutiveErrors verifica que o circuito abre e para de chamar o store
+func Test_Breaker_OpensAfterConsecutiveErrors(t *testing.T) {
+	fake := &fakeStore{err: errors.New("redis fora do ar")}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	registry := metrics.NewRegistry()
+	s := NewStore(fake, Config{FailureThreshold: 3, Cooldown: 10 * time.Second, Metrics: registry, Now: clock.Now})
+	ctx := context.Background()
+
+	// Três erros consecutivos chegam ao store e abrem o circuito
+	for i := 0; i < 3; i++ {
+		_, err := s.IsBlocked(ctx, "k")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	assert.Equal(t, 3, fake.calls)
+	assert.Equal(t, Open, s.State())
+
+	state, _ := registry.Gauge("ratelimiter_store_circuit_state", nil)
+	assert.Equal(t, float64(Open), state)
+
+	// Com o circuito aberto, o store não é mais chamado
+	for i := 0; i < 5; i++ {
+		_, err := s.Increment(ctx, "k", time.Second)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	}
+	assert.Equal(t, 3, fake.calls, "O store não deveria ser chamado com o circuito aberto")
+	assert.Equal(t, float64(5), registry.Counter("ratelimiter_store_circuit_short_circuits_total", nil))
+
+	// Antes do fim do cooldown o circuito continua aberto
+	clock.now = clock.now.Add(9 * time.Second)
+	_, err := s.IsBlocked(ctx, "k")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, fake.calls)
+}
+
+// Test_Breaker_HalfOpenProbe verifica a chamada de teste após o cooldown
+func Test_Breaker_HalfOpenProbe(t *testing.T) {
+	fake := &fakeStore{err: errors.New("redis fora do ar")}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	s := NewStore(fake, Config{FailureThreshold: 2, Cooldown: 5 * time.Second, Now: clock.Now})
+	ctx := context.Background()
+
+	_ = s.Block(ctx, "k", time.Second, db.BlockInfo{})
+	_ = s.Block(ctx, "k", time.Second, db.BlockInfo{})
+	assert.Equal(t, Open, s.State())
+
+	// Após o cooldown, uma chamada de teste que falha reabre o circuito
+	clock.now = clock.now.Add(5 * time.Second)
+	err := s.Reset(ctx, "k")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, fake.calls)
+	assert.Equal(t, Open, s.State())
+
+	// Após um novo cooldown, uma chamada de teste bem-sucedida fecha o circuito
+	clock.now = clock.now.Add(5 * time.Second)
+	fake.err = nil
+	_, err = s.Count(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, Closed, s.State())
+
+	_, err = s.IsBlocked(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, fake.calls)
+}
+
+// Test_Breaker_SuccessResetsFailures verifica que apenas erros consecutivos abrem o circuito
+func Test_Breaker_SuccessResetsFailures(t *testing.T) {
+	fake := &fakeStore{}
+	s := NewStore(fake, Config{FailureThreshold: 2, Cooldown: time.Second})
+	ctx := context.Background()
+
+	fake.err = errors.New("erro transitório")
+	_, _ = s.IsBlocked(ctx, "k")
+	fake.err = nil
+	_, _ = s.IsBlocked(ctx, "k")
+	fake.err = errors.New("erro transitório")
+	_, _ = s.IsBlocked(ctx, "k")
+
+	assert.Equal(t, Closed, s.State(), "Erros não consecutivos não deveriam abrir o circuito")
+}
+
+// Test_Breaker_IgnoresContextErrors verifica que o cancelamento e o prazo do próprio contexto
+// não contam como falhas do store
+func Test_Breaker_IgnoresContextErrors(t *testing.T) {
+	fake := &fakeStore{}
+	s := NewStore(fake, Config{FailureThreshold: 2, Cooldown: time.Second})
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	fake.err = fmt.Errorf("erro ao executar script de contagem: %w", context.Canceled)
+	for i := 0; i < 3; i++ {
+		_, _ = s.Increment(canceled, "k", time.Second)
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Unix(0, 0))
+	defer cancel()
+	fake.err = context.DeadlineExceeded
+	for i := 0; i < 3; i++ {
+		_, _ = s.Increment(expired, "k", time.Second)
+	}
+	assert.Equal(t, Closed, s.State(), "Clientes que desconectam não deveriam abrir o circuito")
+
+	// Um prazo estourado no store, com o contexto da chamada ainda válido, é uma falha
+	_, _ = s.Increment(context.Background(), "k", time.Second)
+	_, _ = s.Increment(context.Background(), "k", time.Second)
+	assert.Equal(t, Open, s.State())
+}
+
+// Test_Breaker_CloseClosesFallback verifica que Close fecha também o Fallback
+func Test_Breaker_CloseClosesFallback(t *testing.T) {
+	next, fallback := &fakeStore{}, &fakeStore{}
+	s := NewStore(next, Config{Fallback: fallback})
+
+	assert.NoError(t, s.Close())
+	assert.True(t, next.closed)
+	assert.True(t, fallback.closed)
+}
+
+// Test_Breaker_HooksRunInBackground verifica que a chamada que abre o circuito não espera o
+// OnFallback e que um panic nos hooks não derruba o processo
+func Test_Breaker_HooksRunInBackground(t *testing.T) {
+	fake := &fakeStore{err: errors.New("redis fora do ar")}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	release := make(chan struct{})
+	var order []string
+	s := NewStore(fake, Config{
+		FailureThreshold: 1,
+		Cooldown:         time.Second,
+		Now:              clock.Now,
+		Fallback:         &fakeStore{},
+		OnFallback: func(db.Store) {
+			<-release
+			order = append(order, "fallback")
+		},
+		OnRecover: func(db.Store) {
+			order = append(order, "recover")
+			panic("falha na reconciliação")
+		},
+	})
+	ctx := context.Background()
+
+	_, err := s.Increment(ctx, "k", time.Second)
+	assert.Error(t, err)
+	assert.Equal(t, Open, s.State(), "A chamada deveria voltar sem esperar o OnFallback")
+
+	// O OnRecover só roda depois do OnFallback, mesmo que o circuito feche antes
+	clock.now = clock.now.Add(time.Second)
+	fake.err = nil
+	_, err = s.Increment(ctx, "k", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, Closed, s.State())
+	close(release)
+	s.hooks.Wait()
+	assert.Equal(t, []string{"fallback", "recover"}, order)
+}
@@ -0,0 +1,283 @@
+package breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/infra/db/memory"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_Breaker_FallbackInheritsRedisCounts verifica que, ao abrir o circuito, o fallback em
+// memória recebe as contagens copiadas do Redis, com os TTLs descontados da idade da cópia
+func Test_Breaker_FallbackInheritsRedisCounts(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	redisSt := redisStore.NewRedisStore(client)
+
+	ctx := context.Background()
+	_, err = redisSt.IncrementBy(ctx, "ip_192.0.2.1", 7, time.Minute)
+	require.NoError(t, err)
+	_, err = redisSt.IncrementBy(ctx, "token_abc", 3, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, redisSt.Block(ctx, "blocked_ip_192.0.2.2", 5*time.Minute, db.BlockInfo{Reason: db.ReasonRateLimitExceeded}))
+
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	fallback := memory.NewMemoryStore(memory.Config{Now: clock.Now})
+	baseline := db.NewBaseline(redisSt, db.BaselineConfig{Timeout: 100 * time.Millisecond, Now: clock.Now})
+	require.NoError(t, baseline.Refresh(ctx))
+
+	// O Redis cai 10s depois da última cópia
+	clock.now = clock.now.Add(10 * time.Second)
+	mr.Close()
+
+	seeded := 0
+	s := NewStore(redisSt, Config{
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+		Now:              clock.Now,
+		Fallback:         fallback,
+		OnFallback: func(db.Store) {
+			var seedErr error
+			seeded, seedErr = baseline.Seed(ctx, fallback)
+			assert.NoError(t, seedErr)
+		},
+	})
+
+	_, err = s.Increment(ctx, "ip_192.0.2.1", time.Minute)
+	require.Error(t, err, "A chamada que encontra o Redis fora do ar falha")
+	assert.Equal(t, Open, s.State())
+	s.hooks.Wait()
+	assert.Equal(t, 3, seeded)
+
+	// Com o circuito aberto, as chamadas seguem para o fallback, que parte das contagens do Redis
+	count, err := s.Increment(ctx, "ip_192.0.2.1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), count)
+	count, err = s.Count(ctx, "token_abc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	blocked, err := s.IsBlocked(ctx, "blocked_ip_192.0.2.2")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	entries, err := fallback.Dump(ctx, "ip_*")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 50*time.Second, entries[0].TTL, "O TTL desconta os 10s desde a cópia")
+}
+
+// Test_Breaker_ReconcilesOnRecovery verifica que, quando o circuito fecha, as contagens que
+// divergiram no fallback são levadas ao Redis conforme a política de reconciliação
+func Test_Breaker_ReconcilesOnRecovery(t *testing.T) {
+	for _, tc := range []struct {
+		policy   db.ReconcilePolicy
+		wantA    string
+		wantB    bool
+		wantCapA string
+	}{
+		{db.ReconcilePrimary, "6", false, "6"},
+		{db.ReconcileMax, "7", true, "7"},
+		{db.ReconcileSumCapped, "9", true, "9"},
+	} {
+		for _, capped := range []bool{false, true} {
+			mr, err := miniredis.Run()
+			require.NoError(t, err)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+			redisSt := redisStore.NewRedisStore(client)
+			ctx := context.Background()
+			_, err = redisSt.IncrementBy(ctx, "ip_a", 4, time.Minute)
+			require.NoError(t, err)
+
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			fallback := memory.NewMemoryStore(memory.Config{Now: clock.Now})
+			baseline := db.NewBaseline(redisSt, db.BaselineConfig{Timeout: 100 * time.Millisecond, Now: clock.Now})
+			require.NoError(t, baseline.Refresh(ctx))
+			reconcileCfg := db.ReconcileConfig{Policy: tc.policy, Baseline: baseline.SeededCount}
+			if capped {
+				reconcileCfg.Cap = func(string) int64 { return 10 }
+			}
+			reconciled := 0
+			s := NewStore(redisSt, Config{
+				FailureThreshold: 1,
+				Cooldown:         10 * time.Second,
+				Now:              clock.Now,
+				Fallback:         fallback,
+				OnFallback: func(db.Store) {
+					_, err := baseline.Seed(ctx, fallback)
+					assert.NoError(t, err)
+				},
+				OnRecover: func(db.Store) {
+					var reconcileErr error
+					reconciled, reconcileErr = db.Reconcile(ctx, redisSt, fallback, reconcileCfg)
+					assert.NoError(t, reconcileErr)
+				},
+			})
+
+			// Durante a queda, esta instância conta 3 requisições de "a" (semeado com 4) e 2 de "b"
+			// no fallback, e outra instância, já reconectada, leva "a" a 6 no Redis
+			mr.SetError("redis fora do ar")
+			_, err = s.Increment(ctx, "ip_a", time.Minute)
+			require.Error(t, err)
+			require.Equal(t, Open, s.State())
+			s.hooks.Wait()
+			for i := 0; i < 3; i++ {
+				_, err = s.Increment(ctx, "ip_a", time.Minute)
+				require.NoError(t, err)
+			}
+			_, err = s.IncrementBy(ctx, "ip_b", 2, time.Minute)
+			require.NoError(t, err)
+			mr.SetError("")
+			require.NoError(t, mr.Set("ip_a", "6"))
+			mr.SetTTL("ip_a", 30*time.Second)
+
+			// A chamada de teste depois do cooldown fecha o circuito e reconcilia
+			clock.now = clock.now.Add(10 * time.Second)
+			_, err = s.Increment(ctx, "ip_c", time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, Closed, s.State())
+			s.hooks.Wait()
+
+			wantA := tc.wantA
+			if capped {
+				wantA = tc.wantCapA
+			}
+			name := string(tc.policy)
+			assert.Equal(t, wantA, mustGet(t, mr, "ip_a"), name)
+			assert.Equal(t, tc.wantB, mr.Exists("ip_b"), name)
+			if tc.policy == db.ReconcilePrimary {
+				assert.Zero(t, reconciled, name)
+			} else {
+				assert.Equal(t, 2, reconciled, name)
+				assert.Greater(t, mr.TTL("ip_a"), 30*time.Second, "%s: a janela mais longa prevalece", name)
+			}
+
+			remaining, err := fallback.Dump(ctx, "*")
+			require.NoError(t, err)
+			assert.Empty(t, remaining, "%s: o fallback deveria ser limpo depois da reconciliação", name)
+
+			client.Close()
+			mr.Close()
+		}
+	}
+}
+
+// Test_Breaker_ReconcileSumDoesNotDoubleSeed verifica que, com ReconcileSumCapped, as contagens
+// semeadas no fallback não são somadas de novo e que uma nova queda não recombina as antigas
+func Test_Breaker_ReconcileSumDoesNotDoubleSeed(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	redisSt := redisStore.NewRedisStore(client)
+	ctx := context.Background()
+	_, err = redisSt.IncrementBy(ctx, "ip_a", 50, time.Minute)
+	require.NoError(t, err)
+
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	fallback := memory.NewMemoryStore(memory.Config{Now: clock.Now})
+	baseline := db.NewBaseline(redisSt, db.BaselineConfig{Timeout: 100 * time.Millisecond, Now: clock.Now})
+	s := NewStore(redisSt, Config{
+		FailureThreshold: 1,
+		Cooldown:         10 * time.Second,
+		Now:              clock.Now,
+		Fallback:         fallback,
+		OnFallback: func(db.Store) {
+			_, err := baseline.Seed(ctx, fallback)
+			assert.NoError(t, err)
+		},
+		OnRecover: func(db.Store) {
+			_, err := db.Reconcile(ctx, redisSt, fallback, db.ReconcileConfig{
+				Policy:   db.ReconcileSumCapped,
+				Cap:      func(string) int64 { return 100 },
+				Baseline: baseline.SeededCount,
+			})
+			assert.NoError(t, err)
+		},
+	})
+	outage := func(requests int) {
+		t.Helper()
+		require.NoError(t, baseline.Refresh(ctx))
+		mr.SetError("redis fora do ar")
+		_, err := s.Increment(ctx, "ip_a", time.Minute)
+		require.Error(t, err)
+		require.Equal(t, Open, s.State())
+		s.hooks.Wait()
+		for i := 0; i < requests; i++ {
+			_, err = s.Increment(ctx, "ip_a", time.Minute)
+			require.NoError(t, err)
+		}
+		mr.SetError("")
+		clock.now = clock.now.Add(10 * time.Second)
+		_, err = s.Increment(ctx, "ip_c", time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, Closed, s.State())
+		s.hooks.Wait()
+	}
+
+	// 50 antes da queda e 10 durante ela
+	outage(10)
+	assert.Equal(t, "60", mustGet(t, mr, "ip_a"), "As contagens semeadas não deveriam ser somadas duas vezes")
+
+	// Na próxima queda, só as novas requisições são somadas
+	outage(5)
+	assert.Equal(t, "65", mustGet(t, mr, "ip_a"))
+}
+
+// mustGet lê uma chave do miniredis, falhando o teste se ela não existir
+func mustGet(t *testing.T, mr *miniredis.Miniredis, key string) string {
+	t.Helper()
+	val, err := mr.Get(key)
+	require.NoError(t, err)
+	return val
+}
+
+// Test_Breaker_FallbackOnlyLimiterKeys verifica que a cópia para o fallback e a reconciliação se
+// limitam aos padrões das chaves do rate limiter, deixando de fora as demais chaves do Redis
+func Test_Breaker_FallbackOnlyLimiterKeys(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	redisSt := redisStore.NewRedisStore(client)
+
+	ctx := context.Background()
+	_, err = redisSt.IncrementBy(ctx, "ip_192.0.2.1", 4, time.Minute)
+	require.NoError(t, err)
+	mr.Set("session_abc", "dados da aplicação")
+	mr.HSet("token_limits", "premium", "100/1s/1m")
+
+	matches := []string{"ip_*", "token_*"}
+	fallback := memory.NewMemoryStore(memory.Config{})
+	baseline := db.NewBaseline(redisSt, db.BaselineConfig{
+		Matches: matches,
+		Exclude: func(key string) bool { return key == "token_limits" },
+	})
+	n, err := baseline.Seed(ctx, fallback)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	entries, err := fallback.Dump(ctx, "*")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "ip_192.0.2.1", entries[0].Key)
+
+	// Uma chave alheia no fallback não é levada ao Redis
+	require.NoError(t, fallback.Restore(ctx, []db.SnapshotEntry{{Key: "session_xyz", Value: "1"}}))
+	_, err = db.Reconcile(ctx, redisSt, fallback, db.ReconcileConfig{Policy: db.ReconcileMax, Matches: matches})
+	require.NoError(t, err)
+	assert.False(t, mr.Exists("session_xyz"))
+	assert.True(t, mr.Exists("session_abc"))
+	assert.True(t, mr.Exists("token_limits"))
+}
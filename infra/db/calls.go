@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// callCounterKey é a chave do CallCounter no contexto.
+type callCounterKey struct{}
+
+// CallCounter conta as operações do store feitas com um contexto, para medir quantas idas ao
+// store cada decisão custa.
+type CallCounter struct {
+	n atomic.Int64
+}
+
+// WithCallCounter retorna um contexto derivado de ctx cujas operações no ObservedStore são
+// somadas ao contador retornado. Um contador novo substitui o de ctx, se houver.
+func WithCallCounter(ctx context.Context) (context.Context, *CallCounter) {
+	calls := &CallCounter{}
+	return context.WithValue(ctx, callCounterKey{}, calls), calls
+}
+
+// Count retorna quantas operações foram contadas até agora.
+func (c *CallCounter) Count() int {
+	return int(c.n.Load())
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// LimitResolver resolve os limites aplicáveis a um identificador (IP ou token).
+type LimitResolver interface {
+	ResolveLimit(ctx context.Context, identifier string, isToken bool) (max int, window, block time.Duration, err error)
+}
+
+// LimitPreloader é implementado por resolvers que conseguem carregar antecipadamente
+// os limites de um lote de tokens, evitando consultas na primeira requisição.
+type LimitPreloader interface {
+	Preload(ctx context.Context, tokens []string) error
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+// CountDelta é um incremento de contador anunciado por uma instância às demais.
+type CountDelta struct {
+	// Origin identifica a instância que fez o incremento.
+	Origin string
+	// Key é a chave do contador.
+	Key string
+	// N é o valor somado ao contador.
+	N int64
+	// Window é a janela do contador, usada como TTL quando a chave ainda não existe no destino.
+	Window time.Duration
+}
+
+// CountBroadcaster distribui os incrementos de contador entre instâncias de MemoryStore.
+// Pode ser implementado sobre um canal pub/sub ou um mecanismo de gossip; a entrega não
+// precisa ser garantida, já que as contagens compartilhadas são aproximadas.
+type CountBroadcaster interface {
+	// Publish anuncia um incremento às demais instâncias.
+	Publish(delta CountDelta)
+	// Subscribe registra o handler chamado para cada incremento recebido. Incrementos da
+	// própria instância podem ser entregues; o MemoryStore os descarta pelo Origin.
+	Subscribe(handler func(CountDelta)) (unsubscribe func())
+}
+
+// LocalBroadcaster é um CountBroadcaster dentro do mesmo processo, útil para vários rate
+// limiters em memória no mesmo binário e para testes. A entrega é síncrona.
+type LocalBroadcaster struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]func(CountDelta)
+}
+
+// NewLocalBroadcaster cria um LocalBroadcaster sem inscritos.
+func NewLocalBroadcaster() *LocalBroadcaster {
+	return &LocalBroadcaster{handlers: make(map[int]func(CountDelta))}
+}
+
+// Publish entrega o incremento a todos os inscritos.
+func (b *LocalBroadcaster) Publish(delta CountDelta) {
+	b.mu.RLock()
+	handlers := make([]func(CountDelta), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(delta)
+	}
+}
+
+// Subscribe registra um handler e retorna a função que cancela a inscrição.
+func (b *LocalBroadcaster) Subscribe(handler func(CountDelta)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
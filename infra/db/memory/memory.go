@@ -0,0 +1,503 @@
+// Package memory implementa db.Store em memória, para uso sem Redis.
+//
+// Cada MemoryStore conta apenas as requisições da própria instância: com várias instâncias
+// atrás de um balanceador, cada uma aplica o limite sozinha e o total aceito pode chegar a
+// N vezes o configurado. Para contagens exatas entre instâncias, use o RedisStore. Com um
+// CountBroadcaster, as instâncias trocam os incrementos e passam a compartilhar as contagens
+// de forma aproximada: um incremento só é visto pelas outras instâncias depois de entregue, e
+// bloqueios e remoções de chaves continuam locais.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// purgeEvery é a quantidade de escritas entre as limpezas das chaves expiradas.
+const purgeEvery = 1024
+
+// Config define os parâmetros do MemoryStore.
+type Config struct {
+	// Broadcaster compartilha os incrementos com outras instâncias (opcional).
+	Broadcaster CountBroadcaster
+	// InstanceID identifica a instância nos incrementos anunciados (padrão: aleatório).
+	InstanceID string
+	// Now permite injetar o relógio usado nas expirações nos testes (opcional).
+	Now func() time.Time
+}
+
+// entry é o valor de uma chave: um contador, um valor bruto ou o estado de um leaky bucket.
+type entry struct {
+	count     int64
+	value     []byte
+	level     float64
+	lastLeak  time.Time
+	expiresAt time.Time
+}
+
+// MemoryStore implementa a interface Store em memória.
+type MemoryStore struct {
+	cfg         Config
+	unsubscribe func()
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	writes  int
+}
+
+// NewMemoryStore cria um MemoryStore e, se houver um Broadcaster, passa a aplicar os
+// incrementos recebidos das outras instâncias.
+func NewMemoryStore(cfg Config) *MemoryStore {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = randomID()
+	}
+
+	ms := &MemoryStore{cfg: cfg, entries: make(map[string]*entry)}
+	if cfg.Broadcaster != nil {
+		ms.unsubscribe = cfg.Broadcaster.Subscribe(ms.apply)
+	}
+	return ms
+}
+
+// randomID gera um identificador de instância.
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// apply soma um incremento recebido de outra instância, sem anunciá-lo de novo.
+func (ms *MemoryStore) apply(delta CountDelta) {
+	if delta.Origin == ms.cfg.InstanceID {
+		return
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.incr(delta.Key, delta.N, delta.Window)
+}
+
+// publish anuncia um incremento local. Deve ser chamado sem o lock.
+func (ms *MemoryStore) publish(key string, n int64, window time.Duration) {
+	if ms.cfg.Broadcaster == nil {
+		return
+	}
+	ms.cfg.Broadcaster.Publish(CountDelta{Origin: ms.cfg.InstanceID, Key: key, N: n, Window: window})
+}
+
+// lookup retorna a entrada da chave, descartando-a se estiver expirada. Deve ser chamado com o lock.
+func (ms *MemoryStore) lookup(key string) *entry {
+	e, ok := ms.entries[key]
+	if !ok {
+		return nil
+	}
+	if !e.expiresAt.IsZero() && !ms.cfg.Now().Before(e.expiresAt) {
+		delete(ms.entries, key)
+		return nil
+	}
+	return e
+}
+
+// store grava uma entrada, limpando periodicamente as expiradas. Deve ser chamado com o lock.
+func (ms *MemoryStore) store(key string, e *entry) {
+	ms.entries[key] = e
+	ms.writes++
+	if ms.writes%purgeEvery != 0 {
+		return
+	}
+	now := ms.cfg.Now()
+	for k, v := range ms.entries {
+		if !v.expiresAt.IsZero() && !now.Before(v.expiresAt) {
+			delete(ms.entries, k)
+		}
+	}
+}
+
+// expiry retorna o instante de expiração para o TTL informado (zero para sem expiração).
+func (ms *MemoryStore) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return ms.cfg.Now().Add(ttl)
+}
+
+// incr soma n ao contador e define o TTL quando a chave é criada. Deve ser chamado com o lock.
+func (ms *MemoryStore) incr(key string, n int64, window time.Duration) int64 {
+	e := ms.lookup(key)
+	if e == nil {
+		e = &entry{expiresAt: ms.expiry(max(window, time.Millisecond))}
+		ms.store(key, e)
+	}
+	e.count += n
+	return e.count
+}
+
+// Increment incrementa o contador, definindo o TTL da janela quando a chave é criada.
+func (ms *MemoryStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return ms.IncrementBy(ctx, key, 1, window)
+}
+
+// IncrementBy soma n ao contador; o TTL da janela só é definido quando a chave é criada.
+func (ms *MemoryStore) IncrementBy(_ context.Context, key string, n int64, window time.Duration) (int64, error) {
+	ms.mu.Lock()
+	total := ms.incr(key, n, window)
+	ms.mu.Unlock()
+
+	ms.publish(key, n, window)
+	return total, nil
+}
+
+// IncrementWithTTL incrementa o contador e retorna o tempo até a janela expirar.
+func (ms *MemoryStore) IncrementWithTTL(_ context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	ms.mu.Lock()
+	count := ms.incr(key, 1, window)
+	e := ms.lookup(key)
+	if e.expiresAt.IsZero() {
+		e.expiresAt = ms.expiry(max(window, time.Millisecond))
+	}
+	ttl := e.expiresAt.Sub(ms.cfg.Now())
+	ms.mu.Unlock()
+
+	ms.publish(key, 1, window)
+	return count, ttl, nil
+}
+
+// CheckAndCount aplica a janela fixa de forma atômica (ver db.Store).
+func (ms *MemoryStore) CheckAndCount(_ context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	ms.mu.Lock()
+	allowed, remaining, retryAfter, counted, err := ms.checkAndCount(keys, limit, window, blockDuration, now)
+	ms.mu.Unlock()
+
+	if counted {
+		ms.publish(keys.Counter, 1, window)
+	}
+	return allowed, remaining, retryAfter, err
+}
+
+// CheckAndCountWithGlobal aplica a janela fixa e incrementa o contador global de forma atômica
+// (ver db.Store).
+func (ms *MemoryStore) CheckAndCountWithGlobal(_ context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global db.GlobalCount) (bool, int64, time.Duration, int64, error) {
+	ms.mu.Lock()
+	globalCount := ms.incr(global.Key, 1, global.Window)
+	if global.Limit > 0 && globalCount > global.Limit {
+		// Acima do teto global, o identificador não é contado: só o bloqueio é lido
+		retryAfter, _ := ms.blocked(keys.Block, now)
+		ms.mu.Unlock()
+		ms.publish(global.Key, 1, global.Window)
+		return false, 0, retryAfter, globalCount, nil
+	}
+	allowed, remaining, retryAfter, counted, err := ms.checkAndCount(keys, limit, window, blockDuration, now)
+	ms.mu.Unlock()
+
+	ms.publish(global.Key, 1, global.Window)
+	if counted {
+		ms.publish(keys.Counter, 1, window)
+	}
+	return allowed, remaining, retryAfter, globalCount, err
+}
+
+// checkAndCount contém a lógica de CheckAndCount e informa se o contador foi incrementado.
+// Deve ser chamado com o lock.
+func (ms *MemoryStore) checkAndCount(keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, bool, error) {
+	if retryAfter, blocked := ms.blocked(keys.Block, now); blocked {
+		if len(keys.SeverityTiers) == 0 || ms.lookup(keys.Block).expiresAt.IsZero() {
+			return false, 0, retryAfter, false, nil
+		}
+		// Com faixas de severidade, as requisições bloqueadas continuam contando e podem
+		// prolongar o bloqueio
+		count := ms.incr(keys.Counter, 1, window)
+		if !keys.KeepCounterOnBlock {
+			count += limit + 1
+		}
+		if duration := severityDuration(keys.SeverityTiers, count, retryAfter); duration > retryAfter {
+			var offenses int64
+			if e := ms.lookup(keys.Offenses); e != nil {
+				offenses = e.count
+			}
+			return false, 0, duration, true, ms.writeBlock(keys.Block, duration, offenses, now)
+		}
+		return false, 0, retryAfter, true, nil
+	}
+
+	count := ms.incr(keys.Counter, 1, window)
+	if count <= limit {
+		return true, limit - count, 0, true, nil
+	}
+	if count <= limit+keys.GraceOverage {
+		// Tolerância: rejeita sem bloquear nem contar infração, até o fim da janela
+		return false, 0, max(ms.lookup(keys.Counter).expiresAt.Sub(ms.cfg.Now()), 0), true, nil
+	}
+
+	blockDuration = severityDuration(keys.SeverityTiers, count, blockDuration)
+	if err := ms.block(keys, blockDuration, now); err != nil {
+		return false, 0, 0, true, err
+	}
+	if !keys.KeepCounterOnBlock {
+		delete(ms.entries, keys.Counter)
+	}
+	return false, 0, max(blockDuration, 0), true, nil
+}
+
+// blocked informa se a chave de bloqueio existe e o tempo restante do bloqueio. Deve ser
+// chamado com o lock.
+func (ms *MemoryStore) blocked(key string, now time.Time) (time.Duration, bool) {
+	blocked := ms.lookup(key)
+	if blocked == nil {
+		return 0, false
+	}
+	retryAfter := blocked.expiresAt.Sub(ms.cfg.Now())
+	info := &db.BlockInfo{}
+	if json.Unmarshal(blocked.value, info) == nil && !info.ExpiresAt.IsZero() {
+		retryAfter = info.ExpiresAt.Sub(now)
+	}
+	return max(retryAfter, 0), true
+}
+
+// severityDuration retorna a maior entre base e a duração da maior faixa de severidade
+// atingida com count requisições.
+func severityDuration(tiers []db.SeverityTier, count int64, base time.Duration) time.Duration {
+	for _, tier := range tiers {
+		if count >= tier.Threshold && tier.Duration > base {
+			base = tier.Duration
+		}
+	}
+	return base
+}
+
+// block conta a infração e grava o bloqueio com os metadados. Deve ser chamado com o lock.
+func (ms *MemoryStore) block(keys db.CountKeys, blockDuration time.Duration, now time.Time) error {
+	offenses := ms.incr(keys.Offenses, 1, db.OffenseWindow)
+	if blockDuration <= 0 {
+		return nil
+	}
+	return ms.writeBlock(keys.Block, blockDuration, offenses, now)
+}
+
+// writeBlock grava o bloqueio com os metadados. Deve ser chamado com o lock.
+func (ms *MemoryStore) writeBlock(key string, blockDuration time.Duration, offenses int64, now time.Time) error {
+	val, err := json.Marshal(db.BlockInfo{
+		Reason:       db.ReasonRateLimitExceeded,
+		OffenseCount: offenses,
+		StartedAt:    now,
+		ExpiresAt:    now.Add(blockDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao serializar metadados do bloqueio: %w", err)
+	}
+	ms.store(key, &entry{value: val, expiresAt: ms.expiry(blockDuration)})
+	return nil
+}
+
+// SlidingWindow aplica a aproximação de janela deslizante com dois buckets, como o RedisStore:
+// o bucket anterior é ponderado pela fração da janela que ainda se sobrepõe, e o bucket atual
+// só é incrementado quando a requisição é permitida.
+func (ms *MemoryStore) SlidingWindow(_ context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	if window.Milliseconds() <= 0 {
+		return false, 0, fmt.Errorf("janela inválida para a janela deslizante: %s", window)
+	}
+
+	ms.mu.Lock()
+	allowed, estimate, currentKey := ms.slidingWindow(key, limit, window, now)
+	ms.mu.Unlock()
+
+	if allowed {
+		ms.publish(currentKey, 1, 2*window)
+	}
+	return allowed, estimate, nil
+}
+
+// SlidingWindowCheckAndCount aplica a janela deslizante com a verificação e a gravação do
+// bloqueio de forma atômica (ver db.Store).
+func (ms *MemoryStore) SlidingWindowCheckAndCount(_ context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	if window.Milliseconds() <= 0 {
+		return false, 0, 0, fmt.Errorf("janela inválida para a janela deslizante: %s", window)
+	}
+
+	ms.mu.Lock()
+	if retryAfter, blocked := ms.blocked(keys.Block, now); blocked {
+		ms.mu.Unlock()
+		return false, 0, retryAfter, nil
+	}
+	allowed, estimate, currentKey := ms.slidingWindow(keys.Counter, limit, window, now)
+	if allowed {
+		ms.mu.Unlock()
+		ms.publish(currentKey, 1, 2*window)
+		return true, estimate, 0, nil
+	}
+	err := ms.block(keys, blockDuration, now)
+	ms.mu.Unlock()
+	return false, estimate, max(blockDuration, 0), err
+}
+
+// slidingWindow contém a lógica de SlidingWindow e retorna o bucket atual. Deve ser chamado
+// com o lock.
+func (ms *MemoryStore) slidingWindow(key string, limit int64, window time.Duration, now time.Time) (bool, float64, string) {
+	windowMs := window.Milliseconds()
+	nowMs := now.UnixMilli()
+	bucket := nowMs / windowMs
+	currentKey := fmt.Sprintf("%s:%d", key, bucket)
+	previousKey := fmt.Sprintf("%s:%d", key, bucket-1)
+	weight := float64(windowMs-nowMs%windowMs) / float64(windowMs)
+
+	var current, previous int64
+	if e := ms.lookup(currentKey); e != nil {
+		current = e.count
+	}
+	if e := ms.lookup(previousKey); e != nil {
+		previous = e.count
+	}
+
+	estimate := float64(previous)*weight + float64(current)
+	if estimate+1 > float64(limit) {
+		return false, estimate, currentKey
+	}
+	current = ms.incr(currentKey, 1, 2*window)
+	return true, float64(previous)*weight + float64(current), currentKey
+}
+
+// LeakyBucket aplica o leaky bucket de forma atômica (ver db.Store). O nível é local: não é
+// compartilhado pelo Broadcaster.
+func (ms *MemoryStore) LeakyBucket(_ context.Context, keys db.CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	if leakInterval <= 0 {
+		return false, 0, 0, fmt.Errorf("intervalo de vazamento inválido para o leaky bucket: %s", leakInterval)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if retryAfter, blocked := ms.blocked(keys.Block, now); blocked {
+		return false, 0, retryAfter, nil
+	}
+
+	level, last := 0.0, now
+	if e := ms.lookup(keys.Counter); e != nil {
+		level, last = e.level, e.lastLeak
+	}
+	if elapsed := now.Sub(last); elapsed > 0 {
+		level = max(level-float64(elapsed)/float64(leakInterval), 0)
+		last = now
+	}
+
+	if level+1 > float64(capacity) {
+		return false, level, time.Duration((level + 1 - float64(capacity)) * float64(leakInterval)), nil
+	}
+	level++
+	ttl := time.Duration(level * float64(leakInterval))
+	ms.store(keys.Counter, &entry{level: level, lastLeak: last, expiresAt: ms.expiry(ttl)})
+	return true, level, 0, nil
+}
+
+// Count retorna o valor atual de um contador sem incrementá-lo (0 se a chave não existir).
+func (ms *MemoryStore) Count(_ context.Context, key string) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if e := ms.lookup(key); e != nil {
+		return e.count, nil
+	}
+	return 0, nil
+}
+
+// IsBlocked verifica se uma chave está marcada como bloqueada.
+func (ms *MemoryStore) IsBlocked(_ context.Context, key string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.lookup(key) != nil, nil
+}
+
+// Block marca uma chave como bloqueada por uma determinada duração, gravando os metadados em JSON.
+func (ms *MemoryStore) Block(ctx context.Context, key string, duration time.Duration, info db.BlockInfo) error {
+	val, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar metadados do bloqueio: %w", err)
+	}
+	return ms.Set(ctx, key, val, duration)
+}
+
+// BlockInfo lê os metadados de um bloqueio. Retorna nil se a chave não estiver bloqueada.
+func (ms *MemoryStore) BlockInfo(ctx context.Context, key string) (*db.BlockInfo, error) {
+	val, _ := ms.Get(ctx, key)
+	if val == nil {
+		return nil, nil
+	}
+	info := &db.BlockInfo{}
+	if err := json.Unmarshal(val, info); err != nil {
+		return nil, fmt.Errorf("erro ao desserializar metadados do bloqueio: %w", err)
+	}
+	return info, nil
+}
+
+// Get lê o valor de uma chave. Retorna nil se a chave não existir.
+func (ms *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	e := ms.lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	if e.value == nil {
+		// Contadores são lidos como texto, como no Redis
+		return []byte(fmt.Sprint(e.count)), nil
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set grava o valor de uma chave com o TTL informado.
+func (ms *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.store(key, &entry{value: append([]byte(nil), value...), expiresAt: ms.expiry(ttl)})
+	return nil
+}
+
+// Reset remove uma chave.
+func (ms *MemoryStore) Reset(_ context.Context, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.entries, key)
+	return nil
+}
+
+// ResetAll remove as chaves de uma só vez, com o lock.
+func (ms *MemoryStore) ResetAll(_ context.Context, keys ...string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, key := range keys {
+		delete(ms.entries, key)
+	}
+	return nil
+}
+
+// DeleteMatching remove as chaves que casam com o padrão glob e são aceitas por allow.
+func (ms *MemoryStore) DeleteMatching(_ context.Context, match string, allow func(key string) bool) (int, error) {
+	re, err := globRegexp(match)
+	if err != nil {
+		return 0, fmt.Errorf("padrão inválido %q: %w", match, err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	deleted := 0
+	for key := range ms.entries {
+		if ms.lookup(key) != nil && re.MatchString(key) && allow(key) {
+			delete(ms.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Close cancela a inscrição no Broadcaster.
+func (ms *MemoryStore) Close() error {
+	if ms.unsubscribe != nil {
+		ms.unsubscribe()
+	}
+	return nil
+}
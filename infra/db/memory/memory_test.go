@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/infra/db/storetest"
+	"rateLimiter/internal/clock"
+)
+
+var _ db.Store = (*MemoryStore)(nil)
+
+// fakeNow é um relógio manual para as expirações
+type fakeNow struct{ now time.Time }
+
+func (f *fakeNow) Now() time.Time          { return f.now }
+func (f *fakeNow) Advance(d time.Duration) { f.now = f.now.Add(d) }
+
+// Test_MemoryStore_CheckAndCount verifica a contagem, o bloqueio e a expiração na janela fixa
+func Test_MemoryStore_CheckAndCount(t *testing.T) {
+	clock := &fakeNow{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore(Config{Now: clock.Now})
+	defer store.Close()
+
+	ctx := context.Background()
+	keys := db.CountKeys{Counter: "ip_1", Block: "blocked_ip_1", Offenses: "offenses_ip_1"}
+	for i := int64(1); i <= 3; i++ {
+		allowed, remaining, _, err := store.CheckAndCount(ctx, keys, 3, time.Second, time.Minute, clock.now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, 3-i, remaining)
+	}
+
+	allowed, _, retryAfter, err := store.CheckAndCount(ctx, keys, 3, time.Second, time.Minute, clock.now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	info, err := store.BlockInfo(ctx, keys.Block)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
+	assert.Equal(t, int64(1), info.OffenseCount)
+
+	// Depois do bloqueio, o contador recomeça
+	clock.Advance(time.Minute)
+	allowed, remaining, _, err := store.CheckAndCount(ctx, keys, 3, time.Second, time.Minute, clock.now)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(2), remaining)
+}
+
+// Test_MemoryStore_Expiration verifica que contadores e valores expiram com o TTL
+func Test_MemoryStore_Expiration(t *testing.T) {
+	clock := &fakeNow{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore(Config{Now: clock.Now})
+	defer store.Close()
+
+	ctx := context.Background()
+	_, err := store.IncrementBy(ctx, "bytes", 300, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "k", []byte("v"), 30*time.Second))
+
+	clock.Advance(30 * time.Second)
+	total, err := store.IncrementBy(ctx, "bytes", 200, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), total, "A janela não deveria ser renovada pelo segundo incremento")
+	val, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Nil(t, val)
+
+	clock.Advance(30 * time.Second)
+	count, err := store.Count(ctx, "bytes")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+// Test_MemoryStore_SlidingWindow verifica o peso do bucket anterior na janela deslizante
+func Test_MemoryStore_SlidingWindow(t *testing.T) {
+	store := NewMemoryStore(Config{})
+	defer store.Close()
+
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		allowed, _, err := store.SlidingWindow(ctx, "sw", 4, time.Minute, start)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, _, err := store.SlidingWindow(ctx, "sw", 4, time.Minute, start)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Na metade da janela seguinte, o bucket anterior ainda pesa 2 requisições
+	allowed, count, err := store.SlidingWindow(ctx, "sw", 4, time.Minute, start.Add(90*time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 3.0, count)
+}
+
+// Test_MemoryStore_Broadcaster verifica que duas instâncias ligadas por um broadcaster
+// compartilham as contagens e que, sem ele, cada uma conta sozinha
+func Test_MemoryStore_Broadcaster(t *testing.T) {
+	ctx := context.Background()
+	keys := db.CountKeys{Counter: "ip_2", Block: "blocked_ip_2", Offenses: "offenses_ip_2"}
+	now := time.Now()
+
+	allowedUntilRejected := func(stores ...*MemoryStore) int {
+		allowed := 0
+		for i := 0; i < 20; i++ {
+			ok, _, _, err := stores[i%len(stores)].CheckAndCount(ctx, keys, 4, time.Minute, time.Minute, now)
+			require.NoError(t, err)
+			if !ok {
+				return allowed
+			}
+			allowed++
+		}
+		return allowed
+	}
+
+	isolatedA, isolatedB := NewMemoryStore(Config{}), NewMemoryStore(Config{})
+	assert.Equal(t, 8, allowedUntilRejected(isolatedA, isolatedB), "Sem broadcaster, cada instância aplica o limite sozinha")
+
+	broadcaster := NewLocalBroadcaster()
+	storeA := NewMemoryStore(Config{Broadcaster: broadcaster, InstanceID: "a"})
+	storeB := NewMemoryStore(Config{Broadcaster: broadcaster, InstanceID: "b"})
+	defer storeA.Close()
+	defer storeB.Close()
+
+	assert.Equal(t, 4, allowedUntilRejected(storeA, storeB), "Com broadcaster, o limite deveria valer para as duas instâncias juntas")
+
+	countA, err := storeA.Count(ctx, "shared")
+	require.NoError(t, err)
+	assert.Zero(t, countA)
+	_, err = storeB.IncrementBy(ctx, "shared", 5, time.Minute)
+	require.NoError(t, err)
+	countA, err = storeA.Count(ctx, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), countA)
+	countB, err := storeB.Count(ctx, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), countB, "O próprio incremento não deveria ser aplicado duas vezes")
+
+	// Depois de fechado, o store deixa de receber os incrementos
+	require.NoError(t, storeA.Close())
+	_, err = storeB.IncrementBy(ctx, "shared", 1, time.Minute)
+	require.NoError(t, err)
+	countA, err = storeA.Count(ctx, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), countA)
+}
+
+// Test_MemoryStore_DeleteMatching verifica o padrão glob e o filtro das chaves
+func Test_MemoryStore_DeleteMatching(t *testing.T) {
+	store := NewMemoryStore(Config{})
+	defer store.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"blocked_ip_10.0.0.1", "blocked_ip_10.0.0.2", "blocked_ip_10.0.1.1", "blocked_token_a", "ip_10.0.0.1"} {
+		require.NoError(t, store.Set(ctx, key, []byte("1"), time.Minute))
+	}
+
+	deleted, err := store.DeleteMatching(ctx, "*_ip_10.0.0.[0-9]", func(key string) bool {
+		return key != "blocked_ip_10.0.0.2"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	for key, exists := range map[string]bool{
+		"blocked_ip_10.0.0.1": false,
+		"blocked_ip_10.0.0.2": true,
+		"blocked_ip_10.0.1.1": true,
+		"ip_10.0.0.1":         true,
+	} {
+		val, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, exists, val != nil, key)
+	}
+}
+
+// Test_MemoryStore_Contract executa o contrato do Store
+func Test_MemoryStore_Contract(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	storetest.StoreContractTest(t, func() db.Store {
+		return NewMemoryStore(Config{Now: fake.Now})
+	}, storetest.WithClock(fake))
+}
+
+// Test_MemoryStore_Snapshot verifica que Dump e Restore preservam contadores, valores e o leaky bucket
+func Test_MemoryStore_Snapshot(t *testing.T) {
+	clock := &fakeNow{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	source := NewMemoryStore(Config{Now: clock.Now})
+	defer source.Close()
+
+	ctx := context.Background()
+	_, err := source.IncrementBy(ctx, "ip_1", 4, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, source.Set(ctx, "offenses_ip_1", []byte("2"), 0))
+	require.NoError(t, source.Block(ctx, "blocked_ip_2", time.Minute, db.BlockInfo{Reason: db.ReasonRateLimitExceeded}))
+	keys := db.CountKeys{Counter: "ip_3", Block: "blocked_ip_3"}
+	_, _, _, err = source.LeakyBucket(ctx, keys, 5, time.Second, clock.now)
+	require.NoError(t, err)
+
+	entries, err := source.Dump(ctx, "*")
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	target := NewMemoryStore(Config{Now: clock.Now})
+	defer target.Close()
+	require.NoError(t, target.Restore(ctx, entries))
+
+	count, err := target.Increment(ctx, "ip_1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count, "O contador restaurado continua incrementável")
+	info, err := target.BlockInfo(ctx, "blocked_ip_2")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
+	_, level, _, err := target.LeakyBucket(ctx, keys, 5, time.Second, clock.now)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, level, 0.001, "O nível do leaky bucket é preservado")
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// Dump retorna as chaves que casam com o padrão glob, no formato do RedisStore: contadores e
+// valores em Value e o leaky bucket nos campos level e last_leak_ts (em ms).
+func (ms *MemoryStore) Dump(_ context.Context, match string) ([]db.SnapshotEntry, error) {
+	re, err := globRegexp(match)
+	if err != nil {
+		return nil, fmt.Errorf("padrão inválido %q: %w", match, err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	now := ms.cfg.Now()
+	var entries []db.SnapshotEntry
+	for key := range ms.entries {
+		e := ms.lookup(key)
+		if e == nil || !re.MatchString(key) {
+			continue
+		}
+		entry := db.SnapshotEntry{Key: key}
+		switch {
+		case e.value != nil:
+			entry.Value = string(e.value)
+		case !e.lastLeak.IsZero():
+			entry.Fields = map[string]string{
+				"level":        strconv.FormatFloat(e.level, 'f', -1, 64),
+				"last_leak_ts": strconv.FormatInt(e.lastLeak.UnixMilli(), 10),
+			}
+		default:
+			entry.Value = strconv.FormatInt(e.count, 10)
+		}
+		if !e.expiresAt.IsZero() {
+			entry.TTL = e.expiresAt.Sub(now)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Restore grava as chaves, substituindo as existentes. Valores inteiros voltam como contadores,
+// para que possam ser incrementados, e os demais como valores brutos.
+func (ms *MemoryStore) Restore(_ context.Context, entries []db.SnapshotEntry) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, snap := range entries {
+		e := &entry{expiresAt: ms.expiry(snap.TTL)}
+		switch {
+		case snap.Fields != nil:
+			level, err := strconv.ParseFloat(snap.Fields["level"], 64)
+			if err != nil {
+				return fmt.Errorf("nível inválido do leaky bucket em %q: %w", snap.Key, err)
+			}
+			lastLeak, err := strconv.ParseFloat(snap.Fields["last_leak_ts"], 64)
+			if err != nil {
+				return fmt.Errorf("último vazamento inválido do leaky bucket em %q: %w", snap.Key, err)
+			}
+			e.level, e.lastLeak = level, time.UnixMilli(int64(lastLeak))
+		default:
+			if count, err := strconv.ParseInt(snap.Value, 10, 64); err == nil {
+				e.count = count
+			} else {
+				e.value = []byte(snap.Value)
+			}
+		}
+		ms.store(snap.Key, e)
+	}
+	return nil
+}
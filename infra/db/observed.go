@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"rateLimiter/pkg/metrics"
+)
+
+// ObservedStore é um decorator que registra latência e erros de qualquer Store.
+type ObservedStore struct {
+	next    Store
+	metrics metrics.Recorder
+}
+
+// NewObservedStore cria um ObservedStore em volta do store informado.
+func NewObservedStore(next Store, recorder metrics.Recorder) *ObservedStore {
+	if recorder == nil {
+		recorder = metrics.Noop{}
+	}
+	return &ObservedStore{next: next, metrics: recorder}
+}
+
+// observe registra a duração da operação e, se houver, o erro, e a soma ao CallCounter do contexto.
+func (s *ObservedStore) observe(ctx context.Context, method string, start time.Time, err error) {
+	if calls, ok := ctx.Value(callCounterKey{}).(*CallCounter); ok {
+		calls.n.Add(1)
+	}
+	labels := metrics.Labels{"method": method}
+	s.metrics.ObserveDuration("ratelimiter_store_operation_duration_seconds", time.Since(start), labels)
+	if err != nil {
+		s.metrics.IncCounter("ratelimiter_store_errors_total", labels)
+	}
+}
+
+// Increment delega ao store e registra a operação.
+func (s *ObservedStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	start := time.Now()
+	count, err := s.next.Increment(ctx, key, window)
+	s.observe(ctx, "Increment", start, err)
+	return count, err
+}
+
+// IncrementBy delega ao store e registra a operação.
+func (s *ObservedStore) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	start := time.Now()
+	total, err := s.next.IncrementBy(ctx, key, n, window)
+	s.observe(ctx, "IncrementBy", start, err)
+	return total, err
+}
+
+// IncrementWithTTL delega ao store e registra a operação.
+func (s *ObservedStore) IncrementWithTTL(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	start := time.Now()
+	count, ttl, err := s.next.IncrementWithTTL(ctx, key, window)
+	s.observe(ctx, "IncrementWithTTL", start, err)
+	return count, ttl, err
+}
+
+// CheckAndCount delega ao store e registra a operação.
+func (s *ObservedStore) CheckAndCount(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	start := time.Now()
+	allowed, remaining, retryAfter, err := s.next.CheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	s.observe(ctx, "CheckAndCount", start, err)
+	return allowed, remaining, retryAfter, err
+}
+
+// CheckAndCountWithGlobal delega ao store e registra a operação.
+func (s *ObservedStore) CheckAndCountWithGlobal(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global GlobalCount) (bool, int64, time.Duration, int64, error) {
+	start := time.Now()
+	allowed, remaining, retryAfter, globalCount, err := s.next.CheckAndCountWithGlobal(ctx, keys, limit, window, blockDuration, now, global)
+	s.observe(ctx, "CheckAndCountWithGlobal", start, err)
+	return allowed, remaining, retryAfter, globalCount, err
+}
+
+// SlidingWindow delega ao store e registra a operação.
+func (s *ObservedStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	start := time.Now()
+	allowed, count, err := s.next.SlidingWindow(ctx, key, limit, window, now)
+	s.observe(ctx, "SlidingWindow", start, err)
+	return allowed, count, err
+}
+
+// SlidingWindowCheckAndCount delega ao store e registra a operação.
+func (s *ObservedStore) SlidingWindowCheckAndCount(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	start := time.Now()
+	allowed, count, retryAfter, err := s.next.SlidingWindowCheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	s.observe(ctx, "SlidingWindowCheckAndCount", start, err)
+	return allowed, count, retryAfter, err
+}
+
+// LeakyBucket delega ao store e registra a operação.
+func (s *ObservedStore) LeakyBucket(ctx context.Context, keys CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	start := time.Now()
+	allowed, level, retryAfter, err := s.next.LeakyBucket(ctx, keys, capacity, leakInterval, now)
+	s.observe(ctx, "LeakyBucket", start, err)
+	return allowed, level, retryAfter, err
+}
+
+// Count delega ao store e registra a operação.
+func (s *ObservedStore) Count(ctx context.Context, key string) (int64, error) {
+	start := time.Now()
+	count, err := s.next.Count(ctx, key)
+	s.observe(ctx, "Count", start, err)
+	return count, err
+}
+
+// IsBlocked delega ao store e registra a operação.
+func (s *ObservedStore) IsBlocked(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	blocked, err := s.next.IsBlocked(ctx, key)
+	s.observe(ctx, "IsBlocked", start, err)
+	return blocked, err
+}
+
+// Block delega ao store e registra a operação.
+func (s *ObservedStore) Block(ctx context.Context, key string, duration time.Duration, info BlockInfo) error {
+	start := time.Now()
+	err := s.next.Block(ctx, key, duration, info)
+	s.observe(ctx, "Block", start, err)
+	return err
+}
+
+// BlockInfo delega ao store e registra a operação.
+func (s *ObservedStore) BlockInfo(ctx context.Context, key string) (*BlockInfo, error) {
+	start := time.Now()
+	info, err := s.next.BlockInfo(ctx, key)
+	s.observe(ctx, "BlockInfo", start, err)
+	return info, err
+}
+
+// Get delega ao store e registra a operação.
+func (s *ObservedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	val, err := s.next.Get(ctx, key)
+	s.observe(ctx, "Get", start, err)
+	return val, err
+}
+
+// Set delega ao store e registra a operação.
+func (s *ObservedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := s.next.Set(ctx, key, value, ttl)
+	s.observe(ctx, "Set", start, err)
+	return err
+}
+
+// Reset delega ao store e registra a operação.
+func (s *ObservedStore) Reset(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Reset(ctx, key)
+	s.observe(ctx, "Reset", start, err)
+	return err
+}
+
+// ResetAll delega ao store e registra a operação.
+func (s *ObservedStore) ResetAll(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	err := s.next.ResetAll(ctx, keys...)
+	s.observe(ctx, "ResetAll", start, err)
+	return err
+}
+
+// DeleteMatching delega ao store e registra a operação.
+func (s *ObservedStore) DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error) {
+	start := time.Now()
+	n, err := s.next.DeleteMatching(ctx, match, allow)
+	s.observe(ctx, "DeleteMatching", start, err)
+	return n, err
+}
+
+// Close delega ao store e registra a operação.
+func (s *ObservedStore) Close() error {
+	start := time.Now()
+	err := s.next.Close()
+	s.observe(context.Background(), "Close", start, err)
+	return err
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"rateLimiter/pkg/metrics"
+)
+
+// fakeStore é um store em memória mínimo que retorna o erro configurado
+type fakeStore struct {
+	err error
+}
+
+func (f *fakeStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return 1, f.err
+}
+
+func (f *fakeStore) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	return n, f.err
+}
+
+func (f *fakeStore) IncrementWithTTL(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	return 1, window, f.err
+}
+
+func (f *fakeStore) CheckAndCount(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	return true, limit - 1, 0, f.err
+}
+
+func (f *fakeStore) CheckAndCountWithGlobal(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global GlobalCount) (bool, int64, time.Duration, int64, error) {
+	return true, 0, 0, 0, f.err
+}
+
+func (f *fakeStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	return true, 1, f.err
+}
+
+func (f *fakeStore) SlidingWindowCheckAndCount(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	return true, 1, 0, f.err
+}
+
+func (f *fakeStore) LeakyBucket(ctx context.Context, keys CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	return true, 1, 0, f.err
+}
+
+func (f *fakeStore) Count(ctx context.Context, key string) (int64, error) {
+	return 1, f.err
+}
+
+func (f *fakeStore) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return false, f.err
+}
+
+func (f *fakeStore) Block(ctx context.Context, key string, duration time.Duration, info BlockInfo) error {
+	return f.err
+}
+
+func (f *fakeStore) BlockInfo(ctx context.Context, key string) (*BlockInfo, error) {
+	return nil, f.err
+}
+
+func (f *fakeStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, f.err
+}
+
+func (f *fakeStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return f.err
+}
+
+func (f *fakeStore) Reset(ctx context.Context, key string) error {
+	return f.err
+}
+
+func (f *fakeStore) ResetAll(ctx context.Context, keys ...string) error {
+	return f.err
+}
+
+func (f *fakeStore) DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error) {
+	return 0, f.err
+}
+
+func (f *fakeStore) Close() error {
+	return f.err
+}
+
+// exerciseStore chama cada método do store uma vez
+func exerciseStore(s Store) {
+	ctx := context.Background()
+	_, _ = s.Increment(ctx, "k", time.Second)
+	_, _ = s.IncrementBy(ctx, "k", 2, time.Second)
+	_, _, _ = s.IncrementWithTTL(ctx, "k", time.Second)
+	_, _, _, _ = s.CheckAndCount(ctx, CountKeys{Counter: "k", Block: "b", Offenses: "o"}, 1, time.Second, time.Second, time.Now())
+	_, _, _, _, _ = s.CheckAndCountWithGlobal(ctx, CountKeys{Counter: "k", Block: "b", Offenses: "o"}, 1, time.Second, time.Second, time.Now(), GlobalCount{Key: "g", Window: time.Second})
+	_, _, _ = s.SlidingWindow(ctx, "k", 1, time.Second, time.Now())
+	_, _, _, _ = s.SlidingWindowCheckAndCount(ctx, CountKeys{Counter: "k", Block: "b", Offenses: "o"}, 1, time.Second, time.Second, time.Now())
+	_, _, _, _ = s.LeakyBucket(ctx, CountKeys{Counter: "k", Block: "b"}, 1, time.Second, time.Now())
+	_, _ = s.Count(ctx, "k")
+	_, _ = s.IsBlocked(ctx, "k")
+	_ = s.Block(ctx, "k", time.Second, BlockInfo{})
+	_, _ = s.BlockInfo(ctx, "k")
+	_, _ = s.Get(ctx, "k")
+	_ = s.Set(ctx, "k", nil, time.Second)
+	_ = s.Reset(ctx, "k")
+	_ = s.ResetAll(ctx, "k", "b")
+	_, _ = s.DeleteMatching(ctx, "k*", func(string) bool { return true })
+	_ = s.Close()
+}
+
+var storeMethods = []string{"Increment", "IncrementBy", "IncrementWithTTL", "CheckAndCount", "CheckAndCountWithGlobal", "SlidingWindow", "SlidingWindowCheckAndCount", "LeakyBucket", "Count", "IsBlocked", "Block", "BlockInfo", "Get", "Set", "Reset", "ResetAll", "DeleteMatching", "Close"}
+
+// Test_ObservedStore_RecordsLatency verifica que cada método registra a latência
+func Test_ObservedStore_RecordsLatency(t *testing.T) {
+	registry := metrics.NewRegistry()
+	exerciseStore(NewObservedStore(&fakeStore{}, registry))
+
+	for _, method := range storeMethods {
+		labels := metrics.Labels{"method": method}
+		h, ok := registry.Histogram("ratelimiter_store_operation_duration_seconds", labels)
+		assert.True(t, ok, "Latência de %s deveria ser registrada", method)
+		assert.Equal(t, uint64(1), h.Count, "Latência de %s deveria ter uma observação", method)
+		assert.Equal(t, float64(0), registry.Counter("ratelimiter_store_errors_total", labels),
+			"Nenhum erro deveria ser registrado para %s", method)
+	}
+}
+
+// Test_ObservedStore_RecordsErrors verifica que erros são contados por método e repassados ao chamador
+func Test_ObservedStore_RecordsErrors(t *testing.T) {
+	registry := metrics.NewRegistry()
+	storeErr := errors.New("falha no backend")
+	s := NewObservedStore(&fakeStore{err: storeErr}, registry)
+
+	_, err := s.Increment(context.Background(), "k", time.Second)
+	assert.ErrorIs(t, err, storeErr)
+
+	exerciseStore(s)
+
+	assert.Equal(t, float64(2), registry.Counter("ratelimiter_store_errors_total", metrics.Labels{"method": "Increment"}))
+	for _, method := range storeMethods[1:] {
+		assert.Equal(t, float64(1), registry.Counter("ratelimiter_store_errors_total", metrics.Labels{"method": method}),
+			"Erro de %s deveria ser contado", method)
+	}
+}
+
+// Test_ObservedStore_CallCounter verifica que as operações feitas com o contexto são contadas
+func Test_ObservedStore_CallCounter(t *testing.T) {
+	s := NewObservedStore(&fakeStore{}, nil)
+	ctx, calls := WithCallCounter(context.Background())
+
+	_, _ = s.Increment(ctx, "k", time.Second)
+	_, _ = s.IsBlocked(ctx, "k")
+	_, _ = s.Count(context.Background(), "k")
+	assert.Equal(t, 2, calls.Count(), "Só as operações com o contexto do contador são contadas")
+
+	inner, innerCalls := WithCallCounter(ctx)
+	_ = s.Reset(inner, "k")
+	assert.Equal(t, 1, innerCalls.Count())
+	assert.Equal(t, 2, calls.Count(), "O contador novo substitui o anterior")
+}
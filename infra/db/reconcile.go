@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// ReconcilePolicy define como as contagens do fallback e do store principal são combinadas
+// quando o principal volta a responder.
+type ReconcilePolicy string
+
+const (
+	// ReconcilePrimary mantém as contagens do principal e descarta as do fallback.
+	ReconcilePrimary ReconcilePolicy = "primary"
+	// ReconcileMax fica com a maior das duas contagens de cada chave (conservador).
+	ReconcileMax ReconcilePolicy = "max"
+	// ReconcileSumCapped soma as duas contagens, limitada por ReconcileConfig.Cap.
+	ReconcileSumCapped ReconcilePolicy = "sum-capped"
+)
+
+// ReconcileConfig define os parâmetros de Reconcile.
+type ReconcileConfig struct {
+	// Policy é a política de combinação (padrão: ReconcilePrimary).
+	Policy ReconcilePolicy
+	// Matches são os padrões (glob do Redis) das chaves reconciliadas (padrão: "*").
+	Matches []string
+	// Cap retorna o teto da soma de cada chave em ReconcileSumCapped (opcional; nil ou um
+	// retorno menor ou igual a zero deixam a soma sem teto).
+	Cap func(key string) int64
+	// Baseline retorna a contagem com que cada chave foi semeada no fallback ao ativá-lo (ex.:
+	// Baseline.SeededCount). Em ReconcileSumCapped, só o que o fallback contou além dela é
+	// somado, para que as contagens anteriores à queda não entrem duas vezes (opcional).
+	Baseline func(key string) int64
+}
+
+// ReconcileFallback é o store de fallback reconciliado, que precisa também remover as suas
+// chaves depois da reconciliação.
+type ReconcileFallback interface {
+	Snapshotter
+	DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error)
+}
+
+// Reconcile grava no store principal as chaves que divergiram do fallback enquanto o principal
+// esteve fora, conforme a política, e retorna quantas chaves foram gravadas. Os contadores
+// (valores inteiros) são combinados; bloqueios e demais chaves do fallback só são copiados
+// quando o principal não os tem. Com ReconcilePrimary, nada é gravado. Depois da gravação, as
+// chaves do fallback são removidas, para que não voltem a ser combinadas na próxima
+// recuperação. O resultado é aproximado: o que for contado no principal entre a leitura e a
+// gravação se perde.
+func Reconcile(ctx context.Context, primary Snapshotter, fallback ReconcileFallback, cfg ReconcileConfig) (int, error) {
+	if len(cfg.Matches) == 0 {
+		cfg.Matches = []string{"*"}
+	}
+	n, err := reconcile(ctx, primary, fallback, cfg)
+	if err != nil {
+		return 0, err
+	}
+	for _, match := range cfg.Matches {
+		if _, err := fallback.DeleteMatching(ctx, match, func(string) bool { return true }); err != nil {
+			return n, fmt.Errorf("erro ao limpar o fallback: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// reconcile combina as chaves do fallback com as do principal e grava o resultado.
+func reconcile(ctx context.Context, primary, fallback Snapshotter, cfg ReconcileConfig) (int, error) {
+	if cfg.Policy == "" || cfg.Policy == ReconcilePrimary {
+		return 0, nil
+	}
+
+	fallbackEntries, err := dumpMatching(ctx, fallback, cfg.Matches)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler as contagens do fallback: %w", err)
+	}
+	if len(fallbackEntries) == 0 {
+		return 0, nil
+	}
+	primaryEntries, err := dumpMatching(ctx, primary, cfg.Matches)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler as contagens do store principal: %w", err)
+	}
+	current := make(map[string]SnapshotEntry, len(primaryEntries))
+	for _, entry := range primaryEntries {
+		current[entry.Key] = entry
+	}
+
+	var merged []SnapshotEntry
+	for _, entry := range fallbackEntries {
+		if entry, ok := reconcileEntry(cfg, current, entry); ok {
+			merged = append(merged, entry)
+		}
+	}
+	if len(merged) == 0 {
+		return 0, nil
+	}
+	if err := primary.Restore(ctx, merged); err != nil {
+		return 0, fmt.Errorf("erro ao gravar as contagens reconciliadas: %w", err)
+	}
+	return len(merged), nil
+}
+
+// reconcileEntry combina uma chave do fallback com a do principal e informa se o principal
+// precisa ser atualizado.
+func reconcileEntry(cfg ReconcileConfig, current map[string]SnapshotEntry, entry SnapshotEntry) (SnapshotEntry, bool) {
+	existing, ok := current[entry.Key]
+	if !ok {
+		return entry, true
+	}
+	fallbackCount, fallbackErr := strconv.ParseInt(entry.Value, 10, 64)
+	primaryCount, primaryErr := strconv.ParseInt(existing.Value, 10, 64)
+	if entry.Fields != nil || existing.Fields != nil || fallbackErr != nil || primaryErr != nil {
+		return SnapshotEntry{}, false // não é um contador: o principal prevalece
+	}
+
+	count := primaryCount
+	switch cfg.Policy {
+	case ReconcileMax:
+		count = max(primaryCount, fallbackCount)
+	case ReconcileSumCapped:
+		if cfg.Baseline != nil {
+			// O fallback começou com as contagens do principal: só o que ele contou depois é somado
+			fallbackCount = max(fallbackCount-cfg.Baseline(entry.Key), 0)
+		}
+		count = primaryCount + fallbackCount
+		if cfg.Cap != nil {
+			if limit := cfg.Cap(entry.Key); limit > 0 && count > limit {
+				count = max(limit, primaryCount)
+			}
+		}
+	}
+	if count == primaryCount {
+		return SnapshotEntry{}, false
+	}
+	// A janela mais longa das duas, para não liberar a cota antes do principal
+	existing.Value = strconv.FormatInt(count, 10)
+	if existing.TTL > 0 && (entry.TTL == 0 || entry.TTL > existing.TTL) {
+		existing.TTL = entry.TTL
+	}
+	return existing, true
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+)
+
+// WithAuditStream registra cada bloqueio gravado pelo store no stream informado (XADD), para
+// que um consumidor separado processe os eventos. Cada entrada traz o identificador em hash,
+// o escopo (ip ou token), o motivo e o horário do bloqueio. O registro é best-effort: uma
+// falha é apenas registrada em log e não altera a decisão. Vazio desativa a auditoria.
+func WithAuditStream(stream string) Option {
+	return func(rs *RedisStore) {
+		rs.auditStream = stream
+	}
+}
+
+// auditBlock acrescenta ao stream de auditoria o bloqueio gravado em blockKey.
+func (rs *RedisStore) auditBlock(ctx context.Context, blockKey, reason string, at time.Time) {
+	if rs.auditStream == "" {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	scope, identifier := auditIdentifier(blockKey)
+	sum := sha256.Sum256([]byte(identifier))
+	err := rs.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: rs.auditStream,
+		Values: map[string]interface{}{
+			"identifier": hex.EncodeToString(sum[:8]),
+			"scope":      scope,
+			"reason":     reason,
+			"timestamp":  at.UnixMilli(),
+		},
+	}).Err()
+	if err != nil {
+		log.Printf("Aviso: erro ao registrar bloqueio no stream de auditoria %q: %v", rs.auditStream, err)
+	}
+}
+
+// auditIdentifier extrai o escopo e o identificador de uma chave de bloqueio no formato
+// "<prefixos>blocked_ip_<identificador>" ou "<prefixos>blocked_token_<identificador>". Chaves
+// fora do formato são registradas inteiras, com o escopo vazio.
+func auditIdentifier(blockKey string) (scope, identifier string) {
+	i := strings.Index(blockKey, "blocked_")
+	if i < 0 {
+		return "", blockKey
+	}
+	rest := blockKey[i+len("blocked_"):]
+	for _, scope := range []string{"ip", "token"} {
+		if identifier, ok := strings.CutPrefix(rest, scope+"_"); ok {
+			return scope, identifier
+		}
+	}
+	return "", blockKey
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+// Test_RedisStore_AuditStream verifica que só os bloqueios novos são registrados no stream
+func Test_RedisStore_AuditStream(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	WithAuditStream("audit")(store)
+
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	keys := db.CountKeys{Counter: "app:ip_c", Block: "app:blocked_ip_c", Offenses: "app:offenses_ip_c", GraceOverage: 1}
+
+	// Duas permitidas e uma rejeição de tolerância: nada é registrado
+	for i := 0; i < 3; i++ {
+		_, _, _, err := store.CheckAndCount(ctx, keys, 2, time.Second, time.Minute, now)
+		require.NoError(t, err)
+	}
+	assert.False(t, mr.Exists("audit"))
+
+	// A seguinte grava o bloqueio; as rejeições durante o bloqueio não geram novas entradas
+	for i := 0; i < 3; i++ {
+		allowed, _, retryAfter, err := store.CheckAndCount(ctx, keys, 2, time.Second, time.Minute, now)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, time.Minute, retryAfter)
+	}
+
+	entries, err := store.client.XRange(ctx, "audit", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	sum := sha256.Sum256([]byte("c"))
+	assert.Equal(t, map[string]interface{}{
+		"identifier": hex.EncodeToString(sum[:8]),
+		"scope":      "ip",
+		"reason":     db.ReasonRateLimitExceeded,
+		"timestamp":  "1717236000000",
+	}, entries[0].Values)
+
+	// Bloqueios gravados diretamente (ex.: manuais) também são registrados
+	require.NoError(t, store.Block(ctx, "app:blocked_token_abc", time.Minute, db.BlockInfo{Reason: "manual", StartedAt: now}))
+	entries, err = store.client.XRange(ctx, "audit", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "token", entries[1].Values["scope"])
+	assert.Equal(t, "manual", entries[1].Values["reason"])
+}
+
+// Test_RedisStore_AuditStream_BestEffort verifica que uma falha no stream não altera a decisão
+func Test_RedisStore_AuditStream_BestEffort(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	WithAuditStream("audit")(store)
+	// Uma string no lugar do stream faz o XADD falhar
+	require.NoError(t, mr.Set("audit", "x"))
+
+	ctx := context.Background()
+	now := time.Now()
+	_, _, _, err := store.CheckAndCount(ctx, testCountKeys, 1, time.Second, time.Minute, now)
+	require.NoError(t, err)
+	allowed, _, retryAfter, err := store.CheckAndCount(ctx, testCountKeys, 1, time.Second, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+	assert.True(t, mr.Exists("blocked_ip_c"))
+	require.NoError(t, store.Block(ctx, "blocked_ip_d", time.Minute, db.BlockInfo{Reason: "manual", StartedAt: now}))
+}
+
+// Test_RedisStore_AuditStream_SlidingWindow verifica o registro do bloqueio da janela deslizante
+func Test_RedisStore_AuditStream_SlidingWindow(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	WithAuditStream("audit")(store)
+
+	ctx := context.Background()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		_, _, _, err := store.SlidingWindowCheckAndCount(ctx, testCountKeys, 1, time.Second, time.Minute, now)
+		require.NoError(t, err)
+	}
+	entries, err := store.client.XRange(ctx, "audit", "-", "+").Result()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// WithBlockCodec define a serialização dos metadados dos bloqueios (padrão: db.JSONBlockCodec).
+// Os scripts Lua só sabem gravar JSON: com outro codec, o bloqueio gravado por um script é
+// regravado no formato do codec logo em seguida, com duas idas a mais ao Redis por bloqueio novo.
+// A leitura aceita os dois formatos, então bloqueios em JSON (anteriores à troca do codec ou
+// prolongados pelas faixas de severidade) continuam válidos.
+func WithBlockCodec(codec db.BlockCodec) Option {
+	return func(rs *RedisStore) {
+		if codec != nil {
+			rs.blockCodec = codec
+		}
+	}
+}
+
+// jsonCodec informa se os bloqueios são gravados em JSON, o formato dos scripts.
+func (rs *RedisStore) jsonCodec() bool {
+	_, ok := rs.blockCodec.(db.JSONBlockCodec)
+	return ok
+}
+
+// decodeBlock lê os metadados de um bloqueio com o codec configurado ou, como alternativa, em
+// JSON. O literal legado "blocked" resulta em metadados vazios.
+func (rs *RedisStore) decodeBlock(val []byte) (*db.BlockInfo, error) {
+	info := &db.BlockInfo{}
+	if string(val) == "blocked" {
+		// Bloqueios gravados antes dos metadados guardavam apenas o literal "blocked"
+		return info, nil
+	}
+	err := rs.blockCodec.Unmarshal(val, info)
+	if err != nil && !rs.jsonCodec() {
+		*info = db.BlockInfo{}
+		if json.Unmarshal(val, info) == nil {
+			return info, nil
+		}
+	}
+	return info, err
+}
+
+// blockWritten trata o bloqueio que um script acabou de gravar em blockKey: regrava os
+// metadados no formato do codec e registra o bloqueio no stream de auditoria e no journal.
+func (rs *RedisStore) blockWritten(ctx context.Context, blockKey string, now time.Time) {
+	if !rs.jsonCodec() {
+		if err := rs.recodeBlock(ctx, blockKey); err != nil {
+			log.Printf("Aviso: erro ao regravar os metadados do bloqueio %q com o codec: %v", blockKey, err)
+		}
+	}
+	rs.auditBlock(ctx, blockKey, db.ReasonRateLimitExceeded, now)
+	rs.journalBlock(ctx, blockKey, now)
+}
+
+// recodeBlock regrava, no formato do codec e com o TTL restante, os metadados em JSON de um
+// bloqueio. Um bloqueio que expirou ou já está no formato do codec é mantido.
+func (rs *RedisStore) recodeBlock(ctx context.Context, key string) error {
+	pipe := rs.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	}
+	ttl := pttl.Val()
+	info := db.BlockInfo{}
+	if ttl <= 0 || json.Unmarshal([]byte(get.Val()), &info) != nil {
+		return nil
+	}
+	val, err := rs.blockCodec.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return rs.client.SetXX(ctx, key, val, ttl).Err()
+}
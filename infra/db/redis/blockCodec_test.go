@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+// compactCodec grava os metadados como "motivo|infrações|início|fim", com os horários em ms
+type compactCodec struct{}
+
+func (compactCodec) Marshal(info db.BlockInfo) ([]byte, error) {
+	return []byte(fmt.Sprintf("%s|%d|%d|%d", info.Reason, info.OffenseCount,
+		info.StartedAt.UnixMilli(), info.ExpiresAt.UnixMilli())), nil
+}
+
+func (compactCodec) Unmarshal(data []byte, info *db.BlockInfo) error {
+	parts := strings.Split(string(data), "|")
+	if len(parts) != 4 {
+		return fmt.Errorf("metadados compactos inválidos: %q", data)
+	}
+	values := make([]int64, 3)
+	for i, part := range parts[1:] {
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	*info = db.BlockInfo{
+		Reason:       parts[0],
+		OffenseCount: values[0],
+		StartedAt:    time.UnixMilli(values[1]).UTC(),
+		ExpiresAt:    time.UnixMilli(values[2]).UTC(),
+	}
+	return nil
+}
+
+// Test_RedisStore_BlockCodec_RoundTrip verifica que os metadados voltam iguais pelos dois codecs
+func Test_RedisStore_BlockCodec_RoundTrip(t *testing.T) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	info := db.BlockInfo{
+		Reason:       db.ReasonManual,
+		OffenseCount: 3,
+		StartedAt:    start,
+		ExpiresAt:    start.Add(time.Hour),
+	}
+	for name, codec := range map[string]db.BlockCodec{"json": db.JSONBlockCodec{}, "compact": compactCodec{}} {
+		t.Run(name, func(t *testing.T) {
+			mr, store := setupTestStore(t)
+			defer mr.Close()
+			defer store.Close()
+			WithBlockCodec(codec)(store)
+
+			ctx := context.Background()
+			require.NoError(t, store.Block(ctx, "blocked_ip_1", time.Hour, info))
+			expected, err := codec.Marshal(info)
+			require.NoError(t, err)
+			raw, err := mr.Get("blocked_ip_1")
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), raw)
+
+			read, err := store.BlockInfo(ctx, "blocked_ip_1")
+			require.NoError(t, err)
+			require.NotNil(t, read)
+			assert.Equal(t, info.Reason, read.Reason)
+			assert.Equal(t, info.OffenseCount, read.OffenseCount)
+			assert.True(t, info.StartedAt.Equal(read.StartedAt))
+			assert.True(t, info.ExpiresAt.Equal(read.ExpiresAt))
+		})
+	}
+}
+
+// Test_RedisStore_BlockCodec_Scripts verifica que os bloqueios gravados pelos scripts passam para
+// o formato do codec e que os bloqueios em JSON continuam legíveis
+func Test_RedisStore_BlockCodec_Scripts(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	WithBlockCodec(compactCodec{})(store)
+
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		_, _, _, err := store.CheckAndCount(ctx, testCountKeys, 1, time.Second, time.Minute, now)
+		require.NoError(t, err)
+	}
+	raw, err := mr.Get("blocked_ip_c")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s|1|%d|%d", db.ReasonRateLimitExceeded, now.UnixMilli(), now.Add(time.Minute).UnixMilli()), raw)
+	assert.Equal(t, time.Minute, mr.TTL("blocked_ip_c"))
+
+	// O tempo restante do bloqueio vem dos metadados no formato do codec
+	allowed, _, retryAfter, err := store.CheckAndCount(ctx, testCountKeys, 1, time.Second, time.Minute, now.Add(15*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 45*time.Second, retryAfter)
+
+	// Um bloqueio em JSON, gravado antes da troca do codec, ainda é lido
+	require.NoError(t, mr.Set("blocked_ip_old", `{"reason":"manual","offense_count":2}`))
+	info, err := store.BlockInfo(ctx, "blocked_ip_old")
+	require.NoError(t, err)
+	assert.Equal(t, db.ReasonManual, info.Reason)
+	assert.Equal(t, int64(2), info.OffenseCount)
+
+	_, err = (&RedisStore{blockCodec: db.JSONBlockCodec{}}).decodeBlock([]byte("manual|1|0|0"))
+	require.Error(t, err, "Com o codec JSON, só JSON é aceito")
+}
@@ -0,0 +1,260 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// checkAndCountLua faz o fluxo da janela fixa: verifica o bloqueio, incrementa o contador e,
+// ao exceder o limite, conta a infração, grava o bloqueio com os metadados em JSON e zera o
+// contador (a não ser que ARGV[8] seja 1). As primeiras ARGV[9] requisições além do limite são
+// só rejeitadas, com o tempo até o fim da janela.
+//
+// Com faixas de severidade (ARGV[12] > 0), o bloqueio dura o maior entre ARGV[3] e a duração
+// da maior faixa atingida, e as requisições rejeitadas durante o bloqueio continuam contando:
+// ao atingir uma faixa mais longa, o bloqueio é prolongado. Com o contador zerado no bloqueio,
+// o limite mais a requisição que bloqueou são somados à contagem.
+//
+// KEYS[1] = contador, KEYS[2] = bloqueio, KEYS[3] = infrações
+// ARGV[1] = limite, ARGV[2] = janela em ms, ARGV[3] = bloqueio em ms,
+// ARGV[4] = janela das infrações em ms, ARGV[5..7] = reason, started_at e expires_at já em JSON,
+// ARGV[8] = 1 para manter o contador ao bloquear, ARGV[9] = requisições de tolerância,
+// ARGV[10] = janela do contador global em ms e ARGV[11] = teto do contador global (ver
+// checkAndCountWithGlobalScript), ARGV[12] = número de faixas, seguidas de limiar, bloqueio em
+// ms e expires_at em JSON de cada uma
+//
+// Retorna {permitida, restantes, valor do bloqueio existente ou "", PTTL do bloqueio}, com
+// restantes -1 quando a requisição acabou de gravar o bloqueio.
+const checkAndCountLua = `
+local function severityBlock(count, ms, expiresAt)
+	for i = 0, tonumber(ARGV[12]) - 1 do
+		local at = 13 + i * 3
+		local tierMs = tonumber(ARGV[at + 1])
+		if count >= tonumber(ARGV[at]) and tierMs > ms then
+			ms, expiresAt = tierMs, ARGV[at + 2]
+		end
+	end
+	return ms, expiresAt
+end
+
+local function block(offenses, ms, expiresAt)
+	local info = '{"reason":' .. ARGV[5] .. ',"offense_count":' .. offenses ..
+		',"started_at":' .. ARGV[6] .. ',"expires_at":' .. expiresAt .. '}'
+	redis.call('SET', KEYS[2], info, 'PX', ms)
+	return info
+end
+
+local function countRequest()
+	local count = redis.call('INCR', KEYS[1])
+	if count == 1 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	end
+	return count
+end
+
+local function checkAndCount()
+	local limit = tonumber(ARGV[1])
+	local blocked = redis.call('GET', KEYS[2])
+	if blocked then
+		local ttl = redis.call('PTTL', KEYS[2])
+		if tonumber(ARGV[12]) > 0 and ttl >= 0 then
+			local count = countRequest()
+			if ARGV[8] ~= '1' then
+				count = count + limit + 1
+			end
+			local ms, expiresAt = severityBlock(count, ttl, '')
+			if ms > ttl then
+				blocked = block(tonumber(redis.call('GET', KEYS[3]) or '0'), ms, expiresAt)
+				ttl = ms
+			end
+		end
+		return {0, 0, blocked, ttl}
+	end
+
+	local count = countRequest()
+	if count <= limit then
+		return {1, limit - count, '', 0}
+	end
+	if count <= limit + tonumber(ARGV[9]) then
+		return {0, 0, '', redis.call('PTTL', KEYS[1])}
+	end
+
+	local offenses = redis.call('INCR', KEYS[3])
+	if offenses == 1 then
+		redis.call('PEXPIRE', KEYS[3], ARGV[4])
+	end
+	local blockMs, expiresAt = severityBlock(count, tonumber(ARGV[3]), ARGV[7])
+	local remaining = 0
+	if blockMs > 0 then
+		block(offenses, blockMs, expiresAt)
+		remaining = -1
+	end
+	if ARGV[8] ~= '1' then
+		redis.call('DEL', KEYS[1])
+	end
+	return {0, remaining, '', blockMs}
+end
+`
+
+// checkAndCountScript executa checkAndCountLua em uma única ida ao Redis.
+var checkAndCountScript = redis.NewScript(checkAndCountLua + `
+return checkAndCount()
+`)
+
+// checkAndCountWithGlobalScript incrementa antes o contador global (KEYS[4], com a janela em
+// ms em ARGV[10]) e acrescenta o seu valor à resposta de checkAndCountLua. Quando o contador
+// global passa do teto em ARGV[11] (0: sem teto), a requisição é rejeitada sem contar o
+// identificador: só o bloqueio dele é lido.
+var checkAndCountWithGlobalScript = redis.NewScript(checkAndCountLua + `
+local global = redis.call('INCR', KEYS[4])
+if global == 1 then
+	redis.call('PEXPIRE', KEYS[4], ARGV[10])
+end
+local globalLimit = tonumber(ARGV[11])
+if globalLimit > 0 and global > globalLimit then
+	local blocked = redis.call('GET', KEYS[2])
+	if blocked then
+		return {0, 0, blocked, redis.call('PTTL', KEYS[2]), global}
+	end
+	return {0, 0, '', 0, global}
+end
+local res = checkAndCount()
+table.insert(res, global)
+return res
+`)
+
+// CheckAndCount aplica a janela fixa em uma única operação atômica (ver db.Store).
+func (rs *RedisStore) CheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	args, err := checkAndCountArgs(keys, limit, window, blockDuration, now)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	var res []interface{}
+	err = rs.scripted(ctx, func() (err error) {
+		res, err = checkAndCountScript.Run(ctx, rs.client, []string{keys.Counter, keys.Block, keys.Offenses}, args...).Slice()
+		return err
+	}, func() (err error) {
+		res, err = rs.checkAndCountPipeline(ctx, keys, limit, window, blockDuration, now)
+		return err
+	})
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("erro ao executar script de contagem: %w", err)
+	}
+	if len(res) != 4 {
+		return false, 0, 0, fmt.Errorf("resposta inesperada do script de contagem: %v", res)
+	}
+	allowed, remaining, retryAfter := rs.parseCheckAndCount(res, now)
+	if newBlock(res) {
+		rs.blockWritten(ctx, keys.Block, now)
+	}
+	return allowed, remaining, retryAfter, nil
+}
+
+// CheckAndCountWithGlobal aplica a janela fixa e incrementa o contador global na mesma
+// operação atômica (ver db.Store).
+func (rs *RedisStore) CheckAndCountWithGlobal(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global db.GlobalCount) (bool, int64, time.Duration, int64, error) {
+	args, err := checkAndCountArgs(keys, limit, window, blockDuration, now)
+	if err != nil {
+		return false, 0, 0, 0, err
+	}
+	args[9], args[10] = max(global.Window.Milliseconds(), 1), max(global.Limit, 0)
+
+	var res []interface{}
+	err = rs.scripted(ctx, func() (err error) {
+		res, err = checkAndCountWithGlobalScript.Run(ctx, rs.client,
+			[]string{keys.Counter, keys.Block, keys.Offenses, global.Key}, args...).Slice()
+		return err
+	}, func() error {
+		globalCount, _, err := rs.incrementPipeline(ctx, global.Key, 1, global.Window)
+		if err != nil {
+			return err
+		}
+		if global.Limit > 0 && globalCount > global.Limit {
+			if res, err = rs.blockedPipeline(ctx, keys.Block); res == nil && err == nil {
+				res = []interface{}{int64(0), int64(0), "", int64(0)}
+			}
+			res = append(res, globalCount)
+			return err
+		}
+		res, err = rs.checkAndCountPipeline(ctx, keys, limit, window, blockDuration, now)
+		res = append(res, globalCount)
+		return err
+	})
+	if err != nil {
+		return false, 0, 0, 0, fmt.Errorf("erro ao executar script de contagem: %w", err)
+	}
+	if len(res) != 5 {
+		return false, 0, 0, 0, fmt.Errorf("resposta inesperada do script de contagem: %v", res)
+	}
+	allowed, remaining, retryAfter := rs.parseCheckAndCount(res, now)
+	if newBlock(res) {
+		rs.blockWritten(ctx, keys.Block, now)
+	}
+	globalCount, _ := res[4].(int64)
+	return allowed, remaining, retryAfter, globalCount, nil
+}
+
+// checkAndCountArgs monta os argumentos de checkAndCountLua.
+func checkAndCountArgs(keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) ([]interface{}, error) {
+	reason, _ := json.Marshal(db.ReasonRateLimitExceeded)
+	startedAt, err := json.Marshal(now)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar início do bloqueio: %w", err)
+	}
+	expiresAt, err := json.Marshal(now.Add(blockDuration))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar fim do bloqueio: %w", err)
+	}
+	keepCounter := 0
+	if keys.KeepCounterOnBlock {
+		keepCounter = 1
+	}
+	args := []interface{}{
+		limit, max(window.Milliseconds(), 1), blockDuration.Milliseconds(), db.OffenseWindow.Milliseconds(),
+		string(reason), string(startedAt), string(expiresAt), keepCounter, max(keys.GraceOverage, 0),
+		0, 0, len(keys.SeverityTiers),
+	}
+	for _, tier := range keys.SeverityTiers {
+		tierExpiresAt, err := json.Marshal(now.Add(tier.Duration))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao serializar fim do bloqueio: %w", err)
+		}
+		args = append(args, tier.Threshold, tier.Duration.Milliseconds(), string(tierExpiresAt))
+	}
+	return args, nil
+}
+
+// parseCheckAndCount interpreta os quatro primeiros valores retornados por checkAndCountLua.
+func (rs *RedisStore) parseCheckAndCount(res []interface{}, now time.Time) (bool, int64, time.Duration) {
+	allowed, _ := res[0].(int64)
+	remaining, _ := res[1].(int64)
+	blocked, _ := res[2].(string)
+	ttlMs, _ := res[3].(int64)
+	if allowed == 1 {
+		return true, remaining, 0
+	}
+
+	retryAfter := time.Duration(ttlMs) * time.Millisecond
+	if blocked != "" {
+		// Já bloqueado: o tempo restante vem dos metadados, com o TTL como alternativa
+		// para bloqueios sem expiração registrada (ex.: o literal legado "blocked")
+		if info, err := rs.decodeBlock([]byte(blocked)); err == nil && !info.ExpiresAt.IsZero() {
+			retryAfter = info.ExpiresAt.Sub(now)
+		}
+	}
+	return false, 0, max(retryAfter, 0)
+}
+
+// newBlock informa se a resposta de checkAndCountLua indica um bloqueio gravado pela própria
+// requisição, e não um bloqueio já existente ou uma rejeição de tolerância.
+func newBlock(res []interface{}) bool {
+	remaining, _ := res[1].(int64)
+	return remaining < 0
+}
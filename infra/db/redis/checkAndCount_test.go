@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+// commandCounter conta os comandos (e pipelines) enviados ao Redis
+type commandCounter struct {
+	n atomic.Int64
+}
+
+func (c *commandCounter) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	c.n.Add(1)
+	return ctx, nil
+}
+
+func (c *commandCounter) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (c *commandCounter) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	c.n.Add(1)
+	return ctx, nil
+}
+
+func (c *commandCounter) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+var testCountKeys = db.CountKeys{Counter: "ip_c", Block: "blocked_ip_c", Offenses: "offenses_ip_c"}
+
+// Test_RedisStore_CheckAndCount_Decisions verifica permissões, bloqueio e metadados gravados pelo script
+func Test_RedisStore_CheckAndCount_Decisions(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	for expected := int64(2); expected >= 0; expected-- {
+		allowed, remaining, retryAfter, err := store.CheckAndCount(ctx, testCountKeys, 3, time.Second, time.Minute, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, expected, remaining)
+		assert.Zero(t, retryAfter)
+	}
+	assert.Equal(t, time.Second, mr.TTL("ip_c"))
+
+	// A quarta requisição excede o limite: bloqueia e zera o contador
+	allowed, _, retryAfter, err := store.CheckAndCount(ctx, testCountKeys, 3, time.Second, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+	assert.False(t, mr.Exists("ip_c"))
+	assert.Equal(t, time.Minute, mr.TTL("blocked_ip_c"))
+	assert.Equal(t, db.OffenseWindow, mr.TTL("offenses_ip_c"))
+
+	info, err := store.BlockInfo(ctx, "blocked_ip_c")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
+	assert.Equal(t, int64(1), info.OffenseCount)
+	assert.True(t, now.Equal(info.StartedAt))
+	assert.True(t, now.Add(time.Minute).Equal(info.ExpiresAt))
+
+	// Enquanto bloqueado, o contador não é incrementado e o tempo restante segue o instante informado
+	allowed, _, retryAfter, err = store.CheckAndCount(ctx, testCountKeys, 3, time.Second, time.Minute, now.Add(20*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, retryAfter)
+	assert.False(t, mr.Exists("ip_c"))
+}
+
+// Test_RedisStore_CheckAndCount_LegacyBlock verifica que um bloqueio legado usa o TTL como tempo restante
+func Test_RedisStore_CheckAndCount_LegacyBlock(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	require.NoError(t, mr.Set("blocked_ip_c", "blocked"))
+	mr.SetTTL("blocked_ip_c", 30*time.Second)
+
+	allowed, _, retryAfter, err := store.CheckAndCount(context.Background(), testCountKeys, 3, time.Second, time.Minute, time.Now())
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, retryAfter)
+}
+
+// Test_RedisStore_CheckAndCount_SingleRoundTrip verifica que cada verificação faz uma única ida ao Redis
+func Test_RedisStore_CheckAndCount_SingleRoundTrip(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	counter := &commandCounter{}
+	store.client.AddHook(counter)
+
+	// A primeira execução pode precisar de EVALSHA + EVAL para carregar o script
+	_, _, _, err := store.CheckAndCount(ctx, testCountKeys, 2, time.Second, time.Minute, time.Now())
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		before := counter.n.Load()
+		_, _, _, err := store.CheckAndCount(ctx, testCountKeys, 2, time.Second, time.Minute, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), counter.n.Load()-before, "Chamada %d deveria usar um único comando", i+1)
+	}
+}
+
+// Test_RedisStore_CheckAndCount_Concurrent verifica que, sob concorrência, exatamente o limite é permitido
+func Test_RedisStore_CheckAndCount_Concurrent(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	const limit, clients = 20, 100
+	var allowedCount atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			allowed, _, _, err := store.CheckAndCount(context.Background(), testCountKeys, limit, time.Minute, time.Minute, time.Now())
+			assert.NoError(t, err)
+			if allowed {
+				allowedCount.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(limit), allowedCount.Load())
+	offenses, err := mr.Get("offenses_ip_c")
+	require.NoError(t, err)
+	assert.Equal(t, "1", offenses, "Só a primeira rejeição gera infração; as demais encontram o bloqueio")
+}
+
+// Test_RedisStore_CheckAndCountWithGlobal verifica o contador global e que as duas contagens
+// usam um único comando
+func Test_RedisStore_CheckAndCountWithGlobal(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	counter := &commandCounter{}
+	store.client.AddHook(counter)
+	global := db.GlobalCount{Key: "global", Window: 10 * time.Second}
+
+	_, _, _, globalCount, err := store.CheckAndCountWithGlobal(ctx, testCountKeys, 1, time.Second, time.Minute, time.Now(), global)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), globalCount)
+	assert.Equal(t, 10*time.Second, mr.TTL("global"))
+
+	before := counter.n.Load()
+	allowed, _, retryAfter, globalCount, err := store.CheckAndCountWithGlobal(ctx, testCountKeys, 1, time.Second, time.Minute, time.Now(), global)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counter.n.Load()-before, "As duas contagens deveriam usar um único comando")
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+	assert.Equal(t, int64(2), globalCount, "O contador global deveria contar também as requisições bloqueadas")
+}
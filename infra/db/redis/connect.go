@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+)
+
+// ConnectPolicy define as tentativas da conexão inicial com o Redis, para que um servidor
+// iniciado antes do Redis espere por ele em vez de encerrar.
+type ConnectPolicy struct {
+	// MaxAttempts é o total de PINGs, incluindo o primeiro (0 não limita as tentativas, só o tempo).
+	MaxAttempts int
+	// MaxWait é o tempo máximo de espera somando as tentativas (0 não limita o tempo).
+	MaxWait time.Duration
+	// BaseBackoff é a espera após a primeira falha; ela dobra a cada nova tentativa, até MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// PingTimeout é o tempo máximo de cada PING (0 usa 5 segundos).
+	PingTimeout time.Duration
+}
+
+// WaitForRedis envia PINGs ao Redis até a primeira resposta, com espera exponencial entre as
+// tentativas, e retorna o último erro quando as tentativas ou o tempo de MaxWait se esgotam.
+// Sem MaxAttempts nem MaxWait, faz uma única tentativa.
+func WaitForRedis(ctx context.Context, client *redis.Client, policy ConnectPolicy) error {
+	if policy.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.MaxWait)
+		defer cancel()
+	}
+	if policy.MaxAttempts <= 0 && policy.MaxWait <= 0 {
+		policy.MaxAttempts = 1
+	}
+	pingTimeout := policy.PingTimeout
+	if pingTimeout <= 0 {
+		pingTimeout = 5 * time.Second
+	}
+
+	backoff := policy.BaseBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := client.Ping(pingCtx).Err()
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return fmt.Errorf("sem resposta após %d tentativas: %w", attempt, err)
+		}
+		log.Printf("Redis indisponível (tentativa %d): %v; nova tentativa em %s", attempt, err, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("sem resposta após %d tentativas em %s: %w", attempt, policy.MaxWait, err)
+		case <-timer.C:
+		}
+		if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
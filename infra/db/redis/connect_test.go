@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freeAddr reserva um endereço local e o libera, para um Redis que só sobe depois
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+// Test_WaitForRedis verifica que a conexão inicial espera o Redis subir
+func Test_WaitForRedis(t *testing.T) {
+	addr := freeAddr(t)
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1})
+	defer client.Close()
+
+	mr := miniredis.NewMiniRedis()
+	defer mr.Close()
+	started := make(chan error, 1)
+	time.AfterFunc(150*time.Millisecond, func() { started <- mr.StartAddr(addr) })
+
+	start := time.Now()
+	err := WaitForRedis(context.Background(), client, ConnectPolicy{
+		MaxAttempts: 20,
+		MaxWait:     5 * time.Second,
+		BaseBackoff: 20 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+	})
+	require.NoError(t, <-started)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+// Test_WaitForRedis_GivesUp verifica que as tentativas e o tempo máximo encerram a espera
+func Test_WaitForRedis_GivesUp(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: freeAddr(t), MaxRetries: -1})
+	defer client.Close()
+
+	err := WaitForRedis(context.Background(), client, ConnectPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond})
+	require.ErrorContains(t, err, "3 tentativas")
+
+	start := time.Now()
+	err = WaitForRedis(context.Background(), client, ConnectPolicy{MaxWait: 100 * time.Millisecond, BaseBackoff: 10 * time.Millisecond})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Sem limites, uma única tentativa
+	require.Error(t, WaitForRedis(context.Background(), client, ConnectPolicy{}))
+}
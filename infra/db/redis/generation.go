@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// WithBlockJournal registra no journal cada bloqueio gravado pelo store e descarta os removidos
+// por Reset, ResetAll e DeleteMatching, para que GenerationWatcher os reaplique depois de um
+// reinício do Redis. Nos bloqueios gravados pelos scripts, custa uma ida a mais ao Redis para
+// ler os metadados. Uma falha no journal é apenas registrada em log.
+func WithBlockJournal(journal db.BlockJournal) Option {
+	return func(rs *RedisStore) {
+		rs.blockJournal = journal
+	}
+}
+
+// journalBlock registra no journal o bloqueio que um script acabou de gravar em blockKey.
+func (rs *RedisStore) journalBlock(ctx context.Context, blockKey string, now time.Time) {
+	if rs.blockJournal == nil {
+		return
+	}
+	pipe := rs.client.Pipeline()
+	get := pipe.Get(ctx, blockKey)
+	pttl := pipe.PTTL(ctx, blockKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Aviso: erro ao ler o bloqueio %q para o journal: %v", blockKey, err)
+		}
+		return
+	}
+	info, err := rs.decodeBlock([]byte(get.Val()))
+	if err != nil || pttl.Val() <= 0 {
+		return
+	}
+	// O fim do bloqueio vem do TTL, que inclui o prolongamento das faixas de severidade
+	info.ExpiresAt = now.Add(pttl.Val())
+	rs.recordBlock(ctx, blockKey, *info)
+}
+
+// recordBlock registra o bloqueio no journal, se houver um.
+func (rs *RedisStore) recordBlock(ctx context.Context, key string, info db.BlockInfo) {
+	if rs.blockJournal == nil {
+		return
+	}
+	if err := rs.blockJournal.Record(ctx, key, info); err != nil {
+		log.Printf("Aviso: erro ao registrar o bloqueio %q no journal: %v", key, err)
+	}
+}
+
+// forgetBlocks descarta do journal as chaves removidas do store.
+func (rs *RedisStore) forgetBlocks(ctx context.Context, keys ...string) {
+	if rs.blockJournal == nil || len(keys) == 0 {
+		return
+	}
+	if err := rs.blockJournal.Forget(ctx, keys...); err != nil {
+		log.Printf("Aviso: erro ao descartar bloqueios do journal: %v", err)
+	}
+}
+
+// ReapplyBlocks regrava os bloqueios ainda válidos do journal que não existem mais no Redis,
+// com o tempo restante de cada um, e retorna quantos foram regravados. Bloqueios presentes
+// (gravados de novo ou já reaplicados por outra instância) são mantidos.
+func (rs *RedisStore) ReapplyBlocks(ctx context.Context, now time.Time) (int, error) {
+	if rs.blockJournal == nil {
+		return 0, nil
+	}
+	active, err := rs.blockJournal.Active(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler o journal de bloqueios: %w", err)
+	}
+	reapplied := 0
+	for key, info := range active {
+		val, err := rs.blockCodec.Marshal(info)
+		if err != nil {
+			return reapplied, fmt.Errorf("erro ao serializar metadados do bloqueio: %w", err)
+		}
+		set, err := rs.client.SetNX(ctx, key, val, info.ExpiresAt.Sub(now)).Result()
+		if err != nil {
+			return reapplied, fmt.Errorf("erro ao reaplicar o bloqueio %q: %w", key, err)
+		}
+		if set {
+			reapplied++
+		}
+	}
+	return reapplied, nil
+}
+
+// GenerationWatcher detecta reinícios do Redis que perderam os dados, por meio de um marcador
+// de geração: uma chave sem expiração com um valor aleatório. Se o marcador some ou muda, o
+// Redis reiniciou sem persistência (ou foi esvaziado) e os bloqueios e contadores se perderam;
+// o watcher avisa em log, grava um novo marcador e reaplica os bloqueios do journal do store.
+// Com persistência (AOF), o marcador sobrevive ao reinício e nada é feito. Com uma política de
+// eviction que descarta chaves sem TTL (allkeys-*), a remoção do marcador conta como reinício.
+type GenerationWatcher struct {
+	store *RedisStore
+	key   string
+	now   func() time.Time
+
+	mu         sync.Mutex
+	generation string
+}
+
+// NewGenerationWatcher cria um watcher que guarda o marcador de geração em key.
+func NewGenerationWatcher(store *RedisStore, key string) *GenerationWatcher {
+	return &GenerationWatcher{store: store, key: key, now: time.Now}
+}
+
+// Check compara o marcador com a geração conhecida e informa se o Redis reiniciou desde a
+// última verificação. A primeira chamada só grava (ou adota) o marcador.
+func (w *GenerationWatcher) Check(ctx context.Context) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current, err := w.store.client.Get(ctx, w.key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("erro ao ler o marcador de geração: %w", err)
+	}
+	if err == nil && current == w.generation {
+		return false, nil
+	}
+	if errors.Is(err, redis.Nil) {
+		// Marcador ausente: grava uma nova geração, a não ser que outra instância acabe de gravar
+		if current, err = w.mark(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	restarted := w.generation != ""
+	w.generation = current
+	if !restarted {
+		return false, nil
+	}
+
+	log.Printf("Aviso: o Redis reiniciou ou perdeu os dados (marcador de geração %q alterado); "+
+		"os bloqueios e contadores anteriores foram perdidos", w.key)
+	reapplied, err := w.store.ReapplyBlocks(ctx, w.now())
+	if err != nil {
+		return true, err
+	}
+	if reapplied > 0 {
+		log.Printf("%d bloqueios ainda válidos reaplicados a partir do journal.", reapplied)
+	}
+	return true, nil
+}
+
+// mark grava um marcador novo, sem sobrescrever o de outra instância, e retorna o que ficou.
+func (w *GenerationWatcher) mark(ctx context.Context) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("erro ao gerar o marcador de geração: %w", err)
+	}
+	generation := hex.EncodeToString(buf)
+	set, err := w.store.client.SetNX(ctx, w.key, generation, 0).Result()
+	if err != nil {
+		return "", fmt.Errorf("erro ao gravar o marcador de geração: %w", err)
+	}
+	if set {
+		return generation, nil
+	}
+	current, err := w.store.client.Get(ctx, w.key).Result()
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler o marcador de geração: %w", err)
+	}
+	return current, nil
+}
+
+// Run verifica o marcador a cada intervalo até o contexto ser cancelado.
+func (w *GenerationWatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Check(ctx); err != nil {
+			log.Printf("Erro ao verificar a geração do Redis: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
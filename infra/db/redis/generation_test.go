@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+// restartEmpty simula um reinício do Redis sem persistência: o servidor sobe de novo no mesmo
+// endereço, sem nenhuma chave
+func restartEmpty(t *testing.T, mr *miniredis.Miniredis) *miniredis.Miniredis {
+	addr := mr.Addr()
+	mr.Close()
+	restarted := miniredis.NewMiniRedis()
+	require.NoError(t, restarted.StartAddr(addr))
+	return restarted
+}
+
+// Test_GenerationWatcher_DetectsRestart verifica que a troca do marcador é detectada e os bloqueios reaplicados
+func Test_GenerationWatcher_DetectsRestart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	journal := db.NewMemoryBlockJournal()
+	store := NewRedisStore(client, WithBlockJournal(journal))
+	watcher := NewGenerationWatcher(store, "generation")
+
+	ctx := context.Background()
+	restarted, err := watcher.Check(ctx)
+	require.NoError(t, err)
+	assert.False(t, restarted, "A primeira verificação só grava o marcador")
+	assert.True(t, mr.Exists("generation"))
+
+	// Um bloqueio do script, um manual e um removido pela administração
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		_, _, _, err := store.CheckAndCount(ctx, testCountKeys, 1, time.Second, time.Minute, now)
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Block(ctx, "blocked_token_t", time.Hour, db.BlockInfo{Reason: db.ReasonManual, StartedAt: now}))
+	require.NoError(t, store.Block(ctx, "blocked_ip_d", time.Hour, db.BlockInfo{Reason: db.ReasonManual, StartedAt: now}))
+	require.NoError(t, store.Reset(ctx, "blocked_ip_d"))
+
+	restarted, err = watcher.Check(ctx)
+	require.NoError(t, err)
+	assert.False(t, restarted)
+
+	mr = restartEmpty(t, mr)
+	defer mr.Close()
+	restarted, err = watcher.Check(ctx)
+	require.NoError(t, err)
+	assert.True(t, restarted, "O marcador ausente indica o reinício")
+	assert.True(t, mr.Exists("generation"))
+
+	assert.True(t, mr.Exists("blocked_ip_c"))
+	assert.InDelta(t, time.Minute.Seconds(), mr.TTL("blocked_ip_c").Seconds(), 1)
+	info, err := store.BlockInfo(ctx, "blocked_token_t")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonManual, info.Reason)
+	assert.False(t, mr.Exists("blocked_ip_d"), "O bloqueio removido não deveria voltar")
+
+	// A nova geração é estável
+	restarted, err = watcher.Check(ctx)
+	require.NoError(t, err)
+	assert.False(t, restarted)
+}
+
+// Test_GenerationWatcher_SharedMarker verifica que instâncias diferentes dividem o marcador e detectam o mesmo reinício
+func Test_GenerationWatcher_SharedMarker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	first := NewGenerationWatcher(NewRedisStore(client), "generation")
+	second := NewGenerationWatcher(NewRedisStore(client), "generation")
+
+	ctx := context.Background()
+	for _, w := range []*GenerationWatcher{first, second} {
+		restarted, err := w.Check(ctx)
+		require.NoError(t, err)
+		assert.False(t, restarted)
+	}
+
+	// Depois do reinício, a primeira grava o marcador novo e a segunda o adota
+	mr.FlushAll()
+	for i, w := range []*GenerationWatcher{first, second, first, second} {
+		restarted, err := w.Check(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, i < 2, restarted)
+	}
+	assert.Equal(t, first.generation, second.generation)
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// leakyBucketScript aplica o leaky bucket: o nível (level) do hash em KEYS[1] é a fila virtual
+// de requisições, que vaza uma requisição a cada intervalo desde last_leak_ts. A requisição é
+// admitida se ainda couber no bucket; caso contrário, é descartada sem bloqueio. Um bloqueio
+// existente em KEYS[2] é respeitado.
+//
+// O horário vem do cliente (ARGV[3]) ou, com ARGV[4] = 1, do próprio Redis (TIME).
+//
+// KEYS[1] = hash do bucket, KEYS[2] = bloqueio
+// ARGV[1] = capacidade, ARGV[2] = intervalo de vazamento em ms (fracionário), ARGV[3] = horário do cliente
+// em ms, ARGV[4] = 1 para usar o horário do Redis
+//
+// Retorna {permitida, nível como texto, valor do bloqueio existente ou "", espera em ms}.
+var leakyBucketScript = redis.NewScript(`
+local blocked = redis.call('GET', KEYS[2])
+if blocked then
+	return {0, '0', blocked, redis.call('PTTL', KEYS[2])}
+end
+
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+if ARGV[4] == '1' then
+	-- Necessário até o Redis 5 para replicar os efeitos de um script que lê TIME
+	redis.replicate_commands()
+	local t = redis.call('TIME')
+	now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+end
+
+local state = redis.call('HMGET', KEYS[1], 'level', 'last_leak_ts')
+local level = tonumber(state[1] or '0')
+local last = tonumber(state[2] or tostring(now))
+if now > last then
+	level = math.max(level - (now - last) / interval, 0)
+	last = now
+end
+
+if level + 1 > capacity then
+	return {0, tostring(level), '', math.floor((level + 1 - capacity) * interval + 0.5)}
+end
+
+level = level + 1
+redis.call('HMSET', KEYS[1], 'level', tostring(level), 'last_leak_ts', tostring(last))
+redis.call('PEXPIRE', KEYS[1], math.ceil(level * interval))
+return {1, tostring(level), '', 0}
+`)
+
+// LeakyBucket aplica o leaky bucket de forma atômica (ver db.Store). Com WithServerTime, now é
+// ignorado e o vazamento segue o horário do Redis.
+func (rs *RedisStore) LeakyBucket(ctx context.Context, keys db.CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	if leakInterval <= 0 {
+		return false, 0, 0, fmt.Errorf("intervalo de vazamento inválido para o leaky bucket: %s", leakInterval)
+	}
+	// Em ms fracionários, para ritmos acima de uma requisição por ms
+	intervalMs := strconv.FormatFloat(float64(leakInterval)/float64(time.Millisecond), 'f', -1, 64)
+
+	serverTime := 0
+	if rs.serverTime {
+		serverTime = 1
+	}
+	var res []interface{}
+	err := rs.scripted(ctx, func() (err error) {
+		res, err = leakyBucketScript.Run(ctx, rs.client, []string{keys.Counter, keys.Block},
+			capacity, intervalMs, now.UnixMilli(), serverTime).Slice()
+		return err
+	}, nil)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("erro ao executar script de leaky bucket: %w", err)
+	}
+	if len(res) != 4 {
+		return false, 0, 0, fmt.Errorf("resposta inesperada do script de leaky bucket: %v", res)
+	}
+
+	levelStr, _ := res[1].(string)
+	level, err := strconv.ParseFloat(levelStr, 64)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("erro ao converter nível do leaky bucket: %w", err)
+	}
+	allowed, _, retryAfter := rs.parseCheckAndCount(res, now)
+	return allowed, level, retryAfter, nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// RedisLimitResolver lê limites por token de um hash do Redis.
+//
+// Cada campo do hash é um token e o valor tem o formato "max/janela/bloqueio",
+// por exemplo "100/1s/5m". Tokens ausentes do hash, e IPs, usam o resolver de fallback.
+type RedisLimitResolver struct {
+	client   *redis.Client
+	hashKey  string
+	fallback db.LimitResolver
+}
+
+// NewRedisLimitResolver cria um resolver que consulta o hash informado.
+func NewRedisLimitResolver(client *redis.Client, hashKey string, fallback db.LimitResolver) *RedisLimitResolver {
+	return &RedisLimitResolver{client: client, hashKey: hashKey, fallback: fallback}
+}
+
+// ResolveLimit retorna o limite do token armazenado no Redis ou o limite do fallback.
+func (r *RedisLimitResolver) ResolveLimit(ctx context.Context, identifier string, isToken bool) (int, time.Duration, time.Duration, error) {
+	if !isToken {
+		return r.fallback.ResolveLimit(ctx, identifier, isToken)
+	}
+
+	val, err := r.client.HGet(ctx, r.hashKey, identifier).Result()
+	if err == redis.Nil {
+		return r.fallback.ResolveLimit(ctx, identifier, isToken)
+	} else if err != nil {
+		return 0, 0, 0, fmt.Errorf("erro ao ler limite do token no Redis: %w", err)
+	}
+
+	max, window, block, err := ParseLimit(val)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("limite inválido para o token no hash %s: %w", r.hashKey, err)
+	}
+	return max, window, block, nil
+}
+
+// ParseLimit interpreta um limite no formato "max/janela/bloqueio" (ex.: "100/1s/5m").
+func ParseLimit(val string) (int, time.Duration, time.Duration, error) {
+	parts := strings.Split(val, "/")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("formato esperado max/janela/bloqueio, recebido %q", val)
+	}
+
+	max, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("erro ao converter máximo de requisições: %w", err)
+	}
+	window, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("erro ao converter janela: %w", err)
+	}
+	block, err := time.ParseDuration(parts[2])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("erro ao converter duração do bloqueio: %w", err)
+	}
+	return max, window, block, nil
+}
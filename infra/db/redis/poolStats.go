@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/pkg/metrics"
+)
+
+// PoolStats retorna as estatísticas do pool de conexões do cliente Redis.
+func (rs *RedisStore) PoolStats() *redis.PoolStats {
+	return rs.client.PoolStats()
+}
+
+// PoolStatsCollector publica periodicamente as estatísticas do pool de conexões do Redis, para
+// correlacionar a latência do rate limiter com o esgotamento do pool. Hits, misses e timeouts
+// são acumulados desde a criação do cliente; as conexões são o retrato do momento.
+type PoolStatsCollector struct {
+	store    *RedisStore
+	recorder metrics.Recorder
+}
+
+// NewPoolStatsCollector cria um coletor das estatísticas do pool do store informado.
+func NewPoolStatsCollector(store *RedisStore, recorder metrics.Recorder) *PoolStatsCollector {
+	return &PoolStatsCollector{store: store, recorder: recorder}
+}
+
+// Collect lê as estatísticas do pool e as publica como gauges.
+func (c *PoolStatsCollector) Collect() {
+	stats := c.store.PoolStats()
+	for name, value := range map[string]uint32{
+		"ratelimiter_redis_pool_hits_total":     stats.Hits,
+		"ratelimiter_redis_pool_misses_total":   stats.Misses,
+		"ratelimiter_redis_pool_timeouts_total": stats.Timeouts,
+		"ratelimiter_redis_pool_total_conns":    stats.TotalConns,
+		"ratelimiter_redis_pool_idle_conns":     stats.IdleConns,
+		"ratelimiter_redis_pool_stale_conns":    stats.StaleConns,
+	} {
+		c.recorder.SetGauge(name, float64(value), nil)
+	}
+}
+
+// Run coleta as estatísticas a cada intervalo até o contexto ser cancelado.
+func (c *PoolStatsCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.Collect()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
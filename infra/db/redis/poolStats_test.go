@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/pkg/metrics"
+)
+
+// Test_PoolStatsCollector verifica que as estatísticas do pool são publicadas após algumas operações
+func Test_PoolStatsCollector(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := store.Increment(ctx, "ip_192.168.1.1", time.Minute)
+		require.NoError(t, err)
+	}
+	stats := store.PoolStats()
+	assert.Positive(t, stats.Hits+stats.Misses, "As operações deveriam passar pelo pool")
+	assert.Positive(t, stats.TotalConns)
+
+	registry := metrics.NewRegistry()
+	NewPoolStatsCollector(store, registry).Collect()
+	for _, name := range []string{
+		"ratelimiter_redis_pool_hits_total",
+		"ratelimiter_redis_pool_misses_total",
+		"ratelimiter_redis_pool_timeouts_total",
+		"ratelimiter_redis_pool_total_conns",
+		"ratelimiter_redis_pool_idle_conns",
+		"ratelimiter_redis_pool_stale_conns",
+	} {
+		value, ok := registry.Gauge(name, nil)
+		assert.True(t, ok, "%s deveria ser publicado", name)
+		assert.GreaterOrEqual(t, value, float64(0), name)
+	}
+	conns, _ := registry.Gauge("ratelimiter_redis_pool_total_conns", nil)
+	assert.Equal(t, float64(stats.TotalConns), conns)
+}
@@ -5,21 +5,62 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"golang.org/x/net/context"
+	"sync/atomic"
 	"time"
+
+	"rateLimiter/infra/db"
 )
 
 // RedisStore implementa a interface Store usando Redis.
 type RedisStore struct {
 	client *redis.Client
+	// serverTime faz a janela deslizante usar o horário do Redis em vez do informado pelo cliente.
+	serverTime bool
+	// retryPolicy define as novas tentativas diante de erros transitórios (padrão: nenhuma).
+	retryPolicy RetryPolicy
+	// auditStream é o stream que recebe os eventos de bloqueio (vazio: desativado).
+	auditStream string
+	// noScripts indica que o Redis rejeitou os scripts Lua (ver scripted).
+	noScripts atomic.Bool
+	// blockCodec serializa os metadados dos bloqueios (padrão: JSON).
+	blockCodec db.BlockCodec
+	// blockJournal guarda os bloqueios para reaplicá-los após um reinício do Redis (nil: desativado).
+	blockJournal db.BlockJournal
+}
+
+// Option configura o RedisStore.
+type Option func(*RedisStore)
+
+// WithServerTime faz a janela deslizante calcular os buckets com o horário do Redis (TIME),
+// de modo que instâncias com relógios dessincronizados tomem as mesmas decisões. Sem a opção,
+// vale o horário informado pelo rate limiter.
+func WithServerTime(enabled bool) Option {
+	return func(rs *RedisStore) {
+		rs.serverTime = enabled
+	}
 }
 
 // NewRedisStore cria uma nova instância de RedisStore.
-func NewRedisStore(client *redis.Client) *RedisStore {
-	return &RedisStore{client: client}
+func NewRedisStore(client *redis.Client, opts ...Option) *RedisStore {
+	rs := &RedisStore{client: client, blockCodec: db.JSONBlockCodec{}}
+	for _, opt := range opts {
+		opt(rs)
+	}
+	return rs
 }
 
 // Increment usa uma transação Redis para incrementar e possivelmente definir TTL
 func (rs *RedisStore) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var count int64
+	err := rs.retry(ctx, func() (err error) {
+		count, err = rs.increment(ctx, key, window)
+		return err
+	})
+	return count, err
+}
+
+// increment contém a lógica de Increment, executada a cada tentativa.
+func (rs *RedisStore) increment(ctx context.Context, key string, window time.Duration) (int64, error) {
 	// Primeiro verificamos se a chave já existe
 	exists, err := rs.client.Exists(ctx, key).Result()
 	if err != nil {
@@ -47,32 +88,188 @@ func (rs *RedisStore) Increment(ctx context.Context, key string, window time.Dur
 	return count, nil
 }
 
-// IsBlocked verifica se uma chave está marcada como bloqueada.
-func (rs *RedisStore) IsBlocked(ctx context.Context, key string) (bool, error) {
-	val, err := rs.client.Get(ctx, key).Result()
+// incrementByScript soma ARGV[1] ao contador e define o TTL (ARGV[2] em ms) só quando a chave é criada.
+var incrementByScript = redis.NewScript(`
+local total = redis.call('INCRBY', KEYS[1], ARGV[1])
+if total == tonumber(ARGV[1]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return total
+`)
+
+// IncrementBy soma n ao contador; o TTL da janela só é definido quando a chave é criada.
+func (rs *RedisStore) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	var total int64
+	err := rs.scripted(ctx, func() (err error) {
+		total, err = incrementByScript.Run(ctx, rs.client, []string{key}, n, max(window.Milliseconds(), 1)).Int64()
+		return err
+	}, func() (err error) {
+		total, _, err = rs.incrementPipeline(ctx, key, n, window)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao incrementar contador: %w", err)
+	}
+	return total, nil
+}
+
+// incrementWithTTLScript incrementa o contador, define o TTL (ARGV[1] em ms) quando a chave é
+// criada e retorna {contagem, TTL restante em ms}. Um contador sem expiração recebe a janela.
+var incrementWithTTLScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// IncrementWithTTL incrementa o contador e retorna, na mesma ida ao Redis, o tempo até a
+// janela expirar.
+func (rs *RedisStore) IncrementWithTTL(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	var res []interface{}
+	err := rs.scripted(ctx, func() (err error) {
+		res, err = incrementWithTTLScript.Run(ctx, rs.client, []string{key}, max(window.Milliseconds(), 1)).Slice()
+		return err
+	}, func() error {
+		count, ttl, err := rs.incrementPipeline(ctx, key, 1, window)
+		res = []interface{}{count, ttl.Milliseconds()}
+		return err
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("erro ao incrementar contador: %w", err)
+	}
+	count, _ := res[0].(int64)
+	ttl, _ := res[1].(int64)
+	return count, time.Duration(ttl) * time.Millisecond, nil
+}
+
+// Count retorna o valor atual de um contador sem incrementá-lo (0 se a chave não existir).
+func (rs *RedisStore) Count(ctx context.Context, key string) (int64, error) {
+	var count int64
+	err := rs.retry(ctx, func() (err error) {
+		count, err = rs.client.Get(ctx, key).Int64()
+		return err
+	})
 	if err == redis.Nil {
-		return false, nil // Chave não existe, não está bloqueada
+		return 0, nil
 	} else if err != nil {
+		return 0, fmt.Errorf("erro ao ler contador no Redis: %w", err)
+	}
+	return count, nil
+}
+
+// IsBlocked verifica se uma chave está marcada como bloqueada.
+// Qualquer valor presente na chave conta como bloqueio.
+func (rs *RedisStore) IsBlocked(ctx context.Context, key string) (bool, error) {
+	var exists int64
+	err := rs.retry(ctx, func() (err error) {
+		exists, err = rs.client.Exists(ctx, key).Result()
+		return err
+	})
+	if err != nil {
 		return false, fmt.Errorf("erro ao verificar chave de bloqueio no Redis: %w", err)
 	}
-	return val == "blocked", nil // Se a chave existir e o valor for "blocked"
+	return exists > 0, nil
 }
 
-// Block marca uma chave como bloqueada por uma determinada duração.
-func (rs *RedisStore) Block(ctx context.Context, key string, duration time.Duration) error {
-	err := rs.client.Set(ctx, key, "blocked", duration).Err()
+// Block marca uma chave como bloqueada por uma determinada duração, gravando os metadados em JSON.
+func (rs *RedisStore) Block(ctx context.Context, key string, duration time.Duration, info db.BlockInfo) error {
+	val, err := rs.blockCodec.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar metadados do bloqueio: %w", err)
+	}
+
+	err = rs.retry(ctx, func() error {
+		return rs.client.Set(ctx, key, val, duration).Err()
+	})
 	if err != nil {
 		return fmt.Errorf("erro ao definir chave de bloqueio no Redis: %w", err)
 	}
+	rs.auditBlock(ctx, key, info.Reason, info.StartedAt)
+	if info.ExpiresAt.IsZero() && duration > 0 {
+		info.ExpiresAt = time.Now().Add(duration)
+	}
+	rs.recordBlock(ctx, key, info)
+	return nil
+}
+
+// BlockInfo lê os metadados de um bloqueio. Retorna nil se a chave não estiver bloqueada.
+func (rs *RedisStore) BlockInfo(ctx context.Context, key string) (*db.BlockInfo, error) {
+	val, err := rs.get(ctx, key)
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("erro ao ler chave de bloqueio no Redis: %w", err)
+	}
+
+	info, err := rs.decodeBlock(val)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao desserializar metadados do bloqueio: %w", err)
+	}
+	return info, nil
+}
+
+// Get lê o valor de uma chave. Retorna nil se a chave não existir.
+func (rs *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := rs.get(ctx, key)
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("erro ao ler chave no Redis: %w", err)
+	}
+	return val, nil
+}
+
+// get lê o valor bruto de uma chave, repetindo a leitura diante de erros transitórios.
+func (rs *RedisStore) get(ctx context.Context, key string) ([]byte, error) {
+	var val []byte
+	err := rs.retry(ctx, func() (err error) {
+		val, err = rs.client.Get(ctx, key).Bytes()
+		return err
+	})
+	return val, err
+}
+
+// Set grava o valor de uma chave com o TTL informado.
+func (rs *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := rs.retry(ctx, func() error {
+		return rs.client.Set(ctx, key, value, ttl).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar chave no Redis: %w", err)
+	}
 	return nil
 }
 
 // Reset remove uma chave do Redis (usado para limpar contadores após bloqueio, por exemplo).
 func (rs *RedisStore) Reset(ctx context.Context, key string) error {
-	err := rs.client.Del(ctx, key).Err()
+	err := rs.retry(ctx, func() error {
+		return rs.client.Del(ctx, key).Err()
+	})
 	if err != nil && !errors.Is(err, redis.Nil) { // Ignora erro se a chave não existir
 		return fmt.Errorf("erro ao deletar chave no Redis: %w", err)
 	}
+	rs.forgetBlocks(ctx, key)
+	return nil
+}
+
+// ResetAll remove as chaves com um único DEL, atômico no Redis.
+func (rs *RedisStore) ResetAll(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	err := rs.retry(ctx, func() error {
+		return rs.client.Del(ctx, keys...).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao deletar chaves no Redis: %w", err)
+	}
+	rs.forgetBlocks(ctx, keys...)
 	return nil
 }
 
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/infra/db/storetest"
+)
+
+// setupTestStore configura um RedisStore sobre um servidor Redis em memória
+func setupTestStore(t *testing.T) (*miniredis.Miniredis, *RedisStore) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	return mr, NewRedisStore(client)
+}
+
+// Test_RedisStore_BlockInfo_RoundTrip verifica que os metadados do bloqueio são preservados
+func Test_RedisStore_BlockInfo_RoundTrip(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	ctx := context.Background()
+
+	info := db.BlockInfo{
+		Reason:       db.ReasonRateLimitExceeded,
+		OffenseCount: 3,
+		StartedAt:    time.Date(2025, 5, 10, 12, 30, 0, 0, time.UTC),
+	}
+	require.NoError(t, store.Block(ctx, "blocked_ip_1.2.3.4", time.Minute, info))
+
+	blocked, err := store.IsBlocked(ctx, "blocked_ip_1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	got, err := store.BlockInfo(ctx, "blocked_ip_1.2.3.4")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, info.Reason, got.Reason)
+	assert.Equal(t, info.OffenseCount, got.OffenseCount)
+	assert.True(t, info.StartedAt.Equal(got.StartedAt))
+	assert.Equal(t, time.Minute, mr.TTL("blocked_ip_1.2.3.4"))
+}
+
+// Test_RedisStore_BlockInfo_Missing verifica a leitura de uma chave sem bloqueio
+func Test_RedisStore_BlockInfo_Missing(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	got, err := store.BlockInfo(context.Background(), "blocked_ip_5.6.7.8")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+// Test_RedisStore_IsBlocked_AnyValue verifica que qualquer valor presente conta como bloqueio,
+// inclusive o literal "blocked" usado antes dos metadados
+func Test_RedisStore_IsBlocked_AnyValue(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	ctx := context.Background()
+
+	require.NoError(t, mr.Set("blocked_token_legacy", "blocked"))
+
+	blocked, err := store.IsBlocked(ctx, "blocked_token_legacy")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	info, err := store.BlockInfo(ctx, "blocked_token_legacy")
+	require.NoError(t, err)
+	assert.NotNil(t, info, "O formato antigo deveria continuar legível")
+}
+
+// Test_RedisStore_GetSet verifica a gravação e a leitura de valores com TTL
+func Test_RedisStore_GetSet(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	val, err := store.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, val)
+
+	require.NoError(t, store.Set(ctx, "k", []byte("v"), time.Minute))
+	val, err = store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v"), val)
+	assert.Equal(t, time.Minute, mr.TTL("k"))
+}
+
+// Test_RedisStore_ScanCounters verifica a leitura dos contadores que casam com o padrão
+func Test_RedisStore_ScanCounters(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	for i := 0; i < 1200; i++ {
+		require.NoError(t, mr.Set(fmt.Sprintf("ip_10.0.%d.%d", i/256, i%256), strconv.Itoa(i)))
+	}
+	require.NoError(t, mr.Set("ip_not_a_counter", "blocked"))
+	require.NoError(t, mr.Set("token_abc", "7"))
+
+	counters, err := store.ScanCounters(context.Background(), "ip_*")
+	require.NoError(t, err)
+	assert.Len(t, counters, 1200, "Valores não numéricos deveriam ser ignorados")
+	assert.Equal(t, int64(1199), counters["ip_10.0.4.175"])
+	assert.NotContains(t, counters, "token_abc")
+}
+
+// Test_RedisStore_IncrementBy verifica a soma e que a janela não é renovada pelos incrementos seguintes
+func Test_RedisStore_IncrementBy(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	ctx := context.Background()
+	total, err := store.IncrementBy(ctx, "bytes", 300, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), total)
+
+	mr.FastForward(20 * time.Second)
+	total, err = store.IncrementBy(ctx, "bytes", 200, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), total)
+	assert.Equal(t, 40*time.Second, mr.TTL("bytes"))
+}
+
+// Test_RedisStore_DeleteMatching verifica a remoção em lotes e o filtro das chaves
+func Test_RedisStore_DeleteMatching(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	for i := 0; i < 2*scanBatchSize+10; i++ {
+		require.NoError(t, mr.Set(fmt.Sprintf("blocked_ip_%d", i), "blocked"))
+	}
+	require.NoError(t, mr.Set("blocked_token_keep", "blocked"))
+	require.NoError(t, mr.Set("ip_1", "3"))
+
+	deleted, err := store.DeleteMatching(context.Background(), "blocked_*", func(key string) bool {
+		return key != "blocked_token_keep"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2*scanBatchSize+10, deleted)
+	assert.Equal(t, []string{"blocked_token_keep", "ip_1"}, mr.Keys())
+}
+
+// miniredisClock avança as expirações do miniredis; o horário informado ao store é o do sistema
+type miniredisClock struct{ mr *miniredis.Miniredis }
+
+func (c miniredisClock) Now() time.Time          { return time.Now() }
+func (c miniredisClock) Advance(d time.Duration) { c.mr.FastForward(d) }
+
+// Test_RedisStore_Contract executa o contrato do Store
+func Test_RedisStore_Contract(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	storetest.StoreContractTest(t, func() db.Store {
+		mr.FlushAll()
+		return NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	}, storetest.WithClock(miniredisClock{mr}))
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+)
+
+// RetryPolicy define as novas tentativas das operações do store diante de erros transitórios
+// (conexão perdida, timeout, TxFailedErr, Redis carregando ou em failover). Erros permanentes,
+// como erros de script ou de tipo, nunca são repetidos.
+type RetryPolicy struct {
+	// MaxAttempts é o total de tentativas, incluindo a primeira (0 ou 1 desliga as repetições).
+	MaxAttempts int
+	// BaseBackoff é a espera antes da segunda tentativa; ela dobra a cada nova tentativa.
+	BaseBackoff time.Duration
+	// Jitter é a fração aleatória (0 a 1) acrescida a cada espera, para que instâncias não
+	// repitam em sincronia.
+	Jitter float64
+}
+
+// WithRetryPolicy faz o store repetir as operações do caminho da requisição que falharem por
+// erros transitórios. Como uma conexão pode cair depois de o Redis executar o comando, uma
+// repetição pode contar a mesma requisição duas vezes. As repetições do store envolvem as do
+// cliente (redis.Options.MaxRetries), que só cobrem erros de rede em um mesmo comando.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(rs *RedisStore) {
+		rs.retryPolicy = policy
+	}
+}
+
+// retry executa op seguindo a política de repetição e retorna o último erro.
+func (rs *RedisStore) retry(ctx context.Context, op func() error) error {
+	err := op()
+	for attempt := 1; attempt < rs.retryPolicy.MaxAttempts && isTransient(err); attempt++ {
+		timer := time.NewTimer(rs.retryPolicy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = op()
+	}
+	return err
+}
+
+// backoff retorna a espera antes da tentativa seguinte à informada (a partir de 1).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.BaseBackoff << (attempt - 1)
+	if p.Jitter > 0 {
+		wait += time.Duration(rand.Float64() * p.Jitter * float64(wait))
+	}
+	return wait
+}
+
+// transientReplies são os prefixos das respostas do Redis que indicam uma condição passageira.
+var transientReplies = []string{"LOADING ", "READONLY ", "CLUSTERDOWN ", "TRYAGAIN ", "MASTERDOWN "}
+
+// isTransient informa se vale a pena repetir a operação que falhou com err.
+func isTransient(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, redis.Nil), errors.Is(err, redis.ErrClosed),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, redis.TxFailedErr),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range transientReplies {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replyError é uma resposta de erro do Redis
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+func (replyError) RedisError()     {}
+
+// flakyHook falha os primeiros comandos com o erro informado e deixa os seguintes passarem
+type flakyHook struct {
+	failures int64
+	err      error
+	calls    atomic.Int64
+}
+
+func (f *flakyHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	if f.calls.Add(1) <= f.failures {
+		return ctx, f.err
+	}
+	return ctx, nil
+}
+
+func (f *flakyHook) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (f *flakyHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (f *flakyHook) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// setupFlakyStore cria um store cujo cliente falha os primeiros comandos com err
+func setupFlakyStore(t *testing.T, failures int64, err error, opts ...Option) (*RedisStore, *flakyHook) {
+	mr, store := setupTestStore(t)
+	t.Cleanup(mr.Close)
+	t.Cleanup(func() { _ = store.Close() })
+
+	hook := &flakyHook{failures: failures, err: err}
+	store.client.AddHook(hook)
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store, hook
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, Jitter: 0.5}
+
+// Test_RedisStore_Retry_Transient verifica que a política recupera as operações após erros transitórios
+func Test_RedisStore_Retry_Transient(t *testing.T) {
+	for name, err := range map[string]error{
+		"eof":        io.EOF,
+		"reset":      &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		"tx failed":  redis.TxFailedErr,
+		"loading":    replyError("LOADING Redis is loading the dataset in memory"),
+		"cluster":    replyError("TRYAGAIN Multiple keys request during rehashing of slot"),
+		"io timeout": &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, hook := setupFlakyStore(t, 2, err, WithRetryPolicy(testRetryPolicy))
+
+			total, err := store.IncrementBy(context.Background(), "k", 5, time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), total)
+			assert.GreaterOrEqual(t, hook.calls.Load(), int64(3))
+		})
+	}
+}
+
+// timeoutError é um erro de rede por tempo esgotado
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// Test_RedisStore_Retry_Exhausted verifica que as tentativas respeitam MaxAttempts
+func Test_RedisStore_Retry_Exhausted(t *testing.T) {
+	store, hook := setupFlakyStore(t, 10, io.EOF, WithRetryPolicy(testRetryPolicy))
+
+	_, err := store.Count(context.Background(), "k")
+	require.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, int64(3), hook.calls.Load())
+}
+
+// Test_RedisStore_Retry_Permanent verifica que erros permanentes não são repetidos
+func Test_RedisStore_Retry_Permanent(t *testing.T) {
+	store, hook := setupFlakyStore(t, 1, replyError("WRONGTYPE Operation against a key holding the wrong kind of value"), WithRetryPolicy(testRetryPolicy))
+
+	_, err := store.Count(context.Background(), "k")
+	require.Error(t, err)
+	assert.Equal(t, int64(1), hook.calls.Load())
+}
+
+// Test_RedisStore_Retry_DisabledByDefault verifica que, sem política, nada é repetido
+func Test_RedisStore_Retry_DisabledByDefault(t *testing.T) {
+	store, hook := setupFlakyStore(t, 1, io.EOF)
+
+	_, err := store.Count(context.Background(), "k")
+	require.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, int64(1), hook.calls.Load())
+}
+
+// Test_RedisStore_Retry_ContextCanceled verifica que a espera entre tentativas termina com o contexto
+func Test_RedisStore_Retry_ContextCanceled(t *testing.T) {
+	store, hook := setupFlakyStore(t, 10, io.EOF, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseBackoff: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := store.Count(ctx, "k")
+	require.ErrorIs(t, err, io.EOF)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int64(1), hook.calls.Load())
+}
+
+// Test_RetryPolicy_Backoff verifica o crescimento exponencial e o limite do jitter
+func Test_RetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: 10 * time.Millisecond, Jitter: 0.5}
+	for attempt, base := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond} {
+		wait := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, wait, base)
+		assert.Less(t, wait, base+base/2)
+	}
+	assert.Equal(t, 20*time.Millisecond, RetryPolicy{BaseBackoff: 10 * time.Millisecond}.backoff(2))
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/net/context"
+)
+
+// scanBatchSize é o número de chaves pedido a cada iteração do SCAN e lido em cada MGET.
+const scanBatchSize = 500
+
+// ScanCounters percorre as chaves com SCAN (sem bloquear o Redis como KEYS) e lê os valores
+// em lotes. Chaves cujo valor não é um inteiro são ignoradas.
+func (rs *RedisStore) ScanCounters(ctx context.Context, match string) (map[string]int64, error) {
+	counters := make(map[string]int64)
+	var cursor uint64
+	for {
+		keys, next, err := rs.client.Scan(ctx, cursor, match, scanBatchSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao percorrer chaves no Redis: %w", err)
+		}
+
+		if len(keys) > 0 {
+			values, err := rs.client.MGet(ctx, keys...).Result()
+			if err != nil {
+				return nil, fmt.Errorf("erro ao ler contadores no Redis: %w", err)
+			}
+			for i, value := range values {
+				s, ok := value.(string)
+				if !ok {
+					continue // a chave expirou entre o SCAN e o MGET
+				}
+				if count, err := strconv.ParseInt(s, 10, 64); err == nil {
+					counters[keys[i]] = count
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return counters, nil
+		}
+	}
+}
+
+// DeleteMatching percorre as chaves com SCAN e remove, em lotes, as que casam com o padrão e
+// são aceitas por allow. As chaves são removidas só ao fim da varredura, para que a remoção não
+// interfira no cursor.
+func (rs *RedisStore) DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error) {
+	var matched []string
+	var cursor uint64
+	for {
+		keys, next, err := rs.client.Scan(ctx, cursor, match, scanBatchSize).Result()
+		if err != nil {
+			return 0, fmt.Errorf("erro ao percorrer chaves no Redis: %w", err)
+		}
+		for _, key := range keys {
+			if allow(key) {
+				matched = append(matched, key)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	deleted := 0
+	for start := 0; start < len(matched); start += scanBatchSize {
+		n, err := rs.client.Del(ctx, matched[start:min(start+scanBatchSize, len(matched))]...).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("erro ao remover chaves no Redis: %w", err)
+		}
+		deleted += int(n)
+	}
+	rs.forgetBlocks(ctx, matched...)
+	return deleted, nil
+}
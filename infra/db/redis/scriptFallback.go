@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// ErrScriptsUnavailable indica que a operação exige scripts Lua e o Redis não os aceita.
+var ErrScriptsUnavailable = errors.New("o Redis não aceita scripts Lua, exigidos por esta operação")
+
+// isScriptingDisabled informa se err é a resposta de um Redis que não executa scripts: EVAL ou
+// EVALSHA negados pelas ACLs (NOPERM), renomeados ou removidos (unknown command) ou o script
+// ausente sem EVAL para carregá-lo (NOSCRIPT).
+func isScriptingDisabled(err error) bool {
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		return false
+	}
+	msg := redisErr.Error()
+	switch {
+	case strings.HasPrefix(msg, "NOSCRIPT"):
+		return true
+	case strings.HasPrefix(msg, "NOPERM"), strings.HasPrefix(msg, "ERR unknown command"):
+		// Só quando o comando negado é EVAL/EVALSHA, e não o acesso a uma das chaves
+		return strings.Contains(strings.ToLower(msg), "eval")
+	}
+	return false
+}
+
+// disableScripts passa o store para os caminhos sem Lua, avisando uma única vez.
+func (rs *RedisStore) disableScripts(err error) {
+	if !rs.noScripts.Swap(true) {
+		log.Printf("Aviso: o Redis não aceita scripts Lua (%v); a janela fixa e os incrementos passam a usar "+
+			"pipelines, sem atomicidade: requisições simultâneas podem exceder o limite", err)
+	}
+}
+
+// ScriptsDisabled informa se o store detectou que o Redis não aceita scripts Lua e passou a
+// usar os caminhos com pipelines.
+func (rs *RedisStore) ScriptsDisabled() bool {
+	return rs.noScripts.Load()
+}
+
+// scripted executa run, que usa um script Lua, com a política de repetição. Se o Redis não
+// aceitar scripts, passa a executar fallback nesta e nas próximas chamadas; sem fallback, a
+// operação falha com ErrScriptsUnavailable.
+func (rs *RedisStore) scripted(ctx context.Context, run, fallback func() error) error {
+	if !rs.noScripts.Load() {
+		err := rs.retry(ctx, run)
+		if !isScriptingDisabled(err) {
+			return err
+		}
+		rs.disableScripts(err)
+	}
+	if fallback == nil {
+		return ErrScriptsUnavailable
+	}
+	return rs.retry(ctx, fallback)
+}
+
+// incrementPipeline soma n ao contador e lê o TTL no mesmo pipeline, definindo a janela quando a
+// chave é criada ou está sem expiração. Equivale a incrementByScript e incrementWithTTLScript,
+// mas sem atomicidade.
+func (rs *RedisStore) incrementPipeline(ctx context.Context, key string, n int64, window time.Duration) (int64, time.Duration, error) {
+	window = max(window, time.Millisecond)
+	pipe := rs.client.Pipeline()
+	incr := pipe.IncrBy(ctx, key, n)
+	pttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+	ttl := pttl.Val()
+	if incr.Val() == n || ttl < 0 {
+		if err := rs.client.PExpire(ctx, key, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		ttl = window
+	}
+	return incr.Val(), ttl, nil
+}
+
+// blockedPipeline retorna a resposta de checkAndCountLua para um identificador bloqueado, ou
+// nil se a chave de bloqueio não existir.
+func (rs *RedisStore) blockedPipeline(ctx context.Context, blockKey string) ([]interface{}, error) {
+	pipe := rs.client.Pipeline()
+	get := pipe.Get(ctx, blockKey)
+	pttl := pipe.PTTL(ctx, blockKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if get.Err() != nil {
+		return nil, nil
+	}
+	return []interface{}{int64(0), int64(0), get.Val(), pttl.Val().Milliseconds()}, nil
+}
+
+// checkAndCountPipeline segue o fluxo de checkAndCountLua com comandos avulsos e pipelines,
+// retornando a resposta no mesmo formato. Entre a verificação e a gravação outras requisições
+// podem ser contadas, e um bloqueio existente não é prolongado pelas faixas de severidade.
+func (rs *RedisStore) checkAndCountPipeline(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) ([]interface{}, error) {
+	if res, err := rs.blockedPipeline(ctx, keys.Block); err != nil || res != nil {
+		return res, err
+	}
+
+	count, ttl, err := rs.incrementPipeline(ctx, keys.Counter, 1, window)
+	if err != nil {
+		return nil, err
+	}
+	if count <= limit {
+		return []interface{}{int64(1), limit - count, "", int64(0)}, nil
+	}
+	if count <= limit+max(keys.GraceOverage, 0) {
+		return []interface{}{int64(0), int64(0), "", ttl.Milliseconds()}, nil
+	}
+
+	offenses, _, err := rs.incrementPipeline(ctx, keys.Offenses, 1, db.OffenseWindow)
+	if err != nil {
+		return nil, err
+	}
+	for _, tier := range keys.SeverityTiers {
+		if count >= tier.Threshold && tier.Duration > blockDuration {
+			blockDuration = tier.Duration
+		}
+	}
+	pipe := rs.client.Pipeline()
+	remaining := int64(0)
+	if blockDuration.Milliseconds() > 0 {
+		info, err := json.Marshal(db.BlockInfo{
+			Reason:       db.ReasonRateLimitExceeded,
+			OffenseCount: offenses,
+			StartedAt:    now,
+			ExpiresAt:    now.Add(blockDuration),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao serializar metadados do bloqueio: %w", err)
+		}
+		pipe.Set(ctx, keys.Block, info, blockDuration)
+		remaining = -1
+	}
+	if !keys.KeepCounterOnBlock {
+		pipe.Del(ctx, keys.Counter)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return []interface{}{int64(0), remaining, "", blockDuration.Milliseconds()}, nil
+}
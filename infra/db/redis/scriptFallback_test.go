@@ -0,0 +1,127 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+// denyScripts simula um Redis com ACL que nega EVAL e EVALSHA
+type denyScripts struct{}
+
+func (denyScripts) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if name := cmd.Name(); name == "eval" || name == "evalsha" {
+		return ctx, replyError("NOPERM this user has no permissions to run the '" + name + "' command or its subcommand")
+	}
+	return ctx, nil
+}
+
+func (denyScripts) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (denyScripts) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (denyScripts) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// Test_IsScriptingDisabled verifica quais respostas indicam scripts negados
+func Test_IsScriptingDisabled(t *testing.T) {
+	for msg, expected := range map[string]bool{
+		"NOPERM this user has no permissions to run the 'eval' command":                   true,
+		"NOPERM User default has no permissions to run the 'evalsha' command":             true,
+		"NOSCRIPT No matching script. Please use EVAL.":                                   true,
+		"ERR unknown command 'EVAL', with args beginning with: ":                          true,
+		"NOPERM this user has no permissions to access one of the keys used as arguments": false,
+		"ERR unknown command 'XADD'":                                                      false,
+		"WRONGTYPE Operation against a key holding the wrong kind of value":               false,
+	} {
+		assert.Equal(t, expected, isScriptingDisabled(replyError(msg)), msg)
+	}
+	assert.False(t, isScriptingDisabled(errors.New("NOPERM eval")), "Só respostas do Redis contam")
+	assert.False(t, isScriptingDisabled(nil))
+}
+
+// Test_RedisStore_ScriptFallback verifica que, sem scripts, a janela fixa continua aplicando o limite
+func Test_RedisStore_ScriptFallback(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	store.client.AddHook(denyScripts{})
+
+	ctx := context.Background()
+	require.NoError(t, store.Verify(ctx))
+	assert.True(t, store.ScriptsDisabled())
+
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	for expected := int64(2); expected >= 0; expected-- {
+		allowed, remaining, _, err := store.CheckAndCount(ctx, testCountKeys, 3, time.Second, time.Minute, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, expected, remaining)
+	}
+	assert.Equal(t, time.Second, mr.TTL("ip_c"))
+
+	// A quarta excede o limite: bloqueia com os metadados e zera o contador
+	allowed, remaining, retryAfter, err := store.CheckAndCount(ctx, testCountKeys, 3, time.Second, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Zero(t, remaining)
+	assert.Equal(t, time.Minute, retryAfter)
+	assert.False(t, mr.Exists("ip_c"))
+
+	info, err := store.BlockInfo(ctx, "blocked_ip_c")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
+	assert.Equal(t, int64(1), info.OffenseCount)
+	assert.True(t, now.Add(time.Minute).Equal(info.ExpiresAt))
+
+	// Durante o bloqueio, o tempo restante vem dos metadados
+	allowed, _, retryAfter, err = store.CheckAndCount(ctx, testCountKeys, 3, time.Second, time.Minute, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 50*time.Second, retryAfter)
+}
+
+// Test_RedisStore_ScriptFallback_Detected verifica a troca automática na primeira operação negada
+func Test_RedisStore_ScriptFallback_Detected(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+	store.client.AddHook(denyScripts{})
+
+	ctx := context.Background()
+	assert.False(t, store.ScriptsDisabled())
+	total, err := store.IncrementBy(ctx, "k", 5, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.True(t, store.ScriptsDisabled())
+	assert.Equal(t, time.Minute, mr.TTL("k"))
+
+	count, ttl, err := store.IncrementWithTTL(ctx, "k", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), count)
+	assert.Equal(t, time.Minute, ttl)
+
+	global := db.GlobalCount{Key: "global", Window: time.Second}
+	for i := 1; i <= 2; i++ {
+		allowed, _, _, globalCount, err := store.CheckAndCountWithGlobal(ctx, testCountKeys, 1, time.Second, time.Minute, time.Now(), global)
+		require.NoError(t, err)
+		assert.Equal(t, i == 1, allowed)
+		assert.Equal(t, int64(i), globalCount)
+	}
+	assert.True(t, mr.Exists("blocked_ip_c"))
+
+	// Os algoritmos que só existem em Lua informam a falta de scripts
+	_, _, err = store.SlidingWindow(ctx, "s", 1, time.Second, time.Now())
+	require.ErrorIs(t, err, ErrScriptsUnavailable)
+	_, _, _, err = store.LeakyBucket(ctx, testCountKeys, 1, time.Second, time.Now())
+	require.ErrorIs(t, err, ErrScriptsUnavailable)
+}
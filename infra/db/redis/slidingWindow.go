@@ -0,0 +1,175 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// slidingWindowLua implementa a aproximação de janela deslizante com dois buckets:
+// a contagem estimada é o bucket atual somado ao bucket anterior ponderado pela fração
+// da janela anterior que ainda se sobrepõe à janela deslizante. Tudo roda no Redis de
+// forma atômica, e o bucket atual só é incrementado quando a requisição é permitida.
+//
+// Os buckets são calculados no script, a partir do horário do cliente (ARGV[3]) ou, com
+// ARGV[4] = 1, do horário do próprio Redis (TIME), para que instâncias com relógios
+// diferentes usem os mesmos buckets.
+//
+// KEYS[1] = prefixo dos buckets
+// ARGV[1] = limite, ARGV[2] = janela em ms, ARGV[3] = horário do cliente em ms,
+// ARGV[4] = 1 para usar o horário do Redis
+//
+// Retorna {permitida, contagem estimada como texto}.
+const slidingWindowLua = `
+local function slidingWindow()
+	local limit = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	if ARGV[4] == '1' then
+		-- Necessário até o Redis 5 para replicar os efeitos de um script que lê TIME
+		redis.replicate_commands()
+		local t = redis.call('TIME')
+		now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+	end
+
+	local bucket = math.floor(now / window)
+	local elapsed = now - bucket * window
+	local currentKey = KEYS[1] .. ':' .. string.format('%d', bucket)
+	local previousKey = KEYS[1] .. ':' .. string.format('%d', bucket - 1)
+
+	local current = tonumber(redis.call('GET', currentKey) or '0')
+	local previous = tonumber(redis.call('GET', previousKey) or '0')
+	local weight = (window - elapsed) / window
+
+	local estimate = previous * weight + current
+	if estimate + 1 > limit then
+		return {0, tostring(estimate)}
+	end
+
+	current = redis.call('INCR', currentKey)
+	if current == 1 then
+		redis.call('PEXPIRE', currentKey, window * 2)
+	end
+	return {1, tostring(previous * weight + current)}
+end
+`
+
+// slidingWindowScript executa slidingWindowLua em uma única ida ao Redis.
+var slidingWindowScript = redis.NewScript(slidingWindowLua + `
+return slidingWindow()
+`)
+
+// slidingWindowCheckAndCountScript verifica o bloqueio, aplica slidingWindowLua e, quando a
+// requisição é rejeitada, conta a infração e grava o bloqueio, tudo na mesma operação.
+//
+// KEYS[2] = bloqueio, KEYS[3] = infrações
+// ARGV[5] = bloqueio em ms, ARGV[6] = janela das infrações em ms,
+// ARGV[7..9] = reason, started_at e expires_at já em JSON
+//
+// Retorna {permitida, contagem estimada, valor do bloqueio existente ou "", PTTL do bloqueio}.
+var slidingWindowCheckAndCountScript = redis.NewScript(slidingWindowLua + `
+local blocked = redis.call('GET', KEYS[2])
+if blocked then
+	return {0, '0', blocked, redis.call('PTTL', KEYS[2])}
+end
+
+local res = slidingWindow()
+if res[1] == 1 then
+	return {1, res[2], '', 0}
+end
+
+local offenses = redis.call('INCR', KEYS[3])
+if offenses == 1 then
+	redis.call('PEXPIRE', KEYS[3], ARGV[6])
+end
+local blockMs = tonumber(ARGV[5])
+if blockMs > 0 then
+	local info = '{"reason":' .. ARGV[7] .. ',"offense_count":' .. offenses ..
+		',"started_at":' .. ARGV[8] .. ',"expires_at":' .. ARGV[9] .. '}'
+	redis.call('SET', KEYS[2], info, 'PX', blockMs)
+end
+return {0, res[2], '', blockMs}
+`)
+
+// SlidingWindow aplica o limite com a janela deslizante aproximada e retorna se a requisição
+// foi permitida e a contagem estimada na janela. Com WithServerTime, now é ignorado e os
+// buckets seguem o horário do Redis.
+func (rs *RedisStore) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	windowMs := window.Milliseconds()
+	if windowMs <= 0 {
+		return false, 0, fmt.Errorf("janela inválida para a janela deslizante: %s", window)
+	}
+
+	serverTime := 0
+	if rs.serverTime {
+		serverTime = 1
+	}
+	var res []interface{}
+	err := rs.scripted(ctx, func() (err error) {
+		res, err = slidingWindowScript.Run(ctx, rs.client, []string{key}, limit, windowMs, now.UnixMilli(), serverTime).Slice()
+		return err
+	}, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("erro ao executar script de janela deslizante: %w", err)
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("resposta inesperada do script de janela deslizante: %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	estimateStr, _ := res[1].(string)
+	estimate, err := strconv.ParseFloat(estimateStr, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("erro ao converter contagem da janela deslizante: %w", err)
+	}
+	return allowed == 1, estimate, nil
+}
+
+// SlidingWindowCheckAndCount aplica a janela deslizante com a verificação e a gravação do
+// bloqueio na mesma operação atômica (ver db.Store).
+func (rs *RedisStore) SlidingWindowCheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	windowMs := window.Milliseconds()
+	if windowMs <= 0 {
+		return false, 0, 0, fmt.Errorf("janela inválida para a janela deslizante: %s", window)
+	}
+	blockArgs, err := checkAndCountArgs(keys, limit, window, blockDuration, now)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	serverTime := 0
+	if rs.serverTime {
+		serverTime = 1
+	}
+	// Os argumentos do bloqueio são os de checkAndCountLua, do terceiro ao sétimo
+	args := append([]interface{}{limit, windowMs, now.UnixMilli(), serverTime}, blockArgs[2:7]...)
+	var res []interface{}
+	err = rs.scripted(ctx, func() (err error) {
+		res, err = slidingWindowCheckAndCountScript.Run(ctx, rs.client,
+			[]string{keys.Counter, keys.Block, keys.Offenses}, args...).Slice()
+		return err
+	}, nil)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("erro ao executar script de janela deslizante: %w", err)
+	}
+	if len(res) != 4 {
+		return false, 0, 0, fmt.Errorf("resposta inesperada do script de janela deslizante: %v", res)
+	}
+
+	estimateStr, _ := res[1].(string)
+	estimate, err := strconv.ParseFloat(estimateStr, 64)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("erro ao converter contagem da janela deslizante: %w", err)
+	}
+	allowed, _, retryAfter := rs.parseCheckAndCount(res, now)
+	if blocked, _ := res[2].(string); !allowed && blocked == "" && retryAfter > 0 {
+		// Sem bloqueio existente, a rejeição com duração positiva gravou o bloqueio
+		rs.blockWritten(ctx, keys.Block, now)
+	}
+	return allowed, estimate, retryAfter, nil
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+
+	"rateLimiter/infra/db"
+)
+
+// Dump percorre as chaves com SCAN e lê, em lotes, o tipo, o valor e o TTL de cada uma. Só
+// chaves simples e hashes são exportadas; as de outros tipos são ignoradas.
+func (rs *RedisStore) Dump(ctx context.Context, match string) ([]db.SnapshotEntry, error) {
+	var entries []db.SnapshotEntry
+	var cursor uint64
+	for {
+		keys, next, err := rs.client.Scan(ctx, cursor, match, scanBatchSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao percorrer chaves no Redis: %w", err)
+		}
+
+		if len(keys) > 0 {
+			batch, err := rs.dumpKeys(ctx, keys)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, batch...)
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return entries, nil
+		}
+	}
+}
+
+// dumpKeys lê um lote de chaves: primeiro o tipo, depois o valor e o TTL.
+func (rs *RedisStore) dumpKeys(ctx context.Context, keys []string) ([]db.SnapshotEntry, error) {
+	types := make([]*redis.StatusCmd, len(keys))
+	if _, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			types[i] = pipe.Type(ctx, key)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("erro ao ler o tipo das chaves no Redis: %w", err)
+	}
+
+	values := make([]redis.Cmder, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	if _, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			switch types[i].Val() {
+			case "string":
+				values[i] = pipe.Get(ctx, key)
+			case "hash":
+				values[i] = pipe.HGetAll(ctx, key)
+			default:
+				continue // a chave expirou depois do SCAN ou não é do rate limiter
+			}
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	}); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("erro ao ler chaves no Redis: %w", err)
+	}
+
+	entries := make([]db.SnapshotEntry, 0, len(keys))
+	for i, key := range keys {
+		entry := db.SnapshotEntry{Key: key}
+		switch cmd := values[i].(type) {
+		case *redis.StringCmd:
+			if cmd.Err() != nil {
+				continue // a chave expirou entre o TYPE e o GET
+			}
+			entry.Value = cmd.Val()
+		case *redis.StringStringMapCmd:
+			if len(cmd.Val()) == 0 {
+				continue
+			}
+			entry.Fields = cmd.Val()
+		default:
+			continue
+		}
+		// PTTL retorna -1 para chaves sem expiração e -2 para chaves que já expiraram
+		ttl := ttls[i].Val()
+		if ttl == -2 {
+			continue
+		}
+		if ttl > 0 {
+			entry.TTL = ttl
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Restore grava as chaves em lotes, cada lote numa transação. Hashes são recriados do zero,
+// para que campos antigos não se misturem aos restaurados.
+func (rs *RedisStore) Restore(ctx context.Context, entries []db.SnapshotEntry) error {
+	for start := 0; start < len(entries); start += scanBatchSize {
+		batch := entries[start:min(start+scanBatchSize, len(entries))]
+		if _, err := rs.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, entry := range batch {
+				if entry.Fields == nil {
+					pipe.Set(ctx, entry.Key, entry.Value, entry.TTL)
+					continue
+				}
+				pipe.Del(ctx, entry.Key)
+				fields := make([]interface{}, 0, 2*len(entry.Fields))
+				for field, value := range entry.Fields {
+					fields = append(fields, field, value)
+				}
+				pipe.HMSet(ctx, entry.Key, fields...)
+				if entry.TTL > 0 {
+					pipe.PExpire(ctx, entry.Key, entry.TTL)
+				}
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("erro ao restaurar chaves no Redis: %w", err)
+		}
+	}
+	return nil
+}
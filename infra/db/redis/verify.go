@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/context"
+)
+
+// verifyTTL é a expiração da chave usada na verificação, caso ela não seja removida ao final.
+const verifyTTL = time.Minute
+
+// verifyLua usa, dentro de um script, os mesmos comandos dos scripts do store.
+const verifyLua = `
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return redis.call('PTTL', KEYS[1])
+`
+
+// verifyTimeLua lê o horário do Redis como o script da janela deslizante com WithServerTime.
+const verifyTimeLua = `
+redis.replicate_commands()
+return redis.call('TIME')
+`
+
+// verifyStep é um comando conferido por Verify.
+type verifyStep struct {
+	command string
+	run     func() error
+	// script indica que o passo executa um script Lua.
+	script bool
+}
+
+// Verify confere se o Redis aceita os comandos usados pelo store (INCR, PEXPIRE, PTTL, EVAL e
+// EVALSHA, além de TIME em scripts com WithServerTime), executando cada um sobre uma chave
+// temporária. Redis antigos ou gerenciados com comandos restritos falham aqui, com o nome do
+// comando no erro, e não no caminho da requisição. A exceção são os scripts negados pelo Redis
+// (ver isScriptingDisabled): o store passa a usar os caminhos com pipelines e Verify não falha;
+// ScriptsDisabled informa a mudança.
+func (rs *RedisStore) Verify(ctx context.Context) error {
+	key := fmt.Sprintf("ratelimiter:verify:%d", time.Now().UnixNano())
+	defer rs.client.Del(ctx, key)
+
+	script := redis.NewScript(verifyLua)
+	steps := []verifyStep{
+		{"INCR", func() error { return rs.client.Incr(ctx, key).Err() }, false},
+		{"PEXPIRE", func() error { return rs.client.PExpire(ctx, key, verifyTTL).Err() }, false},
+		{"PTTL", func() error {
+			ttl, err := rs.client.PTTL(ctx, key).Result()
+			if err == nil && ttl <= 0 {
+				return fmt.Errorf("a chave ficou sem expiração (PTTL %s)", ttl)
+			}
+			return err
+		}, false},
+		{"EVAL", func() error { return script.Eval(ctx, rs.client, []string{key}, verifyTTL.Milliseconds()).Err() }, true},
+		{"EVALSHA", func() error { return script.EvalSha(ctx, rs.client, []string{key}, verifyTTL.Milliseconds()).Err() }, true},
+	}
+	if rs.serverTime {
+		steps = append(steps, verifyStep{"TIME em scripts", func() error { return rs.client.Eval(ctx, verifyTimeLua, nil).Err() }, true})
+	}
+
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			if step.script && isScriptingDisabled(err) {
+				// Os passos seguintes também são scripts
+				rs.disableScripts(err)
+				return nil
+			}
+			return fmt.Errorf("o Redis não aceitou %s, usado pelo rate limiter: %w", step.command, err)
+		}
+	}
+	return nil
+}
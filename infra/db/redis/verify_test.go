@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+var _ db.Verifier = (*RedisStore)(nil)
+
+// rejectCommands simula um Redis gerenciado que não aceita alguns comandos
+type rejectCommands struct {
+	names []string
+}
+
+func (r rejectCommands) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	for _, name := range r.names {
+		if cmd.Name() == name {
+			return ctx, errors.New("ERR unknown command '" + name + "'")
+		}
+	}
+	return ctx, nil
+}
+
+func (r rejectCommands) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (r rejectCommands) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (r rejectCommands) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// Test_RedisStore_Verify verifica que o miniredis aceita todos os comandos usados
+func Test_RedisStore_Verify(t *testing.T) {
+	mr, store := setupTestStore(t)
+	defer mr.Close()
+	defer store.Close()
+
+	require.NoError(t, store.Verify(context.Background()))
+	require.NoError(t, NewRedisStore(store.client, WithServerTime(true)).Verify(context.Background()))
+	assert.Empty(t, mr.Keys(), "A verificação não deveria deixar chaves para trás")
+}
+
+// Test_RedisStore_Verify_Unsupported verifica que o erro indica o comando rejeitado
+func Test_RedisStore_Verify_Unsupported(t *testing.T) {
+	for _, command := range []string{"eval", "evalsha", "pexpire"} {
+		t.Run(command, func(t *testing.T) {
+			mr, store := setupTestStore(t)
+			defer mr.Close()
+			defer store.Close()
+			store.client.AddHook(rejectCommands{names: []string{command}})
+
+			err := store.Verify(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "não aceitou "+strings.ToUpper(command)+",")
+			assert.Contains(t, err.Error(), "unknown command")
+		})
+	}
+}
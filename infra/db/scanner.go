@@ -0,0 +1,10 @@
+package db
+
+import "context"
+
+// CounterScanner é implementado por stores capazes de listar os contadores existentes.
+// É usado por tarefas periódicas (ex.: métricas de utilização), nunca no caminho da requisição.
+type CounterScanner interface {
+	// ScanCounters retorna o valor dos contadores cujas chaves casam com o padrão (glob do Redis).
+	ScanCounters(ctx context.Context, match string) (map[string]int64, error)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// SnapshotEntry é uma chave do store com o valor e o tempo restante até expirar.
+type SnapshotEntry struct {
+	// Key é o nome completo da chave, com o prefixo.
+	Key string
+	// Value é o valor das chaves simples (contadores, bloqueios e infrações).
+	Value string
+	// Fields são os campos das chaves hash (ex.: o estado do leaky bucket); nil nas chaves simples.
+	Fields map[string]string
+	// TTL é o tempo restante até a chave expirar (0: a chave não expira).
+	TTL time.Duration
+}
+
+// Snapshotter é implementado por stores capazes de exportar e restaurar as chaves, para migrar
+// o estado do rate limiter entre servidores. Não é usado no caminho da requisição.
+type Snapshotter interface {
+	// Dump retorna as chaves que casam com o padrão (glob do Redis).
+	Dump(ctx context.Context, match string) ([]SnapshotEntry, error)
+	// Restore grava as chaves, substituindo as existentes, com o TTL de cada uma.
+	Restore(ctx context.Context, entries []SnapshotEntry) error
+}
+
+// dumpMatching retorna as chaves que casam com algum dos padrões, sem repetir as que casam com
+// mais de um.
+func dumpMatching(ctx context.Context, s Snapshotter, matches []string) ([]SnapshotEntry, error) {
+	if len(matches) == 1 {
+		return s.Dump(ctx, matches[0])
+	}
+	seen := make(map[string]bool)
+	var entries []SnapshotEntry
+	for _, match := range matches {
+		found, err := s.Dump(ctx, match)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range found {
+			if !seen[entry.Key] {
+				seen[entry.Key] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, nil
+}
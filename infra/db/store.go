@@ -5,11 +5,108 @@ import (
 	"time"
 )
 
+// Motivos registrados nos bloqueios.
+const (
+	// ReasonRateLimitExceeded é o motivo registrado quando o limite de requisições é excedido.
+	ReasonRateLimitExceeded = "rate_limit_exceeded"
+	// ReasonBandwidthExceeded é o motivo registrado quando a cota de bytes é excedida.
+	ReasonBandwidthExceeded = "bandwidth_exceeded"
+	// ReasonManual é o motivo registrado nos bloqueios feitos por um operador.
+	ReasonManual = "manual"
+)
+
+// OffenseWindow é por quanto tempo as infrações de um identificador continuam sendo contadas.
+const OffenseWindow = 24 * time.Hour
+
+// CountKeys são as chaves usadas por CheckAndCount.
+type CountKeys struct {
+	// Counter é o contador de requisições da janela.
+	Counter string
+	// Block é a chave de bloqueio, gravada com os metadados (BlockInfo) ao exceder o limite.
+	Block string
+	// Offenses conta as infrações do identificador durante OffenseWindow.
+	Offenses string
+	// KeepCounterOnBlock mantém o contador ao gravar o bloqueio, em vez de zerá-lo: quando o
+	// bloqueio termina antes da janela, a próxima requisição ainda conta sobre o total anterior.
+	KeepCounterOnBlock bool
+	// GraceOverage é quantas requisições além do limite são rejeitadas sem bloqueio nem
+	// infração, até o fim da janela; só a seguinte grava o bloqueio completo.
+	GraceOverage int64
+	// SeverityTiers graduam o bloqueio pelas requisições recebidas na janela, ordenadas por
+	// Threshold. Com faixas, as requisições rejeitadas durante o bloqueio continuam contando, e
+	// o bloqueio é prolongado para a Duration da maior faixa atingida.
+	SeverityTiers []SeverityTier
+}
+
+// SeverityTier é uma faixa de severidade: ao atingir Threshold requisições na janela, o
+// identificador fica bloqueado por pelo menos Duration.
+type SeverityTier struct {
+	Threshold int64
+	Duration  time.Duration
+}
+
+// GlobalCount é o contador global incrementado por CheckAndCountWithGlobal.
+type GlobalCount struct {
+	// Key é a chave do contador global.
+	Key string
+	// Window é a janela do contador, definida quando a chave é criada.
+	Window time.Duration
+	// Limit é o teto do contador global (0: sem teto). Quando o incremento passa dele, a
+	// requisição é rejeitada sem que as chaves do identificador sejam verificadas ou contadas.
+	Limit int64
+}
+
+// BlockInfo são os metadados gravados junto com um bloqueio.
+type BlockInfo struct {
+	Reason       string    `json:"reason"`
+	OffenseCount int64     `json:"offense_count"`
+	StartedAt    time.Time `json:"started_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
 // Store define a interface para o armazenamento de dados do rate limiter.
 type Store interface {
 	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	// IncrementBy soma n ao contador, definindo o TTL da janela quando a chave é criada.
+	IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error)
+	// IncrementWithTTL incrementa o contador como Increment e retorna, na mesma operação
+	// atômica, o tempo até a janela expirar, para que o reset informado seja exato.
+	IncrementWithTTL(ctx context.Context, key string, window time.Duration) (count int64, ttl time.Duration, err error)
+	// CheckAndCount verifica o bloqueio, incrementa o contador e, se o limite for excedido,
+	// conta a infração, grava o bloqueio e zera o contador, tudo de forma atômica. Retorna se a
+	// requisição foi permitida, quantas requisições ainda cabem na janela e, quando rejeitada,
+	// o tempo até o fim do bloqueio (calculado a partir de now).
+	CheckAndCount(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (allowed bool, remaining int64, retryAfter time.Duration, err error)
+	// CheckAndCountWithGlobal faz o mesmo que CheckAndCount e, na mesma operação atômica e antes
+	// dele, incrementa o contador global, retornando o seu valor. Acima de GlobalCount.Limit, a
+	// requisição é rejeitada sem contar o identificador, com retryAfter igual ao tempo restante
+	// do bloqueio dele, se houver, ou zero.
+	CheckAndCountWithGlobal(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global GlobalCount) (allowed bool, remaining int64, retryAfter time.Duration, globalCount int64, err error)
+	SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (allowed bool, count float64, err error)
+	// SlidingWindowCheckAndCount aplica a janela deslizante de SlidingWindow (keys.Counter é o
+	// prefixo dos buckets) verificando o bloqueio antes e, quando a requisição é rejeitada,
+	// contando a infração e gravando o bloqueio, tudo de forma atômica, como CheckAndCount.
+	SlidingWindowCheckAndCount(ctx context.Context, keys CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (allowed bool, count float64, retryAfter time.Duration, err error)
+	// LeakyBucket aplica o leaky bucket de forma atômica: o nível guardado em keys.Counter vaza
+	// uma requisição a cada leakInterval, e a requisição é admitida se ainda couber em capacity.
+	// Um bloqueio em keys.Block é respeitado, mas a rejeição não bloqueia nem conta infração:
+	// retryAfter é o tempo até caber mais uma requisição.
+	LeakyBucket(ctx context.Context, keys CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (allowed bool, level float64, retryAfter time.Duration, err error)
+	Count(ctx context.Context, key string) (int64, error)
 	IsBlocked(ctx context.Context, key string) (bool, error)
-	Block(ctx context.Context, key string, duration time.Duration) error
+	Block(ctx context.Context, key string, duration time.Duration, info BlockInfo) error
+	BlockInfo(ctx context.Context, key string) (*BlockInfo, error)
+	// Get retorna o valor bruto de uma chave (nil se ela não existir).
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set grava um valor bruto com expiração.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	Reset(ctx context.Context, key string) error
+	// ResetAll remove todas as chaves informadas em uma única operação atômica, ignorando as
+	// que não existem.
+	ResetAll(ctx context.Context, keys ...string) error
+	// DeleteMatching remove as chaves que casam com o padrão (glob do Redis) e para as quais
+	// allow retorna true, retornando quantas foram removidas. É uma operação administrativa,
+	// nunca usada no caminho da requisição.
+	DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error)
 	Close() error
 }
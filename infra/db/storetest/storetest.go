@@ -0,0 +1,537 @@
+// Package storetest contém o contrato que toda implementação de db.Store precisa cumprir.
+// Cada store chama StoreContractTest nos seus testes; o contrato é a referência da semântica
+// do Store.
+package storetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/infra/db"
+)
+
+// Clock é o relógio visto pelo store: Advance precisa fazer as expirações do store avançarem
+// (ex.: clock.Fake injetado no store, ou o FastForward do miniredis).
+type Clock interface {
+	Now() time.Time
+	Advance(d time.Duration)
+}
+
+// Option configura o contrato.
+type Option func(*contract)
+
+// contract agrupa as opções do contrato.
+type contract struct {
+	clock Clock
+}
+
+// WithClock informa o relógio do store. Sem ele, os casos de expiração são ignorados.
+func WithClock(clock Clock) Option {
+	return func(c *contract) {
+		c.clock = clock
+	}
+}
+
+// StoreContractTest executa o contrato do Store. newStore é chamado em cada caso e deve
+// retornar um store vazio; o contrato o fecha ao final.
+func StoreContractTest(t *testing.T, newStore func() db.Store, opts ...Option) {
+	c := &contract{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	cases := []struct {
+		name    string
+		expires bool
+		run     func(t *testing.T, c *contract, store db.Store)
+	}{
+		{"Increment", false, testIncrement},
+		{"IncrementTTL", true, testIncrementTTL},
+		{"IncrementBy", false, testIncrementBy},
+		{"IncrementWithTTL", true, testIncrementWithTTL},
+		{"Block", false, testBlock},
+		{"BlockExpiry", true, testBlockExpiry},
+		{"GetSet", false, testGetSet},
+		{"SetExpiry", true, testSetExpiry},
+		{"Reset", false, testReset},
+		{"ResetAll", false, testResetAll},
+		{"CheckAndCount", false, testCheckAndCount},
+		{"CheckAndCountExpiry", true, testCheckAndCountExpiry},
+		{"CheckAndCountKeepCounter", true, testCheckAndCountKeepCounter},
+		{"CheckAndCountGrace", false, testCheckAndCountGrace},
+		{"CheckAndCountSeverity", false, testCheckAndCountSeverity},
+		{"SlidingWindowCheckAndCount", false, testSlidingWindowCheckAndCount},
+		{"LeakyBucket", false, testLeakyBucket},
+		{"DeleteMatching", false, testDeleteMatching},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expires && c.clock == nil {
+				t.Skip("store sem relógio controlável")
+			}
+			store := newStore()
+			defer store.Close()
+			tc.run(t, c, store)
+		})
+	}
+
+	t.Run("Close", func(t *testing.T) {
+		assert.NoError(t, newStore().Close())
+	})
+}
+
+// now retorna o instante do relógio do store, ou o horário do sistema sem relógio.
+func (c *contract) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// testIncrement verifica que o contador começa em 1 e é lido por Count sem ser incrementado.
+func testIncrement(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	count, err := store.Count(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "Um contador inexistente vale 0")
+
+	for i := int64(1); i <= 3; i++ {
+		count, err := store.Increment(ctx, "counter", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+	count, err = store.Count(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+// testIncrementTTL verifica que a janela é definida na criação e não é renovada pelos incrementos.
+func testIncrementTTL(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	_, err := store.Increment(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+
+	c.clock.Advance(time.Second)
+	count, err := store.Increment(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	c.clock.Advance(time.Second)
+	count, err = store.Count(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "O contador deveria expirar com a janela da criação")
+
+	count, err = store.Increment(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+// testIncrementBy verifica que IncrementBy soma n e compartilha o contador com Increment.
+func testIncrementBy(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	total, err := store.IncrementBy(ctx, "bytes", 100, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), total)
+
+	total, err = store.IncrementBy(ctx, "bytes", 50, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), total)
+
+	total, err = store.Increment(ctx, "bytes", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(151), total)
+}
+
+// testIncrementWithTTL verifica que a contagem e o TTL retornados são os da mesma janela: o TTL
+// diminui com o tempo, não é renovado pelos incrementos e recomeça com o contador.
+func testIncrementWithTTL(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	count, ttl, err := store.IncrementWithTTL(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, 2*time.Second, ttl)
+
+	c.clock.Advance(500 * time.Millisecond)
+	count, ttl, err = store.IncrementWithTTL(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 1500*time.Millisecond, ttl)
+
+	count, err = store.Increment(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count, "IncrementWithTTL compartilha o contador com Increment")
+
+	c.clock.Advance(1500 * time.Millisecond)
+	count, ttl, err = store.IncrementWithTTL(ctx, "counter", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "O contador recomeça com a janela")
+	assert.Equal(t, 2*time.Second, ttl)
+}
+
+// testBlock verifica o bloqueio e a leitura dos seus metadados.
+func testBlock(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	blocked, err := store.IsBlocked(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+	info, err := store.BlockInfo(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.Nil(t, info, "Sem bloqueio não há metadados")
+
+	startedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	want := db.BlockInfo{
+		Reason:       db.ReasonRateLimitExceeded,
+		OffenseCount: 2,
+		StartedAt:    startedAt,
+		ExpiresAt:    startedAt.Add(time.Minute),
+	}
+	require.NoError(t, store.Block(ctx, "blocked_ip", time.Minute, want))
+
+	blocked, err = store.IsBlocked(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	info, err = store.BlockInfo(ctx, "blocked_ip")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, want.Reason, info.Reason)
+	assert.Equal(t, want.OffenseCount, info.OffenseCount)
+	assert.True(t, want.StartedAt.Equal(info.StartedAt))
+	assert.True(t, want.ExpiresAt.Equal(info.ExpiresAt))
+}
+
+// testBlockExpiry verifica que o bloqueio termina com a duração informada.
+func testBlockExpiry(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	require.NoError(t, store.Block(ctx, "blocked_ip", 2*time.Second, db.BlockInfo{Reason: db.ReasonRateLimitExceeded}))
+
+	c.clock.Advance(time.Second)
+	blocked, err := store.IsBlocked(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	c.clock.Advance(time.Second)
+	blocked, err = store.IsBlocked(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+	info, err := store.BlockInfo(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+// testGetSet verifica a gravação e a leitura de valores brutos.
+func testGetSet(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	val, err := store.Get(ctx, "raw")
+	require.NoError(t, err)
+	assert.Nil(t, val, "Uma chave inexistente é lida como nil")
+
+	require.NoError(t, store.Set(ctx, "raw", []byte("value"), time.Minute))
+	val, err = store.Get(ctx, "raw")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), val)
+
+	_, err = store.Increment(ctx, "counter", time.Minute)
+	require.NoError(t, err)
+	val, err = store.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), val, "Contadores são lidos como texto")
+}
+
+// testSetExpiry verifica que valores brutos expiram com o TTL.
+func testSetExpiry(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	require.NoError(t, store.Set(ctx, "raw", []byte("value"), time.Second))
+
+	c.clock.Advance(time.Second)
+	val, err := store.Get(ctx, "raw")
+	require.NoError(t, err)
+	assert.Nil(t, val)
+}
+
+// testResetAll verifica que ResetAll remove contador, bloqueio e infrações de uma vez, ignora
+// chaves inexistentes e preserva as demais.
+func testResetAll(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	require.NoError(t, store.ResetAll(ctx))
+
+	_, err := store.Increment(ctx, "counter", time.Minute)
+	require.NoError(t, err)
+	_, err = store.Increment(ctx, "offenses", time.Minute)
+	require.NoError(t, err)
+	_, err = store.Increment(ctx, "other", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, store.Block(ctx, "blocked_ip", time.Minute, db.BlockInfo{}))
+
+	require.NoError(t, store.ResetAll(ctx, "counter", "blocked_ip", "offenses", "missing"))
+
+	for _, key := range []string{"counter", "offenses"} {
+		count, err := store.Count(ctx, key)
+		require.NoError(t, err)
+		assert.Zero(t, count, key)
+	}
+	blocked, err := store.IsBlocked(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+	count, err := store.Count(ctx, "other")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Chaves não informadas deveriam ser preservadas")
+}
+
+// testReset verifica que Reset remove contadores e bloqueios, e ignora chaves inexistentes.
+func testReset(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	require.NoError(t, store.Reset(ctx, "missing"))
+
+	_, err := store.Increment(ctx, "counter", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, store.Block(ctx, "blocked_ip", time.Minute, db.BlockInfo{}))
+
+	require.NoError(t, store.Reset(ctx, "counter"))
+	require.NoError(t, store.Reset(ctx, "blocked_ip"))
+
+	count, err := store.Count(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	blocked, err := store.IsBlocked(ctx, "blocked_ip")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+// countKeys são as chaves usadas nos casos de CheckAndCount.
+var countKeys = db.CountKeys{Counter: "ip_1", Block: "blocked_ip_1", Offenses: "offenses_ip_1"}
+
+// testCheckAndCount verifica a contagem, o bloqueio ao exceder o limite e a rejeição seguinte.
+func testCheckAndCount(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	now := c.now()
+	for i := int64(1); i <= 2; i++ {
+		allowed, remaining, _, err := store.CheckAndCount(ctx, countKeys, 2, time.Minute, time.Minute, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, 2-i, remaining)
+	}
+
+	allowed, remaining, retryAfter, err := store.CheckAndCount(ctx, countKeys, 2, time.Minute, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, int64(0), remaining)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	info, err := store.BlockInfo(ctx, countKeys.Block)
+	require.NoError(t, err)
+	require.NotNil(t, info, "Exceder o limite deveria gravar o bloqueio")
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
+	assert.Equal(t, int64(1), info.OffenseCount)
+	count, err := store.Count(ctx, countKeys.Counter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "O contador deveria ser zerado ao bloquear")
+
+	// Bloqueado: a requisição é rejeitada sem ser contada
+	allowed, _, retryAfter, err = store.CheckAndCount(ctx, countKeys, 2, time.Minute, time.Minute, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 50*time.Second, retryAfter)
+	count, err = store.Count(ctx, countKeys.Counter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+// testCheckAndCountExpiry verifica que a contagem recomeça quando o bloqueio termina.
+func testCheckAndCountExpiry(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, _, _, err := store.CheckAndCount(ctx, countKeys, 1, time.Minute, 2*time.Second, c.now())
+		require.NoError(t, err)
+	}
+
+	c.clock.Advance(2 * time.Second)
+	allowed, remaining, _, err := store.CheckAndCount(ctx, countKeys, 1, time.Minute, 2*time.Second, c.now())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(0), remaining)
+	count, err := store.Count(ctx, countKeys.Offenses)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "As infrações continuam contando depois do bloqueio")
+}
+
+// testCheckAndCountKeepCounter verifica que, com KeepCounterOnBlock, o contador sobrevive ao
+// bloqueio e a primeira requisição após um bloqueio mais curto que a janela volta a bloquear.
+func testCheckAndCountKeepCounter(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	keys := countKeys
+	keys.KeepCounterOnBlock = true
+	for i := 0; i < 2; i++ {
+		_, _, _, err := store.CheckAndCount(ctx, keys, 1, time.Minute, 2*time.Second, c.now())
+		require.NoError(t, err)
+	}
+	count, err := store.Count(ctx, keys.Counter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "O contador deveria ser mantido ao bloquear")
+
+	c.clock.Advance(2 * time.Second)
+	allowed, _, retryAfter, err := store.CheckAndCount(ctx, keys, 1, time.Minute, 2*time.Second, c.now())
+	require.NoError(t, err)
+	assert.False(t, allowed, "A cota da janela continua esgotada depois do bloqueio")
+	assert.Equal(t, 2*time.Second, retryAfter)
+	count, err = store.Count(ctx, keys.Offenses)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+// testCheckAndCountGrace verifica que as requisições dentro da tolerância são rejeitadas até o
+// fim da janela sem bloqueio nem infração, e que a seguinte grava o bloqueio completo.
+func testCheckAndCountGrace(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	keys := countKeys
+	keys.GraceOverage = 2
+	allowed, _, _, err := store.CheckAndCount(ctx, keys, 1, time.Minute, time.Hour, c.now())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	for i := 0; i < 2; i++ {
+		allowed, _, retryAfter, err := store.CheckAndCount(ctx, keys, 1, time.Minute, time.Hour, c.now())
+		require.NoError(t, err)
+		assert.False(t, allowed, "Requisições além do limite são rejeitadas mesmo na tolerância")
+		assert.Positive(t, retryAfter)
+		assert.LessOrEqual(t, retryAfter, time.Minute, "Na tolerância, a espera é só até o fim da janela")
+	}
+	info, err := store.BlockInfo(ctx, keys.Block)
+	require.NoError(t, err)
+	assert.Nil(t, info, "A tolerância não deveria bloquear")
+	count, err := store.Count(ctx, keys.Offenses)
+	require.NoError(t, err)
+	assert.Zero(t, count, "A tolerância não deveria contar infrações")
+
+	allowed, _, retryAfter, err := store.CheckAndCount(ctx, keys, 1, time.Minute, time.Hour, c.now())
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Hour, retryAfter)
+	info, err = store.BlockInfo(ctx, keys.Block)
+	require.NoError(t, err)
+	require.NotNil(t, info, "Além da tolerância, o bloqueio deveria ser gravado")
+	assert.Equal(t, int64(1), info.OffenseCount)
+}
+
+// testCheckAndCountSeverity verifica que o bloqueio dura o da faixa de severidade atingida e
+// é prolongado pelas requisições recebidas durante o bloqueio.
+func testCheckAndCountSeverity(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	keys := countKeys
+	keys.SeverityTiers = []db.SeverityTier{{Threshold: 4, Duration: 10 * time.Minute}, {Threshold: 20, Duration: time.Hour}}
+	check := func() time.Duration {
+		allowed, _, retryAfter, err := store.CheckAndCount(ctx, keys, 2, time.Minute, time.Minute, c.now())
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		return retryAfter
+	}
+	for i := 0; i < 2; i++ {
+		allowed, _, _, err := store.CheckAndCount(ctx, keys, 2, time.Minute, time.Minute, c.now())
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+
+	// Logo acima do limite, vale o bloqueio base
+	assert.Equal(t, time.Minute, check())
+	// A quarta requisição da janela, já bloqueada, atinge a primeira faixa
+	assert.InDelta(t, float64(10*time.Minute), float64(check()), float64(time.Second))
+	for i := 5; i < 20; i++ {
+		retryAfter := check()
+		assert.LessOrEqual(t, retryAfter, 10*time.Minute, "Requisição %d", i)
+		assert.Greater(t, retryAfter, 9*time.Minute, "Requisição %d", i)
+	}
+	// A vigésima atinge a faixa mais longa
+	assert.InDelta(t, float64(time.Hour), float64(check()), float64(time.Second))
+
+	info, err := store.BlockInfo(ctx, keys.Block)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, int64(1), info.OffenseCount, "Prolongar o bloqueio não conta uma nova infração")
+	assert.WithinDuration(t, c.now().Add(time.Hour), info.ExpiresAt, time.Second)
+}
+
+// testSlidingWindowCheckAndCount verifica que a janela deslizante grava o bloqueio ao rejeitar
+// e rejeita as requisições seguintes pelo bloqueio, sem contá-las.
+func testSlidingWindowCheckAndCount(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	// Início de um bucket, para que o bucket anterior não pese na estimativa
+	now := c.now().Truncate(time.Minute)
+	for i := 1; i <= 2; i++ {
+		allowed, estimate, _, err := store.SlidingWindowCheckAndCount(ctx, countKeys, 2, time.Minute, time.Minute, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.InDelta(t, float64(i), estimate, 1e-9)
+	}
+
+	allowed, _, retryAfter, err := store.SlidingWindowCheckAndCount(ctx, countKeys, 2, time.Minute, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+	info, err := store.BlockInfo(ctx, countKeys.Block)
+	require.NoError(t, err)
+	require.NotNil(t, info, "Exceder o limite deveria gravar o bloqueio")
+	assert.Equal(t, int64(1), info.OffenseCount)
+
+	allowed, _, retryAfter, err = store.SlidingWindowCheckAndCount(ctx, countKeys, 2, time.Minute, time.Minute, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 50*time.Second, retryAfter)
+	offenses, err := store.Count(ctx, countKeys.Offenses)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), offenses, "Requisições barradas pelo bloqueio não contam infração")
+}
+
+// testLeakyBucket verifica a admissão até a capacidade, o vazamento no ritmo informado e que
+// um bloqueio existente é respeitado sem que a rejeição por capacidade bloqueie.
+func testLeakyBucket(t *testing.T, c *contract, store db.Store) {
+	ctx := context.Background()
+	now := c.now()
+	for i := 1; i <= 2; i++ {
+		allowed, level, _, err := store.LeakyBucket(ctx, countKeys, 2, time.Second, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.InDelta(t, float64(i), level, 0.001)
+	}
+
+	allowed, _, retryAfter, err := store.LeakyBucket(ctx, countKeys, 2, time.Second, now.Add(400*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, allowed, "O bucket cheio deveria rejeitar")
+	assert.Equal(t, 600*time.Millisecond, retryAfter)
+	blocked, err := store.IsBlocked(ctx, countKeys.Block)
+	require.NoError(t, err)
+	assert.False(t, blocked, "A rejeição do leaky bucket não bloqueia")
+
+	allowed, level, _, err := store.LeakyBucket(ctx, countKeys, 2, time.Second, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, allowed, "Uma requisição deveria ter vazado")
+	assert.InDelta(t, 2, level, 0.001)
+
+	require.NoError(t, store.Block(ctx, countKeys.Block, time.Minute, db.BlockInfo{Reason: db.ReasonBandwidthExceeded}))
+	allowed, _, _, err = store.LeakyBucket(ctx, countKeys, 2, time.Second, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, allowed, "Um bloqueio existente deveria ser respeitado")
+}
+
+// testDeleteMatching verifica a remoção pelo padrão e pelo filtro.
+func testDeleteMatching(t *testing.T, _ *contract, store db.Store) {
+	ctx := context.Background()
+	for _, key := range []string{"blocked_ip_1", "blocked_ip_2", "blocked_token_1", "ip_1"} {
+		require.NoError(t, store.Set(ctx, key, []byte("1"), time.Minute))
+	}
+
+	deleted, err := store.DeleteMatching(ctx, "blocked_ip_*", func(key string) bool {
+		return !strings.HasSuffix(key, "_2")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	for key, exists := range map[string]bool{"blocked_ip_1": false, "blocked_ip_2": true, "blocked_token_1": true, "ip_1": true} {
+		val, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, exists, val != nil, key)
+	}
+}
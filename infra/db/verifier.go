@@ -0,0 +1,12 @@
+package db
+
+import "context"
+
+// Verifier é implementado por stores capazes de conferir, na inicialização, se o backend
+// suporta as operações que usam, para que uma incompatibilidade apareça antes da primeira
+// requisição e não no meio do tráfego.
+type Verifier interface {
+	// Verify executa uma versão inofensiva de cada operação necessária e retorna um erro
+	// indicando a primeira que não é suportada.
+	Verify(ctx context.Context) error
+}
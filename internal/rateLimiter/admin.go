@@ -0,0 +1,143 @@
+package rateLimiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+)
+
+// IdentifierStatus é o estado de um identificador no store, para inspeção.
+type IdentifierStatus struct {
+	Identifier string
+	IsToken    bool
+	// Limit e Window são os limites que valem para o identificador.
+	Limit  int
+	Window time.Duration
+	// Count são as requisições contadas na janela atual (no leaky bucket, que guarda um nível
+	// em vez de um contador, e na janela deslizante, só o bucket atual).
+	Count int64
+	// Offenses são as infrações dentro de db.OffenseWindow.
+	Offenses int64
+	// Block são os metadados do bloqueio (nil se o identificador não estiver bloqueado).
+	Block *db.BlockInfo
+}
+
+// BlockedIdentifier é um bloqueio listado por ListBlocked.
+type BlockedIdentifier struct {
+	Identifier string
+	IsToken    bool
+	Info       db.BlockInfo
+	// TTL é o tempo restante da chave de bloqueio (0: sem expiração).
+	TTL time.Duration
+}
+
+// Status retorna o limite, a contagem, as infrações e o bloqueio do identificador.
+func (rl *RateLimiter) Status(ctx context.Context, identifier string, isToken bool) (*IdentifierStatus, error) {
+	limit, window, _, err := rl.resolveLimit(ctx, identifier, isToken)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao resolver limite: %w", err)
+	}
+	status := &IdentifierStatus{Identifier: identifier, IsToken: isToken, Limit: limit, Window: window}
+
+	key := identifierKey(identifier, isToken)
+	if rl.limiterConfig.Algorithm != config.AlgorithmLeakyBucket {
+		counter := rl.windowCounterKeys(rl.scopedKey(key, isToken), window, rl.clock.Now())[0]
+		if status.Count, err = rl.store.Count(ctx, counter); err != nil {
+			return nil, fmt.Errorf("erro ao ler o contador: %w", err)
+		}
+	}
+	if status.Offenses, err = rl.store.Count(ctx, rl.scopedKey("offenses_"+key, isToken)); err != nil {
+		return nil, fmt.Errorf("erro ao ler as infrações: %w", err)
+	}
+	if status.Block, err = rl.store.BlockInfo(ctx, rl.scopedKey("blocked_"+key, isToken)); err != nil {
+		return nil, fmt.Errorf("erro ao ler o bloqueio: %w", err)
+	}
+	return status, nil
+}
+
+// Block bloqueia o identificador manualmente, com o motivo db.ReasonManual. Com duration 0,
+// vale a duração de bloqueio configurada para o identificador.
+func (rl *RateLimiter) Block(ctx context.Context, identifier string, isToken bool, duration time.Duration) error {
+	if duration <= 0 {
+		_, _, blockDuration, err := rl.resolveLimit(ctx, identifier, isToken)
+		if err != nil {
+			return fmt.Errorf("erro ao resolver limite: %w", err)
+		}
+		duration = rl.capBlock(blockDuration)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duração de bloqueio inválida: %s", duration)
+	}
+
+	now := rl.clock.Now()
+	key := identifierKey(identifier, isToken)
+	err := rl.store.Block(ctx, rl.scopedKey("blocked_"+key, isToken), duration, db.BlockInfo{
+		Reason:    db.ReasonManual,
+		StartedAt: now,
+		ExpiresAt: now.Add(duration),
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao bloquear: %w", err)
+	}
+	return nil
+}
+
+// Unblock remove o bloqueio do identificador, preservando o contador e as infrações, e informa
+// se havia um bloqueio.
+func (rl *RateLimiter) Unblock(ctx context.Context, identifier string, isToken bool) (bool, error) {
+	blockKey := rl.scopedKey("blocked_"+identifierKey(identifier, isToken), isToken)
+	blocked, err := rl.store.IsBlocked(ctx, blockKey)
+	if err != nil {
+		return false, fmt.Errorf("erro ao verificar o bloqueio: %w", err)
+	}
+	if !blocked {
+		return false, nil
+	}
+	if err := rl.store.Reset(ctx, blockKey); err != nil {
+		return false, fmt.Errorf("erro ao remover o bloqueio: %w", err)
+	}
+	return true, nil
+}
+
+// ListBlocked lista os identificadores bloqueados, ordenados pelo fim do bloqueio. As chaves
+// são lidas com SCAN, como em Export, e o store precisa implementar db.Snapshotter.
+func (rl *RateLimiter) ListBlocked(ctx context.Context) ([]BlockedIdentifier, error) {
+	s, err := rl.snapshotter()
+	if err != nil {
+		return nil, err
+	}
+	now := rl.clock.Now()
+	blocked := make([]BlockedIdentifier, 0)
+	for _, isToken := range []bool{false, true} {
+		// Cada escopo tem o próprio prefixo (IPKeyPrefix ou TokenKeyPrefix)
+		scopePrefix := rl.scopedKey("blocked_"+identifierKey("", isToken), isToken)
+		entries, err := s.Dump(ctx, scopePrefix+"*")
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar bloqueios: %w", err)
+		}
+		for _, entry := range entries {
+			item := BlockedIdentifier{
+				Identifier: strings.TrimPrefix(entry.Key, scopePrefix),
+				IsToken:    isToken,
+				TTL:        entry.TTL,
+			}
+			// Bloqueios sem metadados (ex.: o literal legado "blocked") terminam com o TTL da chave
+			if json.Unmarshal([]byte(entry.Value), &item.Info) != nil || item.Info.ExpiresAt.IsZero() {
+				if entry.TTL > 0 {
+					item.Info.ExpiresAt = now.Add(entry.TTL)
+				}
+			}
+			blocked = append(blocked, item)
+		}
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].Info.ExpiresAt.Before(blocked[j].Info.ExpiresAt)
+	})
+	return blocked, nil
+}
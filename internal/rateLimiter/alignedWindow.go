@@ -0,0 +1,15 @@
+package rateLimiter
+
+import (
+	"strconv"
+	"time"
+)
+
+// alignedWindow retorna o identificador da janela alinhada ao relógio que contém now
+// (floor(now / janela), contado desde a época Unix) e o instante em que ela termina. Todas as
+// instâncias e todos os clientes calculam as mesmas janelas, que viram juntas.
+func alignedWindow(now time.Time, window time.Duration) (string, time.Time) {
+	windowMs := max(window.Milliseconds(), 1)
+	bucket := now.UnixMilli() / windowMs
+	return strconv.FormatInt(bucket, 10), time.UnixMilli((bucket + 1) * windowMs).In(now.Location())
+}
@@ -0,0 +1,71 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_AlignedWindow verifica o número e o fim da janela alinhada
+func Test_AlignedWindow(t *testing.T) {
+	bucket, end := alignedWindow(time.Date(2024, 5, 1, 12, 34, 56, 0, time.UTC), time.Minute)
+	assert.Equal(t, "28576114", bucket)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 35, 0, 0, time.UTC), end)
+
+	bucket, end = alignedWindow(time.Date(2024, 5, 1, 12, 35, 0, 0, time.UTC), time.Minute)
+	assert.Equal(t, "28576115", bucket, "A virada pertence à nova janela")
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 36, 0, 0, time.UTC), end)
+}
+
+// Test_RateLimiter_AlignWindows verifica que as janelas viram no limite do relógio,
+// independentemente de quando chegou a primeira requisição de cada cliente
+func Test_RateLimiter_AlignWindows(t *testing.T) {
+	for name, align := range map[string]bool{"aligned": true, "first_request": false} {
+		t.Run(name, func(t *testing.T) {
+			mr, client := setupTestRedis(t)
+			defer mr.Close()
+			defer client.Close()
+
+			cfg := &config.LimiterConfig{MaxRequestsPerIP: 2, WindowIPSeconds: 10, BlockDurationIPSeconds: 60, AlignWindows: align}
+			rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+			ctx := context.Background()
+			boundary := time.Unix(1_700_000_000, 0) // múltiplo de 10s
+
+			// Um cliente começa no início da janela, o outro perto do fim
+			for _, first := range []struct {
+				ip string
+				at time.Duration
+			}{{"192.168.8.1", time.Second}, {"192.168.8.2", 7 * time.Second}} {
+				for i := 0; i < 2; i++ {
+					decision, err := rl.allowAt(ctx, first.ip, false, boundary.Add(first.at))
+					require.NoError(t, err)
+					require.True(t, decision.Allowed)
+					if align {
+						assert.Equal(t, 10*time.Second-first.at, decision.ResetAfter, "O reset é a virada da janela")
+					} else {
+						assert.Zero(t, decision.ResetAfter, "Sem alinhamento, o reset não é conhecido")
+					}
+				}
+			}
+			if align {
+				assert.Equal(t, 3*time.Second, mr.TTL("ip_192.168.8.2:170000000"), "O contador expira na virada")
+			}
+
+			// Logo após a virada, os dois têm a cota renovada só com as janelas alinhadas
+			for _, ip := range []string{"192.168.8.1", "192.168.8.2"} {
+				decision, err := rl.allowAt(ctx, ip, false, boundary.Add(10*time.Second))
+				require.NoError(t, err)
+				assert.Equal(t, align, decision.Allowed, ip)
+				if align {
+					assert.Equal(t, 1, decision.Remaining, ip)
+				}
+			}
+		})
+	}
+}
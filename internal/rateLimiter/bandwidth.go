@@ -0,0 +1,69 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// BandwidthLimiter é implementado por rate limiters que também limitam o volume de bytes
+// das respostas entregues a cada identificador.
+type BandwidthLimiter interface {
+	RecordBytes(ctx context.Context, identifier string, isToken bool, n int64) error
+}
+
+// RecordBytes soma o tamanho de uma resposta já entregue à cota de bytes do identificador.
+// É um limite a posteriori: a resposta que excede a cota é entregue, mas o identificador é
+// bloqueado e a próxima requisição já é rejeitada. Sem MaxBytesPerWindow, não faz nada.
+func (rl *RateLimiter) RecordBytes(ctx context.Context, identifier string, isToken bool, n int64) error {
+	maxBytes := rl.limiterConfig.MaxBytesPerWindow
+	if maxBytes <= 0 || n <= 0 || !rl.Enabled() {
+		return nil
+	}
+
+	_, _, blockDuration, err := rl.resolveLimit(ctx, identifier, isToken)
+	if err != nil {
+		return fmt.Errorf("erro ao resolver limite: %w", err)
+	}
+	blockDuration = rl.capBlock(blockDuration)
+
+	key := identifierKey(identifier, isToken)
+	bytesKey := rl.scopedKey("bytes_"+key, isToken)
+
+	total, err := rl.store.IncrementBy(ctx, bytesKey, n, rl.bandwidthWindow())
+	if err != nil {
+		return fmt.Errorf("erro ao contabilizar bytes: %w", err)
+	}
+	if total <= maxBytes {
+		return nil
+	}
+
+	offenses, err := rl.store.Increment(ctx, rl.scopedKey("offenses_"+key, isToken), db.OffenseWindow)
+	if err != nil {
+		return fmt.Errorf("erro ao contar infrações: %w", err)
+	}
+	now := rl.clock.Now()
+	err = rl.store.Block(ctx, rl.scopedKey("blocked_"+key, isToken), blockDuration, db.BlockInfo{
+		Reason:       db.ReasonBandwidthExceeded,
+		OffenseCount: offenses,
+		StartedAt:    now,
+		ExpiresAt:    now.Add(blockDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao bloquear: %w", err)
+	}
+	if !rl.limiterConfig.KeepCounterOnBlock {
+		_ = rl.store.Reset(ctx, bytesKey)
+	}
+	return nil
+}
+
+// bandwidthWindow retorna a janela da cota de bytes (padrão: 1 minuto).
+func (rl *RateLimiter) bandwidthWindow() time.Duration {
+	if rl.limiterConfig.BandwidthWindowSeconds > 0 {
+		return time.Duration(rl.limiterConfig.BandwidthWindowSeconds) * time.Second
+	}
+	return time.Minute
+}
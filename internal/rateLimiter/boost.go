@@ -0,0 +1,50 @@
+package rateLimiter
+
+import "time"
+
+// TemporaryBoost multiplica todos os limites até o instante Until (ex.: durante um lançamento).
+type TemporaryBoost struct {
+	Multiplier float64
+	Until      time.Time
+}
+
+// active informa se o boost vale no instante now.
+func (b *TemporaryBoost) active(now time.Time) bool {
+	return b != nil && b.Multiplier > 0 && now.Before(b.Until)
+}
+
+// SetBoost agenda um multiplicador temporário para todos os limites, substituindo o anterior.
+// Pode ser chamado em tempo de execução; passar um boost com Multiplier zero o remove.
+func (rl *RateLimiter) SetBoost(boost TemporaryBoost) {
+	if boost.Multiplier <= 0 {
+		rl.boost.Store(nil)
+		return
+	}
+	rl.boost.Store(&boost)
+}
+
+// Boost retorna o boost configurado e se ele está ativo agora, segundo o relógio do rate limiter.
+func (rl *RateLimiter) Boost() (TemporaryBoost, bool) {
+	boost := rl.boost.Load()
+	if boost == nil {
+		return TemporaryBoost{}, false
+	}
+	return *boost, boost.active(rl.clock.Now())
+}
+
+// boostedLimit aplica ao limite resolvido o multiplicador do horário (LimitSchedule), o do
+// aquecimento após a inicialização e o boost ativos no instante now. Um limite positivo reduzido
+// pelo horário continua em pelo menos 1.
+func (rl *RateLimiter) boostedLimit(maxRequests int, now time.Time) int {
+	if multiplier := rl.scheduleMultiplier(now); multiplier != 1 && maxRequests > 0 {
+		maxRequests = max(int(float64(maxRequests)*multiplier), 1)
+	}
+	if multiplier := rl.warmupMultiplier(now); multiplier != 1 {
+		maxRequests = int(float64(maxRequests) * multiplier)
+	}
+	boost := rl.boost.Load()
+	if !boost.active(now) {
+		return maxRequests
+	}
+	return int(float64(maxRequests) * boost.Multiplier)
+}
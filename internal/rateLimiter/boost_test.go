@@ -0,0 +1,62 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// Test_RateLimiter_TemporaryBoost verifica que um boost de 2x dobra o limite até expirar
+func Test_RateLimiter_TemporaryBoost(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 3, BlockDurationIPSeconds: 60}
+	fake := clock.NewFake(time.Date(2024, 11, 29, 9, 0, 0, 0, time.UTC))
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+
+	rl.SetBoost(TemporaryBoost{Multiplier: 2, Until: fake.Now().Add(time.Hour)})
+	_, active := rl.Boost()
+	assert.True(t, active)
+
+	assert.Equal(t, 6, allowedUntilRejected(t, rl, "192.168.6.1", 10), "Com o boost o limite deveria dobrar")
+	decision, err := rl.AllowDecision(context.Background(), "192.168.6.2", false)
+	require.NoError(t, err)
+	assert.Equal(t, 6, decision.Limit)
+
+	// Depois do fim do boost, o limite volta ao configurado
+	fake.Advance(time.Hour)
+	_, active = rl.Boost()
+	assert.False(t, active)
+	assert.Equal(t, 3, allowedUntilRejected(t, rl, "192.168.6.3", 10))
+}
+
+// Test_RateLimiter_TemporaryBoost_FromConfigAndRemoval verifica o boost agendado pela configuração e a sua remoção
+func Test_RateLimiter_TemporaryBoost_FromConfigAndRemoval(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	now := time.Date(2024, 11, 29, 9, 0, 0, 0, time.UTC)
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       4,
+		BlockDurationIPSeconds: 60,
+		BoostMultiplier:        1.5,
+		BoostUntil:             now.Add(time.Hour),
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(clock.NewFake(now)))
+	assert.Equal(t, 6, allowedUntilRejected(t, rl, "192.168.6.4", 10))
+
+	rl.SetBoost(TemporaryBoost{})
+	_, active := rl.Boost()
+	assert.False(t, active)
+	assert.Equal(t, 4, allowedUntilRejected(t, rl, "192.168.6.5", 10))
+}
@@ -0,0 +1,23 @@
+package rateLimiter
+
+import (
+	"time"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// calendarPeriod retorna o identificador do período de calendário que contém now
+// (YYYYMMDD para cotas diárias, YYYYMM para mensais) e o instante em que ele termina.
+func (rl *RateLimiter) calendarPeriod(now time.Time) (string, time.Time) {
+	loc := rl.limiterConfig.CalendarLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	year, month, day := local.Date()
+
+	if rl.limiterConfig.CalendarPeriod == config.CalendarPeriodMonthly {
+		return local.Format("200601"), time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	}
+	return local.Format("20060102"), time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,98 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// Test_RateLimiter_CalendarWindow_ResetsAtMidnight verifica que a cota diária vale até a meia-noite
+// UTC e é renovada no dia seguinte
+func Test_RateLimiter_CalendarWindow_ResetsAtMidnight(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       3,
+		BlockDurationIPSeconds: 60,
+		Algorithm:              config.AlgorithmCalendarWindow,
+		CalendarPeriod:         config.CalendarPeriodDaily,
+	}
+	fake := clock.NewFake(time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC))
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+
+	assert.Equal(t, 1, allowedUntilRejected(t, rl, "192.168.3.1", 1))
+	assert.Equal(t, time.Hour, mr.TTL("ip_192.168.3.1:20240310"), "O contador deveria expirar na virada do dia")
+	assert.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.3.1", 10))
+	assert.Equal(t, time.Hour, mr.TTL("blocked_ip_192.168.3.1"), "O bloqueio deveria durar até a virada do dia")
+
+	// Bem depois da janela de 1s, mas ainda no mesmo dia: a cota continua esgotada
+	fake.Advance(30 * time.Minute)
+	mr.FastForward(30 * time.Minute)
+	decision, err := rl.AllowDecision(context.Background(), "192.168.3.1", false)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 30*time.Minute, decision.RetryAfter, "O bloqueio deveria durar até a meia-noite")
+
+	// Depois da meia-noite a cota é renovada
+	fake.Advance(31 * time.Minute)
+	mr.FastForward(31 * time.Minute)
+	assert.Equal(t, 1, allowedUntilRejected(t, rl, "192.168.3.1", 1))
+	assert.True(t, mr.Exists("ip_192.168.3.1:20240311"))
+	assert.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.3.1", 10))
+}
+
+// Test_RateLimiter_CalendarWindow_Timezone verifica que a virada acontece no fuso configurado
+func Test_RateLimiter_CalendarWindow_Timezone(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       2,
+		BlockDurationIPSeconds: 60,
+		Algorithm:              config.AlgorithmCalendarWindow,
+		CalendarPeriod:         config.CalendarPeriodDaily,
+		CalendarLocation:       saoPaulo,
+	}
+	// 01:00 UTC do dia 11 ainda é dia 10 em BRT
+	fake := clock.NewFake(time.Date(2024, 3, 11, 1, 0, 0, 0, time.UTC))
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+
+	assert.Equal(t, 1, allowedUntilRejected(t, rl, "192.168.3.2", 1))
+	assert.Equal(t, 2*time.Hour, mr.TTL("ip_192.168.3.2:20240310"))
+	assert.Equal(t, 1, allowedUntilRejected(t, rl, "192.168.3.2", 10))
+
+	// A meia-noite UTC já passou, mas a cota só renova às 03:00 UTC
+	fake.Advance(time.Hour)
+	mr.FastForward(time.Hour)
+	assert.Equal(t, 0, allowedUntilRejected(t, rl, "192.168.3.2", 10))
+
+	fake.Advance(time.Hour)
+	mr.FastForward(time.Hour)
+	assert.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.3.2", 10))
+}
+
+// Test_RateLimiter_CalendarPeriod verifica os identificadores e o fim dos períodos diário e mensal
+func Test_RateLimiter_CalendarPeriod(t *testing.T) {
+	now := time.Date(2024, 12, 31, 15, 30, 0, 0, time.UTC)
+
+	daily := &RateLimiter{limiterConfig: &config.LimiterConfig{CalendarPeriod: config.CalendarPeriodDaily}}
+	period, end := daily.calendarPeriod(now)
+	assert.Equal(t, "20241231", period)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), end)
+
+	monthly := &RateLimiter{limiterConfig: &config.LimiterConfig{CalendarPeriod: config.CalendarPeriodMonthly}}
+	period, end = monthly.calendarPeriod(now)
+	assert.Equal(t, "202412", period)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), end)
+}
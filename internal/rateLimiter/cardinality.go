@@ -0,0 +1,93 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// newIdentifiersKey conta os identificadores novos na janela da proteção de cardinalidade.
+const newIdentifiersKey = "cardinality_new"
+
+// Tamanhos das sub-redes usadas por config.CardinalitySubnet.
+const (
+	subnetBitsIPv4 = 24
+	subnetBitsIPv6 = 64
+)
+
+// guardCardinality aplica a proteção de cardinalidade (CardinalityMaxNew) ao identificador e
+// retorna o identificador a contar ou reject = true. Um identificador é novo quando não foi
+// visto na janela da proteção. Enquanto os novos da janela não chegam ao máximo, cada um é
+// registrado e segue normalmente; a partir daí, a proteção está ativa: com
+// config.CardinalitySubnet, um IP novo passa a ser contado pela sub-rede, e os demais
+// identificadores novos são rejeitados, sem criar chaves no store. Os já vistos não mudam.
+//
+// A verificação e o registro não são atômicos: sob concorrência, alguns identificadores além
+// do máximo podem ser registrados.
+func (rl *RateLimiter) guardCardinality(ctx context.Context, identifier string, isToken bool) (string, bool, error) {
+	seenKey := rl.scopedKey("seen_"+identifierKey(identifier, isToken), isToken)
+	seen, err := rl.store.Get(ctx, seenKey)
+	if err != nil {
+		return "", false, fmt.Errorf("erro ao verificar identificador conhecido: %w", err)
+	}
+	if seen != nil {
+		return identifier, false, nil
+	}
+
+	newKey := rl.storeKey(newIdentifiersKey)
+	window := rl.cardinalityWindow()
+	count, err := rl.store.Count(ctx, newKey)
+	if err != nil {
+		return "", false, fmt.Errorf("erro ao contar identificadores novos: %w", err)
+	}
+	if count < int64(rl.limiterConfig.CardinalityMaxNew) {
+		if _, err := rl.store.Increment(ctx, newKey, window); err != nil {
+			return "", false, fmt.Errorf("erro ao contar identificadores novos: %w", err)
+		}
+		if err := rl.store.Set(ctx, seenKey, []byte("1"), window); err != nil {
+			return "", false, fmt.Errorf("erro ao registrar identificador: %w", err)
+		}
+		return identifier, false, nil
+	}
+
+	if rl.limiterConfig.CardinalityFallback != config.CardinalityReject && !isToken {
+		if subnet, ok := subnetIdentifier(identifier); ok {
+			return subnet, false, nil
+		}
+	}
+	return identifier, true, nil
+}
+
+// subnetIdentifier troca o IP no fim do identificador (depois da classe e do host, quando
+// houver) pela sua sub-rede. ok é false se o identificador não terminar em um IP.
+func subnetIdentifier(identifier string) (string, bool) {
+	head, ip := "", identifier
+	if i := strings.LastIndex(identifier, "|"); i >= 0 {
+		head, ip = identifier[:i+1], identifier[i+1:]
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", false
+	}
+	bits := subnetBitsIPv6
+	if addr.Unmap().Is4() {
+		addr, bits = addr.Unmap(), subnetBitsIPv4
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return "", false
+	}
+	return head + "subnet:" + prefix.String(), true
+}
+
+// cardinalityWindow retorna a janela da proteção de cardinalidade (padrão: 1 minuto).
+func (rl *RateLimiter) cardinalityWindow() time.Duration {
+	if rl.limiterConfig.CardinalityWindowSeconds > 0 {
+		return time.Duration(rl.limiterConfig.CardinalityWindowSeconds) * time.Second
+	}
+	return time.Minute
+}
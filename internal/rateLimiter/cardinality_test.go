@@ -0,0 +1,118 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_SubnetIdentifier verifica a troca do IP no fim do identificador pela sub-rede
+func Test_SubnetIdentifier(t *testing.T) {
+	for identifier, expected := range map[string]string{
+		"203.0.113.77":                 "subnet:203.0.113.0/24",
+		"2001:db8:1:2:3:4:5:6":         "subnet:2001:db8:1:2::/64",
+		"::ffff:203.0.113.77":          "subnet:203.0.113.0/24",
+		"read|api.example.com|1.2.3.4": "read|api.example.com|subnet:1.2.3.0/24",
+	} {
+		subnet, ok := subnetIdentifier(identifier)
+		assert.True(t, ok, identifier)
+		assert.Equal(t, expected, subnet, identifier)
+	}
+	for _, identifier := range []string{"abc", "ua:0123", "read|unknown"} {
+		_, ok := subnetIdentifier(identifier)
+		assert.False(t, ok, identifier)
+	}
+}
+
+// Test_RateLimiter_Cardinality_Subnet simula uma rajada de IPs únicos e verifica que, acima do
+// máximo de identificadores novos, eles passam a dividir o contador da sub-rede
+func Test_RateLimiter_Cardinality_Subnet(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       5,
+		MaxRequestsPerToken:    5,
+		BlockDurationIPSeconds: 60,
+		CardinalityMaxNew:      3,
+		CardinalityFallback:    config.CardinalitySubnet,
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	allowed := 0
+	for i := 1; i <= 50; i++ {
+		decision, err := rl.AllowDecision(ctx, fmt.Sprintf("10.1.2.%d", i), false)
+		require.NoError(t, err)
+		if decision.Allowed {
+			allowed++
+		}
+	}
+	// Três IPs com contador próprio, e os demais dividindo o limite da sub-rede
+	assert.Equal(t, 3+5, allowed)
+	for i := 1; i <= 3; i++ {
+		assert.True(t, mr.Exists(fmt.Sprintf("ip_10.1.2.%d", i)), "Os primeiros IPs novos são contados normalmente")
+	}
+	assert.False(t, mr.Exists("ip_10.1.2.4"), "Com a proteção ativa, IPs novos não criam chaves próprias")
+	assert.True(t, mr.Exists("blocked_ip_subnet:10.1.2.0/24"))
+	assert.LessOrEqual(t, len(mr.Keys()), 12, "A rajada não deveria criar chaves por IP")
+
+	// Um IP já visto continua com o próprio contador
+	decision, err := rl.AllowDecision(ctx, "10.1.2.1", false)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 3, decision.Remaining)
+
+	// Tokens novos não têm sub-rede e são rejeitados
+	decision, err = rl.AllowDecision(ctx, "new-token", true)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.False(t, mr.Exists("token_new-token"))
+
+	// Na janela seguinte, a proteção volta a aceitar identificadores novos
+	mr.FastForward(time.Minute)
+	decision, err = rl.AllowDecision(ctx, "new-token", true)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+// Test_RateLimiter_Cardinality_Reject verifica que, com CardinalityReject, os identificadores
+// novos acima do máximo são rejeitados sem criar chaves
+func Test_RateLimiter_Cardinality_Reject(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:         5,
+		BlockDurationIPSeconds:   60,
+		CardinalityMaxNew:        3,
+		CardinalityWindowSeconds: 30,
+		CardinalityFallback:      config.CardinalityReject,
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for i := 1; i <= 20; i++ {
+		decision, err := rl.AllowDecision(ctx, fmt.Sprintf("10.3.0.%d", i), false)
+		require.NoError(t, err)
+		assert.Equal(t, i <= 3, decision.Allowed, "IP %d", i)
+		if !decision.Allowed {
+			assert.Equal(t, 30*time.Second, decision.RetryAfter)
+		}
+	}
+	// Três contadores, três marcas de identificador visto e o contador de novos
+	assert.Len(t, mr.Keys(), 7)
+
+	decision, err := rl.AllowDecision(ctx, "10.3.0.2", false)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed, "Identificadores já vistos não são afetados")
+}
@@ -0,0 +1,18 @@
+package rateLimiter
+
+import "context"
+
+// requestClassKey é o tipo da chave usada para guardar a classe da requisição no contexto.
+type requestClassKey struct{}
+
+// WithRequestClass associa ao contexto a classe da requisição (ex.: "read" ou "write"),
+// usada pelos resolvers para aplicar limites próprios de cada classe.
+func WithRequestClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, requestClassKey{}, class)
+}
+
+// RequestClassFromContext retorna a classe da requisição guardada no contexto ("" se não houver).
+func RequestClassFromContext(ctx context.Context) string {
+	class, _ := ctx.Value(requestClassKey{}).(string)
+	return class
+}
@@ -0,0 +1,26 @@
+package rateLimiter
+
+import (
+	"context"
+	"log/slog"
+
+	"rateLimiter/infra/db"
+)
+
+// logDecision registra em nível debug as chaves usadas e o resultado de uma verificação. A
+// contagem é a da janela após a requisição, derivada das requisições restantes (na janela
+// deslizante, a estimativa arredondada; no leaky bucket, o nível da fila). storeCalls é quantas
+// operações do store a decisão fez, contadas pelo db.ObservedStore.
+func (rl *RateLimiter) logDecision(ctx context.Context, keys db.CountKeys, decision *Decision, storeCalls int) {
+	rl.logger.DebugContext(ctx, "rate limit",
+		slog.String("identifier", decision.Identifier),
+		slog.Bool("is_token", decision.IsToken),
+		slog.String("counter_key", keys.Counter),
+		slog.Int("count", decision.Limit-decision.Remaining),
+		slog.Int("limit", decision.Limit),
+		slog.String("block_key", keys.Block),
+		slog.Bool("allowed", decision.Allowed),
+		slog.Duration("retry_after", decision.RetryAfter),
+		slog.Int("store_calls", storeCalls),
+	)
+}
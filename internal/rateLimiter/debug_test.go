@@ -0,0 +1,60 @@
+package rateLimiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_DebugLogging verifica que o registro de depuração traz as chaves e a contagem
+func Test_RateLimiter_DebugLogging(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 5, BlockDurationIPSeconds: 60, KeyPrefix: "app:", DebugLogging: true}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithLogger(logger))
+
+	for i := 0; i < 2; i++ {
+		_, err := rl.Allow(context.Background(), "192.168.7.1", false)
+		require.NoError(t, err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2, "Deveria haver um registro por requisição")
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "DEBUG", record["level"])
+	assert.Equal(t, "app:ip_192.168.7.1", record["counter_key"])
+	assert.Equal(t, "app:blocked_ip_192.168.7.1", record["block_key"])
+	assert.Equal(t, float64(2), record["count"])
+	assert.Equal(t, float64(5), record["limit"])
+	assert.Equal(t, true, record["allowed"])
+	assert.Equal(t, "2", mustGet(t, mr, "app:ip_192.168.7.1"), "A contagem registrada deveria ser a do store")
+}
+
+// Test_RateLimiter_DebugLogging_Disabled verifica que, sem DebugLogging, nada é registrado
+func Test_RateLimiter_DebugLogging_Disabled(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 5, BlockDurationIPSeconds: 60}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithLogger(logger))
+
+	_, err := rl.Allow(context.Background(), "192.168.7.2", false)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
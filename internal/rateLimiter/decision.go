@@ -0,0 +1,39 @@
+package rateLimiter
+
+import "time"
+
+// Decision é o resultado detalhado de uma verificação de rate limit.
+type Decision struct {
+	// Allowed indica se a requisição pode seguir.
+	Allowed bool
+	// Identifier é o IP ou token avaliado.
+	Identifier string
+	// IsToken indica se o identificador é um token.
+	IsToken bool
+	// Limit é o número máximo de requisições por janela.
+	Limit int
+	// Remaining é quantas requisições ainda cabem na janela atual.
+	Remaining int
+	// RemainingFloat é Remaining com a parte fracionária: no leaky bucket, a cota volta aos
+	// poucos, e uma rejeição pode ter parte de uma vaga já liberada; na janela deslizante, vem
+	// da estimativa ponderada. Nos demais algoritmos, é igual a Remaining.
+	RemainingFloat float64
+	// Window é a duração da janela de contagem.
+	Window time.Duration
+	// RetryAfter é o tempo até o fim do bloqueio, quando a requisição é rejeitada.
+	RetryAfter time.Duration
+	// ResetAfter é o tempo até o contador da janela ser renovado, quando conhecido: nas janelas
+	// alinhadas e nas cotas de calendário, e na cota justa dos tokens, lido com o próprio
+	// incremento (db.Store.IncrementWithTTL). Zero quando desconhecido.
+	ResetAfter time.Duration
+	// FailedOpen indica que o store falhou e a requisição foi permitida pelo modo de falha aberto.
+	FailedOpen bool
+	// Disabled indica que o rate limiting está desligado e a requisição não foi contabilizada.
+	Disabled bool
+	// SoftAllowed indica que a requisição excedeu o limite, mas foi permitida por estar dentro
+	// das violações consecutivas toleradas (config.LimiterConfig.ConsecutiveViolationThreshold).
+	SoftAllowed bool
+	// SoftLimitExceeded indica que a requisição foi permitida, mas a contagem da janela passou do
+	// limite de alerta do escopo (config.LimiterConfig.SoftLimitPerIP ou SoftLimitPerToken).
+	SoftLimitExceeded bool
+}
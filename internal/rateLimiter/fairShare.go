@@ -0,0 +1,98 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FairLimiter é implementado por rate limiters que sabem dividir o limite de um IP
+// entre os tokens que o compartilham.
+type FairLimiter interface {
+	AllowFair(ctx context.Context, ip, token string) (bool, error)
+	AllowFairDecision(ctx context.Context, ip, token string) (*Decision, error)
+}
+
+// AllowFair aplica o limite do token e, em seguida, a cota justa do token dentro do IP.
+//
+// Cada token visto no IP durante a janela recebe uma sub-cota de
+// ceil(MaxRequestsPerIP / tokensAtivos). Um token que já consumiu mais do que a sua
+// sub-cota é rejeitado, de modo que, quando um novo token chega, o token mais acima
+// da sua parte justa é o primeiro a ser barrado. As rejeições por cota justa não geram
+// bloqueio: valem apenas até o fim da janela.
+func (rl *RateLimiter) AllowFair(ctx context.Context, ip, token string) (bool, error) {
+	decision, err := rl.AllowFairDecision(ctx, ip, token)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed, nil
+}
+
+// AllowFairDecision funciona como AllowFair, retornando o detalhamento da decisão.
+// As requisições restantes consideram tanto o limite do token quanto a sua cota no IP.
+func (rl *RateLimiter) AllowFairDecision(ctx context.Context, ip, token string) (*Decision, error) {
+	if token == "" {
+		return rl.AllowDecision(ctx, ip, false)
+	}
+
+	decision, err := rl.AllowDecision(ctx, token, true)
+	if err != nil || !decision.Allowed || decision.FailedOpen || decision.Disabled {
+		return decision, err
+	}
+
+	shareRemaining, shareReset, err := rl.allowFairShare(ctx, ip, token)
+	if err != nil {
+		return rl.onStoreError(err, token, true)
+	}
+	if shareRemaining < 0 {
+		// Sem bloqueio: a cota justa volta quando o contador de uso do token no IP expira
+		decision.Allowed = false
+		decision.Remaining = 0
+		decision.RemainingFloat = 0
+		decision.RetryAfter = shareReset
+		decision.ResetAfter = shareReset
+		return decision, nil
+	}
+	decision.Remaining = min(decision.Remaining, int(shareRemaining))
+	decision.RemainingFloat = min(decision.RemainingFloat, float64(shareRemaining))
+	return decision, nil
+}
+
+// allowFairShare contabiliza o uso do token dentro da janela do IP e retorna quanto ainda
+// resta da sub-cota (negativo quando a cota foi excedida) e o tempo até ela ser renovada.
+func (rl *RateLimiter) allowFairShare(ctx context.Context, ip, token string) (int64, time.Duration, error) {
+	ipLimit, window, _, err := rl.resolveLimit(ctx, ip, false)
+	if err != nil {
+		return 0, 0, fmt.Errorf("erro ao resolver limite do IP: %w", err)
+	}
+	ipLimit = rl.boostedLimit(ipLimit, rl.clock.Now())
+
+	tokensKey := rl.scopedKey("fair_ip_"+ip+"_tokens", false)
+	usageKey := rl.scopedKey("fair_ip_"+ip+"_token_"+token, false)
+
+	usage, reset, err := rl.store.IncrementWithTTL(ctx, usageKey, window)
+	if err != nil {
+		return 0, 0, fmt.Errorf("erro ao incrementar uso do token no IP: %w", err)
+	}
+
+	var activeTokens int64
+	if usage == 1 {
+		// Primeira requisição do token nesta janela: passa a contar como token ativo
+		activeTokens, err = rl.store.Increment(ctx, tokensKey, window)
+	} else {
+		activeTokens, err = rl.store.Count(ctx, tokensKey)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("erro ao contar tokens ativos no IP: %w", err)
+	}
+	if activeTokens < 1 {
+		activeTokens = 1
+	}
+
+	return fairShare(int64(ipLimit), activeTokens) - usage, reset, nil
+}
+
+// fairShare retorna a sub-cota de cada token, arredondada para cima para não desperdiçar o limite.
+func fairShare(limit, activeTokens int64) int64 {
+	return (limit + activeTokens - 1) / activeTokens
+}
@@ -0,0 +1,106 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// countAllowedFair envia n requisições de um token pelo mesmo IP e retorna quantas foram permitidas
+func countAllowedFair(t *testing.T, rl *RateLimiter, ip, token string, n int) int {
+	allowedCount := 0
+	for i := 0; i < n; i++ {
+		allowed, err := rl.AllowFair(context.Background(), ip, token)
+		require.NoError(t, err)
+		if allowed {
+			allowedCount++
+		}
+	}
+	return allowedCount
+}
+
+// Test_RateLimiter_FairShare_GreedyTokenDoesNotStarveOthers verifica que um token guloso
+// não consome todo o limite do IP quando outro token chega na mesma janela
+func Test_RateLimiter_FairShare_GreedyTokenDoesNotStarveOthers(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	// Limite de 10 por IP, limite alto por token para que a cota do IP seja a restrição
+	rl := createTestRateLimiterWithConfig(client, 10, 100, 60, 60)
+	ip := "192.168.1.60"
+
+	// Sozinho, o token guloso pode usar todo o limite do IP
+	greedy := countAllowedFair(t, rl, ip, "greedy", 20)
+	assert.Equal(t, 10, greedy, "Sozinho, o token deveria usar todo o limite do IP")
+
+	// Em primeiro a chegar, primeiro a ser servido, o segundo token não receberia nada.
+	// Com a cota justa ele recebe a sua metade do limite.
+	polite := countAllowedFair(t, rl, ip, "polite", 10)
+	assert.Equal(t, 5, polite, "O segundo token deveria receber a sua cota justa")
+
+	// O token guloso, acima da sua parte, é o primeiro a ser barrado
+	allowed, err := rl.AllowFair(context.Background(), ip, "greedy")
+	require.NoError(t, err)
+	assert.False(t, allowed, "O token acima da sua cota justa deveria ser rejeitado")
+}
+
+// Test_RateLimiter_FairShare_DifferentIPsAreIndependent verifica que as cotas são calculadas por IP
+func Test_RateLimiter_FairShare_DifferentIPsAreIndependent(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 4, 100, 60, 60)
+
+	assert.Equal(t, 4, countAllowedFair(t, rl, "192.168.1.61", "shared-token", 4))
+	assert.Equal(t, 4, countAllowedFair(t, rl, "192.168.1.62", "shared-token", 4),
+		"O mesmo token em outro IP deveria ter uma cota independente")
+}
+
+// Test_RateLimiter_FairShare_TokenLimitStillApplies verifica que o limite do próprio token continua valendo
+func Test_RateLimiter_FairShare_TokenLimitStillApplies(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 10, 3, 60, 60)
+
+	assert.Equal(t, 3, countAllowedFair(t, rl, "192.168.1.63", "small-token", 5),
+		"O limite do token deveria ser aplicado antes da cota justa")
+	assert.True(t, mr.Exists("blocked_token_small-token"))
+}
+
+// Test_FairShare_RoundsUp verifica o arredondamento da sub-cota
+func Test_FairShare_RoundsUp(t *testing.T) {
+	assert.Equal(t, int64(10), fairShare(10, 1))
+	assert.Equal(t, int64(5), fairShare(10, 2))
+	assert.Equal(t, int64(4), fairShare(10, 3))
+}
+
+// Test_RateLimiter_FairShare_RetryAfterFromCounterTTL verifica que a rejeição pela cota justa
+// informa o tempo até o contador de uso do token no IP expirar
+func Test_RateLimiter_FairShare_RetryAfterFromCounterTTL(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 2, MaxRequestsPerToken: 100, WindowIPSeconds: 60, BlockDurationIPSeconds: 60, BlockDurationTokenSeconds: 60}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ip := "192.168.1.64"
+
+	assert.Equal(t, 2, countAllowedFair(t, rl, ip, "tok", 2))
+	mr.FastForward(20 * time.Second)
+
+	decision, err := rl.AllowFairDecision(context.Background(), ip, "tok")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 40*time.Second, decision.RetryAfter)
+	assert.Equal(t, mr.TTL("fair_ip_"+ip+"_token_tok"), decision.ResetAfter, "O reset é o TTL lido com o incremento")
+}
@@ -0,0 +1,134 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// GlobalIdentifier é o identificador das decisões do limite global.
+const GlobalIdentifier = "global"
+
+// GlobalLimiter é implementado por rate limiters com um teto único para todo o tráfego.
+type GlobalLimiter interface {
+	AllowGlobal(ctx context.Context) (*Decision, error)
+	AllowWithGlobal(ctx context.Context, identifier string, isToken bool) (*Decision, LimitHit, error)
+}
+
+// LimitHit indica quais limites rejeitaram a requisição.
+type LimitHit int
+
+const (
+	// LimitHitNone indica que nenhum limite foi atingido.
+	LimitHitNone LimitHit = 0
+	// LimitHitGlobal indica que o limite global foi atingido.
+	LimitHitGlobal LimitHit = 1
+	// LimitHitIdentifier indica que o limite do identificador foi atingido.
+	LimitHitIdentifier LimitHit = 2
+	// LimitHitBoth indica que os dois limites foram atingidos.
+	LimitHitBoth = LimitHitGlobal | LimitHitIdentifier
+)
+
+// String retorna o nome dos limites atingidos (ex.: "global,identifier").
+func (h LimitHit) String() string {
+	switch h {
+	case LimitHitGlobal:
+		return "global"
+	case LimitHitIdentifier:
+		return "identifier"
+	case LimitHitBoth:
+		return "global,identifier"
+	default:
+		return "none"
+	}
+}
+
+// AllowGlobal contabiliza a requisição no contador global, compartilhado por todos os
+// identificadores, e a rejeita quando GlobalLimit é excedido na janela. O limite global não
+// gera bloqueio: as requisições voltam a ser aceitas assim que a janela termina. Sem
+// GlobalLimit, toda requisição é permitida sem acessar o store.
+func (rl *RateLimiter) AllowGlobal(ctx context.Context) (*Decision, error) {
+	if !rl.Enabled() {
+		return disabledDecision(GlobalIdentifier, false), nil
+	}
+	if rl.limiterConfig.GlobalLimit <= 0 {
+		return &Decision{Allowed: true, Identifier: GlobalIdentifier}, nil
+	}
+
+	limit := rl.boostedLimit(rl.limiterConfig.GlobalLimit, rl.clock.Now())
+	window := rl.globalWindow()
+	count, err := rl.store.IncrementBy(ctx, rl.storeKey(GlobalIdentifier), 1, window)
+	if err != nil {
+		return rl.onStoreError(fmt.Errorf("erro ao contabilizar limite global: %w", err), GlobalIdentifier, false)
+	}
+
+	remaining := max(limit-int(count), 0)
+	decision := &Decision{
+		Allowed:        count <= int64(limit),
+		Identifier:     GlobalIdentifier,
+		Limit:          limit,
+		Remaining:      remaining,
+		RemainingFloat: float64(remaining),
+		Window:         window,
+	}
+	if !decision.Allowed {
+		// O contador global não é lido com o TTL: a janela inteira é o maior tempo até a renovação
+		decision.RetryAfter = window
+	}
+	return decision, nil
+}
+
+// AllowWithGlobal avalia o limite global e o do identificador em uma única operação do store
+// (na janela deslizante, em duas) e retorna quais deles foram atingidos. O limite global vem
+// primeiro, como em AllowGlobal seguido de AllowDecision: uma requisição rejeitada por ele é
+// rejeitada com o RetryAfter da janela global, sem consumir a cota do identificador. A decisão
+// descreve o limite do identificador.
+func (rl *RateLimiter) AllowWithGlobal(ctx context.Context, identifier string, isToken bool) (*Decision, LimitHit, error) {
+	if !rl.Enabled() {
+		return disabledDecision(identifier, isToken), LimitHitNone, nil
+	}
+	if rl.limiterConfig.GlobalLimit <= 0 {
+		decision, err := rl.AllowDecision(ctx, identifier, isToken)
+		if err != nil || decision.Allowed {
+			return decision, LimitHitNone, err
+		}
+		return decision, LimitHitIdentifier, nil
+	}
+
+	now := rl.clock.Now()
+	limit := rl.boostedLimit(rl.limiterConfig.GlobalLimit, now)
+	window := rl.globalWindow()
+	decision, globalCount, err := rl.allowWithGlobalAt(ctx, identifier, isToken, now,
+		&db.GlobalCount{Key: rl.storeKey(GlobalIdentifier), Window: window, Limit: int64(limit)})
+	if err != nil {
+		decision, err = rl.onStoreError(err, identifier, isToken)
+		return decision, LimitHitNone, err
+	}
+
+	if globalCount > int64(limit) {
+		// O identificador não foi contado; um RetryAfter indica que ele já estava bloqueado
+		hit := LimitHitGlobal
+		if decision.RetryAfter > 0 {
+			hit |= LimitHitIdentifier
+		}
+		decision.Allowed = false
+		decision.Remaining = 0
+		decision.RemainingFloat = 0
+		decision.RetryAfter = max(decision.RetryAfter, window)
+		return decision, hit, nil
+	}
+	if !decision.Allowed {
+		return decision, LimitHitIdentifier, nil
+	}
+	return decision, LimitHitNone, nil
+}
+
+// globalWindow retorna a janela do limite global (padrão: 1 segundo).
+func (rl *RateLimiter) globalWindow() time.Duration {
+	if rl.limiterConfig.GlobalWindowSeconds > 0 {
+		return time.Duration(rl.limiterConfig.GlobalWindowSeconds) * time.Second
+	}
+	return time.Second
+}
@@ -0,0 +1,160 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+	"rateLimiter/infra/db/memory"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_AllowGlobal verifica que o teto global é compartilhado e renova com a janela
+func Test_RateLimiter_AllowGlobal(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{GlobalLimit: 3, GlobalWindowSeconds: 1}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		decision, err := rl.AllowGlobal(ctx)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i, decision.Remaining)
+	}
+	decision, err := rl.AllowGlobal(ctx)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, GlobalIdentifier, decision.Identifier)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+	assert.False(t, mr.Exists("blocked_global"), "O limite global não deveria gerar bloqueio")
+
+	mr.FastForward(time.Second)
+	decision, err = rl.AllowGlobal(ctx)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed, "O limite global deveria renovar com a janela")
+}
+
+// Test_RateLimiter_AllowGlobal_Unset verifica que, sem GlobalLimit, o store não é acessado
+func Test_RateLimiter_AllowGlobal_Unset(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := NewRateLimiter(&config.LimiterConfig{}, redisStore.NewRedisStore(client))
+	decision, err := rl.AllowGlobal(context.Background())
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Empty(t, mr.Keys())
+}
+
+// Test_RateLimiter_AllowWithGlobal verifica quais limites são informados em cada combinação
+func Test_RateLimiter_AllowWithGlobal(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       2,
+		BlockDurationIPSeconds: 60,
+		GlobalLimit:            4,
+		GlobalWindowSeconds:    10,
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	allow := func(ip string) (*Decision, LimitHit) {
+		decision, hit, err := rl.AllowWithGlobal(ctx, ip, false)
+		require.NoError(t, err)
+		return decision, hit
+	}
+
+	// Nenhum limite atingido
+	decision, hit := allow("10.0.0.1")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, LimitHitNone, hit)
+	decision, hit = allow("10.0.0.1")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, LimitHitNone, hit)
+
+	// Só o limite do identificador
+	decision, hit = allow("10.0.0.1")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, LimitHitIdentifier, hit)
+	assert.Equal(t, time.Minute, decision.RetryAfter)
+
+	// Com o limite global esgotado, um IP novo é rejeitado só pelo global
+	decision, hit = allow("10.0.0.2")
+	assert.True(t, decision.Allowed, "A quarta requisição ainda cabe no limite global")
+	assert.Equal(t, LimitHitNone, hit)
+	decision, hit = allow("10.0.0.3")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, LimitHitGlobal, hit)
+	assert.Equal(t, 10*time.Second, decision.RetryAfter)
+	assert.Equal(t, "global", hit.String())
+
+	// O IP bloqueado com o limite global esgotado atinge os dois
+	decision, hit = allow("10.0.0.1")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, LimitHitBoth, hit)
+	assert.InDelta(t, float64(time.Minute), float64(decision.RetryAfter), float64(time.Second), "Deveria prevalecer a espera mais longa")
+	assert.Equal(t, "global,identifier", hit.String())
+}
+
+// Test_RateLimiter_AllowWithGlobal_DoesNotCountIdentifier verifica que as requisições rejeitadas
+// só pelo limite global não consomem a cota do identificador, nos stores e nos algoritmos
+func Test_RateLimiter_AllowWithGlobal_DoesNotCountIdentifier(t *testing.T) {
+	for _, algorithm := range []string{config.AlgorithmFixedWindow, config.AlgorithmSlidingWindow} {
+		for _, name := range []string{"redis", "memory"} {
+			t.Run(algorithm+"/"+name, func(t *testing.T) {
+				mr, client := setupTestRedis(t)
+				defer mr.Close()
+				defer client.Close()
+
+				var store db.Store = redisStore.NewRedisStore(client)
+				if name == "memory" {
+					store = memory.NewMemoryStore(memory.Config{})
+				}
+				cfg := &config.LimiterConfig{
+					MaxRequestsPerIP:       3,
+					WindowIPSeconds:        60,
+					BlockDurationIPSeconds: 60,
+					GlobalLimit:            2,
+					GlobalWindowSeconds:    60,
+					Algorithm:              algorithm,
+				}
+				rl := NewRateLimiter(cfg, store)
+				ctx := context.Background()
+
+				for i := 0; i < 2; i++ {
+					decision, hit, err := rl.AllowWithGlobal(ctx, "10.0.1.1", false)
+					require.NoError(t, err)
+					require.True(t, decision.Allowed)
+					require.Equal(t, LimitHitNone, hit)
+				}
+				// O limite global esgotado rejeita sem levar o IP ao bloqueio
+				for i := 0; i < 5; i++ {
+					decision, hit, err := rl.AllowWithGlobal(ctx, "10.0.1.1", false)
+					require.NoError(t, err)
+					assert.False(t, decision.Allowed)
+					assert.Equal(t, LimitHitGlobal, hit)
+				}
+
+				decision, err := rl.AllowWithoutCountDecision(ctx, "10.0.1.1", false)
+				require.NoError(t, err)
+				assert.Equal(t, 1, decision.Remaining, "Só as duas requisições permitidas deveriam contar")
+				blocked, err := store.IsBlocked(ctx, "blocked_ip_10.0.1.1")
+				require.NoError(t, err)
+				assert.False(t, blocked)
+			})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package rateLimiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// defaultIdempotencyTTL é por quanto tempo uma chave de idempotência é lembrada quando
+// IdempotencyTTLSeconds não está configurado.
+const defaultIdempotencyTTL = time.Minute
+
+// IdempotentLimiter é implementado por rate limiters que reconhecem requisições repetidas
+// pela chave de idempotência enviada pelo cliente.
+type IdempotentLimiter interface {
+	AllowIdempotentDecision(ctx context.Context, identifier string, isToken bool, idempotencyKey string) (*Decision, error)
+}
+
+// AllowIdempotentDecision funciona como AllowDecision, mas lembra a decisão tomada para a
+// chave de idempotência. Uma repetição dentro do TTL devolve a decisão anterior sem
+// contabilizar a requisição de novo, a não ser que o identificador tenha sido bloqueado
+// depois dela. A chave é separada por identificador, de modo que um cliente não reaproveita
+// a decisão de outro; cabe a quem chama incluir nela o que identifica a requisição (o
+// middleware usa o método, a URL e o corpo).
+func (rl *RateLimiter) AllowIdempotentDecision(ctx context.Context, identifier string, isToken bool, idempotencyKey string) (*Decision, error) {
+	if idempotencyKey == "" || !rl.Enabled() {
+		return rl.AllowDecision(ctx, identifier, isToken)
+	}
+
+	key := rl.scopedKey("idempotency_"+identifierKey(identifier, isToken)+"_"+idempotencyKey, isToken)
+
+	previous, err := rl.store.Get(ctx, key)
+	if err != nil {
+		return rl.onStoreError(fmt.Errorf("erro ao ler chave de idempotência: %w", err), identifier, isToken)
+	}
+	if previous != nil {
+		decision := &Decision{}
+		if err := json.Unmarshal(previous, decision); err == nil {
+			if !decision.Allowed {
+				return decision, nil
+			}
+			// Uma permissão anterior não vale contra um bloqueio aplicado depois dela
+			current, err := rl.peekDecision(ctx, identifier, isToken)
+			if err != nil {
+				return rl.onStoreError(err, identifier, isToken)
+			}
+			if !current.Allowed {
+				return current, nil
+			}
+			return decision, nil
+		}
+		// Um valor corrompido não deve impedir a requisição: segue com uma nova decisão
+	}
+
+	decision, err := rl.AllowDecision(ctx, identifier, isToken)
+	if err != nil || decision.FailedOpen || decision.Disabled {
+		return decision, err
+	}
+
+	val, err := json.Marshal(decision)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar decisão: %w", err)
+	}
+	// A requisição já foi contabilizada; perder a chave só permite uma recontagem futura
+	_ = rl.store.Set(ctx, key, val, rl.idempotencyTTL())
+	return decision, nil
+}
+
+// idempotencyTTL retorna por quanto tempo as chaves de idempotência são lembradas.
+func (rl *RateLimiter) idempotencyTTL() time.Duration {
+	if rl.limiterConfig.IdempotencyTTLSeconds > 0 {
+		return time.Duration(rl.limiterConfig.IdempotencyTTLSeconds) * time.Second
+	}
+	return defaultIdempotencyTTL
+}
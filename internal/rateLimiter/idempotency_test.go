@@ -0,0 +1,99 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_RateLimiter_Idempotency_CountsOnce verifica que a mesma chave de idempotência só é contada uma vez
+func Test_RateLimiter_Idempotency_CountsOnce(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 3, 10, 60, 60)
+	ctx := context.Background()
+
+	first, err := rl.AllowIdempotentDecision(ctx, "192.168.4.1", false, "req-1")
+	require.NoError(t, err)
+	second, err := rl.AllowIdempotentDecision(ctx, "192.168.4.1", false, "req-1")
+	require.NoError(t, err)
+
+	assert.True(t, second.Allowed)
+	assert.Equal(t, first, second, "A repetição deveria devolver a decisão anterior")
+	count, err := mr.Get("ip_192.168.4.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", count, "A repetição não deveria incrementar o contador")
+	assert.Equal(t, time.Minute, mr.TTL("idempotency_ip_192.168.4.1_req-1"))
+
+	// Uma chave diferente é uma nova requisição
+	third, err := rl.AllowIdempotentDecision(ctx, "192.168.4.1", false, "req-2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, third.Remaining)
+}
+
+// Test_RateLimiter_Idempotency_ScopedByIdentifier verifica que a chave de um cliente não vale para outro
+func Test_RateLimiter_Idempotency_ScopedByIdentifier(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 1, 10, 60, 60)
+	ctx := context.Background()
+
+	_, err := rl.AllowIdempotentDecision(ctx, "192.168.4.2", false, "req-1")
+	require.NoError(t, err)
+	_, err = rl.AllowIdempotentDecision(ctx, "192.168.4.3", false, "req-1")
+	require.NoError(t, err)
+
+	assert.True(t, mr.Exists("ip_192.168.4.2"))
+	assert.True(t, mr.Exists("ip_192.168.4.3"))
+}
+
+// Test_RateLimiter_Idempotency_ExpiredKeyCountsAgain verifica que após o TTL a repetição volta a contar
+func Test_RateLimiter_Idempotency_ExpiredKeyCountsAgain(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 3, 10, 60, 60)
+	rl.limiterConfig.IdempotencyTTLSeconds = 1
+	ctx := context.Background()
+
+	first, err := rl.AllowIdempotentDecision(ctx, "192.168.4.4", false, "req-1")
+	require.NoError(t, err)
+	mr.FastForward(1100 * time.Millisecond)
+	second, err := rl.AllowIdempotentDecision(ctx, "192.168.4.4", false, "req-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, first.Remaining)
+	assert.Equal(t, 2, second.Remaining, "A janela de 1s também expirou, então o contador recomeça")
+	assert.True(t, mr.Exists("idempotency_ip_192.168.4.4_req-1"))
+}
+
+// Test_RateLimiter_Idempotency_BlockedAfterReplay verifica que a decisão lembrada não vale
+// contra um bloqueio aplicado depois dela
+func Test_RateLimiter_Idempotency_BlockedAfterReplay(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 1, 10, 60, 60)
+	ctx := context.Background()
+
+	first, err := rl.AllowIdempotentDecision(ctx, "192.168.4.5", false, "req-1")
+	require.NoError(t, err)
+	require.True(t, first.Allowed)
+	exceeded, err := rl.AllowDecision(ctx, "192.168.4.5", false)
+	require.NoError(t, err)
+	require.False(t, exceeded.Allowed)
+
+	replay, err := rl.AllowIdempotentDecision(ctx, "192.168.4.5", false, "req-1")
+	require.NoError(t, err)
+	assert.False(t, replay.Allowed, "O bloqueio deveria prevalecer sobre a decisão lembrada")
+	assert.Greater(t, replay.RetryAfter, time.Duration(0))
+}
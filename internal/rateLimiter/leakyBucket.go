@@ -0,0 +1,38 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// allowLeakyAt aplica o leaky bucket: o bucket vaza o limite a cada janela, ou seja, uma
+// requisição a cada window/limite, e comporta LeakyBucketCapacity requisições na fila
+// virtual (padrão: o próprio limite). As requisições que não cabem são descartadas sem
+// bloqueio nem infração, e RetryAfter indica quando caberá a próxima.
+func (rl *RateLimiter) allowLeakyAt(ctx context.Context, decision *Decision, keys db.CountKeys, window time.Duration, now time.Time) (*Decision, error) {
+	if decision.Limit <= 0 {
+		return decision, nil
+	}
+	capacity := decision.Limit
+	if rl.limiterConfig.LeakyBucketCapacity > 0 {
+		capacity = rl.limiterConfig.LeakyBucketCapacity
+	}
+	leakInterval := window / time.Duration(decision.Limit)
+
+	allowed, level, retryAfter, err := rl.store.LeakyBucket(ctx, keys, int64(capacity), leakInterval, now)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao aplicar o leaky bucket: %w", err)
+	}
+	decision.RemainingFloat = max(float64(capacity)-level, 0)
+	if !allowed {
+		decision.RetryAfter = retryAfter
+		return decision, nil
+	}
+	decision.Allowed = true
+	decision.Remaining = max(capacity-int(math.Ceil(level)), 0)
+	return decision, nil
+}
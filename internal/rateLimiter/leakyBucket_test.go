@@ -0,0 +1,145 @@
+package rateLimiter
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_LeakyBucket_AdmitRate verifica que, com chegadas acima do ritmo de
+// vazamento, a vazão admitida segue o ritmo de vazamento em qualquer padrão de chegada
+func Test_RateLimiter_LeakyBucket_AdmitRate(t *testing.T) {
+	const (
+		limit    = 10 // vaza 10 requisições por segundo
+		duration = 10 * time.Second
+	)
+
+	// Cada padrão retorna os instantes das chegadas, a partir de 0, com média de 20/s
+	patterns := map[string]func() []time.Duration{
+		"steady": func() []time.Duration {
+			var arrivals []time.Duration
+			for at := time.Duration(0); at < duration; at += 50 * time.Millisecond {
+				arrivals = append(arrivals, at)
+			}
+			return arrivals
+		},
+		"bursts": func() []time.Duration {
+			var arrivals []time.Duration
+			for at := time.Duration(0); at < duration; at += time.Second {
+				for i := 0; i < 20; i++ {
+					arrivals = append(arrivals, at)
+				}
+			}
+			return arrivals
+		},
+		"random": func() []time.Duration {
+			rnd := rand.New(rand.NewPCG(1, 2))
+			var arrivals []time.Duration
+			for at := time.Duration(0); at < duration; at += time.Duration(rnd.ExpFloat64() * float64(50*time.Millisecond)) {
+				arrivals = append(arrivals, at)
+			}
+			return arrivals
+		},
+	}
+
+	for name, pattern := range patterns {
+		t.Run(name, func(t *testing.T) {
+			mr, client := setupTestRedis(t)
+			defer mr.Close()
+			defer client.Close()
+
+			cfg := &config.LimiterConfig{MaxRequestsPerIP: limit, BlockDurationIPSeconds: 60, Algorithm: config.AlgorithmLeakyBucket}
+			rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+			ctx := context.Background()
+			start := time.UnixMilli(1_000_000_000)
+
+			admitted := make(map[time.Duration]int) // por segundo
+			total := 0
+			for _, at := range pattern() {
+				decision, err := rl.allowAt(ctx, "192.168.9.1", false, start.Add(at))
+				require.NoError(t, err)
+				if decision.Allowed {
+					admitted[at.Truncate(time.Second)]++
+					total++
+				} else {
+					assert.Positive(t, decision.RetryAfter)
+				}
+			}
+
+			// No total, o ritmo de vazamento mais, no máximo, a capacidade inicial do bucket
+			expected := int(duration/time.Second) * limit
+			assert.GreaterOrEqual(t, total, expected-limit)
+			assert.LessOrEqual(t, total, expected+limit)
+			for second, n := range admitted {
+				assert.LessOrEqual(t, n, 2*limit, "Segundo %s acima do ritmo de vazamento mais a capacidade", second)
+			}
+			assert.False(t, mr.Exists("blocked_ip_192.168.9.1"), "O leaky bucket não deveria bloquear")
+		})
+	}
+}
+
+// Test_RateLimiter_LeakyBucket_Capacity verifica que uma capacidade menor suaviza as rajadas
+func Test_RateLimiter_LeakyBucket_Capacity(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 10, Algorithm: config.AlgorithmLeakyBucket, LeakyBucketCapacity: 2}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+	now := time.UnixMilli(1_000_000_000)
+
+	for i := 0; i < 2; i++ {
+		decision, err := rl.allowAt(ctx, "192.168.9.2", false, now)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 1-i, decision.Remaining)
+	}
+	decision, err := rl.allowAt(ctx, "192.168.9.2", false, now)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed, "A rajada deveria ser contida pela capacidade")
+	assert.Equal(t, 100*time.Millisecond, decision.RetryAfter, "Uma requisição vaza a cada 100ms")
+
+	decision, err = rl.allowAt(ctx, "192.168.9.2", false, now.Add(100*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+// Test_RateLimiter_LeakyBucket_FractionalRemaining verifica a cota fracionária no meio do vazamento
+func Test_RateLimiter_LeakyBucket_FractionalRemaining(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	// Capacidade 2, uma requisição vaza a cada 100ms
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 10, Algorithm: config.AlgorithmLeakyBucket, LeakyBucketCapacity: 2}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+	now := time.UnixMilli(1_000_000_000)
+
+	decision, err := rl.allowAt(ctx, "192.168.9.3", false, now)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, decision.RemainingFloat, 0.001)
+
+	// 25ms depois, um quarto da vaga usada voltou
+	decision, err = rl.allowAt(ctx, "192.168.9.3", false, now.Add(25*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+	assert.InDelta(t, 0.25, decision.RemainingFloat, 0.001)
+
+	// Rejeitada com parte da próxima vaga já liberada
+	decision, err = rl.allowAt(ctx, "192.168.9.3", false, now.Add(85*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 0, decision.Remaining)
+	assert.InDelta(t, 0.85, decision.RemainingFloat, 0.001)
+	assert.Equal(t, 15*time.Millisecond, decision.RetryAfter)
+}
@@ -0,0 +1,33 @@
+package rateLimiter
+
+import (
+	"context"
+	"time"
+)
+
+// limitTokenKey é o tipo da chave usada para guardar no contexto o token da requisição.
+type limitTokenKey struct{}
+
+// WithLimitToken associa ao contexto o token da requisição, usado para resolver os limites do
+// escopo de token no lugar do identificador do contador. O identificador pode trazer prefixos
+// do host, da classe ou do caminho, que separam os contadores mas não fazem parte do token
+// (ex.: um resolver que lê limites por token do Redis). Um token vazio volta a usar o
+// identificador.
+func WithLimitToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, limitTokenKey{}, token)
+}
+
+// LimitTokenFromContext retorna o token guardado no contexto ("" se não houver).
+func LimitTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(limitTokenKey{}).(string)
+	return token
+}
+
+// resolveLimit consulta o resolver de limites com o token do contexto, quando há um, no lugar
+// do identificador do contador.
+func (rl *RateLimiter) resolveLimit(ctx context.Context, identifier string, isToken bool) (int, time.Duration, time.Duration, error) {
+	if token := LimitTokenFromContext(ctx); isToken && token != "" {
+		identifier = token
+	}
+	return rl.resolver.ResolveLimit(ctx, identifier, isToken)
+}
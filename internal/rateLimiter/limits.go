@@ -0,0 +1,267 @@
+package rateLimiter
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+)
+
+// StaticLimitResolver resolve os limites a partir da configuração fixa do rate limiter.
+type StaticLimitResolver struct {
+	limiterConfig *config.LimiterConfig
+}
+
+// NewStaticLimitResolver cria um resolver baseado na configuração.
+func NewStaticLimitResolver(cfg *config.LimiterConfig) *StaticLimitResolver {
+	return &StaticLimitResolver{limiterConfig: cfg}
+}
+
+// ResolveLimit retorna os limites configurados para IP ou token, com a janela configurada
+// (padrão: 1 segundo).
+// Quando o contexto traz uma classe de requisição com limite próprio, ele substitui o geral.
+// Para tokens, a faixa do header de onde o token foi lido prevalece sobre os dois. No contador
+// de um sub-limite de rota, os limites da rota prevalecem sobre todos.
+func (s *StaticLimitResolver) ResolveLimit(ctx context.Context, _ string, isToken bool) (int, time.Duration, time.Duration, error) {
+	maxRequests, window, block, err := s.scopeLimit(ctx, isToken)
+	if route, ok := routeLimit(s.limiterConfig, RouteLimitFromContext(ctx)); ok {
+		if route.MaxRequests > 0 {
+			maxRequests = route.MaxRequests
+		}
+		if route.WindowSeconds > 0 {
+			window = time.Duration(route.WindowSeconds) * time.Second
+		}
+		if route.BlockDurationSeconds > 0 {
+			block = time.Duration(route.BlockDurationSeconds) * time.Second
+		}
+	}
+	return maxRequests, window, block, err
+}
+
+// scopeLimit retorna os limites do escopo (IP ou token), já com a classe e a faixa aplicadas.
+func (s *StaticLimitResolver) scopeLimit(ctx context.Context, isToken bool) (int, time.Duration, time.Duration, error) {
+	classLimit := s.limiterConfig.ClassLimits[RequestClassFromContext(ctx)]
+	if isToken {
+		maxRequests := s.limiterConfig.MaxRequestsPerToken
+		if classLimit.MaxRequestsPerToken > 0 {
+			maxRequests = classLimit.MaxRequestsPerToken
+		}
+		window, block := s.limiterConfig.WindowTokenSeconds, s.limiterConfig.BlockDurationTokenSeconds
+		if tier, ok := s.limiterConfig.TokenTiers[TokenTierFromContext(ctx)]; ok {
+			if tier.MaxRequests > 0 {
+				maxRequests = tier.MaxRequests
+			}
+			if tier.WindowSeconds > 0 {
+				window = tier.WindowSeconds
+			}
+			if tier.BlockDurationSeconds > 0 {
+				block = tier.BlockDurationSeconds
+			}
+		}
+		return maxRequests, windowOrDefault(window), time.Duration(block) * time.Second, nil
+	}
+	maxRequests := s.limiterConfig.MaxRequestsPerIP
+	if classLimit.MaxRequestsPerIP > 0 {
+		maxRequests = classLimit.MaxRequestsPerIP
+	}
+	return maxRequests, windowOrDefault(s.limiterConfig.WindowIPSeconds),
+		time.Duration(s.limiterConfig.BlockDurationIPSeconds) * time.Second, nil
+}
+
+// windowOrDefault converte a janela configurada em segundos (0 usa 1 segundo).
+func windowOrDefault(seconds int) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Second
+}
+
+// resolvedLimit é um limite em cache com o seu instante de expiração.
+type resolvedLimit struct {
+	key       string
+	max       int
+	window    time.Duration
+	block     time.Duration
+	expiresAt time.Time
+}
+
+// inflightLookup é uma consulta em andamento ao resolver decorado, compartilhada entre
+// as requisições concorrentes do mesmo identificador.
+type inflightLookup struct {
+	done  chan struct{}
+	entry resolvedLimit
+	err   error
+}
+
+// CachingLimitResolver mantém em memória os limites de outro resolver, com TTL e
+// tamanho máximo (as entradas menos usadas recentemente são descartadas primeiro).
+// É seguro para uso concorrente: consultas simultâneas ao mesmo identificador
+// resultam em uma única chamada ao resolver decorado.
+type CachingLimitResolver struct {
+	next    db.LimitResolver
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	inflight map[string]*inflightLookup
+}
+
+// NewCachingLimitResolver cria um cache com o TTL e o tamanho máximo informados em volta
+// do resolver. Um maxSize menor ou igual a zero não limita o número de entradas.
+func NewCachingLimitResolver(next db.LimitResolver, ttl time.Duration, maxSize int) *CachingLimitResolver {
+	return &CachingLimitResolver{
+		next:     next,
+		ttl:      ttl,
+		maxSize:  maxSize,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[string]*inflightLookup),
+	}
+}
+
+// ResolveLimit retorna o limite em cache ou consulta o resolver decorado.
+func (c *CachingLimitResolver) ResolveLimit(ctx context.Context, identifier string, isToken bool) (int, time.Duration, time.Duration, error) {
+	key := cacheKey(identifier, isToken)
+	if tier := TokenTierFromContext(ctx); isToken && tier != "" {
+		// O mesmo token lido de headers de faixas diferentes tem limites diferentes
+		key = tier + ":" + key
+	}
+	if class := RequestClassFromContext(ctx); class != "" {
+		// Cada classe de requisição pode ter limites próprios
+		key = "class:" + class + ":" + key
+	}
+	if route := RouteLimitFromContext(ctx); route != "" {
+		// O contador da rota tem os limites do sub-limite, não os do escopo
+		key = "route:" + route + ":" + key
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*resolvedLimit)
+		if c.now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.max, entry.window, entry.block, nil
+		}
+		c.removeElement(elem)
+	}
+
+	if lookup, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-lookup.done
+		return lookup.entry.max, lookup.entry.window, lookup.entry.block, lookup.err
+	}
+
+	lookup := &inflightLookup{done: make(chan struct{})}
+	c.inflight[key] = lookup
+	c.mu.Unlock()
+
+	max, window, block, err := c.next.ResolveLimit(ctx, identifier, isToken)
+	lookup.entry = resolvedLimit{key: key, max: max, window: window, block: block, expiresAt: c.now().Add(c.ttl)}
+	lookup.err = err
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if err == nil {
+		c.add(&lookup.entry)
+	}
+	c.mu.Unlock()
+	close(lookup.done)
+
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return max, window, block, nil
+}
+
+// Preload resolve e armazena em cache os limites dos tokens informados, com a faixa e a classe
+// do contexto, como nas requisições. Para cobrir todas as entradas que o middleware consulta,
+// chame-o com cada contexto de PreloadContexts.
+func (c *CachingLimitResolver) Preload(ctx context.Context, tokens []string) error {
+	for _, token := range tokens {
+		if _, _, _, err := c.ResolveLimit(ctx, token, true); err != nil {
+			return fmt.Errorf("erro ao pré-carregar limite do token %s: %w", token, err)
+		}
+	}
+	return nil
+}
+
+// PreloadContexts retorna os contextos com que o middleware resolve os limites dos tokens na
+// configuração informada: um para cada combinação das faixas de TokenSources com as classes de
+// SplitReadWrite e de PreflightPolicy "separate". Os sub-limites de rota não são
+// pré-carregados, já que não usam os limites por token.
+func PreloadContexts(ctx context.Context, cfg *config.LimiterConfig) []context.Context {
+	tiers := []string{""}
+	if len(cfg.TokenSources) > 0 {
+		tiers = tiers[:0]
+		seen := make(map[string]bool)
+		for _, source := range cfg.TokenSources {
+			if !seen[source.Tier] {
+				seen[source.Tier] = true
+				tiers = append(tiers, source.Tier)
+			}
+		}
+	}
+
+	classes := []string{""}
+	if cfg.SplitReadWrite {
+		classes = []string{config.ClassRead, config.ClassWrite}
+	}
+	if cfg.PreflightPolicy == config.PreflightSeparate {
+		classes = append(classes, config.ClassPreflight)
+	}
+
+	contexts := make([]context.Context, 0, len(tiers)*len(classes))
+	for _, tier := range tiers {
+		for _, class := range classes {
+			preloadCtx := ctx
+			if tier != "" {
+				preloadCtx = WithTokenTier(preloadCtx, tier)
+			}
+			if class != "" {
+				preloadCtx = WithRequestClass(preloadCtx, class)
+			}
+			contexts = append(contexts, preloadCtx)
+		}
+	}
+	return contexts
+}
+
+// Len retorna o número de entradas em cache.
+func (c *CachingLimitResolver) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// add insere uma entrada e descarta as menos usadas se o tamanho máximo for excedido.
+// Deve ser chamado com o lock.
+func (c *CachingLimitResolver) add(entry *resolvedLimit) {
+	entryCopy := *entry
+	c.entries[entry.key] = c.lru.PushFront(&entryCopy)
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// removeElement remove uma entrada do cache. Deve ser chamado com o lock.
+func (c *CachingLimitResolver) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*resolvedLimit).key)
+}
+
+// cacheKey separa IPs e tokens com o mesmo valor.
+func cacheKey(identifier string, isToken bool) string {
+	if isToken {
+		return "token_" + identifier
+	}
+	return "ip_" + identifier
+}
@@ -0,0 +1,256 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// countingResolver conta quantas vezes foi consultado, por identificador
+type countingResolver struct {
+	mu      sync.Mutex
+	calls   int
+	perKey  map[string]int
+	max     int
+	latency time.Duration
+}
+
+func (c *countingResolver) ResolveLimit(ctx context.Context, identifier string, isToken bool) (int, time.Duration, time.Duration, error) {
+	c.mu.Lock()
+	c.calls++
+	if c.perKey == nil {
+		c.perKey = make(map[string]int)
+	}
+	c.perKey[identifier]++
+	c.mu.Unlock()
+
+	time.Sleep(c.latency)
+	return c.max, time.Second, time.Minute, nil
+}
+
+// Test_RateLimiter_DynamicTokenLimits verifica que tokens diferentes recebem limites diferentes do Redis
+func Test_RateLimiter_DynamicTokenLimits(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	mr.HSet("token_limits", "premium", "8/1s/30s")
+	mr.HSet("token_limits", "basic", "2/1s/90s")
+
+	rl := createTestRateLimiterWithConfig(client, 5, 5, 60, 60)
+	resolver := redisStore.NewRedisLimitResolver(client, "token_limits", NewStaticLimitResolver(rl.GetConfig()))
+	rl = NewRateLimiter(rl.GetConfig(), redisStore.NewRedisStore(client), WithLimitResolver(resolver))
+	ctx := context.Background()
+
+	cases := []struct {
+		token string
+		max   int
+		block time.Duration
+	}{
+		{token: "premium", max: 8, block: 30 * time.Second},
+		{token: "basic", max: 2, block: 90 * time.Second},
+		{token: "unknown", max: 5, block: 60 * time.Second}, // usa o limite da configuração
+	}
+
+	for _, c := range cases {
+		for i := 0; i < c.max; i++ {
+			allowed, err := rl.Allow(ctx, c.token, true)
+			require.NoError(t, err)
+			assert.True(t, allowed, "Requisição %d do token %s deveria ser permitida", i+1, c.token)
+		}
+
+		allowed, err := rl.Allow(ctx, c.token, true)
+		require.NoError(t, err)
+		assert.False(t, allowed, "Token %s deveria ser bloqueado após %d requisições", c.token, c.max)
+		assert.Equal(t, c.block, mr.TTL("blocked_token_"+c.token), "Bloqueio do token %s com a duração resolvida", c.token)
+	}
+}
+
+// Test_RateLimiter_DynamicTokenLimits_Invalid verifica que um limite mal formado gera erro claro
+func Test_RateLimiter_DynamicTokenLimits_Invalid(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	mr.HSet("token_limits", "broken", "muitas")
+
+	rl := createTestRateLimiterWithConfig(client, 5, 5, 60, 60)
+	resolver := redisStore.NewRedisLimitResolver(client, "token_limits", NewStaticLimitResolver(rl.GetConfig()))
+	rl = NewRateLimiter(rl.GetConfig(), redisStore.NewRedisStore(client), WithLimitResolver(resolver))
+
+	_, err := rl.Allow(context.Background(), "broken", true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "erro ao resolver limite")
+}
+
+// Test_CachingLimitResolver_TTL verifica que o resolver decorado é consultado uma vez por TTL
+func Test_CachingLimitResolver_TTL(t *testing.T) {
+	next := &countingResolver{max: 7}
+	cache := NewCachingLimitResolver(next, 10*time.Second, 0)
+	now := time.Unix(1000, 0)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		max, _, _, err := cache.ResolveLimit(ctx, "tok", true)
+		require.NoError(t, err)
+		assert.Equal(t, 7, max)
+	}
+	assert.Equal(t, 1, next.calls, "O resolver deveria ser consultado uma única vez dentro do TTL")
+
+	// IP e token com o mesmo valor não compartilham a entrada
+	_, _, _, _ = cache.ResolveLimit(ctx, "tok", false)
+	assert.Equal(t, 2, next.calls)
+
+	// Após o TTL, a entrada é renovada
+	now = now.Add(11 * time.Second)
+	_, _, _, _ = cache.ResolveLimit(ctx, "tok", true)
+	assert.Equal(t, 3, next.calls, "A entrada deveria expirar após o TTL")
+}
+
+// Test_CachingLimitResolver_MaxSize verifica que as entradas menos usadas são descartadas
+func Test_CachingLimitResolver_MaxSize(t *testing.T) {
+	next := &countingResolver{max: 3}
+	cache := NewCachingLimitResolver(next, time.Minute, 2)
+	ctx := context.Background()
+
+	_, _, _, _ = cache.ResolveLimit(ctx, "a", true)
+	_, _, _, _ = cache.ResolveLimit(ctx, "b", true)
+	_, _, _, _ = cache.ResolveLimit(ctx, "a", true) // "a" passa a ser o mais recente
+	_, _, _, _ = cache.ResolveLimit(ctx, "c", true) // descarta "b"
+
+	assert.Equal(t, 2, cache.Len(), "O cache não deveria passar do tamanho máximo")
+	assert.Equal(t, 3, next.calls)
+
+	_, _, _, _ = cache.ResolveLimit(ctx, "a", true)
+	assert.Equal(t, 3, next.calls, "A entrada usada recentemente deveria continuar em cache")
+
+	_, _, _, _ = cache.ResolveLimit(ctx, "b", true)
+	assert.Equal(t, 4, next.calls, "A entrada menos usada deveria ter sido descartada")
+}
+
+// Test_CachingLimitResolver_Concurrent verifica que consultas concorrentes geram uma chamada por identificador
+func Test_CachingLimitResolver_Concurrent(t *testing.T) {
+	next := &countingResolver{max: 3, latency: 20 * time.Millisecond}
+	cache := NewCachingLimitResolver(next, time.Minute, 100)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			max, _, _, err := cache.ResolveLimit(ctx, fmt.Sprintf("tok-%d", i%5), true)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, max)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, 1, next.perKey[fmt.Sprintf("tok-%d", i)],
+			"O resolver deveria ser consultado uma vez por identificador dentro do TTL")
+	}
+}
+
+// Test_CachingLimitResolver_Preload verifica que tokens pré-carregados não consultam o backend
+func Test_CachingLimitResolver_Preload(t *testing.T) {
+	next := &countingResolver{max: 9}
+	cache := NewCachingLimitResolver(next, time.Minute, 100)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Preload(ctx, []string{"tok-a", "tok-b", "tok-c"}))
+	assert.Equal(t, 3, next.calls)
+
+	for _, token := range []string{"tok-a", "tok-b", "tok-c"} {
+		max, _, _, err := cache.ResolveLimit(ctx, token, true)
+		require.NoError(t, err)
+		assert.Equal(t, 9, max)
+	}
+	assert.Equal(t, 3, next.calls, "Tokens pré-carregados não deveriam consultar o backend")
+}
+
+// Test_StaticLimitResolver_ClassLimits verifica que a classe do contexto aplica o limite próprio da classe
+func Test_StaticLimitResolver_ClassLimits(t *testing.T) {
+	resolver := NewStaticLimitResolver(&config.LimiterConfig{
+		MaxRequestsPerIP:    5,
+		MaxRequestsPerToken: 10,
+		ClassLimits: map[string]config.ClassLimit{
+			config.ClassWrite: {MaxRequestsPerIP: 1, MaxRequestsPerToken: 2},
+		},
+	})
+
+	maxRequests, _, _, err := resolver.ResolveLimit(context.Background(), "ip", false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, maxRequests)
+
+	maxRequests, _, _, err = resolver.ResolveLimit(WithRequestClass(context.Background(), config.ClassWrite), "ip", false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, maxRequests)
+
+	maxRequests, _, _, err = resolver.ResolveLimit(WithRequestClass(context.Background(), config.ClassWrite), "tok", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, maxRequests)
+
+	// Classe sem limite próprio usa o limite geral
+	maxRequests, _, _, err = resolver.ResolveLimit(WithRequestClass(context.Background(), config.ClassRead), "tok", true)
+	require.NoError(t, err)
+	assert.Equal(t, 10, maxRequests)
+}
+
+// Test_StaticLimitResolver_TokenTiers verifica que a faixa do contexto aplica os limites próprios aos tokens
+func Test_StaticLimitResolver_TokenTiers(t *testing.T) {
+	resolver := NewStaticLimitResolver(&config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationTokenSeconds: 60,
+		ClassLimits: map[string]config.ClassLimit{
+			config.ClassWrite: {MaxRequestsPerToken: 2},
+		},
+		TokenTiers: map[string]config.TokenTier{
+			"partner": {MaxRequests: 100, WindowSeconds: 10},
+			"premium": {BlockDurationSeconds: 5},
+		},
+	})
+
+	ctx := WithTokenTier(context.Background(), "partner")
+	maxRequests, window, block, err := resolver.ResolveLimit(ctx, "tok", true)
+	require.NoError(t, err)
+	assert.Equal(t, 100, maxRequests)
+	assert.Equal(t, 10*time.Second, window)
+	assert.Equal(t, time.Minute, block)
+
+	// A faixa prevalece sobre a classe, e os valores zero da faixa usam os da classe ou os gerais
+	maxRequests, _, _, err = resolver.ResolveLimit(WithRequestClass(ctx, config.ClassWrite), "tok", true)
+	require.NoError(t, err)
+	assert.Equal(t, 100, maxRequests)
+	maxRequests, window, block, err = resolver.ResolveLimit(WithTokenTier(WithRequestClass(context.Background(), config.ClassWrite), "premium"), "tok", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, maxRequests)
+	assert.Equal(t, time.Second, window)
+	assert.Equal(t, 5*time.Second, block)
+
+	// A faixa não se aplica aos IPs
+	maxRequests, _, _, err = resolver.ResolveLimit(ctx, "ip", false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, maxRequests)
+}
+
+// Test_CachingLimitResolver_TokenTiers verifica que o mesmo token em faixas diferentes não compartilha a entrada
+func Test_CachingLimitResolver_TokenTiers(t *testing.T) {
+	next := &countingResolver{max: 7}
+	cache := NewCachingLimitResolver(next, time.Minute, 0)
+	for _, tier := range []string{"", "partner", "partner", "premium"} {
+		_, _, _, err := cache.ResolveLimit(WithTokenTier(context.Background(), tier), "tok", true)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, next.calls)
+}
@@ -0,0 +1,20 @@
+package rateLimiter
+
+import (
+	"log"
+	"time"
+)
+
+// capBlock limita a duração configurada de um bloqueio a MaxBlockDurationSeconds (0 desliga),
+// como proteção contra erros de configuração. O aviso é registrado uma vez para cada duração
+// acima do teto.
+func (rl *RateLimiter) capBlock(d time.Duration) time.Duration {
+	ceiling := time.Duration(rl.limiterConfig.MaxBlockDurationSeconds) * time.Second
+	if ceiling <= 0 || d <= ceiling {
+		return d
+	}
+	if _, warned := rl.cappedBlocks.LoadOrStore(d, struct{}{}); !warned {
+		log.Printf("Aviso: duração de bloqueio configurada (%s) acima de MAX_BLOCK_DURATION; usando %s", d, ceiling)
+	}
+	return ceiling
+}
@@ -0,0 +1,54 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_MaxBlockDuration verifica que bloqueios configurados acima do teto, inclusive
+// nas faixas de severidade, são limitados a ele
+func Test_RateLimiter_MaxBlockDuration(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:        2,
+		BlockDurationIPSeconds:  86400, // um dia, por engano
+		WindowIPSeconds:         10,
+		MaxBlockDurationSeconds: 3600,
+		BlockSeverity:           []config.BlockSeverityTier{{MinRatio: 5, Duration: 7 * 24 * time.Hour}},
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		ip       string
+		requests int
+	}{
+		{"192.168.6.1", 3},  // bloqueio base
+		{"192.168.6.2", 10}, // faixa de severidade
+	} {
+		var decision *Decision
+		for i := 0; i < tc.requests; i++ {
+			var err error
+			decision, err = rl.AllowDecision(ctx, tc.ip, false)
+			require.NoError(t, err)
+		}
+		assert.False(t, decision.Allowed, tc.ip)
+		assert.Equal(t, time.Hour, mr.TTL("blocked_ip_"+tc.ip), tc.ip)
+		assert.InDelta(t, float64(time.Hour), float64(decision.RetryAfter), float64(time.Second), tc.ip)
+	}
+
+	// Abaixo do teto, a duração configurada continua valendo
+	cfg.BlockDurationIPSeconds = 60
+	assert.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.6.3", 5))
+	assert.Equal(t, time.Minute, mr.TTL("blocked_ip_192.168.6.3"))
+}
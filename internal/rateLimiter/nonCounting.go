@@ -0,0 +1,71 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// NonCountingLimiter é implementado por rate limiters capazes de decidir uma requisição sem
+// contá-la, apenas respeitando um bloqueio ativo (ver config.LimiterConfig.NonCountingMethodsIP).
+type NonCountingLimiter interface {
+	AllowWithoutCountDecision(ctx context.Context, identifier string, isToken bool) (*Decision, error)
+}
+
+// AllowWithoutCountDecision decide a requisição sem incrementar o contador: ela é rejeitada
+// apenas se o identificador estiver bloqueado. Remaining é lido do contador da janela atual
+// (na janela deslizante, só o bucket atual; no leaky bucket, não é lido e fica igual ao limite).
+func (rl *RateLimiter) AllowWithoutCountDecision(ctx context.Context, identifier string, isToken bool) (*Decision, error) {
+	if !rl.Enabled() {
+		return disabledDecision(identifier, isToken), nil
+	}
+	decision, err := rl.peekDecision(ctx, identifier, isToken)
+	if err != nil {
+		return rl.onStoreError(err, identifier, isToken)
+	}
+	return decision, nil
+}
+
+// peekDecision monta a decisão de AllowWithoutCountDecision apenas com leituras.
+func (rl *RateLimiter) peekDecision(ctx context.Context, identifier string, isToken bool) (*Decision, error) {
+	now := rl.clock.Now()
+	maxRequests, window, blockDuration, err := rl.resolveLimit(ctx, identifier, isToken)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao resolver limite: %w", err)
+	}
+	maxRequests = rl.boostedLimit(maxRequests, now)
+	decision := &Decision{
+		Allowed:        true,
+		Identifier:     identifier,
+		IsToken:        isToken,
+		Limit:          maxRequests,
+		Remaining:      maxRequests,
+		RemainingFloat: float64(maxRequests),
+		Window:         window,
+	}
+
+	key := identifierKey(identifier, isToken)
+	block, err := rl.store.BlockInfo(ctx, rl.scopedKey("blocked_"+key, isToken))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao verificar se está bloqueado: %w", err)
+	}
+	if block != nil {
+		decision.Allowed, decision.Remaining, decision.RemainingFloat = false, 0, 0
+		decision.RetryAfter = rl.capBlock(blockDuration)
+		if !block.ExpiresAt.IsZero() {
+			decision.RetryAfter = max(block.ExpiresAt.Sub(now), 0)
+		}
+		return decision, nil
+	}
+
+	if rl.limiterConfig.Algorithm != config.AlgorithmLeakyBucket {
+		current, err := rl.store.Count(ctx, rl.windowCounterKeys(rl.scopedKey(key, isToken), window, now)[0])
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler o contador: %w", err)
+		}
+		decision.Remaining = max(maxRequests-int(current), 0)
+		decision.RemainingFloat = float64(decision.Remaining)
+	}
+	return decision, nil
+}
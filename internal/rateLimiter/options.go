@@ -0,0 +1,54 @@
+package rateLimiter
+
+import (
+	"log/slog"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/internal/clock"
+	"rateLimiter/pkg/metrics"
+)
+
+// Option configura dependências opcionais do RateLimiter.
+type Option func(*RateLimiter)
+
+// WithLimitResolver define de onde vêm os limites de cada identificador.
+// Sem esta opção, os limites da configuração são usados.
+func WithLimitResolver(resolver db.LimitResolver) Option {
+	return func(rl *RateLimiter) {
+		rl.resolver = resolver
+	}
+}
+
+// WithClock define a fonte de tempo usada pelo rate limiter (útil em testes).
+func WithClock(c clock.Clock) Option {
+	return func(rl *RateLimiter) {
+		rl.clock = c
+	}
+}
+
+// WithLogger define o logger usado pelos registros de depuração (DebugLogging). Sem esta
+// opção, slog.Default() é usado.
+func WithLogger(logger *slog.Logger) Option {
+	return func(rl *RateLimiter) {
+		rl.logger = logger
+	}
+}
+
+// WithSnapshotter define o store usado por Export e Import. É necessário quando o store do
+// rate limiter é um decorador (ex.: com métricas ou circuit breaker) sobre um store que
+// implementa db.Snapshotter; sem esta opção, o próprio store é usado, se o implementar.
+func WithSnapshotter(s db.Snapshotter) Option {
+	return func(rl *RateLimiter) {
+		rl.snapshots = s
+	}
+}
+
+// WithMetrics registra, no histograma ratelimiter_store_calls_per_decision (por resultado),
+// quantas operações do store cada decisão fez. As operações são contadas pelo db.ObservedStore,
+// que precisa estar entre os decoradores do store, e o histograma exige um
+// metrics.ValueRecorder, como o metrics.Registry.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(rl *RateLimiter) {
+		rl.recorder = recorder
+	}
+}
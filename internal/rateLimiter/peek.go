@@ -0,0 +1,62 @@
+package rateLimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// ErrPeekUnsupported indica que o algoritmo configurado não permite prever a decisão sem contar.
+var ErrPeekUnsupported = errors.New("o leaky bucket não permite prever a decisão sem contar a requisição")
+
+// WouldAllow informa se a próxima requisição do identificador seria permitida, sem contá-la:
+// compara a contagem atual com o limite e verifica o bloqueio, apenas com leituras. Serve para
+// o cliente decidir antes de um trabalho caro; sob concorrência, outra requisição pode consumir
+// a cota entre a consulta e a chamada a Allow. Com o rate limiting desligado, retorna true.
+func (rl *RateLimiter) WouldAllow(ctx context.Context, identifier string, isToken bool) (bool, error) {
+	if !rl.Enabled() {
+		return true, nil
+	}
+	if rl.limiterConfig.Algorithm == config.AlgorithmLeakyBucket {
+		return false, ErrPeekUnsupported
+	}
+
+	now := rl.clock.Now()
+	maxRequests, window, _, err := rl.resolveLimit(ctx, identifier, isToken)
+	if err != nil {
+		return false, fmt.Errorf("erro ao resolver limite: %w", err)
+	}
+	maxRequests = rl.boostedLimit(maxRequests, now)
+
+	key := identifierKey(identifier, isToken)
+	blocked, err := rl.store.IsBlocked(ctx, rl.scopedKey("blocked_"+key, isToken))
+	if err != nil {
+		return false, fmt.Errorf("erro ao verificar se está bloqueado: %w", err)
+	}
+	if blocked {
+		return false, nil
+	}
+
+	counters := rl.windowCounterKeys(rl.scopedKey(key, isToken), window, now)
+	current, err := rl.store.Count(ctx, counters[0])
+	if err != nil {
+		return false, fmt.Errorf("erro ao ler o contador: %w", err)
+	}
+	if rl.limiterConfig.Algorithm != config.AlgorithmSlidingWindow {
+		// As violações toleradas ainda são permitidas, como em countAt
+		return current+1 <= int64(maxRequests)+rl.toleratedViolations(), nil
+	}
+
+	// Na janela deslizante, a mesma estimativa do store: o bucket anterior pesa pela fração da
+	// janela que ainda se sobrepõe
+	previous, err := rl.store.Count(ctx, counters[1])
+	if err != nil {
+		return false, fmt.Errorf("erro ao ler o contador: %w", err)
+	}
+	windowMs := max(window.Milliseconds(), 1)
+	elapsed := now.UnixMilli() % windowMs
+	estimate := float64(previous)*float64(windowMs-elapsed)/float64(windowMs) + float64(current)
+	return estimate+1 <= float64(maxRequests), nil
+}
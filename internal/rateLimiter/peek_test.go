@@ -0,0 +1,111 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// Test_RateLimiter_WouldAllow verifica que a consulta não altera a contagem e acompanha as chamadas a Allow
+func Test_RateLimiter_WouldAllow(t *testing.T) {
+	for _, algorithm := range []string{config.AlgorithmFixedWindow, config.AlgorithmSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			mr, client := setupTestRedis(t)
+			defer mr.Close()
+			defer client.Close()
+
+			now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+			rl := NewRateLimiter(&config.LimiterConfig{
+				MaxRequestsPerIP:       3,
+				BlockDurationIPSeconds: 60,
+				WindowIPSeconds:        10,
+				Algorithm:              algorithm,
+			}, redisStore.NewRedisStore(client), WithClock(clock.NewFake(now)))
+			ctx := context.Background()
+
+			for i := 0; i < 3; i++ {
+				keys := mr.Keys()
+				for j := 0; j < 5; j++ {
+					ok, err := rl.WouldAllow(ctx, "192.168.20.1", false)
+					require.NoError(t, err)
+					assert.True(t, ok)
+				}
+				assert.Equal(t, keys, mr.Keys(), "A consulta não deveria gravar no store")
+
+				allowed, err := rl.Allow(ctx, "192.168.20.1", false)
+				require.NoError(t, err)
+				require.True(t, allowed)
+			}
+
+			// Com o limite atingido, a próxima seria rejeitada, e a consulta não conta
+			for j := 0; j < 5; j++ {
+				ok, err := rl.WouldAllow(ctx, "192.168.20.1", false)
+				require.NoError(t, err)
+				assert.False(t, ok)
+			}
+			assert.False(t, mr.Exists("blocked_ip_192.168.20.1"), "A consulta não deveria bloquear")
+
+			// Após a rejeição, o bloqueio mantém a consulta negativa
+			allowed, err := rl.Allow(ctx, "192.168.20.1", false)
+			require.NoError(t, err)
+			require.False(t, allowed)
+			ok, err := rl.WouldAllow(ctx, "192.168.20.1", false)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			// Outro identificador não é afetado
+			ok, err = rl.WouldAllow(ctx, "192.168.20.2", false)
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+// Test_RateLimiter_WouldAllow_Violations verifica que as violações toleradas continuam permitidas na consulta
+func Test_RateLimiter_WouldAllow_Violations(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := NewRateLimiter(&config.LimiterConfig{
+		MaxRequestsPerIP:              1,
+		BlockDurationIPSeconds:        60,
+		ConsecutiveViolationThreshold: 2,
+	}, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ok, err := rl.WouldAllow(ctx, "192.168.20.3", false)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		allowed, err := rl.Allow(ctx, "192.168.20.3", false)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	ok, err := rl.WouldAllow(ctx, "192.168.20.3", false)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// Test_RateLimiter_WouldAllow_LeakyBucket verifica o erro do algoritmo sem consulta
+func Test_RateLimiter_WouldAllow_LeakyBucket(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := NewRateLimiter(&config.LimiterConfig{MaxRequestsPerIP: 1, Algorithm: config.AlgorithmLeakyBucket}, redisStore.NewRedisStore(client))
+	_, err := rl.WouldAllow(context.Background(), "192.168.20.4", false)
+	require.ErrorIs(t, err, ErrPeekUnsupported)
+
+	rl.SetEnabled(false)
+	ok, err := rl.WouldAllow(context.Background(), "192.168.20.4", false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,77 @@
+package rateLimiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// defaultPreflightCoalesceWindow é por quanto tempo um preflight espera pela requisição real
+// quando PreflightCoalesceSeconds não está configurado.
+const defaultPreflightCoalesceWindow = 5 * time.Second
+
+// PreflightCoalescer é implementado por rate limiters que contam um preflight do CORS e a
+// requisição real que o segue como uma única requisição.
+type PreflightCoalescer interface {
+	AllowPreflightDecision(ctx context.Context, identifier string, isToken bool, key string) (*Decision, error)
+	AllowAfterPreflightDecision(ctx context.Context, identifier string, isToken bool, key string) (*Decision, error)
+}
+
+// AllowPreflightDecision contabiliza o preflight como AllowDecision e, se ele for permitido,
+// deixa a decisão pendente para a requisição real com a mesma chave (ex.: a origem e o método
+// pedido em Access-Control-Request-Method) durante a janela de coalescência.
+func (rl *RateLimiter) AllowPreflightDecision(ctx context.Context, identifier string, isToken bool, key string) (*Decision, error) {
+	decision, err := rl.AllowDecision(ctx, identifier, isToken)
+	if err != nil || key == "" || !decision.Allowed || decision.FailedOpen || decision.Disabled {
+		return decision, err
+	}
+
+	val, err := json.Marshal(decision)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar decisão: %w", err)
+	}
+	// O preflight já foi contabilizado; perder a pendência só faz a requisição real contar
+	_ = rl.store.Set(ctx, rl.preflightKey(identifier, isToken, key), val, rl.preflightCoalesceWindow())
+	return decision, nil
+}
+
+// AllowAfterPreflightDecision funciona como AllowDecision, mas, se houver um preflight pendente
+// com a mesma chave, consome a pendência e devolve a decisão do preflight sem contabilizar a
+// requisição de novo. Cada preflight cobre uma única requisição real; duas requisições
+// simultâneas com a mesma chave podem, raramente, aproveitar a mesma pendência.
+func (rl *RateLimiter) AllowAfterPreflightDecision(ctx context.Context, identifier string, isToken bool, key string) (*Decision, error) {
+	if key == "" || !rl.Enabled() {
+		return rl.AllowDecision(ctx, identifier, isToken)
+	}
+
+	pendingKey := rl.preflightKey(identifier, isToken, key)
+	pending, err := rl.store.Get(ctx, pendingKey)
+	if err != nil {
+		return rl.onStoreError(fmt.Errorf("erro ao ler o preflight pendente: %w", err), identifier, isToken)
+	}
+	if pending != nil {
+		if err := rl.store.Reset(ctx, pendingKey); err != nil {
+			return rl.onStoreError(fmt.Errorf("erro ao consumir o preflight pendente: %w", err), identifier, isToken)
+		}
+		decision := &Decision{}
+		if err := json.Unmarshal(pending, decision); err == nil {
+			return decision, nil
+		}
+		// Um valor corrompido não deve impedir a requisição: segue com uma nova decisão
+	}
+	return rl.AllowDecision(ctx, identifier, isToken)
+}
+
+// preflightKey retorna a chave do preflight pendente, separada por identificador.
+func (rl *RateLimiter) preflightKey(identifier string, isToken bool, key string) string {
+	return rl.scopedKey("preflight_"+identifierKey(identifier, isToken)+"_"+key, isToken)
+}
+
+// preflightCoalesceWindow retorna por quanto tempo um preflight fica pendente.
+func (rl *RateLimiter) preflightCoalesceWindow() time.Duration {
+	if rl.limiterConfig.PreflightCoalesceSeconds > 0 {
+		return time.Duration(rl.limiterConfig.PreflightCoalesceSeconds) * time.Second
+	}
+	return defaultPreflightCoalesceWindow
+}
@@ -3,15 +3,22 @@ package rateLimiter
 import (
 	"context"
 	"fmt"
+	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"rateLimiter/cmd/server/config"
 	"rateLimiter/infra/db"
+	"rateLimiter/internal/clock"
+	"rateLimiter/pkg/metrics"
 )
 
 // RateLimiterInterface define o contrato para implementações de rate limiter
 type RateLimiterInterface interface {
 	Allow(ctx context.Context, identifier string, isToken bool) (bool, error)
+	AllowDecision(ctx context.Context, identifier string, isToken bool) (*Decision, error)
 	GetConfig() *config.LimiterConfig
 }
 
@@ -19,65 +26,277 @@ type RateLimiterInterface interface {
 type RateLimiter struct {
 	limiterConfig *config.LimiterConfig
 	store         db.Store
+	resolver      db.LimitResolver
+	enabled       atomic.Bool
+	boost         atomic.Pointer[TemporaryBoost]
+	clock         clock.Clock
+	logger        *slog.Logger
+	snapshots     db.Snapshotter
+	recorder      metrics.Recorder
+	cappedBlocks  sync.Map // durações de bloqueio já avisadas por capBlock
+	startedAt     time.Time
 }
 
 // NewRateLimiter cria uma nova instância do RateLimiter.
-func NewRateLimiter(config *config.LimiterConfig, store db.Store) *RateLimiter {
-	return &RateLimiter{
+func NewRateLimiter(config *config.LimiterConfig, store db.Store, opts ...Option) *RateLimiter {
+	rl := &RateLimiter{
 		limiterConfig: config,
 		store:         store,
+		resolver:      NewStaticLimitResolver(config),
+		clock:         clock.Real{},
+		logger:        slog.Default(),
 	}
+	rl.enabled.Store(!config.Disabled)
+	if config.BoostMultiplier > 0 {
+		rl.SetBoost(TemporaryBoost{Multiplier: config.BoostMultiplier, Until: config.BoostUntil})
+	}
+	for _, opt := range opts {
+		opt(rl)
+	}
+	// O aquecimento conta a partir da criação, no relógio configurado
+	rl.startedAt = rl.clock.Now()
+	return rl
 }
 
 // GetConfig retorna a configuração do rate limiter.
 func (rl *RateLimiter) GetConfig() *config.LimiterConfig {
-
-	fmt.Printf("limiterConfig: %v\n", rl.limiterConfig)
 	return rl.limiterConfig
 }
 
+// Clock retorna a fonte de tempo do rate limiter (ver WithClock), para que o middleware use o
+// mesmo relógio das decisões.
+func (rl *RateLimiter) Clock() clock.Clock {
+	return rl.clock
+}
+
+// SetEnabled liga ou desliga o rate limiting em tempo de execução.
+func (rl *RateLimiter) SetEnabled(enabled bool) {
+	rl.enabled.Store(enabled)
+}
+
+// Enabled informa se o rate limiting está ligado.
+func (rl *RateLimiter) Enabled() bool {
+	return rl.enabled.Load()
+}
+
 // Allow verifica se uma requisição deve ser permitida.
+//
+// Com um limite N, as requisições 1..N dentro da janela são permitidas. A
+// requisição N+1 é a que excede o limite: ela é rejeitada, grava a chave de
+// bloqueio com a duração configurada e zera o contador. Enquanto o bloqueio
+// existir, as requisições seguintes são rejeitadas sem incrementar o contador.
+//
+// Se o store falhar e o modo de falha for "open", a requisição é permitida e o erro
+// apenas registrado em log; no modo "closed" o erro é devolvido ao chamador.
+// Com o rate limiting desligado, toda requisição é permitida sem acessar o store.
 func (rl *RateLimiter) Allow(ctx context.Context, identifier string, isToken bool) (bool, error) {
-	var maxRequests int
-	var blockDuration time.Duration
-	var keyPrefix string
-
-	if isToken {
-		maxRequests = rl.limiterConfig.MaxRequestsPerToken
-		blockDuration = time.Duration(rl.limiterConfig.BlockDurationTokenSeconds) * time.Second
-		keyPrefix = "token_"
-	} else {
-		maxRequests = rl.limiterConfig.MaxRequestsPerIP
-		blockDuration = time.Duration(rl.limiterConfig.BlockDurationIPSeconds) * time.Second
-		keyPrefix = "ip_"
+	decision, err := rl.AllowDecision(ctx, identifier, isToken)
+	if err != nil {
+		return false, err
 	}
+	return decision.Allowed, nil
+}
 
-	key := keyPrefix + identifier
-	blockedKey := "blocked_" + key
+// AllowDecision funciona como Allow, mas retorna o detalhamento da decisão
+// (limite, requisições restantes e tempo até o fim do bloqueio).
+func (rl *RateLimiter) AllowDecision(ctx context.Context, identifier string, isToken bool) (*Decision, error) {
+	if !rl.Enabled() {
+		return disabledDecision(identifier, isToken), nil
+	}
 
-	// Verifica se está bloqueado
-	isBlocked, err := rl.store.IsBlocked(ctx, blockedKey)
+	decision, err := rl.allowAt(ctx, identifier, isToken, rl.clock.Now())
 	if err != nil {
-		return false, fmt.Errorf("erro ao verificar se está bloqueado: %w", err)
+		return rl.onStoreError(err, identifier, isToken)
 	}
-	if isBlocked {
-		return false, nil // Bloqueado
+	return decision, nil
+}
+
+// disabledDecision é a decisão retornada quando o rate limiting está desligado.
+func disabledDecision(identifier string, isToken bool) *Decision {
+	return &Decision{Allowed: true, Identifier: identifier, IsToken: isToken, Disabled: true}
+}
+
+// onStoreError aplica o modo de falha configurado a um erro do store.
+func (rl *RateLimiter) onStoreError(err error, identifier string, isToken bool) (*Decision, error) {
+	if rl.limiterConfig.FailureMode == config.FailureModeOpen {
+		log.Printf("Store indisponível, permitindo requisição (modo de falha aberto): %v", err)
+		return &Decision{Allowed: true, Identifier: identifier, IsToken: isToken, FailedOpen: true}, nil
+	}
+	return nil, err
+}
+
+// allowAt contém a lógica de limitação propriamente dita, avaliada no instante now.
+// Receber o instante explicitamente permite testar os algoritmos que dependem do relógio
+// (janela deslizante, cotas de calendário, tempo restante de bloqueio) sem esperas.
+//
+// Na janela fixa e nas cotas de calendário, a verificação do bloqueio, a contagem e o
+// bloqueio ao exceder o limite acontecem em uma única operação atômica do store.
+func (rl *RateLimiter) allowAt(ctx context.Context, identifier string, isToken bool, now time.Time) (*Decision, error) {
+	decision, _, err := rl.allowWithGlobalAt(ctx, identifier, isToken, now, nil)
+	return decision, err
+}
+
+// allowWithGlobalAt funciona como allowAt e, quando global é informado, incrementa também o
+// contador global, retornando o seu valor. Na janela fixa e nas cotas de calendário, as duas
+// contagens acontecem na mesma operação do store.
+func (rl *RateLimiter) allowWithGlobalAt(ctx context.Context, identifier string, isToken bool, now time.Time, global *db.GlobalCount) (*Decision, int64, error) {
+	var calls *db.CallCounter
+	if rl.recorder != nil || rl.limiterConfig.DebugLogging {
+		ctx, calls = db.WithCallCounter(ctx)
 	}
 
-	count, err := rl.store.Increment(ctx, key, time.Second) // Janela de 1 segundo
+	rejectNew := false
+	if rl.limiterConfig.CardinalityMaxNew > 0 {
+		var err error
+		if identifier, rejectNew, err = rl.guardCardinality(ctx, identifier, isToken); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	maxRequests, window, blockDuration, err := rl.resolveLimit(ctx, identifier, isToken)
 	if err != nil {
-		return false, fmt.Errorf("erro ao incrementar contador: %w", err)
+		return nil, 0, fmt.Errorf("erro ao resolver limite: %w", err)
+	}
+	maxRequests = rl.boostedLimit(maxRequests, now)
+	blockDuration = rl.capBlock(blockDuration)
+
+	key := identifierKey(identifier, isToken)
+	keys := db.CountKeys{
+		Counter:  rl.scopedKey(key, isToken),
+		Block:    rl.scopedKey("blocked_"+key, isToken),
+		Offenses: rl.scopedKey("offenses_"+key, isToken),
+
+		KeepCounterOnBlock: rl.limiterConfig.KeepCounterOnBlock,
+		GraceOverage:       int64(rl.limiterConfig.GraceOverage),
+		SeverityTiers:      rl.severityTiers(maxRequests),
+	}
+	decision := &Decision{Identifier: identifier, IsToken: isToken, Limit: maxRequests, Window: window}
+	if rejectNew {
+		// Identificador novo com a proteção de cardinalidade ativa: rejeitado sem ser contado
+		decision.RetryAfter = rl.cardinalityWindow()
+		rl.observeStoreCalls(decision, calls)
+		return decision, 0, nil
+	}
+
+	// Nas janelas alinhadas e nas cotas de calendário, a renovação do contador é conhecida
+	counterWindow, resetAfter := window, time.Duration(0)
+	switch algorithm := rl.limiterConfig.Algorithm; {
+	case algorithm == config.AlgorithmCalendarWindow:
+		// O contador é separado por período e expira na virada, quando a cota é renovada
+		period, end := rl.calendarPeriod(now)
+		keys.Counter += ":" + period
+		counterWindow = end.Sub(now)
+		blockDuration = counterWindow
+		resetAfter = counterWindow
+	case rl.limiterConfig.AlignWindows && (algorithm == "" || algorithm == config.AlgorithmFixedWindow):
+		// Com janelas alinhadas, o contador é separado por janela do relógio e expira na virada
+		bucket, end := alignedWindow(now, window)
+		keys.Counter += ":" + bucket
+		counterWindow = end.Sub(now)
+		resetAfter = counterWindow
 	}
 
-	if count > int64(maxRequests) {
-		err = rl.store.Block(ctx, blockedKey, blockDuration)
-		if err != nil {
-			return false, fmt.Errorf("erro ao bloquear: %w", err)
+	decision, globalCount, err := rl.countAt(ctx, decision, keys, counterWindow, blockDuration, now, global)
+	if err == nil {
+		decision.ResetAfter = resetAfter
+		rl.checkSoftLimit(decision)
+		rl.observeStoreCalls(decision, calls)
+		if rl.limiterConfig.DebugLogging {
+			rl.logDecision(ctx, keys, decision, calls.Count())
 		}
-		// Limpa o contador de requisições após bloquear para evitar que continue incrementando desnecessariamente
-		_ = rl.store.Reset(ctx, key)
-		return false, nil // Limite excedido
 	}
+	return decision, globalCount, err
+}
+
+// countAt aplica o algoritmo configurado às chaves do identificador e preenche a decisão.
+func (rl *RateLimiter) countAt(ctx context.Context, decision *Decision, keys db.CountKeys, window, blockDuration time.Duration, now time.Time, global *db.GlobalCount) (*Decision, int64, error) {
+	var err error
+	if algorithm := rl.limiterConfig.Algorithm; algorithm == config.AlgorithmSlidingWindow || algorithm == config.AlgorithmLeakyBucket {
+		// A janela deslizante e o leaky bucket não têm operação combinada: o contador global é
+		// incrementado à parte
+		var globalCount int64
+		if global != nil {
+			if globalCount, err = rl.store.IncrementBy(ctx, global.Key, 1, global.Window); err != nil {
+				return nil, 0, fmt.Errorf("erro ao contabilizar limite global: %w", err)
+			}
+			if global.Limit > 0 && globalCount > global.Limit {
+				// Acima do teto global, o identificador não é contado: só o bloqueio é lido
+				block, err := rl.store.BlockInfo(ctx, keys.Block)
+				if err != nil {
+					return nil, 0, fmt.Errorf("erro ao verificar se está bloqueado: %w", err)
+				}
+				if block != nil && !block.ExpiresAt.IsZero() {
+					decision.RetryAfter = max(block.ExpiresAt.Sub(now), 0)
+				} else if block != nil {
+					decision.RetryAfter = blockDuration
+				}
+				return decision, globalCount, nil
+			}
+		}
+		if algorithm == config.AlgorithmLeakyBucket {
+			decision, err := rl.allowLeakyAt(ctx, decision, keys, window, now)
+			return decision, globalCount, err
+		}
+		if rl.limiterConfig.StrictLimits {
+			decision, err := rl.allowSlidingStrictAt(ctx, decision, keys, window, blockDuration, now)
+			return decision, globalCount, err
+		}
+		decision, err := rl.allowSlidingAt(ctx, decision, keys, window, blockDuration, now)
+		return decision, globalCount, err
+	}
+
+	// As violações toleradas entram no limite do store: o bloqueio só vem com a última delas
+	tolerated := rl.toleratedViolations()
+	limit := int64(decision.Limit) + tolerated
+
+	var allowed bool
+	var remaining, globalCount int64
+	var retryAfter time.Duration
+	if global != nil {
+		allowed, remaining, retryAfter, globalCount, err = rl.store.CheckAndCountWithGlobal(ctx, keys, limit, window, blockDuration, now, *global)
+	} else {
+		allowed, remaining, retryAfter, err = rl.store.CheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("erro ao contabilizar requisição: %w", err)
+	}
+
+	if allowed && remaining < tolerated {
+		// Além do limite, mas dentro das violações toleradas: a requisição segue sem cota
+		decision.SoftAllowed = true
+	}
+	remaining = max(remaining-tolerated, 0)
+	decision.Allowed = allowed
+	decision.Remaining = int(remaining)
+	decision.RemainingFloat = float64(remaining)
+	decision.RetryAfter = retryAfter
+	return decision, globalCount, nil
+}
+
+// identifierKey retorna a chave base do identificador ("ip_" ou "token_" seguido do
+// identificador), da qual derivam o contador, o bloqueio e as infrações.
+func identifierKey(identifier string, isToken bool) string {
+	if isToken {
+		return "token_" + identifier
+	}
+	return "ip_" + identifier
+}
+
+// storeKey aplica o KeyPrefix e a região configurados a uma chave do store, separando as
+// chaves de instâncias e de regiões do rate limiter que compartilham o mesmo Redis.
+func (rl *RateLimiter) storeKey(key string) string {
+	return rl.limiterConfig.StoreKeyPrefix() + key
+}
+
+// scopePrefix retorna o prefixo configurado para as chaves do escopo (IPKeyPrefix ou TokenKeyPrefix).
+func (rl *RateLimiter) scopePrefix(isToken bool) string {
+	if isToken {
+		return rl.limiterConfig.TokenKeyPrefix
+	}
+	return rl.limiterConfig.IPKeyPrefix
+}
 
-	return true, nil // Permitido
+// scopedKey aplica o KeyPrefix e o prefixo do escopo a uma chave de identificador.
+func (rl *RateLimiter) scopedKey(key string, isToken bool) string {
+	return rl.storeKey(rl.scopePrefix(isToken) + key)
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
 	redisStore "rateLimiter/infra/db/redis"
 )
 
@@ -113,9 +114,10 @@ func Test_RateLimiter_IP_Limiting(t *testing.T) {
 
 	// Verificar se há uma chave de bloqueio no Redis
 	blockedKey := "blocked_ip_" + testIP
-	val, err := client.Get(ctx, blockedKey).Result()
+	info, err := redisStore.NewRedisStore(client).BlockInfo(ctx, blockedKey)
 	assert.NoError(t, err)
-	assert.Equal(t, "blocked", val)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
 
 	// Verificar o TTL da chave de bloqueio
 	ttl, err := client.TTL(ctx, blockedKey).Result()
@@ -156,9 +158,10 @@ func Test_RateLimiter_Token_Limiting(t *testing.T) {
 
 	// Verificar se há uma chave de bloqueio no Redis
 	blockedKey := "blocked_token_" + testToken
-	val, err := client.Get(ctx, blockedKey).Result()
+	info, err := redisStore.NewRedisStore(client).BlockInfo(ctx, blockedKey)
 	assert.NoError(t, err)
-	assert.Equal(t, "blocked", val)
+	require.NotNil(t, info)
+	assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
 
 	// Verificar o TTL da chave de bloqueio para token
 	ttl, err := client.TTL(ctx, blockedKey).Result()
@@ -212,8 +215,8 @@ func Test_RateLimiter_DifferentIdentifiers(t *testing.T) {
 	defer client.Close()
 
 	// Obter configurações do ambiente ou usar valores padrão
-	maxIP := getEnvInt("MAX_REQUESTS_PER_IP", 3)
-	maxToken := getEnvInt("MAX_REQUESTS_PER_TOKEN", 3)
+	maxIP := getEnvInt("MAX_REQUESTS_PER_IP", 5)
+	maxToken := getEnvInt("MAX_REQUESTS_PER_TOKEN", 10)
 
 	// Criar rate limiter com configurações do ambiente
 	rl := createTestRateLimiter(client)
@@ -384,3 +387,338 @@ func Test_RateLimiter_Error_Handling(t *testing.T) {
 			"A mensagem de erro deve explicar qual operação falhou")
 	}
 }
+
+// assertBoundary verifica o comportamento exatamente no limite: a N-ésima requisição é permitida,
+// a (N+1)-ésima dispara o bloqueio com o TTL configurado e o contador é zerado nesse momento
+func assertBoundary(t *testing.T, mr *miniredis.Miniredis, rl *RateLimiter, identifier string, isToken bool, max, blockSeconds int, keyPrefix string) {
+	ctx := context.Background()
+	key := keyPrefix + identifier
+	blockedKey := "blocked_" + key
+
+	for i := 1; i <= max; i++ {
+		allowed, err := rl.Allow(ctx, identifier, isToken)
+		require.NoError(t, err)
+		assert.True(t, allowed, "Requisição %d deveria ser permitida", i)
+		assert.False(t, mr.Exists(blockedKey), "Não deveria haver bloqueio após a requisição %d", i)
+	}
+
+	// Exatamente no limite o contador deve valer N
+	count, err := mr.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(max), count, "O contador deveria estar exatamente no limite")
+
+	// A requisição N+1 é a que dispara o bloqueio
+	allowed, err := rl.Allow(ctx, identifier, isToken)
+	require.NoError(t, err)
+	assert.False(t, allowed, "A requisição %d deveria disparar o bloqueio", max+1)
+	assert.True(t, mr.Exists(blockedKey), "A chave de bloqueio deveria existir após a requisição %d", max+1)
+	assert.Equal(t, time.Duration(blockSeconds)*time.Second, mr.TTL(blockedKey),
+		"O TTL do bloqueio deveria ser definido no momento em que o limite é excedido")
+	assert.False(t, mr.Exists(key), "O contador deveria ser zerado após o bloqueio")
+
+	// Requisições durante o bloqueio não voltam a incrementar o contador
+	allowed, err = rl.Allow(ctx, identifier, isToken)
+	require.NoError(t, err)
+	assert.False(t, allowed, "Requisições durante o bloqueio deveriam ser rejeitadas")
+	assert.False(t, mr.Exists(key), "O contador não deveria ser incrementado durante o bloqueio")
+}
+
+// Test_RateLimiter_Boundary_IP verifica o limite exato para IP
+func Test_RateLimiter_Boundary_IP(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 5, 10, 30, 60)
+	assertBoundary(t, mr, rl, "192.168.1.50", false, 5, 30, "ip_")
+}
+
+// Test_RateLimiter_Boundary_Token verifica o limite exato para token
+func Test_RateLimiter_Boundary_Token(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 5, 10, 30, 60)
+	assertBoundary(t, mr, rl, "token-boundary", true, 10, 60, "token_")
+}
+
+// Test_RateLimiter_Boundary_AfterBlock verifica que, após o bloqueio expirar, o limite volta a valer
+// integralmente, pois o contador foi zerado no momento do bloqueio
+func Test_RateLimiter_Boundary_AfterBlock(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 3, 3, 5, 5)
+	assertBoundary(t, mr, rl, "token-after-block", true, 3, 5, "token_")
+
+	// Avançar além do bloqueio: o mesmo limite exato deve ser aplicado novamente
+	mr.FastForward(6 * time.Second)
+	assertBoundary(t, mr, rl, "token-after-block", true, 3, 5, "token_")
+}
+
+// Test_RateLimiter_KeepCounterOnBlock compara o fim de um bloqueio mais curto que a janela
+// com o contador zerado (padrão) e mantido
+func Test_RateLimiter_KeepCounterOnBlock(t *testing.T) {
+	for name, keep := range map[string]bool{"reset": false, "keep": true} {
+		t.Run(name, func(t *testing.T) {
+			mr, client := setupTestRedis(t)
+			defer mr.Close()
+			defer client.Close()
+
+			// Duas requisições por janela de 10s, com bloqueio de 2s
+			mr.HSet("token_limits", "short-block", "2/10s/2s")
+			cfg := &config.LimiterConfig{MaxRequestsPerToken: 5, BlockDurationTokenSeconds: 60, KeepCounterOnBlock: keep}
+			resolver := redisStore.NewRedisLimitResolver(client, "token_limits", NewStaticLimitResolver(cfg))
+			rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithLimitResolver(resolver))
+			ctx := context.Background()
+
+			for i := 0; i < 3; i++ {
+				allowed, err := rl.Allow(ctx, "short-block", true)
+				require.NoError(t, err)
+				assert.Equal(t, i < 2, allowed, "Requisição %d", i+1)
+			}
+			assert.Equal(t, keep, mr.Exists("token_short-block"), "O contador só deveria sobreviver ao bloqueio com KeepCounterOnBlock")
+
+			mr.FastForward(3 * time.Second)
+			allowed, err := rl.Allow(ctx, "short-block", true)
+			require.NoError(t, err)
+			if keep {
+				assert.False(t, allowed, "Com o contador mantido, a cota da janela continua esgotada")
+				assert.Equal(t, "2", mustGet(t, mr, "offenses_token_short-block"))
+			} else {
+				assert.True(t, allowed, "Com o contador zerado, a cota recomeça após o bloqueio")
+			}
+		})
+	}
+}
+
+// Test_RateLimiter_GraceOverage verifica a escalada em duas etapas: dentro da tolerância, 429
+// só até o fim da janela; além dela, o bloqueio completo
+func Test_RateLimiter_GraceOverage(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 2, BlockDurationIPSeconds: 300, WindowIPSeconds: 10, GraceOverage: 2}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	require.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.7.1", 2))
+	decision, err := rl.AllowDecision(ctx, "192.168.7.1", false)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed, "Dentro da tolerância")
+	assert.Equal(t, 10*time.Second, decision.RetryAfter, "Dentro da tolerância, a espera é só até o fim da janela")
+	assert.False(t, mr.Exists("blocked_ip_192.168.7.1"), "A tolerância não deveria bloquear")
+	assert.False(t, mr.Exists("offenses_ip_192.168.7.1"), "A tolerância não deveria contar infrações")
+
+	// Uma nova janela recomeça a cota sem penalidade
+	mr.FastForward(10 * time.Second)
+	require.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.7.1", 2))
+	for i := 0; i < 2; i++ {
+		decision, err = rl.AllowDecision(ctx, "192.168.7.1", false)
+		require.NoError(t, err)
+		assert.False(t, decision.Allowed)
+		assert.LessOrEqual(t, decision.RetryAfter, 10*time.Second, "Requisição %d dentro da tolerância", i+1)
+	}
+	assert.False(t, mr.Exists("blocked_ip_192.168.7.1"))
+
+	// Além da tolerância, o bloqueio completo
+	decision, err = rl.AllowDecision(ctx, "192.168.7.1", false)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 300*time.Second, decision.RetryAfter)
+	assert.True(t, mr.Exists("blocked_ip_192.168.7.1"))
+	assert.Equal(t, "1", mustGet(t, mr, "offenses_ip_192.168.7.1"))
+}
+
+// Test_RateLimiter_FailureMode_Open verifica que, no modo de falha aberto, erros do store permitem a requisição
+func Test_RateLimiter_FailureMode_Open(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 3, 3, 5, 5)
+	rl.GetConfig().FailureMode = config.FailureModeOpen
+
+	mr.Close()
+
+	allowed, err := rl.Allow(context.Background(), "192.168.1.101", false)
+	assert.NoError(t, err, "No modo de falha aberto o erro não deveria ser propagado")
+	assert.True(t, allowed, "No modo de falha aberto a requisição deveria ser permitida")
+}
+
+// Test_RateLimiter_BlockMetadata verifica que o bloqueio registra motivo, número de infrações e início
+func Test_RateLimiter_BlockMetadata(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 1, 1, 5, 5)
+	store := redisStore.NewRedisStore(client)
+	ctx := context.Background()
+	blockedKey := "blocked_ip_192.168.1.70"
+
+	for offense := int64(1); offense <= 2; offense++ {
+		before := time.Now()
+		for i := 0; i < 2; i++ {
+			_, err := rl.Allow(ctx, "192.168.1.70", false)
+			require.NoError(t, err)
+		}
+
+		info, err := store.BlockInfo(ctx, blockedKey)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, db.ReasonRateLimitExceeded, info.Reason)
+		assert.Equal(t, offense, info.OffenseCount, "O número de infrações deveria crescer a cada bloqueio")
+		assert.False(t, info.StartedAt.Before(before.Truncate(time.Second)), "O início do bloqueio deveria ser o momento atual")
+
+		mr.FastForward(6 * time.Second)
+	}
+}
+
+// Test_RateLimiter_AllowDecision verifica limite, restantes e tempo de bloqueio na decisão
+func Test_RateLimiter_AllowDecision(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 2, 2, 30, 30)
+	ctx := context.Background()
+
+	d, err := rl.AllowDecision(ctx, "192.168.1.80", false)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 2, d.Limit)
+	assert.Equal(t, 1, d.Remaining)
+	assert.Equal(t, time.Second, d.Window)
+
+	d, err = rl.AllowDecision(ctx, "192.168.1.80", false)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+
+	// A requisição que excede o limite informa a duração do bloqueio
+	d, err = rl.AllowDecision(ctx, "192.168.1.80", false)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 30*time.Second, d.RetryAfter)
+
+	// Durante o bloqueio, o tempo restante vem dos metadados do bloqueio
+	d, err = rl.AllowDecision(ctx, "192.168.1.80", false)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.InDelta(t, float64(30*time.Second), float64(d.RetryAfter), float64(time.Second))
+}
+
+// Test_RateLimiter_Disabled verifica que, desligado, o rate limiter permite tudo sem acessar o store
+func Test_RateLimiter_Disabled(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 2, 2, 30, 30)
+	rl.SetEnabled(false)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		d, err := rl.AllowDecision(ctx, "192.168.1.90", false)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed, "Requisição %d deveria passar com o limitador desligado", i+1)
+		assert.True(t, d.Disabled)
+	}
+	assert.False(t, mr.Exists("ip_192.168.1.90"), "Nenhum contador deveria ser criado com o limitador desligado")
+
+	// Religar em tempo de execução volta a aplicar os limites
+	rl.SetEnabled(true)
+	for i := 0; i < 2; i++ {
+		allowed, err := rl.Allow(ctx, "192.168.1.90", false)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, err := rl.Allow(ctx, "192.168.1.90", false)
+	require.NoError(t, err)
+	assert.False(t, allowed, "Com o limitador religado o limite deveria valer")
+}
+
+// Test_RateLimiter_DisabledByConfig verifica o desligamento pela configuração
+func Test_RateLimiter_DisabledByConfig(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 1, MaxRequestsPerToken: 1, Disabled: true}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+
+	assert.False(t, rl.Enabled())
+	for i := 0; i < 3; i++ {
+		allowed, err := rl.Allow(context.Background(), "192.168.1.91", false)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+}
+
+// Test_RateLimiter_AllowAt_SlidingWindowMath avança o instante explicitamente e confere a ponderação
+// da janela deslizante, sem esperas nem FastForward
+func Test_RateLimiter_AllowAt_SlidingWindowMath(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       4,
+		BlockDurationIPSeconds: 60,
+		Algorithm:              config.AlgorithmSlidingWindow,
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+	start := time.UnixMilli(3_000_000_000)
+
+	remainingAt := func(now time.Time) int {
+		decision, err := rl.allowAt(ctx, "192.168.5.1", false, now)
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+		return decision.Remaining
+	}
+
+	for expected := 3; expected >= 0; expected-- {
+		assert.Equal(t, expected, remainingAt(start))
+	}
+
+	// Na metade da janela seguinte, o bucket anterior pesa 0,5: 4*0,5 + 1 = 3
+	assert.Equal(t, 1, remainingAt(start.Add(1500*time.Millisecond)))
+	assert.Equal(t, 0, remainingAt(start.Add(1500*time.Millisecond)))
+
+	// Mais uma janela adiante, o bucket [1s, 2s) com 2 requisições pesa 0,5: 2*0,5 + 1 = 2
+	assert.Equal(t, 2, remainingAt(start.Add(2500*time.Millisecond)))
+
+	// Duas janelas sem requisições zeram a influência dos buckets antigos
+	assert.Equal(t, 3, remainingAt(start.Add(5*time.Second)))
+}
+
+// Test_RateLimiter_AllowAt_RetryAfter verifica que o tempo restante de bloqueio acompanha o instante informado
+func Test_RateLimiter_AllowAt_RetryAfter(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := createTestRateLimiterWithConfig(client, 1, 10, 60, 60)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	decision, err := rl.allowAt(ctx, "192.168.5.2", false, now)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	decision, err = rl.allowAt(ctx, "192.168.5.2", false, now)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 60*time.Second, decision.RetryAfter)
+
+	for _, elapsed := range []time.Duration{20 * time.Second, 45 * time.Second, 90 * time.Second} {
+		decision, err = rl.allowAt(ctx, "192.168.5.2", false, now.Add(elapsed))
+		require.NoError(t, err)
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, max(60*time.Second-elapsed, 0), decision.RetryAfter, "Tempo restante após %s", elapsed)
+	}
+}
@@ -0,0 +1,59 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// windowCounterKeys retorna as chaves do contador que pesam na decisão no instante now, a
+// atual primeiro: o próprio contador na janela fixa e no leaky bucket; o contador do período
+// nas cotas de calendário e da janela nas janelas alinhadas; e os buckets atual e anterior na
+// janela deslizante.
+func (rl *RateLimiter) windowCounterKeys(counter string, window time.Duration, now time.Time) []string {
+	switch algorithm := rl.limiterConfig.Algorithm; {
+	case algorithm == config.AlgorithmCalendarWindow:
+		period, _ := rl.calendarPeriod(now)
+		return []string{counter + ":" + period}
+	case algorithm == config.AlgorithmSlidingWindow:
+		bucket := now.UnixMilli() / max(window.Milliseconds(), 1)
+		return []string{counter + ":" + strconv.FormatInt(bucket, 10), counter + ":" + strconv.FormatInt(bucket-1, 10)}
+	case rl.limiterConfig.AlignWindows && (algorithm == "" || algorithm == config.AlgorithmFixedWindow):
+		bucket, _ := alignedWindow(now, window)
+		return []string{counter + ":" + bucket}
+	}
+	return []string{counter}
+}
+
+// ResetIdentifier apaga, em uma única operação atômica do store, tudo o que o rate limiter
+// guarda sobre o identificador: o contador (inclusive o estado do leaky bucket), o bloqueio,
+// as infrações e a cota de bytes. Na janela deslizante, nas cotas de calendário e nas janelas
+// alinhadas, são apagados os contadores da janela atual (e, na deslizante, da anterior), que
+// são os únicos que ainda pesam na decisão.
+func (rl *RateLimiter) ResetIdentifier(ctx context.Context, identifier string, isToken bool) error {
+	_, window, _, err := rl.resolveLimit(ctx, identifier, isToken)
+	if err != nil {
+		return fmt.Errorf("erro ao resolver limite: %w", err)
+	}
+
+	key := identifierKey(identifier, isToken)
+	counter := rl.scopedKey(key, isToken)
+	keys := []string{
+		counter,
+		rl.scopedKey("blocked_"+key, isToken),
+		rl.scopedKey("offenses_"+key, isToken),
+		rl.scopedKey("bytes_"+key, isToken),
+	}
+
+	if windowKeys := rl.windowCounterKeys(counter, window, rl.clock.Now()); windowKeys[0] != counter {
+		keys = append(keys, windowKeys...)
+	}
+
+	if err := rl.store.ResetAll(ctx, keys...); err != nil {
+		return fmt.Errorf("erro ao resetar o identificador: %w", err)
+	}
+	return nil
+}
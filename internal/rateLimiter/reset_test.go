@@ -0,0 +1,85 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// Test_RateLimiter_ResetIdentifier verifica que um único reset apaga contador, bloqueio e
+// infrações do identificador, sem afetar os demais
+func Test_RateLimiter_ResetIdentifier(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       1,
+		BlockDurationIPSeconds: 60,
+		KeepCounterOnBlock:     true,
+		MaxBytesPerWindow:      1 << 20,
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for _, ip := range []string{"192.168.6.1", "192.168.6.2"} {
+		for i := 0; i < 2; i++ {
+			_, err := rl.AllowDecision(ctx, ip, false)
+			require.NoError(t, err)
+		}
+		require.NoError(t, rl.RecordBytes(ctx, ip, false, 100))
+	}
+	for _, key := range []string{"ip_192.168.6.1", "blocked_ip_192.168.6.1", "offenses_ip_192.168.6.1", "bytes_ip_192.168.6.1"} {
+		require.True(t, mr.Exists(key), "%s deveria existir antes do reset", key)
+	}
+
+	require.NoError(t, rl.ResetIdentifier(ctx, "192.168.6.1", false))
+	for _, key := range []string{"ip_192.168.6.1", "blocked_ip_192.168.6.1", "offenses_ip_192.168.6.1", "bytes_ip_192.168.6.1"} {
+		assert.False(t, mr.Exists(key), "%s deveria ter sido apagada", key)
+	}
+	assert.True(t, mr.Exists("blocked_ip_192.168.6.2"), "Outros identificadores não deveriam ser afetados")
+	assert.True(t, mr.Exists("offenses_ip_192.168.6.2"))
+
+	decision, err := rl.AllowDecision(ctx, "192.168.6.1", false)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed, "Após o reset, a cota recomeça")
+}
+
+// Test_RateLimiter_ResetIdentifier_SlidingWindow verifica que os buckets da janela deslizante
+// que pesam na decisão também são apagados
+func Test_RateLimiter_ResetIdentifier_SlidingWindow(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	fake := clock.NewFake(time.UnixMilli(1_000_000_500))
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 2, BlockDurationIPSeconds: 60, Algorithm: config.AlgorithmSlidingWindow}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+	ctx := context.Background()
+
+	// Uma requisição no bucket anterior e o bloqueio no atual
+	_, err := rl.AllowDecision(ctx, "192.168.6.3", false)
+	require.NoError(t, err)
+	fake.Advance(time.Second)
+	for i := 0; i < 2; i++ {
+		_, err := rl.AllowDecision(ctx, "192.168.6.3", false)
+		require.NoError(t, err)
+	}
+	require.True(t, mr.Exists("blocked_ip_192.168.6.3"))
+
+	require.NoError(t, rl.ResetIdentifier(ctx, "192.168.6.3", false))
+	assert.Empty(t, mr.Keys(), "Nenhuma chave do identificador deveria restar")
+
+	for i := 0; i < 2; i++ {
+		decision, err := rl.AllowDecision(ctx, "192.168.6.3", false)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed, "Após o reset, a janela deslizante recomeça vazia")
+	}
+}
@@ -0,0 +1,35 @@
+package rateLimiter
+
+import (
+	"context"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// routeLimitKey é o tipo da chave usada para guardar o sub-limite da rota no contexto.
+type routeLimitKey struct{}
+
+// WithRouteLimit associa ao contexto o nome do sub-limite da rota (ver config.RouteLimit). Os
+// limites desse sub-limite substituem os do escopo ao resolver o contador da rota.
+func WithRouteLimit(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, routeLimitKey{}, name)
+}
+
+// RouteLimitFromContext retorna o nome do sub-limite da rota guardado no contexto ("" se não houver).
+func RouteLimitFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(routeLimitKey{}).(string)
+	return name
+}
+
+// routeLimit procura o sub-limite pelo nome.
+func routeLimit(cfg *config.LimiterConfig, name string) (config.RouteLimit, bool) {
+	if name == "" {
+		return config.RouteLimit{}, false
+	}
+	for _, route := range cfg.RouteLimits {
+		if route.Name == name {
+			return route, true
+		}
+	}
+	return config.RouteLimit{}, false
+}
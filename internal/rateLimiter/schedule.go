@@ -0,0 +1,31 @@
+package rateLimiter
+
+import "time"
+
+// scheduleMultiplier retorna o multiplicador da primeira faixa de LimitSchedule que contém o
+// horário de now, no fuso ScheduleLocation, ou 1 fora das faixas.
+func (rl *RateLimiter) scheduleMultiplier(now time.Time) float64 {
+	schedule := rl.limiterConfig.LimitSchedule
+	if len(schedule) == 0 {
+		return 1
+	}
+	loc := rl.limiterConfig.ScheduleLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	// Horário de parede, que não muda com o horário de verão
+	hour, minute, second := now.In(loc).Clock()
+	sinceMidnight := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+
+	for _, r := range schedule {
+		inRange := sinceMidnight >= r.Start && sinceMidnight < r.End
+		if r.End <= r.Start {
+			// A faixa vira a meia-noite (ex.: 22:00-06:00)
+			inRange = sinceMidnight >= r.Start || sinceMidnight < r.End
+		}
+		if inRange {
+			return r.Multiplier
+		}
+	}
+	return 1
+}
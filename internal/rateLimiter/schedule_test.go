@@ -0,0 +1,61 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// Test_RateLimiter_LimitSchedule verifica que o limite efetivo muda dentro e fora das faixas de horário
+func Test_RateLimiter_LimitSchedule(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	require.NoError(t, err)
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       4,
+		BlockDurationIPSeconds: 60,
+		LimitSchedule: []config.ScheduleRange{
+			{Start: 9 * time.Hour, End: 18 * time.Hour, Multiplier: 2},   // horário comercial
+			{Start: 22 * time.Hour, End: 6 * time.Hour, Multiplier: 0.5}, // madrugada
+		},
+		ScheduleLocation: saoPaulo,
+	}
+	fake := clock.NewFake(time.Date(2024, 11, 29, 10, 0, 0, 0, saoPaulo))
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		at       time.Time
+		expected int
+	}{
+		{time.Date(2024, 11, 29, 10, 0, 0, 0, saoPaulo), 8},
+		{time.Date(2024, 11, 29, 8, 59, 0, 0, saoPaulo), 4},
+		{time.Date(2024, 11, 29, 18, 0, 0, 0, saoPaulo), 4},
+		{time.Date(2024, 11, 29, 23, 30, 0, 0, saoPaulo), 2},
+		{time.Date(2024, 11, 30, 3, 0, 0, 0, saoPaulo), 2},
+		{time.Date(2024, 11, 30, 6, 0, 0, 0, saoPaulo), 4},
+		// 13:00 UTC são 10:00 em São Paulo
+		{time.Date(2024, 11, 29, 13, 0, 0, 0, time.UTC), 8},
+	} {
+		fake.Set(tc.at)
+		decision, err := rl.AllowDecision(ctx, "192.168.13.1", false)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, decision.Limit, "Limite às %s", tc.at)
+	}
+
+	// O limite efetivo vale para a contagem
+	fake.Set(time.Date(2024, 12, 1, 2, 0, 0, 0, saoPaulo))
+	assert.Equal(t, 2, allowedUntilRejected(t, rl, "192.168.13.2", 10))
+	fake.Set(time.Date(2024, 12, 1, 12, 0, 0, 0, saoPaulo))
+	assert.Equal(t, 8, allowedUntilRejected(t, rl, "192.168.13.3", 10))
+}
@@ -0,0 +1,103 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_ScopeKeyPrefixes verifica que as chaves de cada escopo levam o próprio prefixo
+func Test_RateLimiter_ScopeKeyPrefixes(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		KeyPrefix:                 "svc:",
+		IPKeyPrefix:               "ip:",
+		TokenKeyPrefix:            "tok:",
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := rl.Allow(ctx, "192.168.12.1", false)
+		require.NoError(t, err)
+		_, err = rl.Allow(ctx, "test-token", true)
+		require.NoError(t, err)
+		if i == 0 {
+			assert.ElementsMatch(t, []string{"svc:ip:ip_192.168.12.1", "svc:tok:token_test-token"}, mr.Keys())
+		}
+	}
+
+	// O bloqueio zera o contador
+	assert.ElementsMatch(t, []string{
+		"svc:ip:blocked_ip_192.168.12.1",
+		"svc:ip:offenses_ip_192.168.12.1",
+		"svc:tok:blocked_token_test-token",
+		"svc:tok:offenses_token_test-token",
+	}, mr.Keys())
+
+	// As operações administrativas encontram as chaves de cada escopo
+	blocked, err := rl.ListBlocked(ctx)
+	require.NoError(t, err)
+	scopes := make(map[string]bool, len(blocked))
+	for _, item := range blocked {
+		scopes[item.Identifier] = item.IsToken
+	}
+	assert.Equal(t, map[string]bool{"192.168.12.1": false, "test-token": true}, scopes)
+
+	removed, err := rl.UnblockPattern(ctx, "token_*")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.False(t, mr.Exists("svc:tok:blocked_token_test-token"))
+	assert.True(t, mr.Exists("svc:ip:blocked_ip_192.168.12.1"))
+
+	require.NoError(t, rl.ResetIdentifier(ctx, "192.168.12.1", false))
+	assert.False(t, mr.Exists("svc:ip:blocked_ip_192.168.12.1"))
+	assert.False(t, mr.Exists("svc:ip:offenses_ip_192.168.12.1"))
+}
+
+// Test_RateLimiter_Region verifica que o mesmo identificador tem contadores independentes em cada região
+func Test_RateLimiter_Region(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	limiter := func(region string) *RateLimiter {
+		return NewRateLimiter(&config.LimiterConfig{
+			MaxRequestsPerIP:       2,
+			BlockDurationIPSeconds: 60,
+			KeyPrefix:              "svc:",
+			Region:                 region,
+		}, redisStore.NewRedisStore(client))
+	}
+	ctx := context.Background()
+
+	east, west := limiter("us-east"), limiter("eu-west")
+	assert.Equal(t, 2, allowedUntilRejected(t, east, "192.168.21.1", 5))
+	assert.Equal(t, 2, allowedUntilRejected(t, west, "192.168.21.1", 5), "O bloqueio de uma região não deveria valer na outra")
+	assert.True(t, mr.Exists("svc:us-east:blocked_ip_192.168.21.1"))
+	assert.True(t, mr.Exists("svc:eu-west:blocked_ip_192.168.21.1"))
+
+	// Sem região, as instâncias dividem o contador
+	first, second := limiter(""), limiter("")
+	for _, rl := range []*RateLimiter{first, second} {
+		allowed, err := rl.Allow(ctx, "192.168.21.2", false)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, err := first.Allow(ctx, "192.168.21.2", false)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.True(t, mr.Exists("svc:blocked_ip_192.168.21.2"))
+}
@@ -0,0 +1,23 @@
+package rateLimiter
+
+import (
+	"math"
+
+	"rateLimiter/infra/db"
+)
+
+// severityTiers converte as faixas de BlockSeverity, em múltiplos do limite, em números de
+// requisições na janela para o limite informado.
+func (rl *RateLimiter) severityTiers(limit int) []db.SeverityTier {
+	if len(rl.limiterConfig.BlockSeverity) == 0 {
+		return nil
+	}
+	tiers := make([]db.SeverityTier, 0, len(rl.limiterConfig.BlockSeverity))
+	for _, tier := range rl.limiterConfig.BlockSeverity {
+		tiers = append(tiers, db.SeverityTier{
+			Threshold: int64(math.Ceil(tier.MinRatio * float64(limit))),
+			Duration:  rl.capBlock(tier.Duration),
+		})
+	}
+	return tiers
+}
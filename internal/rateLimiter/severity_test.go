@@ -0,0 +1,56 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_BlockSeverity verifica que a duração do bloqueio acompanha o excesso na janela
+func Test_RateLimiter_BlockSeverity(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       5,
+		BlockDurationIPSeconds: 60,
+		WindowIPSeconds:        10,
+		BlockSeverity: []config.BlockSeverityTier{
+			{MinRatio: 2, Duration: 10 * time.Minute},
+			{MinRatio: 10, Duration: time.Hour},
+		},
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		ip       string
+		requests int
+		expected time.Duration
+	}{
+		{"192.168.5.1", 6, time.Minute},       // logo acima do limite: bloqueio base
+		{"192.168.5.2", 9, time.Minute},       // abaixo de 2x
+		{"192.168.5.3", 10, 10 * time.Minute}, // 2x o limite
+		{"192.168.5.4", 49, 10 * time.Minute}, // abaixo de 10x
+		{"192.168.5.5", 50, time.Hour},        // 10x o limite
+		{"192.168.5.6", 200, time.Hour},       // além da última faixa
+	} {
+		var decision *Decision
+		for i := 0; i < tc.requests; i++ {
+			var err error
+			decision, err = rl.AllowDecision(ctx, tc.ip, false)
+			require.NoError(t, err)
+		}
+		assert.False(t, decision.Allowed, tc.ip)
+		assert.Equal(t, tc.expected, mr.TTL("blocked_ip_"+tc.ip), "%s com %d requisições", tc.ip, tc.requests)
+		assert.InDelta(t, float64(tc.expected), float64(decision.RetryAfter), float64(time.Second), tc.ip)
+		assert.Equal(t, "1", mustGet(t, mr, "offenses_ip_"+tc.ip), "Prolongar o bloqueio não conta uma nova infração")
+	}
+}
@@ -0,0 +1,83 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// allowSlidingAt aplica a janela deslizante. O store decide a contagem de forma atômica;
+// o bloqueio ao exceder o limite é gravado em seguida, em chamadas separadas.
+//
+// Sob concorrência, as requisições que passaram pela verificação do bloqueio antes de ele ser
+// gravado ainda são contadas e podem ser admitidas se a estimativa da janela tiver caído nesse
+// meio-tempo: no pior caso, uma a mais por requisição em andamento do mesmo identificador. As
+// rejeições simultâneas também contam uma infração cada. Com StrictLimits, allowSlidingStrictAt
+// elimina as duas diferenças.
+func (rl *RateLimiter) allowSlidingAt(ctx context.Context, decision *Decision, keys db.CountKeys, window, blockDuration time.Duration, now time.Time) (*Decision, error) {
+	// Verifica se está bloqueado
+	blockInfo, err := rl.store.BlockInfo(ctx, keys.Block)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao verificar se está bloqueado: %w", err)
+	}
+	if blockInfo != nil {
+		if !blockInfo.ExpiresAt.IsZero() {
+			decision.RetryAfter = max(blockInfo.ExpiresAt.Sub(now), 0)
+		}
+		return decision, nil // Bloqueado
+	}
+
+	allowed, estimate, err := rl.store.SlidingWindow(ctx, keys.Counter, int64(decision.Limit), window, now)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao contar na janela deslizante: %w", err)
+	}
+
+	if !allowed {
+		// O número de infrações fica registrado junto com o bloqueio para as ferramentas de inspeção
+		offenses, err := rl.store.Increment(ctx, keys.Offenses, db.OffenseWindow)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao contar infrações: %w", err)
+		}
+
+		err = rl.store.Block(ctx, keys.Block, blockDuration, db.BlockInfo{
+			Reason:       db.ReasonRateLimitExceeded,
+			OffenseCount: offenses,
+			StartedAt:    now,
+			ExpiresAt:    now.Add(blockDuration),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao bloquear: %w", err)
+		}
+		// Limpa o contador de requisições após bloquear para evitar que continue incrementando desnecessariamente
+		if !keys.KeepCounterOnBlock {
+			_ = rl.store.Reset(ctx, keys.Counter)
+		}
+		decision.RetryAfter = blockDuration
+		return decision, nil // Limite excedido
+	}
+
+	decision.Allowed = true
+	decision.Remaining = max(decision.Limit-int(math.Ceil(estimate)), 0)
+	decision.RemainingFloat = max(float64(decision.Limit)-estimate, 0)
+	return decision, nil // Permitido
+}
+
+// allowSlidingStrictAt aplica a janela deslizante com a verificação do bloqueio, a contagem e
+// o bloqueio em uma única operação do store: nenhuma requisição é admitida além do limite.
+func (rl *RateLimiter) allowSlidingStrictAt(ctx context.Context, decision *Decision, keys db.CountKeys, window, blockDuration time.Duration, now time.Time) (*Decision, error) {
+	allowed, estimate, retryAfter, err := rl.store.SlidingWindowCheckAndCount(ctx, keys, int64(decision.Limit), window, blockDuration, now)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao contar na janela deslizante: %w", err)
+	}
+	if !allowed {
+		decision.RetryAfter = retryAfter
+		return decision, nil
+	}
+	decision.Allowed = true
+	decision.Remaining = max(decision.Limit-int(math.Ceil(estimate)), 0)
+	decision.RemainingFloat = max(float64(decision.Limit)-estimate, 0)
+	return decision, nil
+}
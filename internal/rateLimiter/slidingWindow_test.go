@@ -0,0 +1,205 @@
+package rateLimiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// allowedUntilRejected envia requisições até a primeira rejeição (no máximo n) e retorna quantas passaram
+func allowedUntilRejected(t *testing.T, rl *RateLimiter, identifier string, n int) int {
+	for i := 0; i < n; i++ {
+		allowed, err := rl.Allow(context.Background(), identifier, false)
+		require.NoError(t, err)
+		if !allowed {
+			return i
+		}
+	}
+	return n
+}
+
+// Test_RateLimiter_SlidingWindow_ReducesBoundaryBurst compara a rajada na virada da janela
+// entre a janela fixa e a janela deslizante
+func Test_RateLimiter_SlidingWindow_ReducesBoundaryBurst(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	// Janela fixa: 10 requisições no fim de uma janela e outras 10 logo no início da seguinte
+	fixed := createTestRateLimiterWithConfig(client, 10, 10, 60, 60)
+	fixedBurst := allowedUntilRejected(t, fixed, "192.168.2.1", 10)
+	mr.FastForward(time.Second)
+	fixedBurst += allowedUntilRejected(t, fixed, "192.168.2.1", 20)
+	assert.Equal(t, 20, fixedBurst, "A janela fixa permite o dobro do limite na virada")
+
+	// Janela deslizante com o relógio 100ms antes e depois da virada
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       10,
+		BlockDurationIPSeconds: 60,
+		Algorithm:              config.AlgorithmSlidingWindow,
+	}
+	fake := clock.NewFake(time.UnixMilli(1_000_000_900))
+	sliding := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+
+	slidingBurst := allowedUntilRejected(t, sliding, "192.168.2.2", 10)
+	fake.Advance(200 * time.Millisecond)
+	slidingBurst += allowedUntilRejected(t, sliding, "192.168.2.2", 20)
+
+	// Na nova janela, 90% do bucket anterior ainda conta: só resta espaço para 1 requisição
+	assert.Equal(t, 11, slidingBurst, "A janela deslizante deveria conter a rajada na virada")
+	assert.Less(t, slidingBurst, fixedBurst)
+}
+
+// Test_RedisStore_SlidingWindow_Weighting verifica a ponderação do bucket anterior ao longo da janela
+func Test_RedisStore_SlidingWindow_Weighting(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	store := redisStore.NewRedisStore(client)
+	ctx := context.Background()
+	start := time.UnixMilli(2_000_000_000)
+
+	// Preencher o bucket [start, start+1s) até o limite
+	for i := 0; i < 10; i++ {
+		allowed, _, err := store.SlidingWindow(ctx, "sw", 10, time.Second, start.Add(100*time.Millisecond))
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, estimate, err := store.SlidingWindow(ctx, "sw", 10, time.Second, start.Add(900*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.InDelta(t, 10, estimate, 1e-9)
+
+	// Na metade da janela seguinte, metade do bucket anterior ainda conta
+	halfway := start.Add(1500 * time.Millisecond)
+	admitted := 0
+	for i := 0; i < 10; i++ {
+		allowed, _, err := store.SlidingWindow(ctx, "sw", 10, time.Second, halfway)
+		require.NoError(t, err)
+		if allowed {
+			admitted++
+		}
+	}
+	assert.Equal(t, 5, admitted, "Com peso 0,5 no bucket anterior deveriam caber 5 requisições")
+
+	// Duas janelas depois, o bucket anterior já não influencia
+	later := start.Add(3 * time.Second)
+	allowed, estimate, err = store.SlidingWindow(ctx, "sw", 10, time.Second, later)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.InDelta(t, 1, estimate, 1e-9)
+}
+
+// Test_RateLimiter_SlidingWindow_ClockSkew verifica que, com o horário do Redis, instâncias com
+// relógios dessincronizados compartilham os mesmos buckets, o que não acontece com o horário local
+func Test_RateLimiter_SlidingWindow_ClockSkew(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	serverNow := time.UnixMilli(3_000_000_100)
+	mr.SetTime(serverNow)
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       10,
+		BlockDurationIPSeconds: 60,
+		Algorithm:              config.AlgorithmSlidingWindow,
+	}
+
+	admitted := func(serverTime bool, identifier string) int {
+		store := redisStore.NewRedisStore(client, redisStore.WithServerTime(serverTime))
+		// A instância B está com o relógio 1s atrasado, ainda na janela anterior
+		instanceA := NewRateLimiter(cfg, store, WithClock(clock.NewFake(serverNow)))
+		instanceB := NewRateLimiter(cfg, store, WithClock(clock.NewFake(serverNow.Add(-time.Second))))
+		return allowedUntilRejected(t, instanceA, identifier, 10) + allowedUntilRejected(t, instanceB, identifier, 10)
+	}
+
+	assert.Equal(t, 20, admitted(false, "192.168.2.10"), "Com o horário local, cada instância conta em buckets diferentes")
+	assert.Equal(t, 10, admitted(true, "192.168.2.11"), "Com o horário do Redis, o limite deveria valer para as duas instâncias")
+}
+
+// Test_RedisStore_SlidingWindow_ServerTime verifica que, com o horário do Redis, o horário
+// informado é ignorado
+func Test_RedisStore_SlidingWindow_ServerTime(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	store := redisStore.NewRedisStore(client, redisStore.WithServerTime(true))
+	ctx := context.Background()
+	start := time.UnixMilli(4_000_000_000)
+	mr.SetTime(start.Add(100 * time.Millisecond))
+
+	for _, skew := range []time.Duration{-time.Hour, 0, 5 * time.Second, time.Hour} {
+		allowed, _, err := store.SlidingWindow(ctx, "sw", 10, time.Second, start.Add(skew))
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	assert.Equal(t, "4", mustGet(t, mr, "sw:4000000"), "Todas as requisições deveriam cair no bucket do horário do Redis")
+
+	// Metade da janela seguinte no Redis: o bucket anterior pesa 0,5
+	mr.SetTime(start.Add(1500 * time.Millisecond))
+	_, estimate, err := store.SlidingWindow(ctx, "sw", 10, time.Second, start.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.InDelta(t, 3, estimate, 1e-9)
+}
+
+// mustGet lê uma chave do Redis em memória
+func mustGet(t *testing.T, mr *miniredis.Miniredis, key string) string {
+	val, err := mr.Get(key)
+	require.NoError(t, err)
+	return val
+}
+
+// Test_RateLimiter_StrictLimits_Concurrency verifica que, com alta concorrência, a janela fixa e a
+// janela deslizante com StrictLimits admitem exatamente o limite e registram uma única infração
+func Test_RateLimiter_StrictLimits_Concurrency(t *testing.T) {
+	for _, algorithm := range []string{config.AlgorithmFixedWindow, config.AlgorithmSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			mr, client := setupTestRedis(t)
+			defer mr.Close()
+			defer client.Close()
+
+			const maxRequests, concurrency = 10, 200
+			cfg := &config.LimiterConfig{
+				MaxRequestsPerIP:       maxRequests,
+				BlockDurationIPSeconds: 60,
+				Algorithm:              algorithm,
+				StrictLimits:           true,
+			}
+			// Relógio parado no início de um bucket: todas as requisições caem na mesma janela
+			rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(clock.NewFake(time.UnixMilli(5_000_000_000))))
+
+			var admitted atomic.Int64
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			for i := 0; i < concurrency; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					allowed, err := rl.Allow(context.Background(), "192.168.3.1", false)
+					assert.NoError(t, err)
+					if allowed {
+						admitted.Add(1)
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			assert.Equal(t, int64(maxRequests), admitted.Load(), "Nenhuma requisição deveria passar além do limite")
+			assert.Equal(t, "1", mustGet(t, mr, "offenses_ip_192.168.3.1"), "A rajada deveria gerar um único bloqueio")
+		})
+	}
+}
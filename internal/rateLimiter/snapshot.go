@@ -0,0 +1,125 @@
+package rateLimiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"rateLimiter/infra/db"
+)
+
+// snapshotVersion é a versão do formato gravado por Export.
+const snapshotVersion = 1
+
+// ErrSnapshotUnsupported é retornado por Export e Import quando o store não implementa
+// db.Snapshotter e nenhum foi informado com WithSnapshotter.
+var ErrSnapshotUnsupported = errors.New("o store não permite exportar e importar o estado")
+
+// snapshot é o documento JSON gravado por Export.
+type snapshot struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Entries    []snapshotEntry `json:"entries"`
+}
+
+// snapshotEntry é uma chave exportada. A chave não tem o prefixo da instância, e o TTL vira
+// um instante absoluto, para que o tempo entre a exportação e a importação seja descontado.
+type snapshotEntry struct {
+	Key       string            `json:"key"`
+	Value     string            `json:"value,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
+
+// snapshotter retorna o store usado por Export e Import.
+func (rl *RateLimiter) snapshotter() (db.Snapshotter, error) {
+	if rl.snapshots != nil {
+		return rl.snapshots, nil
+	}
+	if s, ok := rl.store.(db.Snapshotter); ok {
+		return s, nil
+	}
+	return nil, ErrSnapshotUnsupported
+}
+
+// Export grava em w, como JSON, todas as chaves do rate limiter (contadores, bloqueios,
+// infrações e demais estados) com o instante em que cada uma expira, e retorna quantas foram
+// exportadas. As chaves são lidas com SCAN, sem bloquear o store, então o resultado não é uma
+// fotografia atômica: requisições atendidas durante a exportação podem ou não aparecer.
+func (rl *RateLimiter) Export(ctx context.Context, w io.Writer) (int, error) {
+	s, err := rl.snapshotter()
+	if err != nil {
+		return 0, err
+	}
+
+	prefix := rl.storeKey("")
+	entries, err := s.Dump(ctx, prefix+"*")
+	if err != nil {
+		return 0, fmt.Errorf("erro ao exportar o estado: %w", err)
+	}
+
+	now := rl.clock.Now()
+	doc := snapshot{Version: snapshotVersion, ExportedAt: now, Entries: make([]snapshotEntry, 0, len(entries))}
+	for _, entry := range entries {
+		exported := snapshotEntry{
+			Key:    strings.TrimPrefix(entry.Key, prefix),
+			Value:  entry.Value,
+			Fields: entry.Fields,
+		}
+		if entry.TTL > 0 {
+			expiresAt := now.Add(entry.TTL)
+			exported.ExpiresAt = &expiresAt
+		}
+		doc.Entries = append(doc.Entries, exported)
+	}
+
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		return 0, fmt.Errorf("erro ao gravar o estado exportado: %w", err)
+	}
+	return len(doc.Entries), nil
+}
+
+// Import lê de r o estado gravado por Export e o grava no store, com o prefixo desta
+// instância, e retorna quantas chaves foram restauradas. O TTL de cada chave é recalculado a
+// partir do horário atual; chaves que expiraram desde a exportação são descartadas. Chaves
+// existentes com o mesmo nome são substituídas.
+func (rl *RateLimiter) Import(ctx context.Context, r io.Reader) (int, error) {
+	s, err := rl.snapshotter()
+	if err != nil {
+		return 0, err
+	}
+
+	var doc snapshot
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("erro ao ler o estado exportado: %w", err)
+	}
+	if doc.Version != snapshotVersion {
+		return 0, fmt.Errorf("versão do estado exportado não suportada: %d", doc.Version)
+	}
+
+	now := rl.clock.Now()
+	entries := make([]db.SnapshotEntry, 0, len(doc.Entries))
+	for _, exported := range doc.Entries {
+		entry := db.SnapshotEntry{
+			Key:    rl.storeKey(exported.Key),
+			Value:  exported.Value,
+			Fields: exported.Fields,
+		}
+		if exported.ExpiresAt != nil {
+			entry.TTL = exported.ExpiresAt.Sub(now)
+			if entry.TTL <= 0 {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := s.Restore(ctx, entries); err != nil {
+		return 0, fmt.Errorf("erro ao importar o estado: %w", err)
+	}
+	return len(entries), nil
+}
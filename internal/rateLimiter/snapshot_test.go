@@ -0,0 +1,126 @@
+package rateLimiter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+	"rateLimiter/infra/db/memory"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+	"rateLimiter/pkg/metrics"
+)
+
+// Test_RateLimiter_ExportImport verifica que o estado exportado de um Redis e importado em
+// outro, com outro prefixo, mantém as chaves, os valores e os TTLs descontados do intervalo
+func Test_RateLimiter_ExportImport(t *testing.T) {
+	srcMr, srcClient := setupTestRedis(t)
+	defer srcMr.Close()
+	defer srcClient.Close()
+	dstMr, dstClient := setupTestRedis(t)
+	defer dstMr.Close()
+	defer dstClient.Close()
+
+	fake := clock.NewFake(time.UnixMilli(1_000_000_000))
+	ctx := context.Background()
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 120,
+		KeyPrefix:                 "old:",
+	}
+	src := NewRateLimiter(cfg, redisStore.NewRedisStore(srcClient), WithClock(fake))
+
+	// Contadores, bloqueios e infrações
+	for i := 0; i < 3; i++ {
+		_, err := src.AllowDecision(ctx, "10.0.0.1", false)
+		require.NoError(t, err)
+		_, err = src.AllowDecision(ctx, "abc", true)
+		require.NoError(t, err)
+	}
+	_, err := src.AllowDecision(ctx, "10.0.0.2", false)
+	require.NoError(t, err)
+	// Estado do leaky bucket, que é um hash
+	leakyCfg := *cfg
+	leakyCfg.Algorithm = config.AlgorithmLeakyBucket
+	leaky := NewRateLimiter(&leakyCfg, redisStore.NewRedisStore(srcClient), WithClock(fake))
+	_, err = leaky.AllowDecision(ctx, "10.0.0.9", false)
+	require.NoError(t, err)
+	// Uma chave sem expiração
+	require.NoError(t, srcMr.Set("old:note", "kept"))
+
+	var buf bytes.Buffer
+	exported, err := src.Export(ctx, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, len(srcMr.Keys()), exported)
+
+	// A importação acontece 5s depois, em outra instância
+	const elapsed = 5 * time.Second
+	fake.Advance(elapsed)
+	dstCfg := *cfg
+	dstCfg.KeyPrefix = "new:"
+	dst := NewRateLimiter(&dstCfg, redisStore.NewRedisStore(dstClient), WithClock(fake))
+	imported, err := dst.Import(ctx, &buf)
+	require.NoError(t, err)
+
+	expected := 0
+	for _, key := range srcMr.Keys() {
+		dstKey := "new:" + strings.TrimPrefix(key, "old:")
+		ttl := srcMr.TTL(key)
+		if ttl > 0 && ttl <= elapsed {
+			assert.False(t, dstMr.Exists(dstKey), "%s expirou antes da importação", key)
+			continue
+		}
+		expected++
+
+		require.True(t, dstMr.Exists(dstKey), "%s deveria ter sido importada", key)
+		require.Equal(t, srcMr.Type(key), dstMr.Type(dstKey))
+		if srcMr.Type(key) == "hash" {
+			srcFields, err := srcMr.HKeys(key)
+			require.NoError(t, err)
+			dstFields, err := dstMr.HKeys(dstKey)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, srcFields, dstFields)
+			for _, field := range srcFields {
+				assert.Equal(t, srcMr.HGet(key, field), dstMr.HGet(dstKey, field), "%s.%s", key, field)
+			}
+		} else {
+			assert.Equal(t, mustGet(t, srcMr, key), mustGet(t, dstMr, dstKey), key)
+		}
+		if ttl == 0 {
+			assert.Zero(t, dstMr.TTL(dstKey), "%s não deveria expirar", key)
+		} else {
+			assert.Equal(t, ttl-elapsed, dstMr.TTL(dstKey), "TTL de %s", key)
+		}
+	}
+	assert.Equal(t, expected, imported)
+	assert.Len(t, dstMr.Keys(), expected)
+	assert.True(t, dstMr.Exists("new:blocked_ip_10.0.0.1"))
+	assert.True(t, dstMr.Exists("new:blocked_token_abc"))
+
+	// O bloqueio continua valendo na nova instância
+	decision, err := dst.AllowDecision(ctx, "10.0.0.1", false)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 55*time.Second, decision.RetryAfter)
+}
+
+// Test_RateLimiter_ExportUnsupported verifica o erro quando o store não exporta o estado (um
+// decorador, sem WithSnapshotter)
+func Test_RateLimiter_ExportUnsupported(t *testing.T) {
+	store := db.NewObservedStore(memory.NewMemoryStore(memory.Config{}), metrics.Noop{})
+	rl := NewRateLimiter(&config.LimiterConfig{MaxRequestsPerIP: 1}, store)
+
+	_, err := rl.Export(context.Background(), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrSnapshotUnsupported)
+	_, err = rl.Import(context.Background(), strings.NewReader("{}"))
+	assert.ErrorIs(t, err, ErrSnapshotUnsupported)
+}
@@ -0,0 +1,45 @@
+package rateLimiter
+
+import (
+	"log"
+
+	"rateLimiter/pkg/metrics"
+)
+
+// softLimitCounter conta as requisições permitidas acima do limite de alerta.
+const softLimitCounter = "ratelimiter_soft_limit_exceeded_total"
+
+// softLimit retorna o limite de alerta configurado para o escopo (0: desligado).
+func (rl *RateLimiter) softLimit(isToken bool) int {
+	if isToken {
+		return rl.limiterConfig.SoftLimitPerToken
+	}
+	return rl.limiterConfig.SoftLimitPerIP
+}
+
+// checkSoftLimit marca a decisão permitida que passou do limite de alerta, contando-a na métrica
+// e registrando em log a primeira requisição da janela além dele. A decisão não muda: quem
+// bloqueia é o limite normal.
+func (rl *RateLimiter) checkSoftLimit(decision *Decision) {
+	soft := rl.softLimit(decision.IsToken)
+	if soft <= 0 || !decision.Allowed || decision.Disabled {
+		return
+	}
+	used := decision.Limit - decision.Remaining
+	if used <= soft {
+		return
+	}
+	decision.SoftLimitExceeded = true
+
+	scope := "ip"
+	if decision.IsToken {
+		scope = "token"
+	}
+	if rl.recorder != nil {
+		rl.recorder.IncCounter(softLimitCounter, metrics.Labels{"scope": scope})
+	}
+	if used == soft+1 {
+		log.Printf("Aviso: %s %s passou do limite de alerta (%d de %d requisições na janela)",
+			scope, decision.Identifier, used, decision.Limit)
+	}
+}
@@ -0,0 +1,23 @@
+package rateLimiter
+
+import (
+	"rateLimiter/infra/db"
+	"rateLimiter/pkg/metrics"
+)
+
+// storeCallsHistogram é o histograma das operações do store por decisão.
+const storeCallsHistogram = "ratelimiter_store_calls_per_decision"
+
+// observeStoreCalls registra quantas operações do store a decisão fez, quando há um recorder
+// que aceita valores no histograma.
+func (rl *RateLimiter) observeStoreCalls(decision *Decision, calls *db.CallCounter) {
+	recorder, ok := rl.recorder.(metrics.ValueRecorder)
+	if !ok || calls == nil {
+		return
+	}
+	outcome := "allowed"
+	if !decision.Allowed {
+		outcome = "rejected"
+	}
+	recorder.ObserveValue(storeCallsHistogram, float64(calls.Count()), metrics.Labels{"outcome": outcome})
+}
@@ -0,0 +1,64 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/pkg/metrics"
+)
+
+// Test_RateLimiter_StoreCallsPerDecision verifica quantas operações do store cada decisão faz
+// nos caminhos permitido, de bloqueio e bloqueado
+func Test_RateLimiter_StoreCallsPerDecision(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm string
+		// operações da requisição permitida, da que excede o limite e da que chega bloqueada
+		allowed, blocking, blocked int
+	}{
+		{config.AlgorithmFixedWindow, 1, 1, 1},
+		{config.AlgorithmSlidingWindow, 2, 5, 1},
+	} {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			mr, client := setupTestRedis(t)
+			defer mr.Close()
+			defer client.Close()
+
+			registry := metrics.NewRegistry()
+			cfg := &config.LimiterConfig{MaxRequestsPerIP: 1, BlockDurationIPSeconds: 60, WindowIPSeconds: 10, Algorithm: tc.algorithm}
+			store := db.NewObservedStore(redisStore.NewRedisStore(client), metrics.Noop{})
+			rl := NewRateLimiter(cfg, store, WithMetrics(registry))
+			ctx := context.Background()
+
+			for _, expected := range []int{tc.allowed, tc.blocking, tc.blocked} {
+				before := storeCallsSum(registry)
+				_, err := rl.AllowDecision(ctx, "192.168.11.1", false)
+				require.NoError(t, err)
+				assert.Equal(t, float64(expected), storeCallsSum(registry)-before)
+			}
+
+			allowed, ok := registry.Histogram(storeCallsHistogram, metrics.Labels{"outcome": "allowed"})
+			require.True(t, ok)
+			assert.Equal(t, uint64(1), allowed.Count)
+			rejected, ok := registry.Histogram(storeCallsHistogram, metrics.Labels{"outcome": "rejected"})
+			require.True(t, ok)
+			assert.Equal(t, uint64(2), rejected.Count)
+		})
+	}
+}
+
+// storeCallsSum soma as operações registradas no histograma, em todos os resultados.
+func storeCallsSum(registry *metrics.Registry) float64 {
+	var sum float64
+	for _, outcome := range []string{"allowed", "rejected"} {
+		if h, ok := registry.Histogram(storeCallsHistogram, metrics.Labels{"outcome": outcome}); ok {
+			sum += h.Sum
+		}
+	}
+	return sum
+}
@@ -0,0 +1,18 @@
+package rateLimiter
+
+import "context"
+
+// tokenTierKey é o tipo da chave usada para guardar a faixa do token no contexto.
+type tokenTierKey struct{}
+
+// WithTokenTier associa ao contexto a faixa de limites do token, definida pelo header de onde
+// ele foi lido (ver config.TokenSource).
+func WithTokenTier(ctx context.Context, tier string) context.Context {
+	return context.WithValue(ctx, tokenTierKey{}, tier)
+}
+
+// TokenTierFromContext retorna a faixa do token guardada no contexto ("" se não houver).
+func TokenTierFromContext(ctx context.Context) string {
+	tier, _ := ctx.Value(tokenTierKey{}).(string)
+	return tier
+}
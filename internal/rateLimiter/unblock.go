@@ -0,0 +1,43 @@
+package rateLimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEmptyPattern é retornado por UnblockPattern quando o padrão está vazio.
+var ErrEmptyPattern = errors.New("padrão de desbloqueio vazio")
+
+// UnblockPattern remove todos os bloqueios cujo identificador casa com o padrão glob e retorna
+// quantos foram removidos. O padrão é aplicado à chave sem o prefixo "blocked_", por exemplo
+// "token_*" para todos os tokens ou "ip_10.0.0.*" para uma sub-rede. Apenas chaves de bloqueio
+// são removidas: contadores e infrações que casem com o padrão são preservados. Com prefixos
+// por escopo, o padrão é aplicado aos bloqueios de cada escopo.
+func (rl *RateLimiter) UnblockPattern(ctx context.Context, pattern string) (int, error) {
+	if pattern == "" {
+		return 0, ErrEmptyPattern
+	}
+
+	total := 0
+	for _, blockedPrefix := range rl.blockedPrefixes() {
+		count, err := rl.store.DeleteMatching(ctx, blockedPrefix+pattern, func(key string) bool {
+			return strings.HasPrefix(key, blockedPrefix)
+		})
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("erro ao remover bloqueios: %w", err)
+		}
+	}
+	return total, nil
+}
+
+// blockedPrefixes retorna os prefixos distintos das chaves de bloqueio dos escopos de IP e token.
+func (rl *RateLimiter) blockedPrefixes() []string {
+	ipPrefix, tokenPrefix := rl.scopedKey("blocked_", false), rl.scopedKey("blocked_", true)
+	if ipPrefix == tokenPrefix {
+		return []string{ipPrefix}
+	}
+	return []string{ipPrefix, tokenPrefix}
+}
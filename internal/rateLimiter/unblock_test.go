@@ -0,0 +1,105 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_UnblockPattern verifica que o desbloqueio por padrão remove exatamente os
+// bloqueios pedidos e preserva os contadores
+func Test_RateLimiter_UnblockPattern(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+	}
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	block := func(identifier string, isToken bool) {
+		for i := 0; i < 2; i++ {
+			_, err := rl.AllowDecision(ctx, identifier, isToken)
+			require.NoError(t, err)
+		}
+		require.True(t, mr.Exists(rl.storeKey(blockKey(identifier, isToken))), "%s deveria estar bloqueado", identifier)
+	}
+	block("10.0.0.1", false)
+	block("10.0.0.2", false)
+	block("10.1.0.1", false)
+	block("abc", true)
+	block("def", true)
+	// Um contador que casa com o padrão não pode ser removido
+	_, err := rl.AllowDecision(ctx, "10.0.0.3", false)
+	require.NoError(t, err)
+
+	count, err := rl.UnblockPattern(ctx, "ip_10.0.0.*")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.False(t, mr.Exists("blocked_ip_10.0.0.1"))
+	assert.False(t, mr.Exists("blocked_ip_10.0.0.2"))
+	assert.True(t, mr.Exists("blocked_ip_10.1.0.1"))
+	assert.True(t, mr.Exists("blocked_token_abc"))
+	assert.True(t, mr.Exists("ip_10.0.0.3"), "Contadores não deveriam ser removidos")
+	assert.True(t, mr.Exists("offenses_ip_10.0.0.1"), "Infrações não deveriam ser removidas")
+
+	count, err = rl.UnblockPattern(ctx, "token_*")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, mr.Exists("blocked_ip_10.1.0.1"))
+
+	decision, err := rl.AllowDecision(ctx, "abc", true)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed, "O token desbloqueado deveria voltar a ser aceito")
+
+	_, err = rl.UnblockPattern(ctx, "")
+	assert.ErrorIs(t, err, ErrEmptyPattern)
+}
+
+// Test_RateLimiter_UnblockPattern_KeyPrefix verifica que o desbloqueio respeita o KeyPrefix
+func Test_RateLimiter_UnblockPattern_KeyPrefix(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+	store := redisStore.NewRedisStore(client)
+	newLimiter := func(prefix string) *RateLimiter {
+		return NewRateLimiter(&config.LimiterConfig{
+			MaxRequestsPerIP:       1,
+			BlockDurationIPSeconds: 60,
+			KeyPrefix:              prefix,
+		}, store)
+	}
+	global, api := newLimiter("global:"), newLimiter("api:")
+	for _, rl := range []*RateLimiter{global, api} {
+		for i := 0; i < 2; i++ {
+			_, err := rl.AllowDecision(ctx, "192.168.9.1", false)
+			require.NoError(t, err)
+		}
+	}
+
+	count, err := api.UnblockPattern(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.False(t, mr.Exists("api:blocked_ip_192.168.9.1"))
+	assert.True(t, mr.Exists("global:blocked_ip_192.168.9.1"), "O bloqueio do outro limiter deveria ser preservado")
+}
+
+// blockKey monta a chave de bloqueio sem o KeyPrefix
+func blockKey(identifier string, isToken bool) string {
+	if isToken {
+		return "blocked_token_" + identifier
+	}
+	return "blocked_ip_" + identifier
+}
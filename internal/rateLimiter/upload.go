@@ -0,0 +1,32 @@
+package rateLimiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// UploadLimiter é implementado por rate limiters que também limitam o volume de bytes enviados
+// pelos clientes no corpo das requisições.
+type UploadLimiter interface {
+	RecordUploadBytes(ctx context.Context, identifier string, isToken bool, n int64) (remaining int64, err error)
+}
+
+// RecordUploadBytes soma n bytes lidos do corpo de uma requisição à cota de upload do
+// identificador e retorna quanto ainda cabe na janela (da cota de bytes, BandwidthWindowSeconds).
+// Um valor negativo indica quantos bytes passaram da cota. Diferente de RecordBytes, não
+// bloqueia: quem lê o corpo interrompe o upload, e os próximos uploads na janela já começam
+// sem cota. Sem MaxUploadBytesPerWindow, a cota é ilimitada.
+func (rl *RateLimiter) RecordUploadBytes(ctx context.Context, identifier string, isToken bool, n int64) (int64, error) {
+	maxBytes := rl.limiterConfig.MaxUploadBytesPerWindow
+	if maxBytes <= 0 || !rl.Enabled() {
+		return math.MaxInt64, nil
+	}
+
+	key := rl.scopedKey("upload_bytes_"+identifierKey(identifier, isToken), isToken)
+	total, err := rl.store.IncrementBy(ctx, key, max(n, 0), rl.bandwidthWindow())
+	if err != nil {
+		return 0, fmt.Errorf("erro ao contabilizar bytes enviados: %w", err)
+	}
+	return maxBytes - total, nil
+}
@@ -0,0 +1,45 @@
+package rateLimiter
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_RecordUploadBytes verifica a cota de bytes enviados, sem bloqueio
+func Test_RateLimiter_RecordUploadBytes(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := NewRateLimiter(&config.LimiterConfig{
+		MaxRequestsPerIP:        10,
+		BlockDurationIPSeconds:  60,
+		MaxUploadBytesPerWindow: 1000,
+		BandwidthWindowSeconds:  30,
+	}, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	remaining, err := rl.RecordUploadBytes(ctx, "192.168.16.1", false, 600)
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), remaining)
+	remaining, err = rl.RecordUploadBytes(ctx, "192.168.16.1", false, 600)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-200), remaining)
+
+	assert.Equal(t, "1200", mustGet(t, mr, "upload_bytes_ip_192.168.16.1"))
+	assert.Equal(t, 30, int(mr.TTL("upload_bytes_ip_192.168.16.1").Seconds()))
+	assert.False(t, mr.Exists("blocked_ip_192.168.16.1"), "A cota de upload não deveria bloquear")
+
+	// Sem cota configurada, tudo cabe
+	unlimited := NewRateLimiter(&config.LimiterConfig{MaxRequestsPerIP: 10}, redisStore.NewRedisStore(client))
+	remaining, err = unlimited.RecordUploadBytes(ctx, "192.168.16.2", false, 600)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), remaining)
+}
@@ -0,0 +1,135 @@
+package rateLimiter
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"rateLimiter/infra/db"
+	"rateLimiter/pkg/metrics"
+)
+
+// utilizationGauge é o gauge com a fração do limite já consumida por identificador.
+const utilizationGauge = "ratelimiter_limit_utilization_ratio"
+
+// gaugeDeleter é implementado por recorders que conseguem remover uma série (ex.: metrics.Registry).
+type gaugeDeleter interface {
+	DeleteGauge(name string, labels metrics.Labels)
+}
+
+// utilization é a utilização de um contador em uma varredura.
+type utilization struct {
+	labels metrics.Labels
+	ratio  float64
+}
+
+// UtilizationMonitor publica periodicamente a utilização (contagem / limite) dos contadores da
+// janela fixa mais ocupados. A varredura roda fora do caminho da requisição e só os topN
+// identificadores viram séries, o que limita a cardinalidade da métrica.
+type UtilizationMonitor struct {
+	scanner  db.CounterScanner
+	resolver db.LimitResolver
+	recorder metrics.Recorder
+	prefix   string
+	topN     int
+	tracked  map[string]metrics.Labels
+	// ipPrefix e tokenPrefix são os prefixos por escopo, depois de prefix.
+	ipPrefix    string
+	tokenPrefix string
+}
+
+// NewUtilizationMonitor cria um monitor que publica a utilização dos topN identificadores.
+// keyPrefix deve ser o mesmo prefixo das chaves do rate limiter observado (StoreKeyPrefix).
+func NewUtilizationMonitor(scanner db.CounterScanner, resolver db.LimitResolver, recorder metrics.Recorder, keyPrefix string, topN int) *UtilizationMonitor {
+	return &UtilizationMonitor{
+		scanner:  scanner,
+		resolver: resolver,
+		recorder: recorder,
+		prefix:   keyPrefix,
+		topN:     topN,
+		tracked:  make(map[string]metrics.Labels),
+	}
+}
+
+// WithScopePrefixes informa os prefixos por escopo (IPKeyPrefix e TokenKeyPrefix) do rate
+// limiter observado e retorna o próprio monitor.
+func (m *UtilizationMonitor) WithScopePrefixes(ipPrefix, tokenPrefix string) *UtilizationMonitor {
+	m.ipPrefix, m.tokenPrefix = ipPrefix, tokenPrefix
+	return m
+}
+
+// Run executa uma varredura a cada intervalo até o contexto ser cancelado. Com um intervalo
+// não positivo, executa uma única varredura.
+func (m *UtilizationMonitor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		if err := m.Scan(ctx); err != nil {
+			log.Printf("Erro ao calcular a utilização dos limites: %v", err)
+		}
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Scan(ctx); err != nil {
+			log.Printf("Erro ao calcular a utilização dos limites: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan lê os contadores, calcula a utilização de cada um e atualiza o gauge dos topN mais
+// ocupados. Identificadores que saíram do top deixam de ser expostos.
+func (m *UtilizationMonitor) Scan(ctx context.Context) error {
+	var all []utilization
+	for _, scope := range []struct {
+		prefix  string
+		name    string
+		isToken bool
+	}{{m.ipPrefix + "ip_", "ip", false}, {m.tokenPrefix + "token_", "token", true}} {
+		counters, err := m.scanner.ScanCounters(ctx, m.prefix+scope.prefix+"*")
+		if err != nil {
+			return err
+		}
+		for key, count := range counters {
+			identifier := strings.TrimPrefix(key, m.prefix+scope.prefix)
+			limit, _, _, err := m.resolver.ResolveLimit(ctx, identifier, scope.isToken)
+			if err != nil || limit <= 0 {
+				continue
+			}
+			all = append(all, utilization{
+				labels: metrics.Labels{"scope": scope.name, "identifier": identifier},
+				ratio:  float64(count) / float64(limit),
+			})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].ratio > all[j].ratio })
+	if len(all) > m.topN {
+		all = all[:m.topN]
+	}
+
+	current := make(map[string]metrics.Labels, len(all))
+	for _, u := range all {
+		series := u.labels["scope"] + "|" + u.labels["identifier"]
+		current[series] = u.labels
+		m.recorder.SetGauge(utilizationGauge, u.ratio, u.labels)
+	}
+	for series, labels := range m.tracked {
+		if _, ok := current[series]; ok {
+			continue
+		}
+		if deleter, ok := m.recorder.(gaugeDeleter); ok {
+			deleter.DeleteGauge(utilizationGauge, labels)
+		} else {
+			m.recorder.SetGauge(utilizationGauge, 0, labels)
+		}
+	}
+	m.tracked = current
+	return nil
+}
@@ -0,0 +1,67 @@
+package rateLimiter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/pkg/metrics"
+)
+
+// Test_UtilizationMonitor_TopN verifica que o gauge de utilização expõe apenas os identificadores mais ocupados
+func Test_UtilizationMonitor_TopN(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 10, MaxRequestsPerToken: 100}
+	counters := map[string]int{
+		"ip_10.0.0.1":   9,  // 0,9
+		"ip_10.0.0.2":   2,  // 0,2
+		"ip_10.0.0.3":   5,  // 0,5
+		"token_abc":     95, // 0,95
+		"token_def":     10, // 0,1
+		"blocked_ip_x":  1,  // ignorada: não é contador
+		"offenses_ip_x": 3,  // ignorada: não é contador
+	}
+	for key, count := range counters {
+		require.NoError(t, mr.Set(key, strconv.Itoa(count)))
+	}
+
+	registry := metrics.NewRegistry()
+	monitor := NewUtilizationMonitor(redisStore.NewRedisStore(client), NewStaticLimitResolver(cfg), registry, "", 3)
+	require.NoError(t, monitor.Scan(context.Background()))
+
+	gauge := func(scope, identifier string) (float64, bool) {
+		return registry.Gauge("ratelimiter_limit_utilization_ratio", metrics.Labels{"scope": scope, "identifier": identifier})
+	}
+
+	for _, expected := range []struct {
+		scope, identifier string
+		ratio             float64
+	}{{"token", "abc", 0.95}, {"ip", "10.0.0.1", 0.9}, {"ip", "10.0.0.3", 0.5}} {
+		value, ok := gauge(expected.scope, expected.identifier)
+		assert.True(t, ok, "%s %s deveria estar entre os mais ocupados", expected.scope, expected.identifier)
+		assert.InDelta(t, expected.ratio, value, 1e-9)
+	}
+	for _, missing := range [][2]string{{"ip", "10.0.0.2"}, {"token", "def"}, {"ip", "x"}} {
+		_, ok := gauge(missing[0], missing[1])
+		assert.False(t, ok, "%s %s não deveria ser exposto", missing[0], missing[1])
+	}
+
+	// Quem sai do top deixa de ser exposto na varredura seguinte
+	require.NoError(t, mr.Set("ip_10.0.0.2", "10"))
+	mr.Del("token_abc")
+	require.NoError(t, monitor.Scan(context.Background()))
+
+	value, ok := gauge("ip", "10.0.0.2")
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, value, 1e-9)
+	_, ok = gauge("token", "abc")
+	assert.False(t, ok, "Contador expirado não deveria continuar exposto")
+}
@@ -0,0 +1,11 @@
+package rateLimiter
+
+// toleratedViolations retorna quantas requisições consecutivas além do limite são permitidas
+// antes da rejeição: ConsecutiveViolationThreshold menos um (0 ou 1 não toleram nenhuma).
+//
+// O próprio contador da janela acompanha as violações. Na janela fixa, depois de passar do
+// limite, toda requisição até a virada da janela também passa, então as violações contadas
+// são sempre consecutivas; a renovação da janela as zera junto com a cota.
+func (rl *RateLimiter) toleratedViolations() int64 {
+	return int64(max(rl.limiterConfig.ConsecutiveViolationThreshold-1, 0))
+}
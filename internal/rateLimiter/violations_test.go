@@ -0,0 +1,61 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+)
+
+// Test_RateLimiter_ConsecutiveViolations verifica que as primeiras violações seguem sem cota e que
+// só as violações sustentadas geram a rejeição e o bloqueio
+func Test_RateLimiter_ConsecutiveViolations(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	rl := NewRateLimiter(&config.LimiterConfig{
+		MaxRequestsPerIP:              2,
+		BlockDurationIPSeconds:        60,
+		ConsecutiveViolationThreshold: 3,
+	}, redisStore.NewRedisStore(client))
+	ctx := context.Background()
+
+	for i, expected := range []struct {
+		allowed, soft bool
+		remaining     int
+	}{
+		{true, false, 1},
+		{true, false, 0},
+		{true, true, 0},
+		{true, true, 0},
+		{false, false, 0},
+	} {
+		decision, err := rl.AllowDecision(ctx, "192.168.15.1", false)
+		require.NoError(t, err)
+		assert.Equal(t, expected.allowed, decision.Allowed, "Requisição %d", i+1)
+		assert.Equal(t, expected.soft, decision.SoftAllowed, "Requisição %d", i+1)
+		assert.Equal(t, expected.remaining, decision.Remaining, "Requisição %d", i+1)
+		assert.Equal(t, 2, decision.Limit)
+	}
+	assert.True(t, mr.Exists("blocked_ip_192.168.15.1"))
+
+	// Uma única violação é tolerada e não deixa bloqueio: a janela seguinte começa do zero
+	for i := 0; i < 3; i++ {
+		decision, err := rl.AllowDecision(ctx, "192.168.15.2", false)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+	assert.False(t, mr.Exists("blocked_ip_192.168.15.2"))
+	mr.FastForward(time.Second)
+	decision, err := rl.AllowDecision(ctx, "192.168.15.2", false)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.False(t, decision.SoftAllowed)
+	assert.Equal(t, 1, decision.Remaining)
+}
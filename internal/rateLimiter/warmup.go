@@ -0,0 +1,18 @@
+package rateLimiter
+
+import "time"
+
+// warmupMultiplier retorna o multiplicador do aquecimento no instante now: WarmupMultiplier na
+// criação do rate limiter, caindo linearmente até 1 ao fim de WarmupSeconds.
+func (rl *RateLimiter) warmupMultiplier(now time.Time) float64 {
+	multiplier := rl.limiterConfig.WarmupMultiplier
+	duration := time.Duration(rl.limiterConfig.WarmupSeconds) * time.Second
+	if multiplier <= 1 || duration <= 0 {
+		return 1
+	}
+	elapsed := max(now.Sub(rl.startedAt), 0)
+	if elapsed >= duration {
+		return 1
+	}
+	return multiplier - (multiplier-1)*float64(elapsed)/float64(duration)
+}
@@ -0,0 +1,55 @@
+package rateLimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/clock"
+)
+
+// Test_RateLimiter_Warmup verifica que o limite efetivo começa multiplicado e cai linearmente até
+// o configurado ao fim do aquecimento
+func Test_RateLimiter_Warmup(t *testing.T) {
+	mr, client := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{MaxRequestsPerIP: 10, BlockDurationIPSeconds: 60, WarmupMultiplier: 3, WarmupSeconds: 60}
+	start := time.Unix(1_700_000_000, 0)
+	fake := clock.NewFake(start)
+	rl := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(fake))
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		elapsed  time.Duration
+		expected int
+	}{
+		{0, 30},
+		{15 * time.Second, 25},
+		{30 * time.Second, 20},
+		{59 * time.Second, 10}, // 10,33 arredondado para baixo
+		{60 * time.Second, 10},
+		{time.Hour, 10},
+	} {
+		fake.Set(start.Add(tc.elapsed))
+		decision, err := rl.AllowDecision(ctx, "192.168.14.1", false)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, decision.Limit, "Limite após %s", tc.elapsed)
+	}
+
+	// O limite do aquecimento vale para a contagem
+	warm := NewRateLimiter(cfg, redisStore.NewRedisStore(client), WithClock(clock.NewFake(start)))
+	assert.Equal(t, 30, allowedUntilRejected(t, warm, "192.168.14.2", 40))
+}
+
+// Test_RateLimiter_Warmup_Disabled verifica que, sem multiplicador, o limite não muda
+func Test_RateLimiter_Warmup_Disabled(t *testing.T) {
+	rl := NewRateLimiter(&config.LimiterConfig{MaxRequestsPerIP: 10, WarmupSeconds: 60}, nil)
+	assert.Equal(t, 10, rl.boostedLimit(10, time.Now()))
+}
@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets são os limites (em segundos) dos histogramas de latência.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// CountBuckets são os limites dos histogramas de contagens pequenas (ex.: operações por decisão).
+var CountBuckets = []float64{1, 2, 3, 4, 5, 6, 8, 10, 15, 20}
+
+// Labels são os rótulos associados a uma métrica.
+type Labels map[string]string
+
+// Recorder é o gancho de métricas usado pelos componentes do rate limiter.
+type Recorder interface {
+	IncCounter(name string, labels Labels)
+	SetGauge(name string, value float64, labels Labels)
+	ObserveDuration(name string, d time.Duration, labels Labels)
+}
+
+// ValueRecorder é implementado por recorders que registram valores adimensionais em histogramas.
+type ValueRecorder interface {
+	ObserveValue(name string, value float64, labels Labels)
+}
+
+// Noop é um Recorder que descarta todas as métricas.
+type Noop struct{}
+
+// IncCounter não faz nada.
+func (Noop) IncCounter(string, Labels) {}
+
+// SetGauge não faz nada.
+func (Noop) SetGauge(string, float64, Labels) {}
+
+// ObserveDuration não faz nada.
+func (Noop) ObserveDuration(string, time.Duration, Labels) {}
+
+// Histogram acumula observações em buckets cumulativos.
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// observe registra um valor no histograma.
+func (h *Histogram) observe(value float64) {
+	h.Count++
+	h.Sum += value
+	for i, upper := range h.Buckets {
+		if value <= upper {
+			h.Counts[i]++
+		}
+	}
+}
+
+// Registry é um Recorder em memória que expõe as métricas no formato texto do Prometheus.
+type Registry struct {
+	mu         sync.RWMutex
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string]*histogramSeries
+}
+
+// histogramSeries guarda um histograma junto com o nome e os rótulos para a exposição.
+type histogramSeries struct {
+	name   string
+	labels Labels
+	hist   *Histogram
+}
+
+// NewRegistry cria um Registry vazio.
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]float64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string]*histogramSeries),
+	}
+}
+
+// IncCounter incrementa um contador em 1.
+func (r *Registry) IncCounter(name string, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[seriesName(name, labels)]++
+}
+
+// SetGauge define o valor de um gauge.
+func (r *Registry) SetGauge(name string, value float64, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[seriesName(name, labels)] = value
+}
+
+// ObserveDuration registra uma duração (em segundos) no histograma da série.
+func (r *Registry) ObserveDuration(name string, d time.Duration, labels Labels) {
+	r.observe(name, d.Seconds(), DefaultBuckets, labels)
+}
+
+// ObserveValue registra um valor no histograma da série, com os buckets de CountBuckets.
+func (r *Registry) ObserveValue(name string, value float64, labels Labels) {
+	r.observe(name, value, CountBuckets, labels)
+}
+
+// observe registra o valor no histograma da série, criando-o com os buckets informados.
+func (r *Registry) observe(name string, value float64, buckets []float64, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series := seriesName(name, labels)
+	h, ok := r.histograms[series]
+	if !ok {
+		h = &histogramSeries{
+			name:   name,
+			labels: labels,
+			hist: &Histogram{
+				Buckets: buckets,
+				Counts:  make([]uint64, len(buckets)),
+			},
+		}
+		r.histograms[series] = h
+	}
+	h.hist.observe(value)
+}
+
+// Histogram retorna uma cópia do histograma da série, se existir.
+func (r *Registry) Histogram(name string, labels Labels) (Histogram, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h, ok := r.histograms[seriesName(name, labels)]
+	if !ok {
+		return Histogram{}, false
+	}
+	snapshot := *h.hist
+	snapshot.Counts = append([]uint64(nil), h.hist.Counts...)
+	return snapshot, true
+}
+
+// Counter retorna o valor atual de um contador.
+func (r *Registry) Counter(name string, labels Labels) float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.counters[seriesName(name, labels)]
+}
+
+// DeleteGauge remove a série de um gauge, para que ela deixe de ser exposta.
+func (r *Registry) DeleteGauge(name string, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.gauges, seriesName(name, labels))
+}
+
+// Gauge retorna o valor atual de um gauge e se ele já foi definido.
+func (r *Registry) Gauge(name string, labels Labels) (float64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	value, ok := r.gauges[seriesName(name, labels)]
+	return value, ok
+}
+
+// ServeHTTP escreve todas as séries no formato texto do Prometheus.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.RLock()
+	lines := make([]string, 0, len(r.counters)+len(r.gauges))
+	for series, value := range r.counters {
+		lines = append(lines, fmt.Sprintf("%s %g", series, value))
+	}
+	for series, value := range r.gauges {
+		lines = append(lines, fmt.Sprintf("%s %g", series, value))
+	}
+	for _, h := range r.histograms {
+		for i, upper := range h.hist.Buckets {
+			lines = append(lines, fmt.Sprintf("%s %d", seriesName(h.name+"_bucket", withLabel(h.labels, "le", fmt.Sprintf("%g", upper))), h.hist.Counts[i]))
+		}
+		lines = append(lines, fmt.Sprintf("%s %d", seriesName(h.name+"_bucket", withLabel(h.labels, "le", "+Inf")), h.hist.Count))
+		lines = append(lines, fmt.Sprintf("%s %g", seriesName(h.name+"_sum", h.labels), h.hist.Sum))
+		lines = append(lines, fmt.Sprintf("%s %d", seriesName(h.name+"_count", h.labels), h.hist.Count))
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(lines)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(w, line)
+	}
+}
+
+// withLabel retorna uma cópia dos rótulos com um rótulo adicional.
+func withLabel(labels Labels, key, value string) Labels {
+	out := make(Labels, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// seriesName monta o identificador da série no formato name{k="v",...}, com rótulos ordenados.
+func seriesName(name string, labels Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
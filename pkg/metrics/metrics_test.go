@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_Registry_CountersAndGauges verifica o registro de contadores e gauges
+func Test_Registry_CountersAndGauges(t *testing.T) {
+	r := NewRegistry()
+
+	r.IncCounter("requests_total", Labels{"result": "allowed"})
+	r.IncCounter("requests_total", Labels{"result": "allowed"})
+	r.IncCounter("requests_total", Labels{"result": "blocked"})
+	r.SetGauge("state", 2, nil)
+
+	assert.Equal(t, float64(2), r.Counter("requests_total", Labels{"result": "allowed"}))
+	assert.Equal(t, float64(1), r.Counter("requests_total", Labels{"result": "blocked"}))
+
+	value, ok := r.Gauge("state", nil)
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), value)
+
+	_, ok = r.Gauge("missing", nil)
+	assert.False(t, ok)
+}
+
+// Test_Registry_ServeHTTP verifica a exposição no formato texto
+func Test_Registry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.IncCounter("requests_total", Labels{"scope": "ip", "result": "allowed"})
+	r.SetGauge("state", 1, nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `requests_total{result="allowed",scope="ip"} 1`)
+	assert.Contains(t, body, "state 1")
+}
+
+// Test_Registry_Histogram verifica o registro de durações em buckets cumulativos
+func Test_Registry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	labels := Labels{"method": "Increment"}
+
+	r.ObserveDuration("latency_seconds", 2*time.Millisecond, labels)
+	r.ObserveDuration("latency_seconds", 200*time.Millisecond, labels)
+
+	h, ok := r.Histogram("latency_seconds", labels)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), h.Count)
+	assert.InDelta(t, 0.202, h.Sum, 1e-9)
+	assert.Equal(t, uint64(0), h.Counts[1], "Bucket de 1ms não deveria conter observações")
+	assert.Equal(t, uint64(1), h.Counts[2], "Bucket de 2,5ms deveria conter a observação de 2ms")
+	assert.Equal(t, uint64(2), h.Counts[len(h.Counts)-1])
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `latency_seconds_bucket{le="+Inf",method="Increment"} 2`)
+	assert.Contains(t, rec.Body.String(), `latency_seconds_count{method="Increment"} 2`)
+}
+
+// Test_Registry_ObserveValue verifica o registro de contagens nos buckets de CountBuckets
+func Test_Registry_ObserveValue(t *testing.T) {
+	r := NewRegistry()
+	labels := Labels{"outcome": "allowed"}
+
+	r.ObserveValue("calls", 1, labels)
+	r.ObserveValue("calls", 5, labels)
+
+	h, ok := r.Histogram("calls", labels)
+	assert.True(t, ok)
+	assert.Equal(t, CountBuckets, h.Buckets)
+	assert.Equal(t, uint64(2), h.Count)
+	assert.Equal(t, float64(6), h.Sum)
+	assert.Equal(t, uint64(1), h.Counts[0], "Bucket 1 deveria conter só a primeira observação")
+	assert.Equal(t, uint64(2), h.Counts[4], "Bucket 5 deveria conter as duas observações")
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// rolloutKey é a chave do contexto com a marcação do middleware anterior nos testes.
+type rolloutKey struct{}
+
+// Test_RateLimit_ActiveWhen verifica que o limite só se aplica às requisições marcadas no contexto
+func Test_RateLimit_ActiveWhen(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       2,
+		BlockDurationIPSeconds: 60,
+		TokenHeaderName:        "API_KEY",
+	})
+	limited := RateLimit(rl, WithActiveWhen(func(r *http.Request) bool {
+		active, _ := r.Context().Value(rolloutKey{}).(bool)
+		return active
+	}))(okHandler)
+	// O middleware anterior marca as requisições do tenant na liberação gradual
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active := r.Header.Get("X-Tenant") == "beta"
+		limited.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rolloutKey{}, active)))
+	})
+
+	do := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 5; i++ {
+		rec := do("stable")
+		assert.Equal(t, http.StatusOK, rec.Code, "Sem a marcação, o limite não se aplica")
+		assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	assert.Empty(t, mr.Keys(), "Requisições sem a marcação não são contabilizadas")
+
+	assert.Equal(t, http.StatusOK, do("beta").Code)
+	assert.Equal(t, http.StatusOK, do("beta").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("beta").Code, "Com a marcação, o limite se aplica")
+	assert.Equal(t, http.StatusOK, do("stable").Code, "O bloqueio não alcança as requisições sem a marcação")
+}
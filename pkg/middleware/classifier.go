@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// MethodClassifier mapeia o método HTTP para a classe do contador ("" usa o contador comum).
+type MethodClassifier func(method string) string
+
+// ReadWriteClassifier classifica os métodos informados como leitura e os demais como escrita.
+// Sem métodos, GET, HEAD e OPTIONS são considerados leituras.
+func ReadWriteClassifier(readMethods ...string) MethodClassifier {
+	if len(readMethods) == 0 {
+		readMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+	reads := make(map[string]bool, len(readMethods))
+	for _, method := range readMethods {
+		reads[strings.ToUpper(method)] = true
+	}
+	return func(method string) string {
+		if reads[method] {
+			return config.ClassRead
+		}
+		return config.ClassWrite
+	}
+}
+
+// requestClass retorna a classe da requisição segundo o classificador configurado. Com
+// config.PreflightSeparate, as requisições OPTIONS ficam na classe config.ClassPreflight.
+func (o *options) requestClass(r *http.Request) string {
+	if r.Method == http.MethodOptions && o.preflightPolicy == config.PreflightSeparate {
+		return config.ClassPreflight
+	}
+	if o.classifier == nil {
+		return ""
+	}
+	return o.classifier(r.Method)
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_ReadWriteClassifier verifica a classificação padrão e a configurável dos métodos
+func Test_ReadWriteClassifier(t *testing.T) {
+	classify := ReadWriteClassifier()
+	assert.Equal(t, config.ClassRead, classify(http.MethodGet))
+	assert.Equal(t, config.ClassRead, classify(http.MethodHead))
+	assert.Equal(t, config.ClassWrite, classify(http.MethodPost))
+	assert.Equal(t, config.ClassWrite, classify(http.MethodDelete))
+
+	custom := ReadWriteClassifier("get", "POST")
+	assert.Equal(t, config.ClassRead, custom(http.MethodPost))
+	assert.Equal(t, config.ClassWrite, custom(http.MethodHead))
+}
+
+// Test_RateLimit_ReadWriteQuotas verifica que esgotar a cota de escrita mantém a de leitura do mesmo cliente
+func Test_RateLimit_ReadWriteQuotas(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          3,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		ClassLimits: map[string]config.ClassLimit{
+			config.ClassWrite: {MaxRequestsPerIP: 1},
+		},
+	})
+	middleware := RateLimit(rl, WithMethodClassifier(ReadWriteClassifier()))(okHandler)
+
+	send := func(method string) int {
+		req := httptest.NewRequest(method, "/", nil)
+		req.RemoteAddr = "192.0.2.110:1000"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost))
+	assert.Equal(t, http.StatusTooManyRequests, send(http.MethodPut), "A cota de escrita é 1")
+
+	// As leituras usam o próprio contador, com o limite geral de 3
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send(http.MethodGet), "Leitura %d deveria ser permitida", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send(http.MethodGet))
+}
+
+// Test_RateLimit_NoClassifier_SharedBucket verifica que, por padrão, leituras e escritas dividem o contador
+func Test_RateLimit_NoClassifier_SharedBucket(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl)(okHandler)
+
+	codes := []int{}
+	for _, method := range []string{http.MethodPost, http.MethodPost, http.MethodGet} {
+		req := httptest.NewRequest(method, "/", nil)
+		req.RemoteAddr = "192.0.2.111:1000"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+// Test_RateLimit_PreflightPolicy verifica que, com skip ou separate, requisições OPTIONS não
+// esgotam a cota das requisições comuns
+func Test_RateLimit_PreflightPolicy(t *testing.T) {
+	tests := []struct {
+		policy         string
+		preflightCodes []int
+		mainAllowed    int
+	}{
+		// Por padrão, os preflights consomem a cota comum
+		{policy: config.PreflightCount, preflightCodes: []int{200, 200, 429, 429, 429}, mainAllowed: 0},
+		{policy: config.PreflightSkip, preflightCodes: []int{200, 200, 200, 200, 200}, mainAllowed: 2},
+		// Contador próprio, com o limite de 3 da classe preflight
+		{policy: config.PreflightSeparate, preflightCodes: []int{200, 200, 200, 429, 429}, mainAllowed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			_, rl := newTestLimiter(t, &config.LimiterConfig{
+				MaxRequestsPerIP:          2,
+				MaxRequestsPerToken:       10,
+				BlockDurationIPSeconds:    10,
+				BlockDurationTokenSeconds: 10,
+				TokenHeaderName:           "API_KEY",
+				ClassLimits: map[string]config.ClassLimit{
+					config.ClassPreflight: {MaxRequestsPerIP: 3},
+				},
+			})
+			middleware := RateLimit(rl, WithPreflightPolicy(tt.policy))(okHandler)
+
+			send := func(method string) int {
+				req := httptest.NewRequest(method, "/", nil)
+				req.RemoteAddr = "192.0.2.120:1000"
+				req.Header.Set("Origin", "https://example.com")
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				rec := httptest.NewRecorder()
+				middleware.ServeHTTP(rec, req)
+				return rec.Code
+			}
+
+			for i, code := range tt.preflightCodes {
+				assert.Equal(t, code, send(http.MethodOptions), "Preflight %d", i+1)
+			}
+			allowed := 0
+			for i := 0; i < 3; i++ {
+				if send(http.MethodPost) == http.StatusOK {
+					allowed++
+				}
+			}
+			assert.Equal(t, tt.mainAllowed, allowed, "Requisições comuns permitidas após os preflights")
+		})
+	}
+}
+
+// Test_RateLimit_PreflightCoalesce verifica que o preflight e a requisição real que o segue
+// consomem uma única unidade da cota
+func Test_RateLimit_PreflightCoalesce(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       10,
+		BlockDurationIPSeconds: 10,
+		PreflightPolicy:        config.PreflightCoalesce,
+	})
+	middleware := RateLimit(rl, WithPreflightPolicy(config.PreflightCoalesce))(okHandler)
+	send := func(method, origin, requestMethod string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.RemoteAddr = "192.0.2.121:1000"
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec
+	}
+	remaining := func(rec *httptest.ResponseRecorder) string {
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Header().Get("X-RateLimit-Remaining")
+	}
+	const origin = "https://app.example.com"
+
+	// O preflight conta; a requisição real que ele cobre não
+	assert.Equal(t, "9", remaining(send(http.MethodOptions, origin, "put")))
+	assert.Equal(t, "9", remaining(send(http.MethodPut, origin, "")))
+
+	// Cada preflight cobre uma única requisição real: a seguinte conta
+	assert.Equal(t, "8", remaining(send(http.MethodPut, origin, "")))
+
+	// Outro método, outra origem ou uma requisição sem Origin não aproveitam o preflight
+	assert.Equal(t, "7", remaining(send(http.MethodOptions, origin, http.MethodDelete)))
+	assert.Equal(t, "6", remaining(send(http.MethodPost, origin, "")))
+	assert.Equal(t, "5", remaining(send(http.MethodDelete, "https://other.example.com", "")))
+	assert.Equal(t, "4", remaining(send(http.MethodDelete, "", "")))
+
+	// A requisição real recebe a decisão do seu preflight, sem contar de novo
+	assert.Equal(t, "7", remaining(send(http.MethodDelete, origin, "")))
+	assert.Equal(t, "3", remaining(send(http.MethodDelete, origin, "")))
+
+	// Depois da janela, o preflight já não cobre a requisição real
+	assert.Equal(t, "2", remaining(send(http.MethodOptions, origin, http.MethodPatch)))
+	mr.FastForward(6 * time.Second)
+	assert.Equal(t, "9", remaining(send(http.MethodPatch, origin, "")), "A janela de 1s do contador também expirou")
+}
+
+// Test_RateLimit_PreflightCoalesceWithGlobal verifica que o preflight coalescido vale também com
+// o limite global, que continua contando as duas requisições
+func Test_RateLimit_PreflightCoalesceWithGlobal(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       10,
+		WindowIPSeconds:        60,
+		BlockDurationIPSeconds: 10,
+		PreflightPolicy:        config.PreflightCoalesce,
+		GlobalLimit:            100,
+		GlobalWindowSeconds:    60,
+	})
+	middleware := RateLimit(rl, WithPreflightPolicy(config.PreflightCoalesce))(okHandler)
+	send := func(method, requestMethod string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.RemoteAddr = "192.0.2.122:1000"
+		req.Header.Set("Origin", "https://app.example.com")
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	assert.Equal(t, "9", send(http.MethodOptions, http.MethodPut).Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "9", send(http.MethodPut, "").Header().Get("X-RateLimit-Remaining"), "A requisição real não deveria contar de novo")
+	assert.Equal(t, "8", send(http.MethodPut, "").Header().Get("X-RateLimit-Remaining"))
+
+	global, err := mr.Get("global")
+	require.NoError(t, err)
+	assert.Equal(t, "3", global, "O limite global deveria contar todas as requisições")
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// clientIP resolve o IP do cliente. Quando a conexão vem de um proxy confiável, o IP é
+// obtido do X-Forwarded-For: por padrão, o primeiro endereço não confiável lido da direita para
+// a esquerda; com config.XForwardedForLeftmost, o endereço mais à esquerda. O endereço da
+// conexão passa pela normalização de WithIdentifierNormalization.
+func (o *options) clientIP(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", err
+	}
+	host = o.identifierNormalization.ip(host)
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !o.isTrustedProxy(remote) {
+		return host, nil
+	}
+
+	hops := forwardedFor(r)
+	if len(hops) == 0 {
+		return host, nil
+	}
+	if o.xffSelect == config.XForwardedForLeftmost {
+		return hops[0].String(), nil
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !o.isTrustedProxy(hops[i]) {
+			return hops[i].String(), nil
+		}
+	}
+	// Todos os saltos são proxies confiáveis: o mais à esquerda é o cliente
+	return hops[0].String(), nil
+}
+
+// isTrustedProxy informa se o endereço pertence a um dos proxies confiáveis.
+func (o *options) isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range o.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor retorna os endereços válidos do X-Forwarded-For, na ordem do header.
+func forwardedFor(r *http.Request) []netip.Addr {
+	var hops []netip.Addr
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, part := range strings.Split(header, ",") {
+			addr, err := netip.ParseAddr(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			hops = append(hops, addr.Unmap())
+		}
+	}
+	return hops
+}
+
+// isPrivateIP informa se o IP é privado (RFC 1918 / RFC 4193) ou de loopback.
+func isPrivateIP(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return addr.IsPrivate() || addr.IsLoopback()
+}
+
+// ParsePrefixes converte uma lista de CIDRs ou IPs isolados em prefixos.
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("erro ao converter CIDR %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao converter IP %q: %w", value, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_ClientIP_TrustedProxies verifica a resolução do IP do cliente com e sem proxy confiável
+func Test_ClientIP_TrustedProxies(t *testing.T) {
+	proxies, err := ParsePrefixes([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+	o := newOptions([]Option{WithTrustedProxies(proxies...)})
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		expected   string
+	}{
+		{name: "sem proxy", remoteAddr: "203.0.113.5:1000", expected: "203.0.113.5"},
+		{name: "origem não confiável ignora XFF", remoteAddr: "203.0.113.5:1000", xff: "198.51.100.9", expected: "203.0.113.5"},
+		{name: "proxy confiável", remoteAddr: "10.0.0.1:1000", xff: "198.51.100.9", expected: "198.51.100.9"},
+		{name: "cadeia de proxies", remoteAddr: "10.0.0.1:1000", xff: "1.1.1.1, 198.51.100.9, 192.0.2.1", expected: "198.51.100.9"},
+		{name: "todos confiáveis", remoteAddr: "10.0.0.1:1000", xff: "10.1.1.1, 10.2.2.2", expected: "10.1.1.1"},
+		{name: "proxy sem XFF", remoteAddr: "10.0.0.1:1000", expected: "10.0.0.1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = c.remoteAddr
+			if c.xff != "" {
+				req.Header.Set("X-Forwarded-For", c.xff)
+			}
+			ip, err := o.clientIP(req)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, ip)
+		})
+	}
+}
+
+// Test_ClientIP_XForwardedForSelect verifica o IP escolhido por cada estratégia em uma cadeia
+// com vários saltos
+func Test_ClientIP_XForwardedForSelect(t *testing.T) {
+	proxies, err := ParsePrefixes([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	// Cliente original (forjável), cliente real conectado ao proxy externo e dois proxies internos
+	const chain = "203.0.113.7, 198.51.100.9, 10.1.1.1, 10.2.2.2"
+	cases := []struct {
+		strategy string
+		expected string
+	}{
+		{strategy: "", expected: "198.51.100.9"},
+		{strategy: config.XForwardedForRightmostUntrusted, expected: "198.51.100.9"},
+		{strategy: config.XForwardedForLeftmost, expected: "203.0.113.7"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.strategy, func(t *testing.T) {
+			o := newOptions([]Option{WithTrustedProxies(proxies...), WithXForwardedForSelect(c.strategy)})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "10.0.0.1:1000"
+			req.Header.Set("X-Forwarded-For", chain)
+			ip, err := o.clientIP(req)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, ip)
+
+			// De uma origem não confiável, o header é ignorado por qualquer estratégia
+			req.RemoteAddr = "203.0.113.50:1000"
+			ip, err = o.clientIP(req)
+			require.NoError(t, err)
+			assert.Equal(t, "203.0.113.50", ip)
+		})
+	}
+}
+
+// Test_ParsePrefixes_Invalid verifica o erro para CIDRs inválidos
+func Test_ParsePrefixes_Invalid(t *testing.T) {
+	_, err := ParsePrefixes([]string{"10.0.0.0/99"})
+	assert.Error(t, err)
+
+	_, err = ParsePrefixes([]string{"not-an-ip"})
+	assert.Error(t, err)
+
+	prefixes, err := ParsePrefixes([]string{" ", "::1"})
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("::1/128")}, prefixes)
+}
+
+// Test_RateLimit_SkipPrivateNetworks verifica que redes privadas não são limitadas,
+// avaliando o cliente resolvido e não o proxy
+func Test_RateLimit_SkipPrivateNetworks(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	proxies, err := ParsePrefixes([]string{"10.0.0.1"})
+	require.NoError(t, err)
+	middleware := RateLimit(rl, WithSkipPrivateNetworks(true), WithTrustedProxies(proxies...))(okHandler)
+
+	send := func(remoteAddr, xff string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// IPs privados e loopback passam sem contabilizar
+	for _, addr := range []string{"192.168.0.10:1000", "172.16.5.4:1000", "127.0.0.1:1000", "[::1]:1000", "[fd00::1]:1000"} {
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, send(addr, ""), "IP privado %s não deveria ser limitado", addr)
+		}
+	}
+	assert.Empty(t, mr.Keys(), "Nenhum contador deveria ser criado para IPs privados")
+
+	// IP público é limitado normalmente
+	assert.Equal(t, http.StatusOK, send("203.0.113.10:1000", ""))
+	assert.Equal(t, http.StatusTooManyRequests, send("203.0.113.10:1000", ""))
+
+	// Cliente público atrás do proxy privado continua limitado
+	assert.Equal(t, http.StatusOK, send("10.0.0.1:1000", "203.0.113.20"))
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.1:1000", "203.0.113.20"),
+		"O cliente público atrás de um proxy privado deveria ser limitado")
+
+	// Cliente privado atrás do proxy é liberado
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("10.0.0.1:1000", "192.168.7.7"))
+	}
+}
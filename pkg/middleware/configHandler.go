@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/internal/rateLimiter"
+)
+
+// redacted substitui os valores sensíveis na configuração exposta por ConfigHandler.
+const redacted = "[REDACTED]"
+
+// ConfigHandler expõe em JSON a configuração efetiva do rate limiter, para comparar os limites
+// carregados por cada instância. A requisição precisa trazer "Authorization: Bearer <secret>";
+// com secret vazio, o endpoint responde 404. Os segredos (IPHashSecret e ConfigEndpointSecret)
+// e os tokens de PreloadTokens são substituídos por "[REDACTED]".
+func ConfigHandler(rl rateLimiter.RateLimiterInterface, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r, secret) {
+			return
+		}
+
+		body, err := json.MarshalIndent(redactConfig(rl.GetConfig()), "", "  ")
+		if err != nil {
+			http.Error(w, "Erro ao serializar a configuração", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(append(body, '\n'))
+	})
+}
+
+// authorizeAdmin verifica o segredo e o método de uma requisição aos endpoints de
+// diagnóstico e, se ela não puder seguir, escreve a resposta: 404 sem segredo configurado, 401
+// sem "Authorization: Bearer <secret>" e 405 para métodos que não sejam GET ou HEAD.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request, secret string) bool {
+	if secret == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Não autorizado", http.StatusUnauthorized)
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+// redactConfig retorna uma cópia da configuração com os valores sensíveis substituídos.
+func redactConfig(cfg *config.LimiterConfig) config.LimiterConfig {
+	safe := *cfg
+	if safe.IPHashSecret != "" {
+		safe.IPHashSecret = redacted
+	}
+	if safe.ConfigEndpointSecret != "" {
+		safe.ConfigEndpointSecret = redacted
+	}
+	if len(safe.PreloadTokens) > 0 {
+		safe.PreloadTokens = make([]string, len(cfg.PreloadTokens))
+		for i := range safe.PreloadTokens {
+			safe.PreloadTokens[i] = redacted
+		}
+	}
+	return safe
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_ConfigHandler verifica a configuração exposta, com os segredos ocultos
+func Test_ConfigHandler(t *testing.T) {
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       5,
+		MaxRequestsPerToken:    10,
+		BlockDurationIPSeconds: 60,
+		TokenHeaderName:        "API_KEY",
+		KeyPrefix:              "svc:",
+		IPHashSecret:           "hmac-secret",
+		PreloadTokens:          []string{"tok-a", "tok-b"},
+		ConfigEndpointSecret:   "s3cr3t",
+		TokenTiers:             map[string]config.TokenTier{"partner": {MaxRequests: 100}},
+	}
+	_, rl := newTestLimiter(t, cfg)
+	handler := ConfigHandler(rl, "s3cr3t")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "hmac-secret")
+	assert.NotContains(t, rec.Body.String(), "s3cr3t")
+	assert.NotContains(t, rec.Body.String(), "tok-a")
+
+	var got config.LimiterConfig
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, 5, got.MaxRequestsPerIP)
+	assert.Equal(t, 10, got.MaxRequestsPerToken)
+	assert.Equal(t, 60, got.BlockDurationIPSeconds)
+	assert.Equal(t, "API_KEY", got.TokenHeaderName)
+	assert.Equal(t, "svc:", got.KeyPrefix)
+	assert.Equal(t, 100, got.TokenTiers["partner"].MaxRequests)
+	assert.Equal(t, redacted, got.IPHashSecret)
+	assert.Equal(t, redacted, got.ConfigEndpointSecret)
+	assert.Equal(t, []string{redacted, redacted}, got.PreloadTokens)
+
+	// A configuração do rate limiter não é alterada
+	assert.Equal(t, "hmac-secret", cfg.IPHashSecret)
+	assert.Equal(t, []string{"tok-a", "tok-b"}, cfg.PreloadTokens)
+}
+
+// Test_ConfigHandler_Unauthorized verifica a exigência do segredo
+func Test_ConfigHandler_Unauthorized(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{MaxRequestsPerIP: 5})
+
+	for name, auth := range map[string]string{"sem header": "", "segredo errado": "Bearer outro", "sem Bearer": "s3cr3t"} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		ConfigHandler(rl, "s3cr3t").ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+		assert.NotContains(t, rec.Body.String(), "MaxRequestsPerIP", name)
+	}
+
+	// Sem segredo configurado, o endpoint não existe
+	req := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	ConfigHandler(rl, "").ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"context"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// decisionContextKey é o tipo da chave usada para guardar a decisão no contexto.
+type decisionContextKey struct{}
+
+// DecisionContextKey é a chave do contexto da requisição onde o middleware guarda o *rateLimiter.Decision.
+var DecisionContextKey = decisionContextKey{}
+
+// DecisionFromContext retorna a decisão de rate limit guardada pelo middleware, se houver.
+func DecisionFromContext(ctx context.Context) (*rateLimiter.Decision, bool) {
+	decision, ok := ctx.Value(DecisionContextKey).(*rateLimiter.Decision)
+	return decision, ok && decision != nil
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// DecisionEvent é o registro publicado para cada decisão de rate limit.
+// O identificador é publicado apenas como hash, sem o IP ou token original.
+type DecisionEvent struct {
+	IdentifierHash string    `json:"identifier_hash"`
+	IsToken        bool      `json:"is_token"`
+	Allowed        bool      `json:"allowed"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// DecisionSink recebe as decisões do middleware (ex.: um produtor Kafka ou NATS).
+type DecisionSink interface {
+	Publish(DecisionEvent)
+}
+
+// newDecisionEvent monta o evento a partir da decisão.
+func newDecisionEvent(d *rateLimiter.Decision, now time.Time) DecisionEvent {
+	return DecisionEvent{
+		IdentifierHash: hashIdentifier(d.Identifier),
+		IsToken:        d.IsToken,
+		Allowed:        d.Allowed,
+		Timestamp:      now,
+	}
+}
+
+// hashIdentifier retorna um hash curto e estável do identificador.
+func hashIdentifier(identifier string) string {
+	sum := sha256.Sum256([]byte(identifier))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AsyncSink publica as decisões em outro sink a partir de uma goroutine, com buffer limitado.
+// Quando o buffer está cheio o evento é descartado e contado, sem bloquear a requisição.
+type AsyncSink struct {
+	next    DecisionSink
+	events  chan DecisionEvent
+	dropped atomic.Uint64
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewAsyncSink cria um AsyncSink com o tamanho de buffer informado e inicia a publicação.
+func NewAsyncSink(next DecisionSink, bufferSize int) *AsyncSink {
+	s := &AsyncSink{
+		next:   next,
+		events: make(chan DecisionEvent, bufferSize),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// run entrega os eventos do buffer ao sink decorado.
+func (s *AsyncSink) run() {
+	defer s.wg.Done()
+	for event := range s.events {
+		s.next.Publish(event)
+	}
+}
+
+// Publish enfileira o evento sem bloquear; descarta-o se o buffer estiver cheio.
+func (s *AsyncSink) Publish(event DecisionEvent) {
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped retorna quantos eventos foram descartados por buffer cheio.
+func (s *AsyncSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close para de aceitar eventos e aguarda a entrega dos que já estão no buffer.
+func (s *AsyncSink) Close() {
+	s.once.Do(func() {
+		close(s.events)
+	})
+	s.wg.Wait()
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// memorySink guarda em memória os eventos publicados
+type memorySink struct {
+	mu     sync.Mutex
+	events []DecisionEvent
+}
+
+func (m *memorySink) Publish(event DecisionEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *memorySink) snapshot() []DecisionEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DecisionEvent(nil), m.events...)
+}
+
+// blockingSink trava a publicação até ser liberado
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (b *blockingSink) Publish(DecisionEvent) {
+	<-b.release
+}
+
+// Test_RateLimit_DecisionSink verifica que as decisões são publicadas com o identificador em hash
+func Test_RateLimit_DecisionSink(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+
+	sink := &memorySink{}
+	async := NewAsyncSink(sink, 10)
+	middleware := RateLimit(rl, WithDecisionSink(async))(okHandler)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.80:1000"
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	async.Close()
+
+	events := sink.snapshot()
+	require.Len(t, events, 2)
+	assert.True(t, events[0].Allowed)
+	assert.False(t, events[1].Allowed)
+	for _, event := range events {
+		assert.False(t, event.IsToken)
+		assert.Equal(t, hashIdentifier("192.0.2.80"), event.IdentifierHash)
+		assert.NotContains(t, event.IdentifierHash, "192.0.2.80", "O identificador não deveria ser publicado em claro")
+		assert.WithinDuration(t, time.Now(), event.Timestamp, time.Minute)
+	}
+	assert.Equal(t, uint64(0), async.Dropped())
+}
+
+// Test_AsyncSink_DropsWhenFull verifica que um buffer cheio descarta em vez de bloquear
+func Test_AsyncSink_DropsWhenFull(t *testing.T) {
+	slow := &blockingSink{release: make(chan struct{})}
+	async := NewAsyncSink(slow, 1)
+
+	done := make(chan struct{})
+	go func() {
+		// Um evento fica preso no sink, outro ocupa o buffer e os demais são descartados
+		for i := 0; i < 10; i++ {
+			async.Publish(DecisionEvent{Allowed: true})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish não deveria bloquear com o buffer cheio")
+	}
+
+	assert.GreaterOrEqual(t, async.Dropped(), uint64(8))
+	close(slow.release)
+	async.Close()
+}
+
+// Test_RateLimit_DecisionSink_DoesNotBlockRequests verifica que um sink travado não atrasa as respostas
+func Test_RateLimit_DecisionSink_DoesNotBlockRequests(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          100,
+		MaxRequestsPerToken:       100,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+
+	slow := &blockingSink{release: make(chan struct{})}
+	async := NewAsyncSink(slow, 2)
+	middleware := RateLimit(rl, WithDecisionSink(async))(okHandler)
+
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.81:1000"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	assert.Greater(t, async.Dropped(), uint64(0), "Eventos excedentes deveriam ser descartados")
+	close(slow.release)
+	async.Close()
+}
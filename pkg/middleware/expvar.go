@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// Chaves do mapa publicado por WithExpvar.
+const (
+	expvarAllowed = "allowed"
+	expvarBlocked = "blocked"
+	expvarErrors  = "errors"
+)
+
+// expvarMu torna atômica a busca e a publicação do mapa em ExpvarMap: expvar.NewMap entra em
+// pânico quando o nome já foi publicado.
+var expvarMu sync.Mutex
+
+// ExpvarMap retorna o mapa com o nome informado (ex.: "ratelimiter") publicado no pacote
+// expvar, publicando-o se ainda não existir, com os contadores allowed, blocked e errors. Chamadas
+// com o mesmo nome retornam o mesmo mapa. Retorna um erro se o nome for vazio ou estiver em
+// uso por outra variável que não seja um *expvar.Map (ex.: "cmdline" ou "memstats").
+func ExpvarMap(name string) (*expvar.Map, error) {
+	if name == "" {
+		return nil, fmt.Errorf("nome do mapa do expvar vazio")
+	}
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	var vars *expvar.Map
+	switch existing := expvar.Get(name).(type) {
+	case nil:
+		vars = expvar.NewMap(name)
+	case *expvar.Map:
+		vars = existing
+	default:
+		return nil, fmt.Errorf("o nome %q já está em uso no expvar por uma variável que não é um mapa", name)
+	}
+	for _, key := range []string{expvarAllowed, expvarBlocked, expvarErrors} {
+		vars.Add(key, 0)
+	}
+	return vars, nil
+}
+
+// WithExpvar publica, no pacote expvar, um mapa com o nome informado (ex.: "ratelimiter") com
+// os contadores allowed e blocked das decisões e errors das falhas do store, seja a resposta de
+// falha no modo fechado ou a requisição permitida pelo modo aberto. É uma alternativa sem
+// dependências ao /metrics, lida em /debug/vars pelo ExpvarHandler. Middlewares com o mesmo
+// nome dividem o mapa. Um nome vazio não publica nada, e um nome em uso por outra variável é
+// registrado em log e também não publica nada; para tratar o erro, use ExpvarMap com
+// WithExpvarMap.
+func WithExpvar(name string) Option {
+	return func(o *options) {
+		o.expvar = nil
+		if name == "" {
+			return
+		}
+		vars, err := ExpvarMap(name)
+		if err != nil {
+			log.Printf("Aviso: os contadores não serão publicados no expvar: %v", err)
+			return
+		}
+		o.expvar = vars
+	}
+}
+
+// WithExpvarMap publica os contadores de WithExpvar no mapa informado, obtido de ExpvarMap
+// (nil não publica nada).
+func WithExpvarMap(vars *expvar.Map) Option {
+	return func(o *options) {
+		o.expvar = vars
+	}
+}
+
+// ExpvarHandler expõe as variáveis do expvar, como expvar.Handler, mas exige o mesmo
+// "Authorization: Bearer <secret>" de ConfigHandler: além dos contadores, o expvar publica a
+// linha de comando do processo (cmdline) e o uso de memória (memstats). Com secret vazio,
+// responde 404.
+func ExpvarHandler(secret string) http.Handler {
+	vars := expvar.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r, secret) {
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		vars.ServeHTTP(w, r)
+	})
+}
+
+// countExpvar soma um ao contador informado, se WithExpvar estiver ativo.
+func (o *options) countExpvar(key string) {
+	if o.expvar != nil {
+		o.expvar.Add(key, 1)
+	}
+}
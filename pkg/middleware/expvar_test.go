@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_RateLimit_Expvar verifica os contadores publicados no expvar para decisões permitidas,
+// rejeitadas e falhas do store
+func Test_RateLimit_Expvar(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       2,
+		BlockDurationIPSeconds: 60,
+	})
+	handler := RateLimit(rl, WithExpvar("test_ratelimiter_expvar"))(okHandler)
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	vars, ok := expvar.Get("test_ratelimiter_expvar").(*expvar.Map)
+	require.True(t, ok)
+	value := func(key string) int64 {
+		v, ok := vars.Get(key).(*expvar.Int)
+		require.True(t, ok, key)
+		return v.Value()
+	}
+	assert.Zero(t, value("errors"), "Os contadores aparecem zerados antes da primeira decisão")
+
+	for i := 0; i < 3; i++ {
+		request()
+	}
+	mr.SetError("redis fora do ar")
+	assert.Equal(t, http.StatusServiceUnavailable, request())
+
+	assert.Equal(t, int64(2), value("allowed"))
+	assert.Equal(t, int64(1), value("blocked"))
+	assert.Equal(t, int64(1), value("errors"))
+	assert.JSONEq(t, `{"allowed": 2, "blocked": 1, "errors": 1}`, vars.String())
+
+	// Outro middleware com o mesmo nome divide o mapa, sem publicá-lo de novo
+	mr.SetError("")
+	other := RateLimit(rl, WithExpvar("test_ratelimiter_expvar"))(okHandler)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "198.51.100.1:12345"
+	other.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, int64(3), value("allowed"))
+}
+
+// Test_ExpvarMap_NameCollision verifica que um nome já usado por uma variável que não é um mapa
+// retorna um erro em vez de entrar em pânico
+func Test_ExpvarMap_NameCollision(t *testing.T) {
+	for _, name := range []string{"cmdline", "memstats", ""} {
+		_, err := ExpvarMap(name)
+		assert.Error(t, err, name)
+	}
+
+	assert.NotPanics(t, func() {
+		_, rl := newTestLimiter(t, &config.LimiterConfig{MaxRequestsPerIP: 2, BlockDurationIPSeconds: 60})
+		handler := RateLimit(rl, WithExpvar("cmdline"))(okHandler)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	first, err := ExpvarMap("test_ratelimiter_expvar_shared")
+	require.NoError(t, err)
+	second, err := ExpvarMap("test_ratelimiter_expvar_shared")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+}
+
+// Test_ExpvarHandler_RequiresSecret verifica que /debug/vars exige o segredo administrativo
+func Test_ExpvarHandler_RequiresSecret(t *testing.T) {
+	serve := func(secret, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		ExpvarHandler(secret).ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve("", "Bearer s3cret").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("s3cret", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("s3cret", "Bearer errado").Code)
+
+	rec := serve("s3cret", "Bearer s3cret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"cmdline"`)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/internal/rateLimiter"
+)
+
+// writeRateLimitHeaders escreve os headers de limite, restante e reset conforme o esquema
+// configurado. O reset é o fim do bloqueio nas respostas rejeitadas e, nas permitidas, o tempo
+// até a renovação do contador, quando conhecido (Decision.ResetAfter), ou a duração da janela
+// (o maior tempo possível até a renovação). Decisões sem limite (rate
+// limiting desligado ou falha aberta) não geram headers.
+func (o *options) writeRateLimitHeaders(w http.ResponseWriter, decision *rateLimiter.Decision) {
+	if decision.Disabled || decision.Limit <= 0 {
+		return
+	}
+
+	limit := strconv.Itoa(decision.Limit)
+	remaining := strconv.Itoa(max(decision.Remaining, 0))
+	reset := decision.Window
+	if decision.ResetAfter > 0 {
+		reset = decision.ResetAfter
+	}
+	if !decision.Allowed {
+		reset = decision.RetryAfter
+	}
+	resetSeconds := strconv.Itoa(ceilSeconds(reset))
+
+	h := w.Header()
+	if o.headerScheme == config.HeaderSchemeXRateLimit || o.headerScheme == config.HeaderSchemeBoth {
+		h.Set("X-RateLimit-Limit", limit)
+		h.Set("X-RateLimit-Remaining", o.fractionalRemaining(decision, remaining))
+		h.Set("X-RateLimit-Reset", resetSeconds)
+	}
+	if o.headerScheme == config.HeaderSchemeDraft || o.headerScheme == config.HeaderSchemeBoth {
+		h.Set("RateLimit-Limit", limit)
+		h.Set("RateLimit-Remaining", remaining)
+		h.Set("RateLimit-Reset", resetSeconds)
+		h.Set("RateLimit-Policy", limit+";w="+strconv.Itoa(ceilSeconds(decision.Window)))
+	}
+}
+
+// fractionalRemaining formata Decision.RemainingFloat com as casas decimais configuradas,
+// arredondando para baixo. Sem casas decimais, retorna o valor inteiro já formatado.
+func (o *options) fractionalRemaining(decision *rateLimiter.Decision, remaining string) string {
+	if o.remainingDecimals == 0 {
+		return remaining
+	}
+	scale := math.Pow10(o.remainingDecimals)
+	// A tolerância evita que 0.3 vire 0.2 pelo erro de representação do float
+	value := math.Floor(max(decision.RemainingFloat, 0)*scale+1e-9) / scale
+	return strconv.FormatFloat(value, 'f', o.remainingDecimals, 64)
+}
+
+// writeOverhead escreve em X-RateLimit-Overhead os microssegundos gastos desde start, com
+// WithOverheadHeader.
+func (o *options) writeOverhead(w http.ResponseWriter, start time.Time) {
+	if o.overheadHeader {
+		w.Header().Set("X-RateLimit-Overhead", strconv.FormatInt(o.clock.Now().Sub(start).Microseconds(), 10))
+	}
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/internal/clock"
+	"rateLimiter/internal/rateLimiter"
+)
+
+// Test_RateLimit_HeaderScheme verifica os headers enviados em cada esquema
+func Test_RateLimit_HeaderScheme(t *testing.T) {
+	xHeaders := []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+	draftHeaders := []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}
+
+	tests := []struct {
+		scheme  string
+		present []string
+		absent  []string
+	}{
+		{scheme: "", present: xHeaders, absent: draftHeaders},
+		{scheme: config.HeaderSchemeXRateLimit, present: xHeaders, absent: draftHeaders},
+		{scheme: config.HeaderSchemeDraft, present: draftHeaders, absent: xHeaders},
+		{scheme: config.HeaderSchemeBoth, present: append(append([]string{}, xHeaders...), draftHeaders...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			_, rl := newTestLimiter(t, &config.LimiterConfig{
+				MaxRequestsPerIP:          5,
+				MaxRequestsPerToken:       10,
+				BlockDurationIPSeconds:    10,
+				BlockDurationTokenSeconds: 10,
+				TokenHeaderName:           "API_KEY",
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.0.2.90:12345"
+			rec := httptest.NewRecorder()
+			RateLimit(rl, WithHeaderScheme(tt.scheme))(okHandler).ServeHTTP(rec, req)
+
+			for _, name := range tt.present {
+				assert.NotEmpty(t, rec.Header().Get(name), "%s deveria estar presente", name)
+			}
+			for _, name := range tt.absent {
+				assert.Empty(t, rec.Header().Get(name), "%s não deveria estar presente", name)
+			}
+		})
+	}
+}
+
+// Test_RateLimit_DraftHeaderValues verifica os valores dos headers do draft nas respostas
+// permitidas e rejeitadas
+func Test_RateLimit_DraftHeaderValues(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    30,
+		BlockDurationTokenSeconds: 30,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithHeaderScheme(config.HeaderSchemeDraft))(okHandler)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.91:12345"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=1", rec.Header().Get("RateLimit-Policy"))
+
+	send()
+	rec = send()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("RateLimit-Reset"), "Na rejeição, o reset deveria ser o fim do bloqueio")
+}
+
+// Test_RateLimit_RemainingDecimals verifica o arredondamento da cota fracionária no header
+func Test_RateLimit_RemainingDecimals(t *testing.T) {
+	tests := []struct {
+		decimals  int
+		remaining float64
+		expected  string
+	}{
+		{decimals: 0, remaining: 1.75, expected: "1"},
+		{decimals: 1, remaining: 1.75, expected: "1.7"},
+		{decimals: 2, remaining: 1.75, expected: "1.75"},
+		{decimals: 1, remaining: 0.3, expected: "0.3"},
+		{decimals: 2, remaining: 0, expected: "0.00"},
+	}
+
+	for _, tt := range tests {
+		o := newOptions([]Option{WithHeaderScheme(config.HeaderSchemeBoth), WithRemainingDecimals(tt.decimals)})
+		rec := httptest.NewRecorder()
+		o.writeRateLimitHeaders(rec, &rateLimiter.Decision{
+			Allowed:        true,
+			Limit:          5,
+			Remaining:      int(tt.remaining),
+			RemainingFloat: tt.remaining,
+			Window:         time.Second,
+		})
+		assert.Equal(t, tt.expected, rec.Header().Get("X-RateLimit-Remaining"), "%v com %d casas", tt.remaining, tt.decimals)
+		assert.Equal(t, strconv.Itoa(int(tt.remaining)), rec.Header().Get("RateLimit-Remaining"), "O header do draft continua inteiro")
+	}
+}
+
+// Test_RateLimit_ResetAfterHeader verifica que o reset usa a renovação conhecida do contador e,
+// sem ela, a duração da janela
+func Test_RateLimit_ResetAfterHeader(t *testing.T) {
+	o := newOptions([]Option{WithHeaderScheme(config.HeaderSchemeXRateLimit)})
+
+	rec := httptest.NewRecorder()
+	o.writeRateLimitHeaders(rec, &rateLimiter.Decision{Allowed: true, Limit: 5, Remaining: 4, Window: time.Minute, ResetAfter: 12300 * time.Millisecond})
+	assert.Equal(t, "13", rec.Header().Get("X-RateLimit-Reset"))
+
+	rec = httptest.NewRecorder()
+	o.writeRateLimitHeaders(rec, &rateLimiter.Decision{Allowed: true, Limit: 5, Remaining: 4, Window: time.Minute})
+	assert.Equal(t, "60", rec.Header().Get("X-RateLimit-Reset"))
+}
+
+// Test_RateLimit_OverheadHeader verifica que o tempo gasto no limitador só é enviado com a opção
+func Test_RateLimit_OverheadHeader(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	send := func(handler http.Handler, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(RateLimit(rl)(okHandler), "192.0.2.62")
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Overhead"))
+
+	// Permitidas e rejeitadas trazem o header
+	handler := RateLimit(rl, WithOverheadHeader(true))(okHandler)
+	rec = send(handler, "192.0.2.62")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	overhead, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Overhead"), 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, overhead, int64(0))
+
+	rec = send(handler, "192.0.2.63")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	overhead, err = strconv.ParseInt(rec.Header().Get("X-RateLimit-Overhead"), 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, overhead, int64(0))
+}
+
+// Test_RateLimit_ClockHeaders verifica que, com o relógio fixado, o reset e os registros do
+// middleware têm valores exatos
+func Test_RateLimit_ClockHeaders(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 5, 1, 10, 0, 7, 250_000_000, time.UTC))
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       5,
+		WindowIPSeconds:           10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		AlignWindows:              true,
+	}, rateLimiter.WithClock(fake))
+	history := NewRequestHistory(10, 10)
+	handler := RateLimit(rl, WithOverheadHeader(true), WithRequestHistory(history))(okHandler)
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.92:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A janela alinhada de 10s vira às 10:00:10, 2,75s depois
+	rec := send()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Overhead"))
+
+	fake.Advance(1500 * time.Millisecond)
+	rec = send()
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Remaining"))
+
+	entries := history.Entries("192.0.2.92", false)
+	require.Len(t, entries, 2)
+	assert.Equal(t, fake.Now(), entries[1].Timestamp)
+	assert.Equal(t, fake.Now().Add(-1500*time.Millisecond), entries[0].Timestamp)
+}
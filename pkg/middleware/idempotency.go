@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+)
+
+// maxIdempotencyBodyBytes limita o corpo lido para compor a chave de idempotência. Requisições
+// com corpos maiores são contadas normalmente, sem a proteção.
+const maxIdempotencyBodyBytes = 1 << 20
+
+// idempotencyContextKey é o tipo da chave usada para guardar no contexto a chave de
+// idempotência da requisição.
+type idempotencyContextKey struct{}
+
+// withIdempotencyKey guarda no contexto a chave de idempotência da requisição, se houver uma.
+func (o *options) withIdempotencyKey(ctx context.Context, r *http.Request) context.Context {
+	if key := o.idempotencyRequestKey(r); key != "" {
+		return context.WithValue(ctx, idempotencyContextKey{}, key)
+	}
+	return ctx
+}
+
+// idempotencyKeyFromContext retorna a chave de idempotência guardada por withIdempotencyKey.
+func idempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyContextKey{}).(string)
+	return key
+}
+
+// idempotencyRequestKey retorna a chave de idempotência da requisição: um hash do valor do
+// header configurado, do método, da URL e do corpo, para que a mesma chave só reconheça
+// repetições da mesma requisição e que um header longo não vire uma chave longa no store. O
+// corpo lido é devolvido à requisição. Sem o header, ou com um corpo maior que
+// maxIdempotencyBodyBytes, a chave é vazia.
+func (o *options) idempotencyRequestKey(r *http.Request) string {
+	if o.idempotencyKey == "" {
+		return ""
+	}
+	key := r.Header.Get(o.idempotencyKey)
+	if key == "" {
+		return ""
+	}
+
+	hash := sha256.New()
+	io.WriteString(hash, key+"\n"+r.Method+" "+r.URL.RequestURI()+"\n")
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotencyBodyBytes+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil || len(body) > maxIdempotencyBodyBytes {
+			return ""
+		}
+		hash.Write(body)
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
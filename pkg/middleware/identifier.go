@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// defaultMaxIdentifierLength é o tamanho máximo de um identificador antes de ser substituído pelo hash.
+const defaultMaxIdentifierLength = 1024
+
+// anonymousToken é o token das requisições com o header vazio e config.EmptyTokenAnonymous.
+const anonymousToken = "anonymous"
+
+// boundIdentifier limita o tamanho de um identificador vindo do cliente, para que um header
+// enorme não vire uma chave enorme no store. Identificadores acima do limite são substituídos
+// pelo seu SHA-256 (o mesmo valor sempre gera a mesma chave) ou, se a rejeição estiver ativa,
+// ok é false.
+func (o *options) boundIdentifier(identifier string) (bounded string, ok bool) {
+	if o.maxIdentifierLength <= 0 || len(identifier) <= o.maxIdentifierLength {
+		return identifier, true
+	}
+	if o.rejectLongIdentifiers {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(identifier))
+	return "sha256:" + hex.EncodeToString(sum[:]), true
+}
+
+// WithIPHashing troca o IP do cliente, nas chaves do store, pelo HMAC-SHA256 do IP com o
+// segredo informado, para que o Redis não guarde IPs (dados pessoais) nem chaves que permitam
+// recuperá-los sem o segredo. O mesmo IP gera sempre a mesma chave; trocar o segredo zera, na
+// prática, os contadores e bloqueios de todos os IPs. Sem segredo, os IPs são usados como vieram.
+// Com a proteção de cardinalidade, os IPs novos além do limite são rejeitados, porque o HMAC não
+// permite agrupá-los por sub-rede.
+func WithIPHashing(secret []byte) Option {
+	return func(o *options) {
+		o.ipHashSecret = secret
+	}
+}
+
+// ipIdentifier retorna o identificador do IP do cliente: o próprio IP ou, com WithIPHashing,
+// "hmac:" seguido do HMAC-SHA256 do IP em hexadecimal.
+func (o *options) ipIdentifier(ip string) string {
+	if len(o.ipHashSecret) == 0 {
+		return ip
+	}
+	mac := hmac.New(sha256.New, o.ipHashSecret)
+	mac.Write([]byte(ip))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// userAgentIdentifier normaliza o User-Agent (minúsculas, espaços colapsados) e retorna um
+// identificador de tamanho fixo baseado no seu hash.
+func userAgentIdentifier(userAgent string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(userAgent), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return "ua:" + hex.EncodeToString(sum[:16])
+}
+
+// emptyTokenHeader informa se o header do token foi enviado sem valor (ex.: "API_KEY:").
+func emptyTokenHeader(r *http.Request, name string) bool {
+	values := r.Header.Values(name)
+	return len(values) > 0 && strings.TrimSpace(values[0]) == ""
+}
+
+// basicAuthPrefix antecede o usuário do Basic Auth no identificador, para que não divida o
+// contador com um token igual vindo de outro header.
+const basicAuthPrefix = "basic:"
+
+// tokenSource retorna o header de onde o token é lido e a sua faixa de limites: o primeiro de
+// cfg.TokenSources presente na requisição (mesmo vazio, para a política de token vazio) ou,
+// sem TokenSources, TokenHeaderName. A fonte do Basic Auth só conta como presente com um
+// usuário válido.
+func tokenSource(r *http.Request, cfg *config.LimiterConfig) config.TokenSource {
+	for _, source := range cfg.TokenSources {
+		if source.BasicAuth {
+			if basicAuthUser(r) != "" {
+				return source
+			}
+			continue
+		}
+		if len(r.Header.Values(source.Header)) > 0 {
+			return source
+		}
+	}
+	if len(cfg.TokenSources) > 0 {
+		return cfg.TokenSources[0]
+	}
+	return config.TokenSource{Header: cfg.TokenHeaderName}
+}
+
+// sourceToken retorna o valor bruto do token na fonte: o header ou, no Basic Auth, o usuário.
+func sourceToken(r *http.Request, source config.TokenSource) string {
+	if source.BasicAuth {
+		if user := basicAuthUser(r); user != "" {
+			return basicAuthPrefix + user
+		}
+		return ""
+	}
+	return r.Header.Get(source.Header)
+}
+
+// basicAuthUser retorna o usuário do header Authorization: Basic, ou "" se o header estiver
+// ausente, usar outro esquema ou estiver malformado.
+func basicAuthUser(r *http.Request) string {
+	user, _, ok := r.BasicAuth()
+	if !ok {
+		return ""
+	}
+	return user
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// IdentifierNormalization define como o token e o IP do cliente são normalizados antes de
+// formar as chaves, para que o mesmo cliente não seja dividido entre contadores.
+type IdentifierNormalization struct {
+	// TrimTokens remove os espaços nas pontas do token: " abc " e "abc" dividem o contador.
+	TrimTokens bool
+	// CaseInsensitiveTokens compara os tokens em minúsculas. Só vale para tokens em que a caixa
+	// não distingue clientes.
+	CaseInsensitiveTokens bool
+	// UnmapIPv4 converte os IPv4 mapeados em IPv6 (::ffff:1.2.3.4) para IPv4 (1.2.3.4) e escreve
+	// os IPv6 na forma canônica.
+	UnmapIPv4 bool
+}
+
+// WithIdentifierNormalization normaliza o token e o IP do cliente antes de formar as chaves (e
+// antes do TokenKeyFunc e do hash de WithIPHashing). Os endereços do X-Forwarded-For já são
+// convertidos para IPv4 sem a opção.
+func WithIdentifierNormalization(n IdentifierNormalization) Option {
+	return func(o *options) {
+		o.identifierNormalization = n
+	}
+}
+
+// token aplica a normalização ao token.
+func (n IdentifierNormalization) token(token string) string {
+	if n.TrimTokens {
+		token = strings.TrimSpace(token)
+	}
+	if n.CaseInsensitiveTokens {
+		token = strings.ToLower(token)
+	}
+	return token
+}
+
+// ip aplica a normalização ao IP. Valores que não são IPs são mantidos.
+func (n IdentifierNormalization) ip(ip string) string {
+	if !n.UnmapIPv4 {
+		return ip
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().String()
+}
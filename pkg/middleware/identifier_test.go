@@ -0,0 +1,397 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_RateLimit_OversizedToken_Hashed verifica que um token enorme vira uma chave de tamanho fixo,
+// sempre a mesma para o mesmo token
+func Test_RateLimit_OversizedToken_Hashed(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithMaxIdentifierLength(64, false))(okHandler)
+	hugeToken := strings.Repeat("a", 1<<20)
+
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("API_KEY", hugeToken)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes,
+		"O token em hash deveria ser contado de forma consistente")
+
+	keys := mr.Keys()
+	require.NotEmpty(t, keys)
+	for _, key := range keys {
+		assert.Less(t, len(key), 128, "Nenhuma chave deveria conter o token original: %.40s...", key)
+	}
+}
+
+// Test_RateLimit_OversizedToken_Rejected verifica a rejeição com 400 quando configurada
+func Test_RateLimit_OversizedToken_Rejected(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithMaxIdentifierLength(64, true))(okHandler)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("API_KEY", strings.Repeat("b", 65))
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, mr.Keys(), "Um token rejeitado não deveria gerar chaves")
+
+	// Um token dentro do limite continua sendo aceito como está
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("API_KEY", strings.Repeat("b", 64))
+	rec = httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, mr.Exists("token_"+strings.Repeat("b", 64)))
+}
+
+// Test_RateLimit_KeyComponents_IPAndUserAgent verifica que trocar só o IP ou só o User-Agent não escapa do limite
+func Test_RateLimit_KeyComponents_IPAndUserAgent(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithKeyComponents(config.KeyComponentsIPAndUserAgent))(okHandler)
+
+	send := func(ip, userAgent string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1000"
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Mesmo User-Agent com IPs rotativos
+	assert.Equal(t, http.StatusOK, send("192.0.2.130", "scraper/1.0"))
+	assert.Equal(t, http.StatusOK, send("192.0.2.131", "scraper/1.0"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.0.2.132", "scraper/1.0"), "Trocar só o IP não deveria escapar")
+
+	// Mesmo IP com User-Agents rotativos
+	assert.Equal(t, http.StatusOK, send("192.0.2.140", "agent-a"))
+	assert.Equal(t, http.StatusOK, send("192.0.2.140", "agent-b"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.0.2.140", "agent-c"), "Trocar só o User-Agent não deveria escapar")
+
+	// Trocando os dois, o cliente é novo
+	assert.Equal(t, http.StatusOK, send("192.0.2.150", "agent-d"))
+}
+
+// Test_RateLimit_KeyComponents_UserAgent verifica o contador por User-Agent normalizado
+func Test_RateLimit_KeyComponents_UserAgent(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithKeyComponents(config.KeyComponentsUserAgent))(okHandler)
+
+	codes := []int{}
+	for i, userAgent := range []string{"Scraper/1.0  (bot)", "scraper/1.0 (BOT)", " SCRAPER/1.0 (bot) "} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1000", 160+i)
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes,
+		"Variações de caixa e espaços deveriam cair no mesmo contador")
+	assert.False(t, mr.Exists("ip_192.0.2.160"), "No modo User-Agent o IP não deveria ser contado")
+	assert.True(t, mr.Exists("blocked_ip_"+userAgentIdentifier("scraper/1.0 (bot)")))
+}
+
+// Test_RateLimit_EmptyTokenPolicy verifica o tratamento do header do token presente, mas vazio
+func Test_RateLimit_EmptyTokenPolicy(t *testing.T) {
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+	}
+	emptyTokenRequest := func(ip string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":12345"
+		req.Header["Api_key"] = []string{""}
+		require.True(t, emptyTokenHeader(req, "API_KEY"))
+		return req
+	}
+
+	for _, policy := range []string{"", config.EmptyTokenFallbackIP} {
+		t.Run("fallback_ip/"+policy, func(t *testing.T) {
+			mr, rl := newTestLimiter(t, cfg)
+			handler := RateLimit(rl, WithEmptyTokenPolicy(policy))(okHandler)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, emptyTokenRequest("192.0.2.1"))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "5", rec.Header().Get("X-RateLimit-Limit"), "Deveria usar o limite por IP")
+			assert.True(t, mr.Exists("ip_192.0.2.1"))
+		})
+	}
+
+	t.Run(config.EmptyTokenReject, func(t *testing.T) {
+		mr, rl := newTestLimiter(t, cfg)
+		handler := RateLimit(rl, WithEmptyTokenPolicy(config.EmptyTokenReject))(okHandler)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, emptyTokenRequest("192.0.2.1"))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, mr.Keys(), "A requisição rejeitada não deveria ser contada")
+
+		// Sem o header, a requisição segue pelo IP
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run(config.EmptyTokenAnonymous, func(t *testing.T) {
+		mr, rl := newTestLimiter(t, cfg)
+		handler := RateLimit(rl, WithEmptyTokenPolicy(config.EmptyTokenAnonymous))(okHandler)
+
+		for i := 0; i < 10; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, emptyTokenRequest(fmt.Sprintf("192.0.2.%d", i+1)))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "10", rec.Header().Get("X-RateLimit-Limit"), "Deveria usar o limite por token")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, emptyTokenRequest("192.0.2.50"))
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code, "Todos os tokens vazios dividem o mesmo contador")
+		assert.True(t, mr.Exists("blocked_token_"+anonymousToken))
+		assert.False(t, mr.Exists("ip_192.0.2.1"))
+	})
+}
+
+// Test_RateLimit_IPHashing verifica que, com o segredo, o IP entra nas chaves só pelo HMAC: o
+// mesmo IP usa sempre o mesmo contador, e segredos diferentes geram chaves diferentes
+func Test_RateLimit_IPHashing(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+	})
+	send := func(handler http.Handler, ip string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	first := RateLimit(rl, WithIPHashing([]byte("segredo-1")))(okHandler)
+	assert.Equal(t, http.StatusOK, send(first, "192.0.2.140"))
+	assert.Equal(t, http.StatusOK, send(first, "192.0.2.140"))
+	assert.Equal(t, http.StatusTooManyRequests, send(first, "192.0.2.140"), "O mesmo IP deveria usar o mesmo contador")
+
+	hashed := (&options{ipHashSecret: []byte("segredo-1")}).ipIdentifier("192.0.2.140")
+	assert.True(t, strings.HasPrefix(hashed, "hmac:"))
+	assert.True(t, mr.Exists("blocked_ip_"+hashed))
+	for _, key := range mr.Keys() {
+		assert.NotContains(t, key, "192.0.2.140", "O IP não deveria aparecer nas chaves")
+	}
+
+	// Com outro segredo (rotação), o mesmo IP cai em outro contador
+	rotated := RateLimit(rl, WithIPHashing([]byte("segredo-2")))(okHandler)
+	assert.Equal(t, http.StatusOK, send(rotated, "192.0.2.140"))
+	rotatedKey := (&options{ipHashSecret: []byte("segredo-2")}).ipIdentifier("192.0.2.140")
+	assert.NotEqual(t, hashed, rotatedKey)
+	assert.True(t, mr.Exists("ip_"+rotatedKey))
+
+	assert.Equal(t, "192.0.2.140", (&options{}).ipIdentifier("192.0.2.140"), "Sem segredo, o IP é usado como veio")
+}
+
+// Test_RateLimit_IdentifierNormalization verifica que as formas do mesmo token e do mesmo IP
+// dividem um único contador
+func Test_RateLimit_IdentifierNormalization(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       3,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+	})
+	handler := RateLimit(rl, WithIdentifierNormalization(IdentifierNormalization{
+		TrimTokens:            true,
+		CaseInsensitiveTokens: true,
+		UnmapIPv4:             true,
+	}))(okHandler)
+	send := func(remoteAddr, token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// IPv4 mapeado em IPv6 e IPv4 puro são o mesmo cliente
+	assert.Equal(t, http.StatusOK, send("[::ffff:192.0.2.1]:1000", ""))
+	assert.Equal(t, http.StatusOK, send("192.0.2.1:1001", ""))
+	assert.Equal(t, http.StatusTooManyRequests, send("[::ffff:c000:201]:1002", ""))
+	assert.True(t, mr.Exists("blocked_ip_192.0.2.1"))
+
+	// Tokens com espaços e caixas diferentes também
+	assert.Equal(t, http.StatusOK, send("192.0.2.2:1000", "  Abc123 "))
+	assert.Equal(t, http.StatusOK, send("192.0.2.2:1000", "abc123"))
+	assert.Equal(t, http.StatusOK, send("192.0.2.2:1000", "\tABC123"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.0.2.2:1000", "abc123 "))
+	assert.True(t, mr.Exists("blocked_token_abc123"))
+	for _, key := range mr.Keys() {
+		assert.NotContains(t, key, "ffff", "O IP mapeado não deveria aparecer nas chaves")
+	}
+}
+
+// Test_IdentifierNormalization_Disabled verifica que, sem a opção, os identificadores não mudam
+func Test_IdentifierNormalization_Disabled(t *testing.T) {
+	var n IdentifierNormalization
+	assert.Equal(t, " Abc ", n.token(" Abc "))
+	assert.Equal(t, "::ffff:192.0.2.1", n.ip("::ffff:192.0.2.1"))
+	assert.Equal(t, "not-an-ip", IdentifierNormalization{UnmapIPv4: true}.ip("not-an-ip"))
+	assert.Equal(t, "2001:db8::1", IdentifierNormalization{UnmapIPv4: true}.ip("2001:DB8:0::1"))
+}
+
+// Test_RateLimit_TokenSources verifica que o primeiro header presente define o token e a faixa de limites
+func Test_RateLimit_TokenSources(t *testing.T) {
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+		TokenSources: []config.TokenSource{
+			{Header: "API_KEY"},
+			{Header: "X-Api-Key", Tier: "partner"},
+			{Header: "Authorization", Tier: "premium"},
+		},
+		TokenTiers: map[string]config.TokenTier{
+			"partner": {MaxRequests: 3},
+			"premium": {MaxRequests: 5},
+		},
+	}
+	mr, rl := newTestLimiter(t, cfg)
+	handler := RateLimit(rl)(okHandler)
+	request := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		header string
+		token  string
+		limit  int
+	}{
+		{"API_KEY", "a", 2},
+		{"X-Api-Key", "b", 3},
+		{"Authorization", "c", 5},
+	} {
+		for i := 0; i < tc.limit; i++ {
+			rec := request(map[string]string{tc.header: tc.token})
+			require.Equal(t, http.StatusOK, rec.Code, tc.header)
+			assert.Equal(t, strconv.Itoa(tc.limit), rec.Header().Get("X-RateLimit-Limit"), tc.header)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, request(map[string]string{tc.header: tc.token}).Code, tc.header)
+		assert.True(t, mr.Exists("blocked_token_"+tc.token), tc.header)
+	}
+
+	// Com mais de um header, vale o primeiro da lista
+	rec := request(map[string]string{"Authorization": "d", "X-Api-Key": "e"})
+	assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"))
+	assert.True(t, mr.Exists("token_e"))
+	assert.False(t, mr.Exists("token_d"))
+}
+
+// Test_RateLimit_BasicAuthSource verifica que o usuário do Basic Auth é o token, independente
+// da senha, e que headers malformados são contados pelo IP
+func Test_RateLimit_BasicAuthSource(t *testing.T) {
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		EmptyTokenPolicy:          config.EmptyTokenReject,
+		TokenSources:              []config.TokenSource{{Header: "Authorization", BasicAuth: true}, {Header: "API_KEY"}},
+	}
+	mr, rl := newTestLimiter(t, cfg)
+	handler := RateLimit(rl)(okHandler)
+	request := func(remoteAddr, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	basic := func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	// O mesmo usuário, com senhas e IPs diferentes, divide o contador
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1", basic("alice:secret")).Code)
+	assert.Equal(t, http.StatusOK, request("192.0.2.2:1", basic("alice:other")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.3:1", basic("alice:secret")).Code)
+	assert.True(t, mr.Exists("blocked_token_basic:alice"))
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1", basic("bob:secret")).Code)
+
+	// Headers malformados não são rejeitados nem viram token: contam pelo IP
+	for i, authorization := range []string{
+		"Basic !!!",
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("sem-dois-pontos")),
+		basic(":senha"),
+		"Bearer alice",
+		"Basic",
+	} {
+		remoteAddr := fmt.Sprintf("198.51.100.%d:1", i+1)
+		assert.Equal(t, http.StatusOK, request(remoteAddr, authorization).Code, authorization)
+		assert.Equal(t, http.StatusTooManyRequests, request(remoteAddr, authorization).Code, authorization)
+	}
+	assert.False(t, mr.Exists("token_basic:"))
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Escopos retornados por um KeyFunc, que definem o conjunto de limites aplicado à chave.
+const (
+	// ScopeIP aplica os limites por IP.
+	ScopeIP = "ip"
+	// ScopeToken aplica os limites por token.
+	ScopeToken = "token"
+)
+
+// KeyFunc determina a chave de contagem da requisição e o escopo (ScopeIP ou ScopeToken) cujos
+// limites se aplicam a ela. Quando ok é false, o middleware usa a identificação padrão.
+type KeyFunc func(r *http.Request) (key string, scope string, ok bool)
+
+// WithKeyFunc substitui a identificação padrão (token ou IP, com cota justa, chave
+// compartilhada e componentes da chave) pela chave e pelo escopo retornados por fn. A chave
+// ainda passa pelo limite de tamanho, pela classe do método e pelo host, quando configurados.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = fn
+	}
+}
+
+// IPKey conta as requisições pelo IP do cliente, com os limites por IP. O X-Forwarded-For só é
+// considerado quando a conexão vem de um dos proxies confiáveis informados.
+func IPKey(trustedProxies ...netip.Prefix) KeyFunc {
+	o := &options{trustedProxies: trustedProxies}
+	return func(r *http.Request) (string, string, bool) {
+		ip, err := o.clientIP(r)
+		if err != nil {
+			return "", "", false
+		}
+		return ip, ScopeIP, true
+	}
+}
+
+// TokenKey conta as requisições pelo token do header informado, com os limites por token.
+// Requisições sem o header não são identificadas.
+func TokenKey(header string) KeyFunc {
+	return func(r *http.Request) (string, string, bool) {
+		token := r.Header.Get(header)
+		if token == "" {
+			return "", "", false
+		}
+		return token, ScopeToken, true
+	}
+}
+
+// ClientCertKey conta as requisições pelo certificado do cliente apresentado no mTLS, com os
+// limites por token. A chave é "cert:" seguido do SHA-256 do DER do certificado folha, de modo
+// que o mesmo certificado use sempre o mesmo contador. Requisições sem TLS ou sem certificado
+// não são identificadas, e com WithKeyFunc(ClientCertKey()) seguem para o token ou o IP.
+func ClientCertKey() KeyFunc {
+	return func(r *http.Request) (string, string, bool) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return "", "", false
+		}
+		sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+		return "cert:" + hex.EncodeToString(sum[:]), ScopeToken, true
+	}
+}
+
+// FirstKey retorna a chave da primeira função que identificar a requisição. O comportamento
+// padrão equivale a FirstKey(TokenKey(header), IPKey(proxies...)).
+func FirstKey(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, string, bool) {
+		for _, fn := range fns {
+			if key, scope, ok := fn(r); ok {
+				return key, scope, true
+			}
+		}
+		return "", "", false
+	}
+}
+
+// HostKey prefixa com o host da requisição a chave retornada por fn, mantendo o seu escopo.
+func HostKey(fn KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, string, bool) {
+		key, scope, ok := fn(r)
+		if !ok {
+			return "", "", false
+		}
+		return normalizeHost(r.Host) + "|" + key, scope, true
+	}
+}
+
+// CompositeKey une as chaves de todas as funções, separadas por "|", em um único contador. A
+// requisição só é identificada quando todas as funções a identificam, e o escopo é o da primeira.
+func CompositeKey(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, string, bool) {
+		if len(fns) == 0 {
+			return "", "", false
+		}
+		keys := make([]string, 0, len(fns))
+		var scope string
+		for i, fn := range fns {
+			key, s, ok := fn(r)
+			if !ok {
+				return "", "", false
+			}
+			if i == 0 {
+				scope = s
+			}
+			keys = append(keys, key)
+		}
+		return strings.Join(keys, "|"), scope, true
+	}
+}
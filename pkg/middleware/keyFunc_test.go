@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// tenantKey identifica a requisição pelo header X-Tenant, com o escopo informado
+func tenantKey(scope string) KeyFunc {
+	return func(r *http.Request) (string, string, bool) {
+		tenant := r.Header.Get("X-Tenant")
+		return "tenant:" + tenant, scope, tenant != ""
+	}
+}
+
+// Test_RateLimit_KeyFunc verifica que o limiter recebe exatamente a chave e o escopo do KeyFunc
+func Test_RateLimit_KeyFunc(t *testing.T) {
+	for _, tc := range []struct {
+		scope   string
+		isToken bool
+	}{
+		{ScopeToken, true},
+		{ScopeIP, false},
+	} {
+		t.Run(tc.scope, func(t *testing.T) {
+			mockRL := new(mockRateLimiter)
+			mockRL.On("GetConfig").Return(&config.LimiterConfig{TokenHeaderName: "API_KEY"})
+			mockRL.On("Allow", mock.Anything, "tenant:acme", tc.isToken).Return(true, nil).Once()
+
+			middleware := RateLimit(mockRL, WithKeyFunc(tenantKey(tc.scope)))(okHandler)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.0.2.1:12345"
+			req.Header.Set("API_KEY", "test-token")
+			req.Header.Set("X-Tenant", "acme")
+			rec := httptest.NewRecorder()
+			middleware.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			mockRL.AssertExpectations(t)
+		})
+	}
+}
+
+// Test_RateLimit_KeyFunc_Fallback verifica que, sem chave do KeyFunc, vale a identificação padrão
+func Test_RateLimit_KeyFunc_Fallback(t *testing.T) {
+	mockRL := new(mockRateLimiter)
+	mockRL.On("GetConfig").Return(&config.LimiterConfig{TokenHeaderName: "API_KEY"})
+	mockRL.On("Allow", mock.Anything, "test-token", true).Return(true, nil).Once()
+	mockRL.On("Allow", mock.Anything, "192.0.2.1", false).Return(true, nil).Once()
+
+	middleware := RateLimit(mockRL, WithKeyFunc(tenantKey(ScopeToken)))(okHandler)
+	for _, token := range []string{"test-token", ""} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		req.Header.Set("API_KEY", token)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockRL.AssertExpectations(t)
+}
+
+// Test_RateLimit_KeyFunc_InvalidScope verifica que um escopo desconhecido não é contabilizado
+func Test_RateLimit_KeyFunc_InvalidScope(t *testing.T) {
+	mockRL := new(mockRateLimiter)
+	mockRL.On("GetConfig").Return(&config.LimiterConfig{TokenHeaderName: "API_KEY"})
+
+	middleware := RateLimit(mockRL, WithKeyFunc(tenantKey("tenant")))(okHandler)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:12345"
+	req.Header.Set("X-Tenant", "acme")
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	mockRL.AssertNotCalled(t, "Allow", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Test_KeyFunc_BuiltIns verifica as estratégias prontas
+func Test_KeyFunc_BuiltIns(t *testing.T) {
+	proxy := netip.MustParsePrefix("10.0.0.0/8")
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Host = "API.Example.com:8080"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	key, scope, ok := IPKey(proxy)(req)
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7", key)
+	assert.Equal(t, ScopeIP, scope)
+
+	_, _, ok = TokenKey("API_KEY")(req)
+	assert.False(t, ok, "Sem o header, TokenKey não identifica a requisição")
+
+	// Padrão: token quando houver, senão o IP
+	defaultKey := FirstKey(TokenKey("API_KEY"), IPKey(proxy))
+	key, scope, _ = defaultKey(req)
+	assert.Equal(t, "203.0.113.7", key)
+	assert.Equal(t, ScopeIP, scope)
+	req.Header.Set("API_KEY", "abc")
+	key, scope, _ = defaultKey(req)
+	assert.Equal(t, "abc", key)
+	assert.Equal(t, ScopeToken, scope)
+
+	key, scope, _ = HostKey(TokenKey("API_KEY"))(req)
+	assert.Equal(t, "api.example.com|abc", key)
+	assert.Equal(t, ScopeToken, scope)
+
+	key, scope, ok = CompositeKey(IPKey(proxy), TokenKey("API_KEY"))(req)
+	assert.True(t, ok)
+	assert.Equal(t, "203.0.113.7|abc", key)
+	assert.Equal(t, ScopeIP, scope, "O escopo é o da primeira função")
+
+	_, _, ok = CompositeKey(IPKey(proxy), TokenKey("X-Missing"))(req)
+	assert.False(t, ok)
+}
+
+// Test_RateLimit_ClientCertKey verifica que o fingerprint do certificado do cliente define o
+// contador, com os limites por token, e que sem certificado vale o token ou o IP
+func Test_RateLimit_ClientCertKey(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+	})
+	handler := RateLimit(rl, WithKeyFunc(ClientCertKey()))(okHandler)
+
+	certA := &x509.Certificate{Raw: []byte("certificado A")}
+	certB := &x509.Certificate{Raw: []byte("certificado B")}
+	fingerprint := func(cert *x509.Certificate) string {
+		sum := sha256.Sum256(cert.Raw)
+		return hex.EncodeToString(sum[:])
+	}
+	do := func(cert *x509.Certificate, token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		req.Header.Set("API_KEY", token)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// O certificado define o contador, mesmo com token e do mesmo IP
+	assert.Equal(t, http.StatusOK, do(certA, "test-token"))
+	assert.Equal(t, http.StatusOK, do(certA, "other-token"))
+	assert.Equal(t, http.StatusTooManyRequests, do(certA, "test-token"), "O certificado usa os limites por token")
+	assert.True(t, mr.Exists("blocked_token_cert:"+fingerprint(certA)))
+	assert.Equal(t, http.StatusOK, do(certB, "test-token"), "Outro certificado tem contador próprio")
+	count, err := mr.Get("token_cert:" + fingerprint(certB))
+	require.NoError(t, err)
+	assert.Equal(t, "1", count)
+
+	// Sem certificado, vale a identificação padrão
+	assert.Equal(t, http.StatusOK, do(nil, "test-token"))
+	assert.True(t, mr.Exists("token_test-token"))
+	assert.Equal(t, http.StatusOK, do(nil, ""))
+	assert.True(t, mr.Exists("ip_192.0.2.1"))
+	assert.False(t, mr.Exists("token_other-token"))
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_RateLimit_NonCountingMethods verifica que HEAD não consome a cota do escopo configurado,
+// mas continua rejeitado enquanto o identificador está bloqueado
+func Test_RateLimit_NonCountingMethods(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+		NonCountingMethodsIP:      []string{http.MethodHead, http.MethodOptions},
+	})
+	handler := RateLimit(rl)(okHandler)
+	send := func(method, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 5; i++ {
+		rec := send(http.MethodHead, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	rec := send(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", send(http.MethodHead, "").Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send(http.MethodGet, "").Code)
+	require.True(t, mr.Exists("blocked_ip_192.0.2.1"))
+
+	// Durante o bloqueio, HEAD também é rejeitado, com o tempo restante
+	rec = send(http.MethodHead, "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("X-RateLimit-Reset"))
+
+	// Os métodos valem só para o escopo configurado: HEAD com token conta
+	assert.Equal(t, http.StatusOK, send(http.MethodHead, "abc").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodHead, "abc").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send(http.MethodHead, "abc").Code)
+}
@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"expvar"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/internal/clock"
+)
+
+// Option configura o comportamento do middleware RateLimit.
+type Option func(*options)
+
+// RejectionHeader é um header extra enviado em toda resposta rejeitada pelo rate limiter.
+type RejectionHeader struct {
+	Name  string
+	Value string
+}
+
+// options agrupa as opções do middleware.
+type options struct {
+	rejectionHeader *RejectionHeader
+	rejectionBody   string
+	// rejectionFormat é o formato do corpo das respostas rejeitadas (config.RejectionFormat*).
+	rejectionFormat string
+	keyByHost       bool
+	keyFunc         KeyFunc
+	sharedKeyFunc   SharedKeyFunc
+	tokenKeyFunc    TokenKeyFunc
+	trustedProxies  []netip.Prefix
+	skipPrivate     bool
+	decisionSink    DecisionSink
+	idempotencyKey  string
+	classifier      MethodClassifier
+	// pathParam extrai o recurso alvo do caminho, que entra na chave do contador.
+	pathParam     PathParamFunc
+	unknownBucket bool
+	keyComponents string
+	// xffSelect escolhe o endereço do X-Forwarded-For (config.XForwardedFor*).
+	xffSelect string
+	// preflightPolicy define como as requisições OPTIONS são contadas (config.Preflight*).
+	preflightPolicy string
+	// emptyTokenPolicy define o tratamento do header do token vazio (config.EmptyToken*).
+	emptyTokenPolicy string
+	// maxIdentifierLength limita o tamanho de tokens e chaves compartilhadas (0 desliga).
+	maxIdentifierLength   int
+	rejectLongIdentifiers bool
+	// storeErrorStatus e storeErrorRetryAfter formam a resposta quando o store falha no modo fechado.
+	storeErrorStatus     int
+	storeErrorRetryAfter time.Duration
+	// headerScheme define os headers de rate limit enviados (config.HeaderScheme*).
+	headerScheme string
+	// remainingDecimals são as casas decimais de X-RateLimit-Remaining (0 mantém inteiro).
+	remainingDecimals int
+	// tarpitDelay é o atraso aplicado antes de cada resposta 429 (0 desliga).
+	tarpitDelay time.Duration
+	// overheadHeader envia o tempo gasto no limitador em X-RateLimit-Overhead.
+	overheadHeader bool
+	// activeWhen decide, por requisição, se o rate limiting se aplica (nil aplica sempre).
+	activeWhen func(r *http.Request) bool
+	// routeNormalization normaliza o caminho antes de casar com as regras por rota.
+	routeNormalization RouteNormalization
+	// identifierNormalization normaliza o token e o IP antes de formar as chaves.
+	identifierNormalization IdentifierNormalization
+	// shadow é o limitador avaliado só para medição, sem afetar a resposta (nil desliga).
+	shadow *shadowLimiter
+	// rejectMessage escolhe a mensagem de cada rejeição (nil usa rejectionBody).
+	rejectMessage RejectMessageFunc
+	// ipHashSecret é o segredo do HMAC aplicado aos IPs nas chaves (vazio usa o IP).
+	ipHashSecret []byte
+	// history guarda as últimas requisições de cada identificador para diagnóstico (nil desliga).
+	history *RequestHistory
+	// expvar recebe os contadores das decisões publicados por WithExpvar (nil desliga).
+	expvar *expvar.Map
+	// clock é a fonte de tempo dos registros do middleware (nil usa a do rate limiter).
+	clock clock.Clock
+}
+
+// maxTarpitDelay é o maior atraso aceito por WithTarpit.
+const maxTarpitDelay = 30 * time.Second
+
+// Resposta padrão para falhas do store no modo de falha fechado.
+const (
+	defaultStoreErrorStatus     = http.StatusServiceUnavailable
+	defaultStoreErrorRetryAfter = 5 * time.Second
+)
+
+// SharedKeyFunc retorna uma chave compartilhada (ex.: a conta do cliente) para a requisição.
+// Quando ok é true, a chave substitui o token e o IP na contagem.
+type SharedKeyFunc func(r *http.Request) (key string, ok bool)
+
+// newOptions aplica as opções informadas sobre os valores padrão.
+func newOptions(opts []Option) *options {
+	o := &options{
+		rejectionBody:        defaultRejectionBody,
+		storeErrorStatus:     defaultStoreErrorStatus,
+		storeErrorRetryAfter: defaultStoreErrorRetryAfter,
+		maxIdentifierLength:  defaultMaxIdentifierLength,
+		headerScheme:         config.HeaderSchemeXRateLimit,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRejectionHeader define um header (ex.: X-Throttled: 1) enviado em toda resposta 429,
+// útil para proxies de monitoramento que não olham o código de status.
+func WithRejectionHeader(name, value string) Option {
+	return func(o *options) {
+		if name == "" {
+			o.rejectionHeader = nil
+			return
+		}
+		o.rejectionHeader = &RejectionHeader{Name: name, Value: value}
+	}
+}
+
+// WithRejectionBody define o modelo do corpo das respostas 429. Os marcadores {limit},
+// {remaining}, {window} e {retry_after} são substituídos pelos valores da decisão, com as
+// durações em segundos (ex.: "rate limited: {limit} req/{window}, retry after {retry_after}").
+// Um modelo vazio mantém a mensagem padrão.
+func WithRejectionBody(template string) Option {
+	return func(o *options) {
+		if template == "" {
+			template = defaultRejectionBody
+		}
+		o.rejectionBody = template
+	}
+}
+
+// WithRejectionFormat define o formato do corpo das respostas rejeitadas:
+// config.RejectionFormatText (o padrão) envia o modelo de WithRejectionBody em texto puro, e
+// config.RejectionFormatProblemJSON envia um documento application/problem+json (RFC 7807) com
+// type, title, status, detail (o modelo renderizado) e retryAfter, em segundos.
+func WithRejectionFormat(format string) Option {
+	return func(o *options) {
+		o.rejectionFormat = format
+	}
+}
+
+// WithHeaderScheme define os headers de rate limit enviados em cada resposta:
+// config.HeaderSchemeXRateLimit (X-RateLimit-*, o padrão), config.HeaderSchemeDraft
+// (RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset e RateLimit-Policy, do draft da IETF)
+// ou config.HeaderSchemeBoth.
+func WithHeaderScheme(scheme string) Option {
+	return func(o *options) {
+		if scheme == "" {
+			scheme = config.HeaderSchemeXRateLimit
+		}
+		o.headerScheme = scheme
+	}
+}
+
+// WithTarpit atrasa cada resposta 429 pela duração informada, tornando as novas tentativas de
+// clientes abusivos mais lentas. O atraso é limitado a 30s e termina antes se o cliente
+// cancelar a requisição, caso em que nada é escrito.
+func WithTarpit(delay time.Duration) Option {
+	return func(o *options) {
+		o.tarpitDelay = min(max(delay, 0), maxTarpitDelay)
+	}
+}
+
+// WithOverheadHeader envia, em X-RateLimit-Overhead, o tempo em microssegundos gasto pelo
+// middleware na requisição até a decisão (identificação do cliente e chamadas ao store), para
+// medir o custo do limitador em produção. O tempo do handler não entra. Só para diagnóstico.
+func WithOverheadHeader(enabled bool) Option {
+	return func(o *options) {
+		o.overheadHeader = enabled
+	}
+}
+
+// WithKeyByHost separa os contadores por host (header Host), para gateways multi-tenant em que
+// vários vhosts compartilham o mesmo processo. O host é normalizado sem porta e em minúsculas.
+func WithKeyByHost(enabled bool) Option {
+	return func(o *options) {
+		o.keyByHost = enabled
+	}
+}
+
+// TokenKeyFunc deriva do token a chave estável usada na contagem (ex.: o claim sub de um JWT).
+// Quando ok é false, o próprio token é usado.
+type TokenKeyFunc func(token string) (key string, ok bool)
+
+// WithTokenKeyFunc conta as requisições com token pela chave derivada por fn, para que tokens
+// renovados do mesmo titular dividam o contador. A validação do token fica a cargo de fn; os
+// limites por token também são resolvidos pela chave derivada.
+func WithTokenKeyFunc(fn TokenKeyFunc) Option {
+	return func(o *options) {
+		o.tokenKeyFunc = fn
+	}
+}
+
+// WithSharedKeyFunc faz com que token e IP de uma mesma conta contem em um único contador.
+// As requisições com chave compartilhada usam os limites de token.
+func WithSharedKeyFunc(fn SharedKeyFunc) Option {
+	return func(o *options) {
+		o.sharedKeyFunc = fn
+	}
+}
+
+// WithTrustedProxies define os proxies confiáveis. Requisições vindas deles têm o IP do cliente
+// resolvido pelo X-Forwarded-For; de qualquer outra origem o header é ignorado.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(o *options) {
+		o.trustedProxies = append(o.trustedProxies, prefixes...)
+	}
+}
+
+// WithXForwardedForSelect define qual endereço do X-Forwarded-For é o IP do cliente quando a
+// conexão vem de um proxy confiável: config.XForwardedForRightmostUntrusted (o padrão) ou
+// config.XForwardedForLeftmost.
+func WithXForwardedForSelect(strategy string) Option {
+	return func(o *options) {
+		o.xffSelect = strategy
+	}
+}
+
+// WithRemainingDecimals faz X-RateLimit-Remaining informar a cota restante com as casas
+// decimais pedidas (Decision.RemainingFloat), arredondada para baixo para nunca prometer mais
+// do que está disponível. RateLimit-Remaining, do draft da IETF, continua inteiro.
+func WithRemainingDecimals(decimals int) Option {
+	return func(o *options) {
+		o.remainingDecimals = max(decimals, 0)
+	}
+}
+
+// WithClock define a fonte de tempo do middleware: o instante dos eventos de DecisionSink e do
+// histórico de requisições e a medição de X-RateLimit-Overhead. Sem esta opção, vale o relógio
+// do rate limiter, quando ele expõe um (rateLimiter.WithClock), ou o do sistema. Com um
+// clock.Fake nos dois, os headers e os registros ficam determinísticos nos testes.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithPreflightPolicy define como as requisições OPTIONS, como o preflight do CORS, são
+// contadas: config.PreflightCount (o padrão) as conta como as demais, config.PreflightSkip as
+// libera sem contabilizar, config.PreflightSeparate as conta em um contador próprio, com os
+// limites da classe config.ClassPreflight, e config.PreflightCoalesce conta o preflight e a
+// requisição real que o segue (mesma Origin e o método de Access-Control-Request-Method) como
+// uma só (ver rateLimiter.PreflightCoalescer).
+func WithPreflightPolicy(policy string) Option {
+	return func(o *options) {
+		o.preflightPolicy = policy
+	}
+}
+
+// WithEmptyTokenPolicy define o tratamento das requisições com o header do token presente, mas
+// vazio: config.EmptyTokenFallbackIP (o padrão) as conta pelo IP, como se não houvesse token,
+// config.EmptyTokenReject as rejeita com 400 e config.EmptyTokenAnonymous as conta em um único
+// token anônimo, com os limites por token.
+func WithEmptyTokenPolicy(policy string) Option {
+	return func(o *options) {
+		o.emptyTokenPolicy = policy
+	}
+}
+
+// WithActiveWhen aplica o rate limiting só às requisições para as quais fn retorna true (ex.:
+// as marcadas no contexto por um middleware anterior, durante a liberação gradual por tenant).
+// As demais seguem sem serem contabilizadas e sem headers de rate limit. Ao contrário de
+// RateLimiter.SetEnabled, que desliga o limitador para todos, a decisão é por requisição.
+func WithActiveWhen(fn func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.activeWhen = fn
+	}
+}
+
+// WithSkipPrivateNetworks libera, sem contabilizar, clientes em redes privadas ou de loopback.
+// A verificação usa o IP do cliente já resolvido, e não o do proxy.
+func WithSkipPrivateNetworks(enabled bool) Option {
+	return func(o *options) {
+		o.skipPrivate = enabled
+	}
+}
+
+// WithDecisionSink publica cada decisão no sink informado. A publicação acontece no caminho
+// da requisição; use um AsyncSink para desacoplar sinks lentos.
+func WithDecisionSink(sink DecisionSink) Option {
+	return func(o *options) {
+		o.decisionSink = sink
+	}
+}
+
+// WithIdempotencyKey faz com que requisições repetidas com o mesmo valor no header informado
+// (ex.: Idempotency-Key) devolvam a decisão anterior sem consumir a cota novamente. A chave
+// vale só para a mesma requisição: método, URL e corpo (até 1 MiB) entram nela. Só tem efeito
+// quando o rate limiter implementa IdempotentLimiter.
+func WithIdempotencyKey(header string) Option {
+	return func(o *options) {
+		o.idempotencyKey = header
+	}
+}
+
+// WithMethodClassifier separa os contadores pela classe do método HTTP (ex.: leitura e escrita).
+// A classe entra na chave do contador e no contexto, onde o resolver de limites pode aplicar
+// limites próprios para cada classe.
+func WithMethodClassifier(fn MethodClassifier) Option {
+	return func(o *options) {
+		o.classifier = fn
+	}
+}
+
+// WithMaxIdentifierLength define o tamanho máximo de tokens e chaves compartilhadas (padrão 1024;
+// 0 desliga o limite). Identificadores maiores são trocados pelo seu hash, ou rejeitados com
+// 400 quando reject é true.
+func WithMaxIdentifierLength(max int, reject bool) Option {
+	return func(o *options) {
+		o.maxIdentifierLength = max
+		o.rejectLongIdentifiers = reject
+	}
+}
+
+// WithUnknownBucket faz com que requisições sem token e sem IP resolvível (ex.: conexões por
+// unix socket) sejam contadas em um único contador global "unknown", em vez de responderem 500.
+// O limite vem da classe config.ClassUnknown, ou do limite por IP quando a classe não o define.
+func WithUnknownBucket(enabled bool) Option {
+	return func(o *options) {
+		o.unknownBucket = enabled
+	}
+}
+
+// WithKeyComponents define o que identifica um cliente sem token: config.KeyComponentsIP (padrão),
+// config.KeyComponentsUserAgent ou config.KeyComponentsIPAndUserAgent. Com os dois componentes,
+// o IP e o User-Agent têm contadores próprios e a requisição precisa caber em ambos, de modo que
+// trocar só o IP ou só o User-Agent não escapa do limite.
+func WithKeyComponents(components string) Option {
+	return func(o *options) {
+		o.keyComponents = components
+	}
+}
+
+// WithStoreErrorResponse define o status e o Retry-After devolvidos quando o store falha e o
+// modo de falha é fechado. O padrão é 503 com Retry-After de 5s, para que o cliente não confunda
+// a indisponibilidade com um 429 de limite excedido. Um retryAfter zero omite o header.
+func WithStoreErrorResponse(status int, retryAfter time.Duration) Option {
+	return func(o *options) {
+		if status == 0 {
+			status = defaultStoreErrorStatus
+		}
+		o.storeErrorStatus = status
+		o.storeErrorRetryAfter = retryAfter
+	}
+}
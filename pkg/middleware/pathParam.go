@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// PathParamFunc extrai do caminho da requisição o recurso alvo (ex.: o ID do usuário em
+// /users/{id}/posts). Quando ok é false, a requisição é contada sem o parâmetro.
+type PathParamFunc func(r *http.Request) (value string, ok bool)
+
+// PathRegexParam extrai o parâmetro do primeiro grupo de captura de pattern aplicado ao caminho
+// da requisição (ex.: ^/users/([^/]+)/posts). Caminhos que não casam não são identificados.
+func PathRegexParam(pattern *regexp.Regexp) PathParamFunc {
+	return func(r *http.Request) (string, bool) {
+		match := pattern.FindStringSubmatch(r.URL.Path)
+		if len(match) < 2 || match[1] == "" {
+			return "", false
+		}
+		return match[1], true
+	}
+}
+
+// PathValueParam lê o parâmetro name do padrão do http.ServeMux (ex.: {id} em
+// "POST /users/{id}/posts"). O ServeMux só preenche os parâmetros ao despachar a requisição,
+// então o middleware precisa envolver o handler da rota, e não o ServeMux inteiro.
+func PathValueParam(name string) PathParamFunc {
+	return func(r *http.Request) (string, bool) {
+		value := r.PathValue(name)
+		return value, value != ""
+	}
+}
+
+// WithPathParam separa os contadores pelo recurso alvo extraído por fn: o parâmetro entra na
+// chave junto com a identificação (token, IP, chave compartilhada ou KeyFunc), de modo que o
+// mesmo cliente tem uma cota para cada recurso, e cada recurso é protegido contra abuso
+// direcionado. Combine com WithMethodClassifier para limitar só as escritas por recurso.
+func WithPathParam(fn PathParamFunc) Option {
+	return func(o *options) {
+		o.pathParam = fn
+	}
+}
+
+// pathKey retorna o componente da chave com o parâmetro do caminho, ou "" se não houver. Valores
+// acima do tamanho máximo dos identificadores são trocados pelo seu hash.
+func (o *options) pathKey(r *http.Request) string {
+	if o.pathParam == nil {
+		return ""
+	}
+	value, ok := o.pathParam(o.routeRequest(r))
+	if !ok {
+		return ""
+	}
+	if o.maxIdentifierLength > 0 && len(value) > o.maxIdentifierLength {
+		sum := sha256.Sum256([]byte(value))
+		value = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return "path:" + value + "|"
+}
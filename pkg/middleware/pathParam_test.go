@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_RateLimit_PathParam verifica que requisições para recursos diferentes são limitadas de
+// forma independente, por cliente, e que caminhos sem o parâmetro usam o contador comum
+func Test_RateLimit_PathParam(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       3,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+	})
+	handler := RateLimit(rl, WithPathParam(PathRegexParam(regexp.MustCompile(`^/users/([^/]+)/posts`))))(okHandler)
+
+	do := func(path, ip, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":12345"
+		req.Header.Set("API_KEY", token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, do("/users/42/posts", "192.0.2.1", ""))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, do("/users/42/posts", "192.0.2.1", ""))
+	assert.True(t, mr.Exists("blocked_ip_path:42|192.0.2.1"))
+	assert.Equal(t, http.StatusOK, do("/users/7/posts", "192.0.2.1", ""), "Outro recurso tem contador próprio")
+	assert.Equal(t, http.StatusOK, do("/users/42/posts", "192.0.2.2", ""), "Outro cliente tem contador próprio no mesmo recurso")
+	assert.Equal(t, http.StatusOK, do("/health", "192.0.2.1", ""), "Caminhos sem o parâmetro usam o contador do IP")
+	assert.True(t, mr.Exists("ip_192.0.2.1"))
+
+	// O parâmetro se soma ao escopo de token
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, do("/users/42/posts", "192.0.2.1", "test-token"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, do("/users/42/posts", "192.0.2.3", "test-token"))
+	assert.Equal(t, http.StatusOK, do("/users/43/posts", "192.0.2.3", "test-token"))
+	assert.True(t, mr.Exists("token_path:43|test-token"))
+}
+
+// Test_RateLimit_PathValueParam verifica a leitura do parâmetro do padrão do http.ServeMux,
+// combinada com a separação de leitura e escrita
+func Test_RateLimit_PathValueParam(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       1,
+		BlockDurationIPSeconds: 60,
+		TokenHeaderName:        "API_KEY",
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/users/{id}/posts", RateLimit(rl,
+		WithPathParam(PathValueParam("id")),
+		WithMethodClassifier(ReadWriteClassifier()),
+	)(okHandler))
+
+	do := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/users/42/posts"))
+	assert.Equal(t, http.StatusTooManyRequests, do(http.MethodPost, "/users/42/posts"))
+	assert.True(t, mr.Exists("blocked_ip_write|path:42|192.0.2.1"))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/users/43/posts"))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/users/42/posts"), "Leituras têm contador próprio")
+}
+
+// Test_RateLimit_RouteNormalization verifica que variações de caixa e de barra final casam com
+// a mesma regra só quando a normalização correspondente está ligada
+func Test_RateLimit_RouteNormalization(t *testing.T) {
+	pattern := regexp.MustCompile(`^/users/([^/]+)/posts$`)
+	cases := []struct {
+		name          string
+		normalization RouteNormalization
+		path          string
+		key           string // contador esperado; "" usa o contador do IP
+	}{
+		{"sem normalização, caminho exato", RouteNormalization{}, "/users/ab/posts", "ip_path:ab|192.0.2.1"},
+		{"sem normalização, caixa diferente", RouteNormalization{}, "/Users/AB/posts", ""},
+		{"sem normalização, barra final", RouteNormalization{}, "/users/ab/posts/", ""},
+		{"caixa ignorada", RouteNormalization{CaseInsensitive: true}, "/Users/AB/Posts", "ip_path:ab|192.0.2.1"},
+		{"caixa ignorada, barra final", RouteNormalization{CaseInsensitive: true}, "/users/ab/posts/", ""},
+		{"barra final ignorada", RouteNormalization{IgnoreTrailingSlash: true}, "/users/ab/posts//", "ip_path:ab|192.0.2.1"},
+		{"barra final ignorada, caixa diferente", RouteNormalization{IgnoreTrailingSlash: true}, "/USERS/ab/posts/", ""},
+		{"ambas", RouteNormalization{CaseInsensitive: true, IgnoreTrailingSlash: true}, "/USERS/Ab/POSTS/", "ip_path:ab|192.0.2.1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, rl := newTestLimiter(t, &config.LimiterConfig{
+				MaxRequestsPerIP:       5,
+				BlockDurationIPSeconds: 60,
+				TokenHeaderName:        "API_KEY",
+			})
+			var handlerPath string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerPath = r.URL.Path
+			})
+			handler := RateLimit(rl, WithPathParam(PathRegexParam(pattern)), WithRouteNormalization(tc.normalization))(next)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			req.RemoteAddr = "192.0.2.1:12345"
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			key := tc.key
+			if key == "" {
+				key = "ip_192.0.2.1"
+			}
+			assert.True(t, mr.Exists(key), "Contador %s", key)
+			assert.Equal(t, tc.path, handlerPath, "O handler recebe o caminho original")
+		})
+	}
+}
+
+// Test_RouteNormalization_Root verifica que a raiz não perde a barra
+func Test_RouteNormalization_Root(t *testing.T) {
+	n := RouteNormalization{CaseInsensitive: true, IgnoreTrailingSlash: true}
+	assert.Equal(t, "/", n.normalize("/"))
+	assert.Equal(t, "/", n.normalize("//"))
+	assert.Equal(t, "/login", n.normalize("/LOGIN/"))
+}
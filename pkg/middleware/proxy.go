@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// ProxyHandler aplica o rate limiting e encaminha as requisições permitidas para target por
+// meio de um httputil.ReverseProxy. As requisições rejeitadas nunca chegam ao backend.
+//
+// O IP usado na contagem é o do cliente, resolvido pelas mesmas regras do RateLimit (inclusive
+// WithTrustedProxies), e não o da conexão do proxy com o backend. O backend recebe
+// X-Forwarded-Host, X-Forwarded-Proto e o X-Forwarded-For com o endereço da conexão ao final;
+// o X-Forwarded-For recebido só é preservado quando vem de um proxy confiável.
+func ProxyHandler(rl rateLimiter.RateLimiterInterface, target *url.URL, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			if o.fromTrustedProxy(pr.In) {
+				pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+			}
+			pr.SetXForwarded()
+		},
+	}
+	return RateLimit(rl, opts...)(proxy)
+}
+
+// fromTrustedProxy informa se a conexão da requisição vem de um dos proxies confiáveis.
+func (o *options) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && o.isTrustedProxy(addr)
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_ProxyHandler verifica que as requisições rejeitadas não chegam ao backend e que o
+// backend recebe os headers de encaminhamento
+func Test_ProxyHandler(t *testing.T) {
+	var hits atomic.Int32
+	var forwardedFor, forwardedHost string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		forwardedFor = r.Header.Get("X-Forwarded-For")
+		forwardedHost = r.Header.Get("X-Forwarded-Host")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	target, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	handler := ProxyHandler(rl, target)
+
+	codes := []int{}
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("GET", "http://api.example.com/", nil)
+		req.RemoteAddr = "203.0.113.70:12345"
+		req.Header.Set("X-Forwarded-For", "192.0.2.1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+	assert.Equal(t, int32(2), hits.Load(), "Requisições rejeitadas não deveriam chegar ao backend")
+	assert.Equal(t, "203.0.113.70", forwardedFor, "O X-Forwarded-For de um cliente não confiável não deveria ser repassado")
+	assert.Equal(t, "api.example.com", forwardedHost)
+}
+
+// Test_ProxyHandler_TrustedProxy verifica que, atrás de um proxy confiável, a contagem usa o
+// cliente real e o X-Forwarded-For recebido é preservado
+func Test_ProxyHandler_TrustedProxy(t *testing.T) {
+	var hits atomic.Int32
+	var forwardedFor string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		forwardedFor = r.Header.Get("X-Forwarded-For")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	target, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	handler := ProxyHandler(rl, target, WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")))
+
+	send := func(client string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.5:12345"
+		req.Header.Set("X-Forwarded-For", client)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Clientes diferentes atrás do mesmo proxy têm contadores próprios
+	assert.Equal(t, http.StatusOK, send("198.51.100.1"))
+	assert.Equal(t, "198.51.100.1, 10.0.0.5", forwardedFor)
+	assert.Equal(t, http.StatusTooManyRequests, send("198.51.100.1"))
+	assert.Equal(t, http.StatusOK, send("198.51.100.2"))
+	assert.Equal(t, int32(2), hits.Load())
+}
@@ -2,55 +2,500 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"rateLimiter/cmd/server/config"
+	"rateLimiter/internal/clock"
 	"rateLimiter/internal/rateLimiter"
 )
 
+// unknownIdentifier é o identificador do contador das requisições sem token e sem IP.
+const unknownIdentifier = "unknown"
+
 // RateLimit é o middleware que aplica o rate limiting.
-func RateLimit(rl rateLimiter.RateLimiterInterface) func(next http.Handler) http.Handler {
+func RateLimit(rl rateLimiter.RateLimiterInterface, opts ...Option) func(next http.Handler) http.Handler {
+	o := newOptions(opts)
+	if o.clock == nil {
+		o.clock = clock.Real{}
+		if source, ok := rl.(interface{ Clock() clock.Clock }); ok {
+			o.clock = source.Clock()
+		}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.Background()
-			var identifier string
+			if o.activeWhen != nil && !o.activeWhen(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := o.clock.Now()
+			if r.Method == http.MethodOptions && o.preflightPolicy == config.PreflightSkip {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := o.withIdempotencyKey(r.Context(), r)
+			if class := o.requestClass(r); class != "" {
+				ctx = rateLimiter.WithRequestClass(ctx, class)
+			}
+			var identifiers []string
 			var isToken bool
 
 			// Tenta obter o token do header
-			token := r.Header.Get(rl.GetConfig().TokenHeaderName)
+			cfg := rl.GetConfig()
+			source := tokenSource(r, cfg)
+			token, ok := o.boundIdentifier(o.tokenKey(o.identifierNormalization.token(sourceToken(r, source))))
+			if !ok {
+				http.Error(w, "Identificador muito longo", http.StatusBadRequest)
+				return
+			}
+			if source.Tier != "" {
+				ctx = rateLimiter.WithTokenTier(ctx, source.Tier)
+			}
+			if token == "" && !source.BasicAuth && emptyTokenHeader(r, source.Header) {
+				// Header do token presente, mas vazio: a política decide entre o IP, a rejeição
+				// e o token anônimo. Um Basic Auth malformado é contado pelo IP
+				switch o.emptyTokenPolicy {
+				case config.EmptyTokenReject:
+					http.Error(w, "Token vazio", http.StatusBadRequest)
+					return
+				case config.EmptyTokenAnonymous:
+					token = anonymousToken
+				}
+			}
+			clientIP, ipErr := o.clientIP(r)
+
+			// Chamadas internas entre serviços não são limitadas
+			if o.skipPrivate && ipErr == nil && isPrivateIP(clientIP) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Um KeyFunc define sozinho a chave e os limites, no lugar da identificação padrão
+			customKey, custom, customToken := "", false, false
+			if o.keyFunc != nil {
+				var scope string
+				customKey, scope, custom = o.keyFunc(r)
+				if custom {
+					if scope != ScopeIP && scope != ScopeToken {
+						log.Printf("Escopo inválido retornado pelo KeyFunc: %q", scope)
+						http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+						return
+					}
+					if customKey, ok = o.boundIdentifier(customKey); !ok {
+						http.Error(w, "Identificador muito longo", http.StatusBadRequest)
+						return
+					}
+					customToken = scope == ScopeToken
+				}
+			}
+
+			sharedKey, shared := "", false
+			if o.sharedKeyFunc != nil && !custom {
+				sharedKey, shared = o.sharedKeyFunc(r)
+			}
+			if shared {
+				if sharedKey, ok = o.boundIdentifier(sharedKey); !ok {
+					http.Error(w, "Identificador muito longo", http.StatusBadRequest)
+					return
+				}
+			}
+
+			fl, fair := rl.(rateLimiter.FairLimiter)
+			fair = fair && !custom && !shared && token != "" && cfg.FairShareTokensPerIP && ipErr == nil
+
+			// O teto global vale para todos e é verificado antes dos limites por identificador. No
+			// caso comum, os dois são avaliados juntos, em uma única operação do store; a cota justa
+			// e as repetições idempotentes verificam o teto global à parte
+			gl, global := rl.(rateLimiter.GlobalLimiter)
+			global = global && cfg.GlobalLimit > 0
+			combined := global && !fair && idempotencyKeyFromContext(ctx) == ""
+			if global && !combined {
+				decision, err := gl.AllowGlobal(ctx)
+				if err != nil {
+					log.Printf("Erro ao verificar o limite global: %v", err)
+					storeUnavailable(w, o)
+					return
+				}
+				if !decision.Allowed {
+					o.publish(decision)
+					o.writeOverhead(w, start)
+					rejectGlobal(w, o, cfg, rateLimiter.LimitHitGlobal, decision)
+					return
+				}
+			}
 
-			if token != "" {
-				identifier = token
+			if fair || (!custom && !shared && token != "") {
+				// Os limites do token são resolvidos pelo token, sem os prefixos do contador
+				ctx = rateLimiter.WithLimitToken(ctx, token)
+			}
+
+			if fair {
+				// Com cota justa, o token também é contabilizado dentro do IP de origem
+				decision, err := fl.AllowFairDecision(ctx, o.bucket(r, o.ipIdentifier(clientIP)), o.bucket(r, token))
+				if err != nil {
+					log.Printf("Erro ao verificar o rate limit para %s (token: true): %v", token, err)
+					storeUnavailable(w, o)
+					return
+				}
+				o.publish(decision)
+				o.recordHistory(r, decision)
+				o.writeOverhead(w, start)
+				o.writeRateLimitHeaders(w, decision)
+				if decision.Disabled {
+					w.Header().Set("X-RateLimit-Disabled", "true")
+				}
+				if !decision.Allowed {
+					w.Header().Set("X-RateLimit-Scope", rateLimiter.LimitHitIdentifier.String())
+					reject(w, r, o, blockStatus(cfg), decision)
+					return
+				}
+				o.serve(next, w, r.WithContext(context.WithValue(ctx, DecisionContextKey, decision)), rl, []string{o.bucket(r, token)}, true)
+				return
+			}
+
+			if custom {
+				identifiers = []string{customKey}
+				isToken = customToken
+
+			} else if shared {
+				// Token e IP da mesma conta contam no mesmo contador, com os limites de token
+				identifiers = []string{"shared|" + sharedKey}
+				isToken = true
+
+			} else if token != "" {
+				identifiers = []string{token}
 				isToken = true
 
 			} else {
-				// Se não houver token, usa o IP
-				clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+				// Se não houver token, usa o IP e/ou o User-Agent, conforme os componentes configurados
+				if o.keyComponents != config.KeyComponentsUserAgent {
+					if ipErr == nil {
+						identifiers = []string{o.ipIdentifier(clientIP)}
+					} else if o.unknownBucket {
+						// Sem token e sem IP: todas essas requisições dividem o mesmo contador
+						identifiers = []string{unknownIdentifier}
+						ctx = rateLimiter.WithRequestClass(ctx, config.ClassUnknown)
+					} else {
+						log.Printf("Erro ao obter o IP do cliente: %v", ipErr)
+						http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+						return
+					}
+				}
+				if o.keyComponents == config.KeyComponentsUserAgent || o.keyComponents == config.KeyComponentsIPAndUserAgent {
+					identifiers = append(identifiers, userAgentIdentifier(r.UserAgent()))
+				}
+				isToken = false
+			}
+
+			// Com mais de um componente, a requisição precisa caber em todos os contadores:
+			// prevalece a decisão mais restritiva
+			var decision *rateLimiter.Decision
+			hit := rateLimiter.LimitHitNone
+			for i, identifier := range identifiers {
+				var d *rateLimiter.Decision
+				var err error
+				switch {
+				case combined && i == 0 && o.separateGlobal(r, cfg, isToken):
+					// O limite global ainda conta a requisição; o do escopo só verifica o bloqueio
+					// ou, no preflight coalescido, é decidido com o preflight
+					if d, err = gl.AllowGlobal(ctx); err == nil && !d.Allowed {
+						hit = rateLimiter.LimitHitGlobal
+					} else if err == nil {
+						d, err = o.allow(ctx, rl, r, o.bucket(r, identifier), isToken)
+					}
+				case combined && i == 0:
+					d, hit, err = gl.AllowWithGlobal(ctx, o.bucket(r, identifier), isToken)
+				default:
+					d, err = o.allow(ctx, rl, r, o.bucket(r, identifier), isToken)
+				}
 				if err != nil {
-					log.Printf("Erro ao obter o IP do cliente: %v", err)
-					http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+					log.Printf("Erro ao verificar o rate limit para %s (token: %t): %v", identifier, isToken, err)
+					storeUnavailable(w, o)
 					return
 				}
+				if decision == nil || !d.Allowed || d.Remaining < decision.Remaining {
+					decision = d
+				}
+				if !d.Allowed {
+					break
+				}
+			}
 
-				identifier = clientIP
-				isToken = false
+			// O sub-limite da rota é um contador à parte do mesmo identificador: mesmo dentro
+			// da própria cota, a requisição é bloqueada se exceder o limite da rota
+			if route := o.routeLimit(r, cfg); route != "" && decision.Allowed && !decision.Disabled {
+				routeIdentifier := routeLimitPrefix + route + "|" + identifiers[0]
+				routeCtx := rateLimiter.WithLimitToken(rateLimiter.WithRouteLimit(ctx, route), "")
+				d, err := o.allow(routeCtx, rl, r, o.bucket(r, routeIdentifier), isToken)
+				if err != nil {
+					log.Printf("Erro ao verificar o sub-limite da rota %s para %s (token: %t): %v", route, identifiers[0], isToken, err)
+					storeUnavailable(w, o)
+					return
+				}
+				if !d.Allowed || d.Remaining < decision.Remaining {
+					decision = d
+				}
 			}
 
-			allowed, err := rl.Allow(ctx, identifier, isToken)
-			if err != nil {
-				log.Printf("Erro ao verificar o rate limit para %s (token: %t): %v", identifier, isToken, err)
-				http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
-				return
+			// O limitador sombra avalia os mesmos contadores, sem afetar a resposta
+			buckets := make([]string, len(identifiers))
+			for i, identifier := range identifiers {
+				buckets[i] = o.bucket(r, identifier)
+			}
+			if o.shadow != nil {
+				o.shadow.evaluate(ctx, buckets, isToken, decision)
 			}
 
-			if !allowed {
-				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-				w.WriteHeader(http.StatusTooManyRequests) // Código HTTP 429
-				_, _ = w.Write([]byte("you have reached the maximum number of requests or actions allowed within a certain time frame"))
+			o.publish(decision)
+			o.recordHistory(r, decision)
+			o.writeOverhead(w, start)
+			o.writeRateLimitHeaders(w, decision)
+			if decision.Disabled {
+				w.Header().Set("X-RateLimit-Disabled", "true")
+			}
+			if decision.SoftAllowed {
+				// Além do limite, mas dentro das violações consecutivas toleradas
+				w.Header().Set("X-RateLimit-Soft-Allowed", "true")
+			}
+
+			if !decision.Allowed {
+				if hit&rateLimiter.LimitHitGlobal != 0 {
+					rejectGlobal(w, o, cfg, hit, decision)
+					return
+				}
+				w.Header().Set("X-RateLimit-Scope", rateLimiter.LimitHitIdentifier.String())
+				reject(w, r, o, blockStatus(cfg), decision)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			// Disponibiliza a decisão para os handlers seguintes
+			o.serve(next, w, r.WithContext(context.WithValue(ctx, DecisionContextKey, decision)), rl, buckets, isToken)
 		})
 	}
 }
+
+// serve chama o próximo handler. Com cota de bytes configurada, mede o corpo da resposta e o
+// soma à cota de cada identificador ao final; com cota de upload, o corpo da requisição é
+// contabilizado à medida que o handler o lê (ver LimitUpload).
+func (o *options) serve(next http.Handler, w http.ResponseWriter, r *http.Request, rl rateLimiter.RateLimiterInterface, buckets []string, isToken bool) {
+	r = o.limitUpload(r, rl, buckets, isToken)
+	bl, ok := rl.(rateLimiter.BandwidthLimiter)
+	if !ok || rl.GetConfig().MaxBytesPerWindow <= 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	cw := &countingWriter{ResponseWriter: w}
+	next.ServeHTTP(cw, r)
+	if cw.hijacked {
+		// O handler assumiu a conexão: o que foi escrito nela não passa pelo ResponseWriter
+		return
+	}
+	for _, bucket := range buckets {
+		if err := bl.RecordBytes(r.Context(), bucket, isToken, cw.written); err != nil {
+			log.Printf("Erro ao contabilizar os bytes da resposta para %s: %v", bucket, err)
+		}
+	}
+}
+
+// tokenKey aplica o TokenKeyFunc configurado, mantendo o token quando ele não deriva uma chave.
+func (o *options) tokenKey(token string) string {
+	if token == "" || o.tokenKeyFunc == nil {
+		return token
+	}
+	if key, ok := o.tokenKeyFunc(token); ok && key != "" {
+		return key
+	}
+	return token
+}
+
+// allow consulta o rate limiter, repassando a chave de idempotência quando configurada ou,
+// com config.PreflightCoalesce, a chave que liga o preflight à requisição real. Os métodos que
+// não contam no escopo só verificam o bloqueio.
+func (o *options) allow(ctx context.Context, rl rateLimiter.RateLimiterInterface, r *http.Request, identifier string, isToken bool) (*rateLimiter.Decision, error) {
+	if nl, ok := rl.(rateLimiter.NonCountingLimiter); ok && nonCountingMethod(r, rl.GetConfig(), isToken) {
+		return nl.AllowWithoutCountDecision(ctx, identifier, isToken)
+	}
+	if il, ok := rl.(rateLimiter.IdempotentLimiter); ok {
+		if key := idempotencyKeyFromContext(ctx); key != "" {
+			return il.AllowIdempotentDecision(ctx, identifier, isToken, key)
+		}
+	}
+	if pc, ok := rl.(rateLimiter.PreflightCoalescer); ok && o.preflightPolicy == config.PreflightCoalesce {
+		if key, preflight := preflightCoalesceKey(r); key != "" {
+			if preflight {
+				return pc.AllowPreflightDecision(ctx, identifier, isToken, key)
+			}
+			return pc.AllowAfterPreflightDecision(ctx, identifier, isToken, key)
+		}
+	}
+	return rl.AllowDecision(ctx, identifier, isToken)
+}
+
+// separateGlobal informa se a requisição é decidida no escopo de um jeito que não tem operação
+// combinada com o limite global: os métodos que não contam e o preflight coalescido. Nesses
+// casos, o limite global é verificado à parte, antes de allow.
+func (o *options) separateGlobal(r *http.Request, cfg *config.LimiterConfig, isToken bool) bool {
+	if nonCountingMethod(r, cfg, isToken) {
+		return true
+	}
+	if o.preflightPolicy != config.PreflightCoalesce {
+		return false
+	}
+	key, _ := preflightCoalesceKey(r)
+	return key != ""
+}
+
+// nonCountingMethod informa se o método da requisição está entre os que não contam no escopo
+// (config.LimiterConfig.NonCountingMethodsIP ou NonCountingMethodsToken).
+func nonCountingMethod(r *http.Request, cfg *config.LimiterConfig, isToken bool) bool {
+	methods := cfg.NonCountingMethodsIP
+	if isToken {
+		methods = cfg.NonCountingMethodsToken
+	}
+	return slices.Contains(methods, r.Method)
+}
+
+// preflightCoalesceKey retorna a chave que liga um preflight do CORS à requisição real: um
+// hash da Origin e do método (o de Access-Control-Request-Method, no preflight). Sem Origin,
+// a chave é vazia e a requisição é contada normalmente.
+func preflightCoalesceKey(r *http.Request) (key string, preflight bool) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return "", false
+	}
+	if r.Method == http.MethodOptions {
+		method := r.Header.Get("Access-Control-Request-Method")
+		if method == "" {
+			return "", false
+		}
+		return hashIdentifier(origin + "|" + strings.ToUpper(method)), true
+	}
+	return hashIdentifier(origin + "|" + r.Method), false
+}
+
+// publish envia a decisão ao sink configurado, se houver, e a conta nos contadores do expvar.
+func (o *options) publish(decision *rateLimiter.Decision) {
+	switch {
+	case !decision.Allowed:
+		o.countExpvar(expvarBlocked)
+	case decision.FailedOpen:
+		o.countExpvar(expvarErrors)
+		o.countExpvar(expvarAllowed)
+	default:
+		o.countExpvar(expvarAllowed)
+	}
+	if o.decisionSink != nil {
+		o.decisionSink.Publish(newDecisionEvent(decision, o.clock.Now()))
+	}
+}
+
+// bucket monta o identificador efetivo do contador, incluindo o parâmetro do caminho quando há
+// um PathParamFunc, a classe do método quando há um classificador e o host quando KeyByHost
+// está ativo.
+func (o *options) bucket(r *http.Request, identifier string) string {
+	identifier = o.pathKey(r) + identifier
+	if class := o.requestClass(r); class != "" {
+		identifier = class + "|" + identifier
+	}
+	if !o.keyByHost {
+		return identifier
+	}
+	return normalizeHost(r.Host) + "|" + identifier
+}
+
+// normalizeHost remove a porta e converte o host para minúsculas.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// reject escreve a resposta de limite excedido, com o corpo montado a partir da decisão, e faz o
+// flush em seguida para que proxies recebam a rejeição imediatamente, mesmo em endpoints de streaming.
+// Com tarpit, a resposta só é escrita depois do atraso configurado.
+func reject(w http.ResponseWriter, r *http.Request, o *options, status int, decision *rateLimiter.Decision) {
+	if !tarpit(r.Context(), o.tarpitDelay) {
+		return
+	}
+	writeRejection(w, o, status, decision)
+}
+
+// rejectGlobal escreve a rejeição pelo limite global, indicando em X-RateLimit-Scope quais
+// limites foram atingidos. Não há tarpit: segurar conexões pioraria a sobrecarga que o teto
+// global protege.
+func rejectGlobal(w http.ResponseWriter, o *options, cfg *config.LimiterConfig, hit rateLimiter.LimitHit, decision *rateLimiter.Decision) {
+	w.Header().Set("X-RateLimit-Scope", hit.String())
+	writeRejection(w, o, globalStatus(cfg), decision)
+}
+
+// writeRejection escreve a resposta de rejeição imediatamente.
+func writeRejection(w http.ResponseWriter, o *options, status int, decision *rateLimiter.Decision) {
+	if o.rejectionHeader != nil {
+		w.Header().Set(o.rejectionHeader.Name, o.rejectionHeader.Value)
+	}
+	template := o.rejectionTemplate(decision)
+	if o.rejectionFormat == config.RejectionFormatProblemJSON {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		_, _ = w.Write(renderProblemDetails(template, status, decision))
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(renderRejectionBody(template, decision)))
+	}
+	if err := http.NewResponseController(w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Erro ao enviar a resposta de limite excedido: %v", err)
+	}
+}
+
+// blockStatus retorna o status das respostas rejeitadas pelo limite: BlockStatusCode, ou 429
+// quando não definido ou fora das faixas 4xx e 5xx.
+func blockStatus(cfg *config.LimiterConfig) int {
+	if cfg.BlockStatusCode < 400 || cfg.BlockStatusCode > 599 {
+		return http.StatusTooManyRequests
+	}
+	return cfg.BlockStatusCode
+}
+
+// globalStatus retorna o status das respostas rejeitadas pelo limite global (429 ou 503).
+func globalStatus(cfg *config.LimiterConfig) int {
+	if cfg.GlobalLimitStatus == http.StatusServiceUnavailable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusTooManyRequests
+}
+
+// tarpit espera pelo atraso informado. Retorna false se o contexto for cancelado antes.
+func tarpit(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// storeUnavailable escreve a resposta usada quando o rate limit não pôde ser verificado.
+func storeUnavailable(w http.ResponseWriter, o *options) {
+	o.countExpvar(expvarErrors)
+	if o.storeErrorRetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(o.storeErrorRetryAfter)))
+	}
+	http.Error(w, http.StatusText(o.storeErrorStatus), o.storeErrorStatus)
+}
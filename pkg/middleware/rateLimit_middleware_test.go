@@ -2,8 +2,14 @@ package middleware
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +20,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"rateLimiter/cmd/server/config"
+	"rateLimiter/infra/db"
 	redisStore "rateLimiter/infra/db/redis"
 	"rateLimiter/internal/rateLimiter"
 )
@@ -31,6 +38,15 @@ func (m *mockRateLimiter) Allow(ctx context.Context, identifier string, isToken
 	return args.Bool(0), args.Error(1)
 }
 
+// AllowDecision reaproveita as expectativas configuradas para Allow
+func (m *mockRateLimiter) AllowDecision(ctx context.Context, identifier string, isToken bool) (*rateLimiter.Decision, error) {
+	args := m.MethodCalled("Allow", ctx, identifier, isToken)
+	if err := args.Error(1); err != nil {
+		return nil, err
+	}
+	return &rateLimiter.Decision{Allowed: args.Bool(0), Identifier: identifier, IsToken: isToken}, nil
+}
+
 func (m *mockRateLimiter) GetConfig() *config.LimiterConfig {
 	args := m.Called()
 	return args.Get(0).(*config.LimiterConfig)
@@ -141,6 +157,9 @@ type redisStoreMock struct {
 	client *redis.Client
 }
 
+// Garantimos que redisStoreMock implementa a interface Store
+var _ db.Store = (*redisStoreMock)(nil)
+
 func (rs *redisStoreMock) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
 	pipe := rs.client.Pipeline()
 	incr := pipe.Incr(ctx, key)
@@ -152,24 +171,132 @@ func (rs *redisStoreMock) Increment(ctx context.Context, key string, window time
 	return incr.Val(), nil
 }
 
+func (rs *redisStoreMock) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	pipe := rs.client.Pipeline()
+	incr := pipe.IncrBy(ctx, key, n)
+	pipe.Expire(ctx, key, window)
+	_, err := pipe.Exec(ctx)
+	return incr.Val(), err
+}
+
+func (rs *redisStoreMock) IncrementWithTTL(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	pipe := rs.client.Pipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	ttl := pipe.PTTL(ctx, key)
+	_, err := pipe.Exec(ctx)
+	return incr.Val(), ttl.Val(), err
+}
+
+func (rs *redisStoreMock) CheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, int64, time.Duration, error) {
+	blocked, err := rs.IsBlocked(ctx, keys.Block)
+	if err != nil || blocked {
+		return false, 0, blockDuration, err
+	}
+	count, err := rs.Increment(ctx, keys.Counter, window)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if count <= limit {
+		return true, limit - count, 0, nil
+	}
+	if err := rs.Block(ctx, keys.Block, blockDuration, db.BlockInfo{Reason: db.ReasonRateLimitExceeded}); err != nil {
+		return false, 0, 0, err
+	}
+	return false, 0, blockDuration, rs.Reset(ctx, keys.Counter)
+}
+
+func (rs *redisStoreMock) CheckAndCountWithGlobal(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time, global db.GlobalCount) (bool, int64, time.Duration, int64, error) {
+	globalCount, err := rs.Increment(ctx, global.Key, global.Window)
+	if err != nil {
+		return false, 0, 0, 0, err
+	}
+	allowed, remaining, retryAfter, err := rs.CheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	return allowed, remaining, retryAfter, globalCount, err
+}
+
+func (rs *redisStoreMock) SlidingWindow(ctx context.Context, key string, limit int64, window time.Duration, now time.Time) (bool, float64, error) {
+	count, err := rs.Increment(ctx, key, window)
+	return count <= limit, float64(count), err
+}
+
+func (rs *redisStoreMock) SlidingWindowCheckAndCount(ctx context.Context, keys db.CountKeys, limit int64, window, blockDuration time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	allowed, remaining, retryAfter, err := rs.CheckAndCount(ctx, keys, limit, window, blockDuration, now)
+	return allowed, float64(limit - remaining), retryAfter, err
+}
+
+func (rs *redisStoreMock) LeakyBucket(ctx context.Context, keys db.CountKeys, capacity int64, leakInterval time.Duration, now time.Time) (bool, float64, time.Duration, error) {
+	allowed, remaining, retryAfter, err := rs.CheckAndCount(ctx, keys, capacity, leakInterval*time.Duration(capacity), 0, now)
+	return allowed, float64(capacity - remaining), retryAfter, err
+}
+
+func (rs *redisStoreMock) Count(ctx context.Context, key string) (int64, error) {
+	count, err := rs.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
 func (rs *redisStoreMock) IsBlocked(ctx context.Context, key string) (bool, error) {
-	val, err := rs.client.Get(ctx, key).Result()
+	exists, err := rs.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+func (rs *redisStoreMock) Block(ctx context.Context, key string, duration time.Duration, info db.BlockInfo) error {
+	return rs.client.Set(ctx, key, info.Reason, duration).Err()
+}
+
+func (rs *redisStoreMock) BlockInfo(ctx context.Context, key string) (*db.BlockInfo, error) {
+	reason, err := rs.client.Get(ctx, key).Result()
 	if err == redis.Nil {
-		return false, nil
+		return nil, nil
 	} else if err != nil {
-		return false, err
+		return nil, err
+	}
+	return &db.BlockInfo{Reason: reason}, nil
+}
+
+func (rs *redisStoreMock) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := rs.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
 	}
-	return val == "blocked", nil
+	return val, err
 }
 
-func (rs *redisStoreMock) Block(ctx context.Context, key string, duration time.Duration) error {
-	return rs.client.Set(ctx, key, "blocked", duration).Err()
+func (rs *redisStoreMock) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return rs.client.Set(ctx, key, value, ttl).Err()
 }
 
 func (rs *redisStoreMock) Reset(ctx context.Context, key string) error {
 	return rs.client.Del(ctx, key).Err()
 }
 
+func (rs *redisStoreMock) ResetAll(ctx context.Context, keys ...string) error {
+	return rs.client.Del(ctx, keys...).Err()
+}
+
+func (rs *redisStoreMock) DeleteMatching(ctx context.Context, match string, allow func(key string) bool) (int, error) {
+	keys, err := rs.client.Keys(ctx, match).Result()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, key := range keys {
+		if allow(key) {
+			if err := rs.client.Del(ctx, key).Err(); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
 func (rs *redisStoreMock) Close() error {
 	return rs.client.Close()
 }
@@ -340,3 +467,1105 @@ func Test_RateLimit_DifferentIPsAndTokens(t *testing.T) {
 	middleware.ServeHTTP(rec, req)
 	assert.Equal(t, http.StatusOK, rec.Code, "Requisição do Token2 deveria ser permitida mesmo com Token1 bloqueado")
 }
+
+// Test_RateLimit_Middleware_RejectionHeader verifica que o header de rejeição aparece apenas nas respostas 429
+func Test_RateLimit_Middleware_RejectionHeader(t *testing.T) {
+	mockRL := new(mockRateLimiter)
+	mockRL.On("GetConfig").Return(&config.LimiterConfig{
+		TokenHeaderName: "API_KEY",
+	})
+	mockRL.On("Allow", mock.Anything, "192.0.2.30", false).Return(true, nil)
+	mockRL.On("Allow", mock.Anything, "192.0.2.31", false).Return(false, nil)
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	middleware := RateLimit(mockRL, WithRejectionHeader("X-Throttled", "1"))(nextHandler)
+
+	// Requisição permitida não deve carregar o header
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.30:12345"
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-Throttled"), "Respostas permitidas não deveriam ter o header de rejeição")
+
+	// Requisição bloqueada deve carregar o header
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.31:12345"
+	rec = httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-Throttled"), "Respostas 429 deveriam ter o header de rejeição")
+
+	// Sem a opção, o header não é enviado
+	middleware = RateLimit(mockRL)(nextHandler)
+	rec = httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-Throttled"), "O header de rejeição deveria estar desligado por padrão")
+}
+
+// Test_RateLimit_FairShare testa o middleware com cota justa entre tokens do mesmo IP
+func Test_RateLimit_FairShare(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+	defer client.Close()
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          4,
+		MaxRequestsPerToken:       100,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		FairShareTokensPerIP:      true,
+	}
+	rl := rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(client))
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	middleware := RateLimit(rl)(nextHandler)
+
+	send := func(token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.40:12345"
+		req.Header.Set("API_KEY", token)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusOK, send("token-a"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("token-a"), "O token A esgotou o limite do IP")
+
+	// O token B ainda recebe a sua parte do IP
+	assert.Equal(t, http.StatusOK, send("token-b"))
+	assert.Equal(t, http.StatusOK, send("token-b"))
+	assert.Equal(t, http.StatusTooManyRequests, send("token-b"), "O token B deveria ser limitado à sua cota justa")
+}
+
+// newTestLimiter cria um rate limiter real sobre um Redis em memória
+func newTestLimiter(t *testing.T, cfg *config.LimiterConfig, opts ...rateLimiter.Option) (*miniredis.Miniredis, *rateLimiter.RateLimiter) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+	t.Cleanup(func() { client.Close() })
+
+	return mr, rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(client), opts...)
+}
+
+// okHandler é o handler final usado nos testes
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// Test_RateLimit_KeyByHost verifica que o mesmo IP em hosts diferentes usa contadores independentes
+func Test_RateLimit_KeyByHost(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithKeyByHost(true))(okHandler)
+
+	send := func(host string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		req.RemoteAddr = "192.0.2.50:12345"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Esgotar o limite no tenant A (variações de caixa e porta são o mesmo host)
+	assert.Equal(t, http.StatusOK, send("tenant-a.example.com"))
+	assert.Equal(t, http.StatusOK, send("Tenant-A.example.com:8080"))
+	assert.Equal(t, http.StatusTooManyRequests, send("tenant-a.example.com"))
+
+	// O tenant B continua disponível para o mesmo IP
+	assert.Equal(t, http.StatusOK, send("tenant-b.example.com"), "O mesmo IP em outro host deveria ter contador próprio")
+	assert.Equal(t, http.StatusOK, send("TENANT-B.EXAMPLE.COM:443"))
+	assert.Equal(t, http.StatusTooManyRequests, send("tenant-b.example.com"))
+}
+
+// Test_RateLimit_KeyByHostTokenLimits verifica que, com o host na chave, o limite por token
+// do hash do Redis continua sendo aplicado
+func Test_RateLimit_KeyByHostTokenLimits(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	mr.HSet("token_limits", "premium", "3/1s/10s")
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	}
+	resolver := rateLimiter.NewCachingLimitResolver(
+		redisStore.NewRedisLimitResolver(client, "token_limits", rateLimiter.NewStaticLimitResolver(cfg)), time.Minute, 0)
+	rl := rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(client), rateLimiter.WithLimitResolver(resolver))
+	middleware := RateLimit(rl, WithKeyByHost(true))(okHandler)
+
+	send := func(host, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		req.RemoteAddr = "192.0.2.51:12345"
+		req.Header.Set("API_KEY", token)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("tenant-a.example.com", "premium")
+	assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"), "O limite do token no hash deveria valer com o host na chave")
+	assert.Equal(t, http.StatusOK, send("tenant-a.example.com", "premium").Code)
+	assert.Equal(t, http.StatusOK, send("tenant-a.example.com", "premium").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("tenant-a.example.com", "premium").Code)
+	assert.True(t, mr.Exists("blocked_token_tenant-a.example.com|premium"), "O bloqueio continua separado por host")
+
+	// Um token fora do hash usa o limite da configuração
+	assert.Equal(t, "1", send("tenant-a.example.com", "basic").Header().Get("X-RateLimit-Limit"))
+}
+
+// Test_RateLimit_PreloadMatchesRequests verifica que os limites pré-carregados com
+// PreloadContexts são os consultados pelas requisições com faixas, classes e host na chave
+func Test_RateLimit_PreloadMatchesRequests(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	mr.HSet("token_limits", "premium", "3/1s/10s")
+
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenSources:              []config.TokenSource{{Header: "API_KEY"}, {Header: "X-Partner-Key", Tier: "partner"}},
+		TokenTiers:                map[string]config.TokenTier{"partner": {}},
+		SplitReadWrite:            true,
+	}
+	resolver := rateLimiter.NewCachingLimitResolver(
+		redisStore.NewRedisLimitResolver(client, "token_limits", rateLimiter.NewStaticLimitResolver(cfg)), time.Minute, 0)
+	for _, ctx := range rateLimiter.PreloadContexts(context.Background(), cfg) {
+		require.NoError(t, resolver.Preload(ctx, []string{"premium"}))
+	}
+	assert.Equal(t, 4, resolver.Len(), "Uma entrada por faixa e classe")
+
+	// Com o hash alterado, só uma consulta fora do cache veria o novo limite
+	mr.HSet("token_limits", "premium", "9/1s/10s")
+	rl := rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(client), rateLimiter.WithLimitResolver(resolver))
+	middleware := RateLimit(rl, WithKeyByHost(true),
+		WithMethodClassifier(ReadWriteClassifier()))(okHandler)
+
+	for _, header := range []string{"API_KEY", "X-Partner-Key"} {
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			req := httptest.NewRequest(method, "/", nil)
+			req.Host = "tenant-a.example.com"
+			req.RemoteAddr = "192.0.2.52:12345"
+			req.Header.Set(header, "premium")
+			rec := httptest.NewRecorder()
+			middleware.ServeHTTP(rec, req)
+			assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"), "%s %s deveria usar o limite pré-carregado", header, method)
+		}
+	}
+	assert.Equal(t, 4, resolver.Len(), "As requisições não deveriam criar entradas novas")
+}
+
+// Test_NormalizeHost verifica a normalização do host
+func Test_NormalizeHost(t *testing.T) {
+	assert.Equal(t, "example.com", normalizeHost("Example.COM:8080"))
+	assert.Equal(t, "example.com", normalizeHost("example.com."))
+	assert.Equal(t, "::1", normalizeHost("[::1]:8080"))
+	assert.Equal(t, "::1", normalizeHost("[::1]"))
+}
+
+// Test_RateLimit_DecisionInContext verifica que o handler seguinte consegue ler a decisão
+func Test_RateLimit_DecisionInContext(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+
+	var got *rateLimiter.Decision
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decision, ok := DecisionFromContext(r.Context())
+		require.True(t, ok, "A decisão deveria estar no contexto")
+		got = decision
+		w.WriteHeader(http.StatusOK)
+	})
+	middleware := RateLimit(rl)(nextHandler)
+
+	for i := 1; i <= 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("API_KEY", "ctx-token")
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, got)
+		assert.True(t, got.Allowed)
+		assert.True(t, got.IsToken)
+		assert.Equal(t, "ctx-token", got.Identifier)
+		assert.Equal(t, 10, got.Limit)
+		assert.Equal(t, 10-i, got.Remaining, "Restantes após a requisição %d", i)
+	}
+}
+
+// Test_DecisionFromContext_Missing verifica a leitura de um contexto sem decisão
+func Test_DecisionFromContext_Missing(t *testing.T) {
+	_, ok := DecisionFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+// Test_RateLimit_Disabled verifica que o middleware vira pass-through e sinaliza o desligamento
+func Test_RateLimit_Disabled(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		Disabled:                  true,
+	})
+	middleware := RateLimit(rl)(okHandler)
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.60:12345"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code, "Requisição %d deveria passar com o limitador desligado", i+1)
+		assert.Equal(t, "true", rec.Header().Get("X-RateLimit-Disabled"))
+	}
+
+	// Ligado, o header não é enviado
+	rl.SetEnabled(true)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.60:12345"
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Disabled"))
+}
+
+// Test_RateLimit_ConsecutiveViolations verifica que uma violação isolada passa sem 429 e que as
+// violações sustentadas recebem o 429
+func Test_RateLimit_ConsecutiveViolations(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:              2,
+		MaxRequestsPerToken:           2,
+		BlockDurationIPSeconds:        10,
+		BlockDurationTokenSeconds:     10,
+		TokenHeaderName:               "API_KEY",
+		ConsecutiveViolationThreshold: 2,
+	})
+	middleware := RateLimit(rl)(okHandler)
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.61:12345"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := send()
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-RateLimit-Soft-Allowed"))
+	}
+
+	// A primeira violação é tolerada
+	rec := send()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("X-RateLimit-Soft-Allowed"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.False(t, mr.Exists("blocked_ip_192.0.2.61"))
+
+	// A segunda seguida recebe o 429 e o bloqueio
+	rec = send()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.True(t, mr.Exists("blocked_ip_192.0.2.61"))
+	assert.Equal(t, http.StatusTooManyRequests, send().Code)
+}
+
+// Test_RateLimit_SharedKeyFunc verifica que token e IP da mesma conta consomem o mesmo contador
+func Test_RateLimit_SharedKeyFunc(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       3,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+
+	// A conta "acme" é identificada pelo seu token ou pelo IP do seu escritório
+	accountOf := func(r *http.Request) (string, bool) {
+		if r.Header.Get("API_KEY") == "acme-token" || strings.HasPrefix(r.RemoteAddr, "192.0.2.70:") {
+			return "acme", true
+		}
+		return "", false
+	}
+	middleware := RateLimit(rl, WithSharedKeyFunc(accountOf))(okHandler)
+
+	send := func(remoteAddr, token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("198.51.100.1:1000", "acme-token"))
+	assert.Equal(t, http.StatusOK, send("192.0.2.70:1000", ""))
+	assert.Equal(t, http.StatusOK, send("198.51.100.2:1000", "acme-token"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.0.2.70:1000", ""),
+		"Token e IP da conta deveriam esgotar o mesmo contador")
+
+	// Requisições fora da conta seguem com os contadores próprios
+	assert.Equal(t, http.StatusOK, send("192.0.2.71:1000", ""))
+	assert.Equal(t, http.StatusOK, send("192.0.2.71:1000", "other-token"))
+}
+
+// Test_RateLimit_StoreDown_FailClosed verifica que a queda do Redis no modo fechado responde 503
+// com Retry-After, e não 429
+func Test_RateLimit_StoreDown_FailClosed(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		FailureMode:               config.FailureModeClosed,
+	})
+	mr.Close()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.90:1000"
+	rec := httptest.NewRecorder()
+	RateLimit(rl)(okHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.NotEqual(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+}
+
+// Test_RateLimit_StoreDown_CustomResponse verifica o status e o Retry-After configurados
+func Test_RateLimit_StoreDown_CustomResponse(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		FailureMode:               config.FailureModeClosed,
+	})
+	mr.Close()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("API_KEY", "abc123")
+	rec := httptest.NewRecorder()
+	RateLimit(rl, WithStoreErrorResponse(http.StatusInternalServerError, 0))(okHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	rec = httptest.NewRecorder()
+	RateLimit(rl, WithStoreErrorResponse(http.StatusServiceUnavailable, 1500*time.Millisecond))(okHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"), "O Retry-After deveria ser arredondado para cima")
+}
+
+// Test_RateLimit_IdempotencyKey verifica que a mesma chave de idempotência consome a cota uma única vez
+func Test_RateLimit_IdempotencyKey(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithIdempotencyKey("Idempotency-Key"))(okHandler)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = "192.0.2.100:1000"
+		req.Header.Set("Idempotency-Key", "pedido-42")
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, "A repetição %d deveria receber a decisão original", i+1)
+	}
+
+	count, err := mr.Get("ip_192.0.2.100")
+	require.NoError(t, err)
+	assert.Equal(t, "1", count)
+
+	// Sem a chave, a próxima requisição excede o limite de 1
+	req := httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "192.0.2.100:1000"
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+// Test_RateLimit_IdempotencyKeyBoundToRequest verifica que a mesma chave de idempotência em
+// requisições diferentes não evita a contagem
+func Test_RateLimit_IdempotencyKeyBoundToRequest(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	var bodies []string
+	middleware := RateLimit(rl, WithIdempotencyKey("Idempotency-Key"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}))
+	send := func(path, body string) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.101:1000"
+		req.Header.Set("Idempotency-Key", "pedido-42")
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	send("/pedidos", `{"valor":1}`)
+	send("/pagamentos", `{"valor":1}`)
+	send("/pedidos", `{"valor":2}`)
+	send("/pedidos", `{"valor":1}`)
+
+	count, err := mr.Get("ip_192.0.2.101")
+	require.NoError(t, err)
+	assert.Equal(t, "3", count, "Só a repetição idêntica deveria deixar de ser contada")
+	assert.Equal(t, []string{`{"valor":1}`, `{"valor":1}`, `{"valor":2}`, `{"valor":1}`}, bodies, "O handler deveria receber o corpo inteiro")
+}
+
+// Test_RateLimit_IdempotencyKeyBounded verifica que um header de idempotência longo não vira
+// uma chave longa no store
+func Test_RateLimit_IdempotencyKeyBounded(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithIdempotencyKey("Idempotency-Key"))(okHandler)
+	send := func(key string) {
+		req := httptest.NewRequest("POST", "/pedidos", nil)
+		req.RemoteAddr = "192.0.2.102:1000"
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	long := strings.Repeat("k", 512*1024)
+	send(long)
+	send(long)
+	send(long[1:])
+
+	count, err := mr.Get("ip_192.0.2.102")
+	require.NoError(t, err)
+	assert.Equal(t, "2", count, "A repetição com o mesmo header continua reconhecida")
+	for _, key := range mr.Keys() {
+		assert.Less(t, len(key), 128, "A chave no store não deveria crescer com o header")
+	}
+}
+
+// Test_RateLimit_RejectionBodyTemplate verifica que o corpo do 429 interpola os valores da decisão
+func Test_RateLimit_RejectionBodyTemplate(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          5,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    30,
+		BlockDurationTokenSeconds: 30,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl,
+		WithRejectionBody("rate limited: {limit} req/{window}, retry after {retry_after}"))(okHandler)
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.120:1000"
+		rec = httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "rate limited: 5 req/1s, retry after 30s", rec.Body.String())
+}
+
+// Test_RateLimit_RejectionBodyDefault verifica que sem modelo a mensagem padrão é mantida
+func Test_RateLimit_RejectionBodyDefault(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    30,
+		BlockDurationTokenSeconds: 30,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithRejectionBody(""))(okHandler)
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.121:1000"
+		rec = httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, defaultRejectionBody, rec.Body.String())
+}
+
+// Test_RateLimit_RejectionProblemJSON verifica o corpo application/problem+json (RFC 7807) de
+// uma requisição bloqueada
+func Test_RateLimit_RejectionProblemJSON(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    30,
+		BlockDurationTokenSeconds: 30,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl,
+		WithRejectionFormat(config.RejectionFormatProblemJSON),
+		WithRejectionBody("limite de {limit} req/{window}"))(okHandler)
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.122:1000"
+		rec = httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, map[string]any{
+		"type":       "about:blank",
+		"title":      "Too Many Requests",
+		"status":     float64(http.StatusTooManyRequests),
+		"detail":     "limite de 1 req/1s",
+		"retryAfter": float64(30),
+	}, problem)
+}
+
+// Test_RateLimit_RejectMessageFunc verifica que cada identificador recebe a mensagem escolhida
+// pela função, e que o retorno vazio mantém o modelo configurado
+func Test_RateLimit_RejectMessageFunc(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       1,
+		BlockDurationIPSeconds:    30,
+		BlockDurationTokenSeconds: 30,
+		TokenHeaderName:           "API_KEY",
+	})
+	premium := map[string]bool{"premium-token": true}
+	middleware := RateLimit(rl,
+		WithRejectionBody("limite de {limit} req/{window}"),
+		WithRejectMessage(func(d *rateLimiter.Decision, isToken bool, identifier string) string {
+			if isToken && premium[identifier] {
+				return "limite atingido; fale com o suporte (nova tentativa em {retry_after})"
+			}
+			return ""
+		}))(okHandler)
+
+	rejectionFor := func(ip, token string) *httptest.ResponseRecorder {
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = ip + ":1000"
+			if token != "" {
+				req.Header.Set("API_KEY", token)
+			}
+			rec = httptest.NewRecorder()
+			middleware.ServeHTTP(rec, req)
+		}
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		return rec
+	}
+
+	assert.Equal(t, "limite atingido; fale com o suporte (nova tentativa em 30s)", rejectionFor("192.0.2.123", "premium-token").Body.String())
+	assert.Equal(t, "limite de 1 req/1s", rejectionFor("192.0.2.124", "basic-token").Body.String())
+	assert.Equal(t, "limite de 1 req/1s", rejectionFor("192.0.2.125", "").Body.String(), "Clientes anônimos recebem a mensagem genérica")
+}
+
+// Test_RateLimit_UnknownBucket verifica que requisições sem token e sem IP dividem um contador com limite próprio
+func Test_RateLimit_UnknownBucket(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		ClassLimits: map[string]config.ClassLimit{
+			config.ClassUnknown: {MaxRequestsPerIP: 2},
+		},
+	})
+	middleware := RateLimit(rl, WithUnknownBucket(true))(okHandler)
+
+	codes := []int{}
+	for _, remoteAddr := range []string{"@", "/tmp/app.sock", "@"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	assert.True(t, mr.Exists("blocked_ip_unknown"))
+}
+
+// Test_RateLimit_UnknownBucket_Disabled verifica que, sem a política, a requisição sem IP continua com erro 500
+func Test_RateLimit_UnknownBucket_Disabled(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "@"
+	rec := httptest.NewRecorder()
+	RateLimit(rl)(okHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+// Test_RateLimit_BandwidthLimit verifica que respostas grandes esgotam a cota de bytes antes das pequenas
+// e que o bloqueio só vale a partir da requisição seguinte
+func Test_RateLimit_BandwidthLimit(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          100,
+		MaxRequestsPerToken:       100,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		MaxBytesPerWindow:         1000,
+		BandwidthWindowSeconds:    60,
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		_, _ = w.Write(make([]byte, size))
+	})
+	middleware := RateLimit(rl)(handler)
+
+	servedUntilRejected := func(ip string, size int) int {
+		served := 0
+		for i := 0; i < 50; i++ {
+			req := httptest.NewRequest("GET", "/?size="+strconv.Itoa(size), nil)
+			req.RemoteAddr = ip + ":12345"
+			rec := httptest.NewRecorder()
+			middleware.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+				return served
+			}
+			assert.Equal(t, size, rec.Body.Len(), "A resposta deveria ser entregue por inteiro")
+			served++
+		}
+		return served
+	}
+
+	// A resposta que ultrapassa a cota ainda é entregue; o bloqueio vale na próxima
+	assert.Equal(t, 3, servedUntilRejected("192.0.2.60", 400))
+	assert.Equal(t, 11, servedUntilRejected("192.0.2.61", 100))
+
+	blocked, err := mr.Get("blocked_ip_192.0.2.60")
+	require.NoError(t, err)
+	assert.Contains(t, blocked, db.ReasonBandwidthExceeded)
+}
+
+// Test_RateLimit_BandwidthLimit_Disabled verifica que, sem cota de bytes, o tamanho da resposta não é contabilizado
+func Test_RateLimit_BandwidthLimit_Disabled(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          100,
+		MaxRequestsPerToken:       100,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 4096))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.62:12345"
+	rec := httptest.NewRecorder()
+	RateLimit(rl)(handler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, mr.Exists("bytes_ip_192.0.2.62"))
+}
+
+// Test_RateLimit_Tarpit verifica que a resposta 429 é atrasada e que as permitidas não são
+func Test_RateLimit_Tarpit(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithTarpit(150*time.Millisecond))(okHandler)
+
+	send := func() (int, time.Duration) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.80:12345"
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code, time.Since(start)
+	}
+
+	code, elapsed := send()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Less(t, elapsed, 100*time.Millisecond, "Requisições permitidas não deveriam ser atrasadas")
+
+	code, elapsed = send()
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+// Test_RateLimit_Tarpit_ContextCanceled verifica que o cancelamento da requisição interrompe a espera
+func Test_RateLimit_Tarpit_ContextCanceled(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithTarpit(time.Hour))(okHandler)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.81:12345"
+	middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	middleware.ServeHTTP(rec, req.WithContext(ctx))
+
+	assert.Less(t, time.Since(start), time.Second, "O cancelamento deveria interromper o atraso")
+	assert.False(t, rec.Flushed)
+	assert.Empty(t, rec.Body.String(), "Nada deveria ser escrito para um cliente que desistiu")
+}
+
+// Test_WithTarpit_Bounded verifica o limite do atraso configurado
+func Test_WithTarpit_Bounded(t *testing.T) {
+	assert.Equal(t, maxTarpitDelay, newOptions([]Option{WithTarpit(time.Hour)}).tarpitDelay)
+	assert.Zero(t, newOptions([]Option{WithTarpit(-time.Second)}).tarpitDelay)
+}
+
+// Test_RateLimit_BlockStatusCode verifica que o status configurado substitui o 429 sem alterar
+// o corpo e os headers da rejeição
+func Test_RateLimit_BlockStatusCode(t *testing.T) {
+	send := func(status int) *httptest.ResponseRecorder {
+		_, rl := newTestLimiter(t, &config.LimiterConfig{
+			MaxRequestsPerIP:          1,
+			MaxRequestsPerToken:       10,
+			BlockDurationIPSeconds:    10,
+			BlockDurationTokenSeconds: 10,
+			TokenHeaderName:           "API_KEY",
+			BlockStatusCode:           status,
+		})
+		middleware := RateLimit(rl, WithRejectionHeader("X-Throttled", "1"))(okHandler)
+
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.0.2.95:12345"
+			rec = httptest.NewRecorder()
+			middleware.ServeHTTP(rec, req)
+		}
+		return rec
+	}
+
+	forbidden := send(http.StatusForbidden)
+	assert.Equal(t, http.StatusForbidden, forbidden.Code)
+
+	standard := send(0)
+	assert.Equal(t, http.StatusTooManyRequests, standard.Code, "Sem configuração, o status deveria ser 429")
+	assert.Equal(t, standard.Body.String(), forbidden.Body.String())
+	assert.Equal(t, "1", forbidden.Header().Get("X-Throttled"))
+	assert.Equal(t, standard.Header().Get("X-RateLimit-Reset"), forbidden.Header().Get("X-RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusTooManyRequests, send(http.StatusOK).Code, "Status fora das faixas 4xx e 5xx deveriam ser ignorados")
+}
+
+// Test_RateLimit_RejectionFlushed verifica que a resposta de limite excedido é enviada com flush
+func Test_RateLimit_RejectionFlushed(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl)(okHandler)
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.100:12345"
+		rec = httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.True(t, rec.Flushed, "A rejeição deveria ser enviada com flush")
+}
+
+// Test_RateLimit_HijackedConnection verifica que handlers que assumem a conexão continuam
+// funcionando, inclusive com a contagem de bytes ativa, e que as rejeições seguintes chegam ao cliente
+func Test_RateLimit_HijackedConnection(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		MaxBytesPerWindow:         1,
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "hijack não suportado", http.StatusInternalServerError)
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		_ = buf.Flush()
+	})
+	server := httptest.NewServer(RateLimit(rl)(handler))
+	defer server.Close()
+
+	get := func() (int, string) {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// Os bytes escritos na conexão assumida não contam para a cota de bytes
+	for i := 0; i < 2; i++ {
+		code, body := get()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "hijacked", body)
+	}
+	code, _ := get()
+	assert.Equal(t, http.StatusTooManyRequests, code)
+}
+
+// Test_RateLimit_StreamingFlush verifica que handlers de streaming continuam podendo fazer flush
+// com a contagem de bytes ativa
+func Test_RateLimit_StreamingFlush(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		MaxBytesPerWindow:         1000,
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("chunk"))
+		w.(http.Flusher).Flush()
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.101:12345"
+	rec := httptest.NewRecorder()
+	RateLimit(rl)(handler).ServeHTTP(rec, req)
+
+	assert.Equal(t, "chunk", rec.Body.String())
+	assert.True(t, rec.Flushed)
+}
+
+// testJWT monta um JWT sem assinatura válida com o claim sub informado
+func testJWT(sub string, issuedAt int64) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":%q,"iat":%d}`, sub, issuedAt)))
+	return header + "." + payload + ".assinatura"
+}
+
+// subFromJWT extrai o claim sub do payload do JWT (sem validar a assinatura)
+func subFromJWT(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Sub == "" {
+		return "", false
+	}
+	return "sub:" + claims.Sub, true
+}
+
+// Test_RateLimit_TokenKeyFunc verifica que JWTs diferentes do mesmo titular dividem o contador
+// e que tokens sem chave derivada usam o próprio token
+func Test_RateLimit_TokenKeyFunc(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          10,
+		MaxRequestsPerToken:       2,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+	})
+	middleware := RateLimit(rl, WithTokenKeyFunc(subFromJWT))(okHandler)
+
+	send := func(token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.110:12345"
+		req.Header.Set("API_KEY", token)
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Um token renovado do mesmo titular continua no mesmo contador
+	assert.Equal(t, http.StatusOK, send(testJWT("user-1", 1000)))
+	assert.Equal(t, http.StatusOK, send(testJWT("user-1", 2000)))
+	assert.Equal(t, http.StatusTooManyRequests, send(testJWT("user-1", 3000)))
+	assert.True(t, mr.Exists("blocked_token_sub:user-1"))
+
+	// Outro titular tem o próprio contador
+	assert.Equal(t, http.StatusOK, send(testJWT("user-2", 1000)))
+
+	// Tokens opacos usam o próprio valor
+	assert.Equal(t, http.StatusOK, send("opaque-token"))
+	assert.True(t, mr.Exists("token_opaque-token"))
+}
+
+// Test_RateLimit_GlobalLimit verifica que, com o teto global atingido, até identificadores novos
+// são rejeitados, com o status configurado
+func Test_RateLimit_GlobalLimit(t *testing.T) {
+	for _, status := range []int{0, http.StatusServiceUnavailable} {
+		_, rl := newTestLimiter(t, &config.LimiterConfig{
+			MaxRequestsPerIP:          10,
+			MaxRequestsPerToken:       10,
+			BlockDurationIPSeconds:    10,
+			BlockDurationTokenSeconds: 10,
+			TokenHeaderName:           "API_KEY",
+			GlobalLimit:               3,
+			GlobalWindowSeconds:       60,
+			GlobalLimitStatus:         status,
+		})
+		middleware := RateLimit(rl, WithTarpit(time.Hour))(okHandler)
+
+		send := func(ip, token string) int {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = ip + ":12345"
+			if token != "" {
+				req.Header.Set("API_KEY", token)
+			}
+			rec := httptest.NewRecorder()
+			middleware.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		for i := 1; i <= 3; i++ {
+			assert.Equal(t, http.StatusOK, send(fmt.Sprintf("192.0.2.%d", 120+i), ""))
+		}
+
+		expected := http.StatusTooManyRequests
+		if status != 0 {
+			expected = status
+		}
+		start := time.Now()
+		assert.Equal(t, expected, send("192.0.2.130", ""), "Um IP novo deveria ser rejeitado pelo teto global")
+		assert.Equal(t, expected, send("192.0.2.131", "token-novo"), "Um token novo deveria ser rejeitado pelo teto global")
+		assert.Less(t, time.Since(start), time.Second, "O teto global não deveria aplicar tarpit")
+	}
+}
+
+// Test_RateLimit_GlobalScopeHeader verifica que a resposta informa qual limite rejeitou a requisição
+func Test_RateLimit_GlobalScopeHeader(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          1,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    10,
+		BlockDurationTokenSeconds: 10,
+		TokenHeaderName:           "API_KEY",
+		GlobalLimit:               2,
+		GlobalWindowSeconds:       60,
+		GlobalLimitStatus:         http.StatusServiceUnavailable,
+	})
+	middleware := RateLimit(rl)(okHandler)
+
+	send := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":12345"
+		rec := httptest.NewRecorder()
+		middleware.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, send("192.0.2.140").Code)
+	rec := send("192.0.2.140")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "identifier", rec.Header().Get("X-RateLimit-Scope"))
+
+	rec = send("192.0.2.141")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "global", rec.Header().Get("X-RateLimit-Scope"))
+
+	rec = send("192.0.2.140")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "global,identifier", rec.Header().Get("X-RateLimit-Scope"))
+}
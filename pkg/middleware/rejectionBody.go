@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// defaultRejectionBody é a mensagem enviada nas respostas 429 quando nenhum modelo é configurado.
+const defaultRejectionBody = "you have reached the maximum number of requests or actions allowed within a certain time frame"
+
+// RejectMessageFunc escolhe a mensagem da rejeição pelo identificador (ex.: direcionar clientes
+// premium ao suporte). identifier é o identificador contado (o token ou o IP, com a classe e o
+// parâmetro do caminho, quando configurados). O retorno aceita os mesmos marcadores de
+// WithRejectionBody; vazio usa o modelo configurado.
+type RejectMessageFunc func(d *rateLimiter.Decision, isToken bool, identifier string) string
+
+// WithRejectMessage define a função que escolhe a mensagem de cada rejeição pelo limite. Vale
+// nos formatos texto e problem+json (no detail).
+func WithRejectMessage(fn RejectMessageFunc) Option {
+	return func(o *options) {
+		o.rejectMessage = fn
+	}
+}
+
+// rejectionTemplate retorna o modelo da mensagem para a decisão: o de RejectMessageFunc, se
+// houver e não for vazio, ou o configurado.
+func (o *options) rejectionTemplate(decision *rateLimiter.Decision) string {
+	if o.rejectMessage != nil {
+		if template := o.rejectMessage(decision, decision.IsToken, decision.Identifier); template != "" {
+			return template
+		}
+	}
+	return o.rejectionBody
+}
+
+// renderRejectionBody substitui os marcadores do modelo pelos valores da decisão.
+func renderRejectionBody(template string, decision *rateLimiter.Decision) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	return strings.NewReplacer(
+		"{limit}", strconv.Itoa(decision.Limit),
+		"{remaining}", strconv.Itoa(decision.Remaining),
+		"{window}", formatSeconds(decision.Window),
+		"{retry_after}", formatSeconds(decision.RetryAfter),
+	).Replace(template)
+}
+
+// problemDetails é o corpo das respostas rejeitadas no formato da RFC 7807.
+type problemDetails struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail"`
+	RetryAfter int    `json:"retryAfter"`
+}
+
+// renderProblemDetails monta o documento application/problem+json da rejeição, com o modelo
+// renderizado como detail.
+func renderProblemDetails(template string, status int, decision *rateLimiter.Decision) []byte {
+	body, _ := json.Marshal(problemDetails{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     renderRejectionBody(template, decision),
+		RetryAfter: ceilSeconds(decision.RetryAfter),
+	})
+	return body
+}
+
+// formatSeconds formata a duração em segundos inteiros, arredondando para cima (ex.: "30s").
+func formatSeconds(d time.Duration) string {
+	return strconv.Itoa(ceilSeconds(d)) + "s"
+}
+
+// ceilSeconds retorna a duração em segundos inteiros, arredondando para cima.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
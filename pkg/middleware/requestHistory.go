@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// maxHistoryPathLength limita o caminho guardado em cada registro do histórico, para que o
+// consumo de memória não dependa do tamanho das URLs enviadas pelos clientes.
+const maxHistoryPathLength = 256
+
+// HistoryEntry é uma requisição registrada no histórico de um identificador.
+type HistoryEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Allowed   bool      `json:"allowed"`
+}
+
+// historyKey identifica o histórico de um identificador no seu escopo.
+type historyKey struct {
+	identifier string
+	isToken    bool
+}
+
+// historyRing é o buffer circular com as últimas requisições de um identificador.
+type historyRing struct {
+	key     historyKey
+	entries []HistoryEntry
+	next    int
+}
+
+// RequestHistory guarda em memória, para diagnóstico, as últimas requisições de cada
+// identificador. Não participa da decisão do rate limit. A memória é limitada: cada
+// identificador guarda no máximo size requisições, e só os maxIdentifiers usados mais
+// recentemente são mantidos.
+type RequestHistory struct {
+	size           int
+	maxIdentifiers int
+
+	mu      sync.Mutex
+	entries map[historyKey]*list.Element
+	lru     *list.List
+}
+
+// NewRequestHistory cria um histórico com size requisições por identificador e no máximo
+// maxIdentifiers identificadores (valores menores que 1 usam 1).
+func NewRequestHistory(size, maxIdentifiers int) *RequestHistory {
+	return &RequestHistory{
+		size:           max(size, 1),
+		maxIdentifiers: max(maxIdentifiers, 1),
+		entries:        make(map[historyKey]*list.Element),
+		lru:            list.New(),
+	}
+}
+
+// Record registra a requisição no histórico do identificador, sobrescrevendo a mais antiga
+// quando o buffer está cheio e descartando o identificador usado há mais tempo quando há
+// identificadores demais.
+func (h *RequestHistory) Record(identifier string, isToken bool, entry HistoryEntry) {
+	if len(entry.Path) > maxHistoryPathLength {
+		entry.Path = entry.Path[:maxHistoryPathLength]
+	}
+	key := historyKey{identifier: identifier, isToken: isToken}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	elem, ok := h.entries[key]
+	if ok {
+		h.lru.MoveToFront(elem)
+	} else {
+		if h.lru.Len() >= h.maxIdentifiers {
+			oldest := h.lru.Back()
+			delete(h.entries, oldest.Value.(*historyRing).key)
+			h.lru.Remove(oldest)
+		}
+		elem = h.lru.PushFront(&historyRing{key: key, entries: make([]HistoryEntry, 0, h.size)})
+		h.entries[key] = elem
+	}
+
+	ring := elem.Value.(*historyRing)
+	if len(ring.entries) < h.size {
+		ring.entries = append(ring.entries, entry)
+		return
+	}
+	ring.entries[ring.next] = entry
+	ring.next = (ring.next + 1) % h.size
+}
+
+// Entries retorna as requisições guardadas do identificador, da mais antiga para a mais recente.
+func (h *RequestHistory) Entries(identifier string, isToken bool) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	elem, ok := h.entries[historyKey{identifier: identifier, isToken: isToken}]
+	if !ok {
+		return []HistoryEntry{}
+	}
+	ring := elem.Value.(*historyRing)
+	entries := make([]HistoryEntry, 0, len(ring.entries))
+	entries = append(entries, ring.entries[ring.next:]...)
+	return append(entries, ring.entries[:ring.next]...)
+}
+
+// WithRequestHistory registra cada decisão do middleware no histórico informado, com o
+// identificador efetivo do contador (o mesmo de Decision.Identifier).
+func WithRequestHistory(h *RequestHistory) Option {
+	return func(o *options) {
+		o.history = h
+	}
+}
+
+// recordHistory registra a decisão no histórico, se houver um.
+func (o *options) recordHistory(r *http.Request, decision *rateLimiter.Decision) {
+	if o.history == nil {
+		return
+	}
+	o.history.Record(decision.Identifier, decision.IsToken, HistoryEntry{
+		Timestamp: o.clock.Now(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Allowed:   decision.Allowed,
+	})
+}
+
+// RequestHistoryHandler expõe em JSON o histórico de um identificador, informado em
+// ?identifier=, no escopo de ?scope= (ScopeIP, o padrão, ou ScopeToken). Exige o mesmo
+// "Authorization: Bearer <secret>" de ConfigHandler; com secret vazio, responde 404.
+func RequestHistoryHandler(h *RequestHistory, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r, secret) {
+			return
+		}
+		identifier := r.URL.Query().Get("identifier")
+		scope := r.URL.Query().Get("scope")
+		if scope == "" {
+			scope = ScopeIP
+		}
+		if identifier == "" || (scope != ScopeIP && scope != ScopeToken) {
+			http.Error(w, "Informe identifier e, opcionalmente, scope (ip ou token)", http.StatusBadRequest)
+			return
+		}
+
+		body, err := json.MarshalIndent(h.Entries(identifier, scope == ScopeToken), "", "  ")
+		if err != nil {
+			http.Error(w, "Erro ao serializar o histórico", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(append(body, '\n'))
+	})
+}
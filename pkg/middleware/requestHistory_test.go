@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_RequestHistory_Ring verifica que cada identificador guarda só as últimas requisições,
+// da mais antiga para a mais recente
+func Test_RequestHistory_Ring(t *testing.T) {
+	h := NewRequestHistory(3, 10)
+	start := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		h.Record("192.0.2.1", false, HistoryEntry{Timestamp: start.Add(time.Duration(i) * time.Second), Path: fmt.Sprintf("/%d", i)})
+	}
+
+	entries := h.Entries("192.0.2.1", false)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"/2", "/3", "/4"}, []string{entries[0].Path, entries[1].Path, entries[2].Path})
+	assert.Equal(t, start.Add(4*time.Second), entries[2].Timestamp)
+	assert.Empty(t, h.Entries("192.0.2.1", true), "O escopo faz parte da chave")
+	assert.NotNil(t, h.Entries("198.51.100.1", false))
+
+	// O caminho guardado é truncado
+	h.Record("long", true, HistoryEntry{Path: "/" + strings.Repeat("a", 2*maxHistoryPathLength)})
+	assert.Len(t, h.Entries("long", true)[0].Path, maxHistoryPathLength)
+}
+
+// Test_RequestHistory_EvictsIdentifiers verifica que, além do limite de identificadores, o
+// usado há mais tempo é descartado
+func Test_RequestHistory_EvictsIdentifiers(t *testing.T) {
+	h := NewRequestHistory(2, 2)
+	h.Record("a", false, HistoryEntry{Path: "/a"})
+	h.Record("b", false, HistoryEntry{Path: "/b"})
+	h.Record("a", false, HistoryEntry{Path: "/a2"})
+	h.Record("c", false, HistoryEntry{Path: "/c"})
+
+	assert.Len(t, h.Entries("a", false), 2)
+	assert.Empty(t, h.Entries("b", false))
+	assert.Len(t, h.Entries("c", false), 1)
+	assert.Equal(t, 2, h.lru.Len())
+}
+
+// Test_RateLimit_RequestHistory verifica que o middleware registra as decisões e que o
+// endpoint expõe o histórico do identificador
+func Test_RateLimit_RequestHistory(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:          2,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+	})
+	history := NewRequestHistory(2, 10)
+	handler := RateLimit(rl, WithRequestHistory(history))(okHandler)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/d", nil)
+	req.Header.Set("API_KEY", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	historyHandler := RequestHistoryHandler(history, "s3cr3t")
+	get := func(query, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/debug/history?"+query, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rec := httptest.NewRecorder()
+		historyHandler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("identifier=192.0.2.1", "s3cr3t")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var entries []HistoryEntry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/b", entries[0].Path)
+	assert.True(t, entries[0].Allowed)
+	assert.Equal(t, "/c", entries[1].Path)
+	assert.False(t, entries[1].Allowed)
+
+	rec = get("identifier=abc&scope=token", "s3cr3t")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.MethodPost, entries[0].Method)
+
+	assert.Equal(t, "[]\n", get("identifier=unknown", "s3cr3t").Body.String())
+	assert.Equal(t, http.StatusBadRequest, get("", "s3cr3t").Code)
+	assert.Equal(t, http.StatusBadRequest, get("identifier=abc&scope=user", "s3cr3t").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("identifier=abc", "wrong").Code)
+
+	// Sem segredo, o endpoint fica desligado
+	rec = httptest.NewRecorder()
+	RequestHistoryHandler(history, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/history?identifier=abc", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// countingWriter conta os bytes do corpo escritos na resposta.
+type countingWriter struct {
+	http.ResponseWriter
+	written  int64
+	hijacked bool
+}
+
+// Write repassa a escrita e acumula o tamanho escrito.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// Flush repassa o flush, para que handlers de streaming continuem funcionando com a contagem.
+func (c *countingWriter) Flush() {
+	_ = http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Hijack repassa o hijack e registra que a conexão deixou de ser controlada pelo servidor HTTP.
+func (c *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(c.ResponseWriter).Hijack()
+	if err == nil {
+		c.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap expõe o ResponseWriter original para o http.ResponseController.
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
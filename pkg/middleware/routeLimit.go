@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// routeLimitPrefix antecede o identificador no contador de um sub-limite de rota.
+const routeLimitPrefix = "route:"
+
+// routeLimit retorna o nome do sub-limite cujo prefixo mais longo casa com o caminho (já
+// normalizado), ou "" se nenhum casar. O prefixo casa por segmentos: /api/reports casa com
+// /api/reports/2024, mas não com /api/reportsx.
+func (o *options) routeLimit(r *http.Request, cfg *config.LimiterConfig) string {
+	if len(cfg.RouteLimits) == 0 {
+		return ""
+	}
+	path := o.routeRequest(r).URL.Path
+	name, longest := "", -1
+	for _, route := range cfg.RouteLimits {
+		if len(route.PathPrefix) > longest && matchesPathPrefix(path, route.PathPrefix) {
+			name, longest = route.Name, len(route.PathPrefix)
+		}
+	}
+	return name
+}
+
+// matchesPathPrefix informa se o caminho está sob o prefixo, respeitando os segmentos.
+func matchesPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_RateLimit_RouteLimits verifica que um token dentro da sua cota geral é bloqueado na rota
+// cara ao esgotar o sub-limite dela, sem perder o acesso às demais rotas.
+func Test_RateLimit_RouteLimits(t *testing.T) {
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:          100,
+		MaxRequestsPerToken:       100,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+		RouteLimits: []config.RouteLimit{
+			{Name: "reports", PathPrefix: "/api/reports", MaxRequests: 2, BlockDurationSeconds: 600},
+			{Name: "yearly", PathPrefix: "/api/reports/yearly", MaxRequests: 1},
+		},
+	}
+	mr, rl := newTestLimiter(t, cfg)
+	handler := RateLimit(rl)(okHandler)
+	request := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := request("/api/reports/2024", "enterprise")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	}
+	rec := request("/api/reports", "enterprise")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.True(t, mr.Exists("blocked_token_route:reports|enterprise"))
+	assert.InDelta(t, 600, mr.TTL("blocked_token_route:reports|enterprise").Seconds(), 1)
+
+	// A cota geral do token segue disponível nas outras rotas, e ela também contou as
+	// requisições à rota cara
+	assert.False(t, mr.Exists("blocked_token_enterprise"))
+	rec = request("/api/orders", "enterprise")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "100", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "96", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, request("/api/reportsx", "enterprise").Code)
+
+	// O prefixo mais longo tem o próprio contador, e outro token tem a própria cota na rota
+	assert.Equal(t, http.StatusOK, request("/api/reports/yearly", "other").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("/api/reports/yearly", "other").Code)
+	assert.Equal(t, http.StatusOK, request("/api/reports/2024", "other").Code)
+
+	// Sem token, o sub-limite vale para o IP
+	assert.Equal(t, http.StatusOK, request("/api/reports", "").Code)
+	assert.Equal(t, http.StatusOK, request("/api/reports", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("/api/reports", "").Code)
+}
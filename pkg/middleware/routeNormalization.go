@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RouteNormalization define como o caminho é normalizado antes de casar com as regras por rota
+// (o PathParamFunc de WithPathParam e os sub-limites por rota da configuração).
+type RouteNormalization struct {
+	// CaseInsensitive compara o caminho em minúsculas: /Login e /login casam com a mesma regra.
+	// Os parâmetros extraídos do caminho também ficam em minúsculas.
+	CaseInsensitive bool
+	// IgnoreTrailingSlash remove as barras finais: /login/ e /login casam com a mesma regra.
+	IgnoreTrailingSlash bool
+}
+
+// WithRouteNormalization normaliza o caminho da requisição antes de casá-lo com as regras por
+// rota. O caminho repassado ao handler não muda. PathValueParam lê os parâmetros já casados
+// pelo http.ServeMux e não é afetado.
+func WithRouteNormalization(n RouteNormalization) Option {
+	return func(o *options) {
+		o.routeNormalization = n
+	}
+}
+
+// normalize aplica a normalização ao caminho. A raiz "/" é mantida.
+func (n RouteNormalization) normalize(path string) string {
+	if n.CaseInsensitive {
+		path = strings.ToLower(path)
+	}
+	if n.IgnoreTrailingSlash && len(path) > 1 {
+		if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+			path = trimmed
+		} else {
+			path = "/"
+		}
+	}
+	return path
+}
+
+// routeRequest retorna a requisição com o caminho normalizado, para casar com as regras por
+// rota. Sem normalização, retorna a própria requisição.
+func (o *options) routeRequest(r *http.Request) *http.Request {
+	if o.routeNormalization == (RouteNormalization{}) {
+		return r
+	}
+	path := o.routeNormalization.normalize(r.URL.Path)
+	if path == r.URL.Path {
+		return r
+	}
+	u := *r.URL
+	u.Path, u.RawPath = path, ""
+	normalized := r.WithContext(r.Context())
+	normalized.URL = &u
+	return normalized
+}
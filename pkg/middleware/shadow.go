@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"log"
+
+	"rateLimiter/internal/rateLimiter"
+	"rateLimiter/pkg/metrics"
+)
+
+// shadowDecisionsCounter conta as decisões do limitador sombra, pelo resultado dele e pelo real.
+const shadowDecisionsCounter = "ratelimiter_shadow_decisions_total"
+
+// shadowLimiter é um limitador avaliado em paralelo ao real, só para medição.
+type shadowLimiter struct {
+	limiter  rateLimiter.RateLimiterInterface
+	recorder metrics.Recorder
+}
+
+// WithShadowLimiter avalia cada requisição também no limitador informado, com a configuração
+// candidata, sem afetar a resposta: a decisão dele é só contada em
+// ratelimiter_shadow_decisions_total, com os rótulos shadow e actual (allowed ou rejected),
+// para comparar as taxas de bloqueio antes de trocar os limites. O limitador sombra recebe os
+// mesmos identificadores que o real e deve usar um KeyPrefix próprio, para não dividir os
+// contadores. A avaliação acontece no caminho da requisição, somando a latência do seu store;
+// requisições rejeitadas pelo teto global ou com cota justa não são avaliadas.
+func WithShadowLimiter(limiter rateLimiter.RateLimiterInterface, recorder metrics.Recorder) Option {
+	return func(o *options) {
+		if limiter == nil {
+			o.shadow = nil
+			return
+		}
+		if recorder == nil {
+			recorder = metrics.Noop{}
+		}
+		o.shadow = &shadowLimiter{limiter: limiter, recorder: recorder}
+	}
+}
+
+// evaluate consulta o limitador sombra para os identificadores da requisição e registra a
+// decisão mais restritiva ao lado da real. Erros do limitador sombra são só registrados em log.
+func (s *shadowLimiter) evaluate(ctx context.Context, buckets []string, isToken bool, actual *rateLimiter.Decision) {
+	allowed := true
+	for _, bucket := range buckets {
+		decision, err := s.limiter.AllowDecision(ctx, bucket, isToken)
+		if err != nil {
+			log.Printf("Erro no limitador sombra para %s (token: %t): %v", bucket, isToken, err)
+			return
+		}
+		if !decision.Allowed {
+			allowed = false
+			break
+		}
+	}
+	s.recorder.IncCounter(shadowDecisionsCounter, metrics.Labels{
+		"shadow": outcomeLabel(allowed),
+		"actual": outcomeLabel(actual.Allowed),
+	})
+}
+
+// outcomeLabel retorna o rótulo do resultado de uma decisão.
+func outcomeLabel(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "rejected"
+}
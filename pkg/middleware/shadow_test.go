@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/rateLimiter"
+	"rateLimiter/pkg/metrics"
+)
+
+// Test_RateLimit_ShadowLimiter verifica que as decisões do limitador sombra são registradas,
+// mas nunca mudam a resposta
+func Test_RateLimit_ShadowLimiter(t *testing.T) {
+	mr, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       3,
+		BlockDurationIPSeconds: 60,
+		TokenHeaderName:        "API_KEY",
+	})
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	// Configuração candidata, mais restritiva, com prefixo próprio no mesmo Redis
+	shadow := rateLimiter.NewRateLimiter(&config.LimiterConfig{
+		MaxRequestsPerIP:       1,
+		BlockDurationIPSeconds: 60,
+		TokenHeaderName:        "API_KEY",
+		KeyPrefix:              "shadow:",
+	}, redisStore.NewRedisStore(client))
+
+	registry := metrics.NewRegistry()
+	handler := RateLimit(rl, WithShadowLimiter(shadow, registry))(okHandler)
+
+	codes := make([]int, 0, 4)
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+		assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"), "Os headers vêm do limitador real")
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes,
+		"As respostas seguem só o limitador real")
+	counter := func(shadow, actual string) float64 {
+		return registry.Counter(shadowDecisionsCounter, metrics.Labels{"shadow": shadow, "actual": actual})
+	}
+	assert.Equal(t, float64(1), counter("allowed", "allowed"))
+	assert.Equal(t, float64(2), counter("rejected", "allowed"), "O limitador sombra teria rejeitado a 2ª e a 3ª")
+	assert.Equal(t, float64(1), counter("rejected", "rejected"))
+	assert.Equal(t, float64(0), counter("allowed", "rejected"))
+	assert.True(t, mr.Exists("shadow:blocked_ip_192.0.2.1"))
+	assert.True(t, mr.Exists("blocked_ip_192.0.2.1"))
+}
+
+// Test_RateLimit_ShadowLimiter_Error verifica que a falha do limitador sombra não afeta a resposta
+func Test_RateLimit_ShadowLimiter_Error(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:       3,
+		BlockDurationIPSeconds: 60,
+		TokenHeaderName:        "API_KEY",
+	})
+	broken, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: broken.Addr()})
+	t.Cleanup(func() { client.Close() })
+	broken.Close()
+	shadow := rateLimiter.NewRateLimiter(&config.LimiterConfig{MaxRequestsPerIP: 1}, redisStore.NewRedisStore(client))
+
+	registry := metrics.NewRegistry()
+	handler := RateLimit(rl, WithShadowLimiter(shadow, registry))(okHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:12345"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, float64(0), registry.Counter(shadowDecisionsCounter, metrics.Labels{"shadow": "allowed", "actual": "allowed"}))
+}
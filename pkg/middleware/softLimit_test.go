@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+	redisStore "rateLimiter/infra/db/redis"
+	"rateLimiter/internal/rateLimiter"
+	"rateLimiter/pkg/metrics"
+)
+
+// Test_RateLimit_SoftLimit verifica que o limite de alerta só conta na métrica e que o bloqueio vem do limite normal
+func Test_RateLimit_SoftLimit(t *testing.T) {
+	mr, _ := newTestLimiter(t, &config.LimiterConfig{})
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	registry := metrics.NewRegistry()
+	rl := rateLimiter.NewRateLimiter(&config.LimiterConfig{
+		MaxRequestsPerIP:          4,
+		MaxRequestsPerToken:       10,
+		BlockDurationIPSeconds:    60,
+		BlockDurationTokenSeconds: 60,
+		TokenHeaderName:           "API_KEY",
+		SoftLimitPerIP:            2,
+		SoftLimitPerToken:         5,
+	}, redisStore.NewRedisStore(client), rateLimiter.WithMetrics(registry))
+
+	var decisions []*rateLimiter.Decision
+	handler := RateLimit(rl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decisions = append(decisions, r.Context().Value(DecisionContextKey).(*rateLimiter.Decision))
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.10:12345"
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	ipLabels := metrics.Labels{"scope": "ip"}
+
+	// Até o limite de alerta, nada é registrado
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, request(""))
+		assert.False(t, decisions[i].SoftLimitExceeded)
+	}
+	assert.Zero(t, registry.Counter("ratelimiter_soft_limit_exceeded_total", ipLabels))
+
+	// Acima do alerta e até o limite: 200, contado na métrica
+	for i := 2; i < 4; i++ {
+		require.Equal(t, http.StatusOK, request(""))
+		assert.True(t, decisions[i].SoftLimitExceeded)
+	}
+	assert.Equal(t, float64(2), registry.Counter("ratelimiter_soft_limit_exceeded_total", ipLabels))
+
+	// Acima do limite: 429 e bloqueio, sem contar como alerta
+	assert.Equal(t, http.StatusTooManyRequests, request(""))
+	assert.True(t, mr.Exists("blocked_ip_192.0.2.10"))
+	assert.Equal(t, float64(2), registry.Counter("ratelimiter_soft_limit_exceeded_total", ipLabels))
+
+	// Os tokens têm o próprio limite de alerta
+	for i := 0; i < 6; i++ {
+		require.Equal(t, http.StatusOK, request("tok"))
+	}
+	assert.Equal(t, float64(1), registry.Counter("ratelimiter_soft_limit_exceeded_total", metrics.Labels{"scope": "token"}))
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"rateLimiter/internal/rateLimiter"
+)
+
+// ErrUploadBudgetExceeded é retornado pela leitura do corpo quando o cliente excede a cota de
+// bytes enviados. O handler pode respondê-lo com 429.
+var ErrUploadBudgetExceeded = errors.New("cota de bytes enviados excedida")
+
+// uploadBudgetBody soma à cota de upload dos identificadores os bytes lidos do corpo.
+type uploadBudgetBody struct {
+	io.ReadCloser
+	ctx         context.Context
+	limiter     rateLimiter.UploadLimiter
+	identifiers []string
+	isToken     bool
+	exceeded    bool
+}
+
+// LimitUpload envolve o corpo de uma requisição para que os bytes lidos sejam somados, a cada
+// leitura, à cota de upload dos identificadores (rateLimiter.UploadLimiter). Ao exceder a cota,
+// a leitura entrega só os bytes que ainda cabiam e retorna ErrUploadBudgetExceeded, assim como
+// as leituras seguintes. Cada leitura é uma operação no store, então leia com buffers de alguns
+// KiB (como io.Copy). Um erro do store é registrado em log e não interrompe o upload.
+func LimitUpload(ctx context.Context, body io.ReadCloser, limiter rateLimiter.UploadLimiter, identifiers []string, isToken bool) io.ReadCloser {
+	return &uploadBudgetBody{ReadCloser: body, ctx: ctx, limiter: limiter, identifiers: identifiers, isToken: isToken}
+}
+
+// Read lê do corpo original e contabiliza os bytes lidos.
+func (b *uploadBudgetBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrUploadBudgetExceeded
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	fit := int64(n)
+	for _, identifier := range b.identifiers {
+		remaining, recordErr := b.limiter.RecordUploadBytes(b.ctx, identifier, b.isToken, int64(n))
+		if recordErr != nil {
+			log.Printf("Erro ao contabilizar os bytes enviados por %s: %v", identifier, recordErr)
+			continue
+		}
+		if remaining < 0 {
+			// Só os bytes que ainda cabiam na cota são entregues
+			b.exceeded = true
+			fit = min(fit, max(int64(n)+remaining, 0))
+		}
+	}
+	if b.exceeded {
+		return int(fit), ErrUploadBudgetExceeded
+	}
+	return n, err
+}
+
+// limitUpload envolve o corpo da requisição com LimitUpload quando a cota de upload está
+// configurada.
+func (o *options) limitUpload(r *http.Request, rl rateLimiter.RateLimiterInterface, buckets []string, isToken bool) *http.Request {
+	ul, ok := rl.(rateLimiter.UploadLimiter)
+	if !ok || rl.GetConfig().MaxUploadBytesPerWindow <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return r
+	}
+	limited := r.WithContext(r.Context())
+	limited.Body = LimitUpload(r.Context(), r.Body, ul, buckets, isToken)
+	return limited
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rateLimiter/cmd/server/config"
+)
+
+// Test_LimitUpload verifica que um corpo grande é cortado exatamente na cota
+func Test_LimitUpload(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:        10,
+		BlockDurationIPSeconds:  10,
+		MaxUploadBytesPerWindow: 100_000,
+	})
+
+	body := io.NopCloser(bytes.NewReader(make([]byte, 1<<20)))
+	limited := LimitUpload(context.Background(), body, rl, []string{"192.0.2.80"}, false)
+	copied, err := io.Copy(io.Discard, limited)
+	require.ErrorIs(t, err, ErrUploadBudgetExceeded)
+	assert.Equal(t, int64(100_000), copied)
+
+	// As leituras seguintes continuam falhando, e um novo upload na janela já começa sem cota
+	n, err := limited.Read(make([]byte, 10))
+	assert.Zero(t, n)
+	require.ErrorIs(t, err, ErrUploadBudgetExceeded)
+
+	again := LimitUpload(context.Background(), io.NopCloser(bytes.NewReader(make([]byte, 10))), rl, []string{"192.0.2.80"}, false)
+	copied, err = io.Copy(io.Discard, again)
+	require.ErrorIs(t, err, ErrUploadBudgetExceeded)
+	assert.Zero(t, copied)
+
+	// Um corpo dentro da cota é lido até o fim
+	small := LimitUpload(context.Background(), io.NopCloser(bytes.NewReader(make([]byte, 5000))), rl, []string{"192.0.2.81"}, false)
+	copied, err = io.Copy(io.Discard, small)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), copied)
+}
+
+// Test_RateLimit_UploadBudget verifica que o middleware contabiliza o corpo lido pelo handler, que
+// responde 429 ao exceder a cota
+func Test_RateLimit_UploadBudget(t *testing.T) {
+	_, rl := newTestLimiter(t, &config.LimiterConfig{
+		MaxRequestsPerIP:        10,
+		BlockDurationIPSeconds:  10,
+		MaxUploadBytesPerWindow: 64 * 1024,
+	})
+	var received int64
+	upload := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := io.Copy(io.Discard, r.Body)
+		received = n
+		if errors.Is(err, ErrUploadBudgetExceeded) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RateLimit(rl)(upload)
+	send := func(size int) int {
+		req := httptest.NewRequest("POST", "/upload", bytes.NewReader(make([]byte, size)))
+		req.RemoteAddr = "192.0.2.82:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(40*1024))
+	assert.Equal(t, int64(40*1024), received)
+	assert.Equal(t, http.StatusTooManyRequests, send(1<<20))
+	assert.Equal(t, int64(24*1024), received, "O upload deveria parar no restante da cota")
+}
@@ -272,3 +272,117 @@ func Test_Concurrent_Requests(t *testing.T) {
 	// Verificar que todas as requisições foram processadas
 	assert.Equal(t, totalRequests, okCount+tooManyCount, "Todas as requisições devem retornar 200 ou 429")
 }
+
+// Test_Stacked_Limiters_With_KeyPrefix monta dois rate limiters com prefixos diferentes no mesmo Redis,
+// um rígido em /api/admin e outro tolerante em /api/public, e confirma que cada um aplica o próprio limite
+func Test_Stacked_Limiters_With_KeyPrefix(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := redisStore.NewRedisStore(client)
+
+	newLimiter := func(prefix string, maxIP int) *rateLimiter.RateLimiter {
+		return rateLimiter.NewRateLimiter(&config.LimiterConfig{
+			MaxRequestsPerIP:          maxIP,
+			MaxRequestsPerToken:       maxIP,
+			BlockDurationIPSeconds:    10,
+			BlockDurationTokenSeconds: 10,
+			TokenHeaderName:           "API_KEY",
+			KeyPrefix:                 prefix,
+		}, store)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/admin/", middleware.RateLimit(newLimiter("admin:", 2))(ok))
+	mux.Handle("/api/public/", middleware.RateLimit(newLimiter("public:", 5))(ok))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(path string) int {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		_, _ = io.ReadAll(resp.Body)
+		return resp.StatusCode
+	}
+
+	// O limite rígido do admin se esgota na terceira requisição
+	assert.Equal(t, http.StatusOK, get("/api/admin/users"))
+	assert.Equal(t, http.StatusOK, get("/api/admin/users"))
+	assert.Equal(t, http.StatusTooManyRequests, get("/api/admin/users"))
+
+	// O mesmo IP continua com a cota inteira no público
+	for i := 1; i <= 5; i++ {
+		assert.Equal(t, http.StatusOK, get("/api/public/items"), "Requisição pública %d deveria ser permitida", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, get("/api/public/items"))
+
+	assert.True(t, mr.Exists("admin:blocked_ip_127.0.0.1"))
+	assert.True(t, mr.Exists("public:blocked_ip_127.0.0.1"))
+	assert.False(t, mr.Exists("blocked_ip_127.0.0.1"), "Nenhuma chave deveria ficar sem prefixo")
+}
+
+// Test_Concurrent_Requests_StrictLimits verifica que, com StrictLimits, a janela deslizante admite
+// exatamente o limite configurado sob alta concorrência
+func Test_Concurrent_Requests_StrictLimits(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	// Horário do Redis parado: todas as requisições caem no mesmo bucket
+	mr.SetTime(time.UnixMilli(6_000_000_000))
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	maxRequests := 10
+	cfg := &config.LimiterConfig{
+		MaxRequestsPerIP:       maxRequests,
+		BlockDurationIPSeconds: 5,
+		TokenHeaderName:        "API_KEY",
+		Algorithm:              config.AlgorithmSlidingWindow,
+		StrictLimits:           true,
+	}
+	rl := rateLimiter.NewRateLimiter(cfg, redisStore.NewRedisStore(client, redisStore.WithServerTime(true)))
+	server := httptest.NewServer(middleware.RateLimit(rl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer server.Close()
+
+	totalRequests := 100
+	var wg sync.WaitGroup
+	responses := make([]int, totalRequests)
+	for i := 0; i < totalRequests; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Logf("Erro ao fazer requisição: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			responses[idx] = resp.StatusCode
+			_, _ = io.ReadAll(resp.Body)
+		}(i)
+	}
+	wg.Wait()
+
+	okCount, tooManyCount := 0, 0
+	for _, status := range responses {
+		switch status {
+		case http.StatusOK:
+			okCount++
+		case http.StatusTooManyRequests:
+			tooManyCount++
+		}
+	}
+	assert.Equal(t, maxRequests, okCount, "O modo estrito deveria admitir exatamente o limite")
+	assert.Equal(t, totalRequests-maxRequests, tooManyCount)
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
@@ -23,6 +24,10 @@ type benchmarkOptions struct {
 	tokenValue    string
 	tokenHeader   string
 	printProgress bool
+	// expectBlockRate é a porcentagem de requisições bloqueadas esperada (negativa desliga a
+	// verificação) e blockRateTolerance a diferença aceita, em pontos percentuais.
+	expectBlockRate    float64
+	blockRateTolerance float64
 }
 
 // Resultados do benchmark
@@ -78,6 +83,34 @@ func main() {
 
 	// Imprimir resultados
 	printResults(results)
+
+	// No modo de verificação, a taxa de bloqueio fora do esperado encerra com erro (ex.: na CI)
+	if opts.expectBlockRate >= 0 {
+		if err := checkBlockRate(results, opts.expectBlockRate, opts.blockRateTolerance); err != nil {
+			fmt.Printf("\nFALHA: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nOK: taxa de bloqueio dentro de %.1f%% ± %.1f pontos percentuais\n", opts.expectBlockRate, opts.blockRateTolerance)
+	}
+}
+
+// blockRate retorna a porcentagem das requisições bloqueadas pelo rate limiter.
+func blockRate(results *benchmarkResults) float64 {
+	if results.totalRequests == 0 {
+		return 0
+	}
+	return float64(results.ratelimitedReqs) * 100 / float64(results.totalRequests)
+}
+
+// checkBlockRate compara a taxa de bloqueio observada com a esperada, aceitando uma diferença de
+// até tolerance pontos percentuais para mais ou para menos.
+func checkBlockRate(results *benchmarkResults, expected, tolerance float64) error {
+	observed := blockRate(results)
+	if math.Abs(observed-expected) > tolerance {
+		return fmt.Errorf("taxa de bloqueio de %.1f%%, fora do intervalo esperado de %.1f%% a %.1f%%",
+			observed, expected-tolerance, expected+tolerance)
+	}
+	return nil
 }
 
 func parseOptions() *benchmarkOptions {
@@ -96,6 +129,8 @@ func parseOptions() *benchmarkOptions {
 	tokenValue := flag.String("token-value", defaultTokenValue, "Valor do token a ser usado")
 	tokenHeader := flag.String("token-header", defaultTokenHeader, "Nome do header de token")
 	printProgress := flag.Bool("progress", true, "Mostrar progresso durante o teste")
+	expectBlockRate := flag.Float64("expect-block-rate", -1, "Porcentagem de bloqueios esperada; fora da tolerância, encerra com erro (negativa desliga)")
+	blockRateTolerance := flag.Float64("block-rate-tolerance", 5, "Diferença aceita na porcentagem de bloqueios, em pontos percentuais")
 
 	flag.Parse()
 
@@ -118,6 +153,9 @@ func parseOptions() *benchmarkOptions {
 		tokenValue:    *tokenValue,
 		tokenHeader:   *tokenHeader,
 		printProgress: *printProgress,
+
+		expectBlockRate:    *expectBlockRate,
+		blockRateTolerance: *blockRateTolerance,
 	}
 }
 
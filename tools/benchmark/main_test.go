@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_CheckBlockRate verifica a comparação da taxa de bloqueio com a esperada e a tolerância
+func Test_CheckBlockRate(t *testing.T) {
+	results := &benchmarkResults{totalRequests: 200, ratelimitedReqs: 180}
+	assert.InDelta(t, 90, blockRate(results), 1e-9)
+
+	for _, tc := range []struct {
+		expected, tolerance float64
+		ok                  bool
+	}{
+		{90, 0, true},
+		{95, 5, true},
+		{85, 5, true},
+		{95, 4.9, false},
+		{80, 5, false},
+		{0, 10, false},
+	} {
+		err := checkBlockRate(results, tc.expected, tc.tolerance)
+		if tc.ok {
+			assert.NoError(t, err, "%.1f ± %.1f", tc.expected, tc.tolerance)
+		} else {
+			assert.ErrorContains(t, err, "fora do intervalo esperado", "%.1f ± %.1f", tc.expected, tc.tolerance)
+		}
+	}
+
+	// Sem requisições, a taxa é zero
+	assert.NoError(t, checkBlockRate(&benchmarkResults{}, 0, 0))
+}